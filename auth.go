@@ -0,0 +1,190 @@
+package surrealdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/surrealdb/surrealdb.go"
+)
+
+// Auth selects how the driver authenticates every connection it opens. Use one
+// of RootAuth, NamespaceAuth, DatabaseAuth, RecordAuth or TokenAuth:
+//
+//	surrealdb.New(surrealdb.Config{
+//	    Endpoint:  "ws://localhost:8000/rpc",
+//	    Namespace: "app",
+//	    Database:  "app",
+//	    Auth:      surrealdb.DatabaseAuth{Username: "svc", Password: "secret"},
+//	})
+//
+// Only RootAuth creates the namespace and database on connect; the other modes
+// run with least privilege and expect both to exist already.
+type Auth interface {
+	// authenticate signs conn in and returns the session token, if the server
+	// issued one.
+	authenticate(ctx context.Context, conn signer, ns, db string) (string, error)
+}
+
+// signer is the sign-in surface shared by *surrealdb.DB and an attached
+// *surrealdb.Session, so one Auth can authenticate either.
+type signer interface {
+	SignIn(ctx context.Context, authData any) (string, error)
+	SignUp(ctx context.Context, authData any) (string, error)
+	Authenticate(ctx context.Context, token string) error
+}
+
+// RootAuth signs in as a root-level system user.
+type RootAuth struct {
+	Username string
+	Password string
+}
+
+func (a RootAuth) authenticate(ctx context.Context, conn signer, _, _ string) (string, error) {
+	if a.Username == "" || a.Password == "" {
+		return "", errors.New("username and password must be provided")
+	}
+	return conn.SignIn(ctx, surrealdb.Auth{Username: a.Username, Password: a.Password})
+}
+
+// NamespaceAuth signs in as a system user defined on the configured namespace
+// (DEFINE USER ... ON NAMESPACE).
+type NamespaceAuth struct {
+	Username string
+	Password string
+}
+
+func (a NamespaceAuth) authenticate(ctx context.Context, conn signer, ns, _ string) (string, error) {
+	if a.Username == "" || a.Password == "" {
+		return "", errors.New("username and password must be provided")
+	}
+	return conn.SignIn(ctx, surrealdb.Auth{Namespace: ns, Username: a.Username, Password: a.Password})
+}
+
+// DatabaseAuth signs in as a system user defined on the configured database
+// (DEFINE USER ... ON DATABASE).
+type DatabaseAuth struct {
+	Username string
+	Password string
+}
+
+func (a DatabaseAuth) authenticate(ctx context.Context, conn signer, ns, db string) (string, error) {
+	if a.Username == "" || a.Password == "" {
+		return "", errors.New("username and password must be provided")
+	}
+	return conn.SignIn(ctx, surrealdb.Auth{Namespace: ns, Database: db, Username: a.Username, Password: a.Password})
+}
+
+// RecordAuth signs in through a record access method
+// (DEFINE ACCESS ... ON DATABASE TYPE RECORD SIGNIN ... SIGNUP ...). Params are
+// passed to the SIGNIN/SIGNUP query as variables, e.g. {"email": ..., "pass": ...}.
+//
+// With SignUp set, the first connection runs SIGNUP instead of SIGNIN; further
+// pooled connections reuse the token it returned.
+type RecordAuth struct {
+	Access string
+	Params map[string]interface{}
+	SignUp bool
+}
+
+func (a RecordAuth) authenticate(ctx context.Context, conn signer, ns, db string) (string, error) {
+	if a.Access == "" {
+		return "", errors.New("record access method must be provided")
+	}
+	vars := make(map[string]interface{}, len(a.Params)+3)
+	for k, v := range a.Params {
+		vars[k] = v
+	}
+	vars["NS"] = ns
+	vars["DB"] = db
+	vars["AC"] = a.Access
+	if a.SignUp {
+		return conn.SignUp(ctx, vars)
+	}
+	return conn.SignIn(ctx, vars)
+}
+
+// TokenAuth authenticates with a pre-issued JWT, e.g. one minted by an
+// external identity provider and verified by DEFINE ACCESS ... TYPE JWT.
+type TokenAuth struct {
+	Token string
+}
+
+func (a TokenAuth) authenticate(ctx context.Context, conn signer, _, _ string) (string, error) {
+	if a.Token == "" {
+		return "", errors.New("token must be provided")
+	}
+	if err := conn.Authenticate(ctx, a.Token); err != nil {
+		return "", err
+	}
+	return a.Token, nil
+}
+
+// pooledAuth returns the Auth used for connections after the first. A record
+// SIGNUP must only run once, so later connections authenticate with the token
+// the signup issued.
+func (dialector *Dialector) pooledAuth() Auth {
+	if ra, ok := dialector.Auth.(RecordAuth); ok && ra.SignUp && dialector.token != "" {
+		return TokenAuth{Token: dialector.token}
+	}
+	return dialector.Auth
+}
+
+// ownsCredentials reports whether the dialector can sign connections in by
+// itself, as opposed to wrapping a caller-supplied Conn.
+func (dialector *Dialector) ownsCredentials() bool {
+	return dialector.Auth != nil || dialector.Credentials != nil
+}
+
+// currentAuth returns the credentials to sign a connection in with: a fresh
+// result from the Credentials provider when one is set, otherwise the static
+// Auth (pooledAuth for every connection but the first).
+func (dialector *Dialector) currentAuth(ctx context.Context, pooled bool) (Auth, error) {
+	if dialector.Credentials != nil {
+		auth, err := dialector.Credentials(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("surrealdb: credentials provider: %w", err)
+		}
+		if auth == nil {
+			return nil, errors.New("surrealdb: credentials provider returned no Auth")
+		}
+		return auth, nil
+	}
+	if pooled {
+		return dialector.pooledAuth(), nil
+	}
+	return dialector.Auth, nil
+}
+
+// signIn authenticates conn with the current credentials. It returns the
+// session token, if any, and whether they are root credentials (which may
+// create the namespace and database).
+func (dialector *Dialector) signIn(ctx context.Context, conn signer, pooled bool) (token string, root bool, err error) {
+	auth, err := dialector.currentAuth(ctx, pooled)
+	if err != nil {
+		return "", false, err
+	}
+	_, root = auth.(RootAuth)
+	token, err = auth.authenticate(ctx, conn, dialector.namespace, dialector.database)
+	return token, root, err
+}
+
+// reauthConn signs a pooled connection in again, e.g. after its token
+// expired, and restores its namespace/database.
+func (dialector *Dialector) reauthConn(ctx context.Context, conn *surrealdb.DB) error {
+	if _, _, err := dialector.signIn(ctx, conn, true); err != nil {
+		return err
+	}
+	return conn.Use(ctx, dialector.namespace, dialector.database)
+}
+
+// isSessionExpired reports whether err is the server rejecting a statement
+// because the session's token has expired.
+func isSessionExpired(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "expired") && (strings.Contains(msg, "token") || strings.Contains(msg, "session"))
+}
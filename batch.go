@@ -7,7 +7,6 @@ import (
 	"reflect"
 	"sync"
 
-	"github.com/surrealdb/surrealdb.go"
 	sdkModels "github.com/surrealdb/surrealdb.go/pkg/models"
 	"gorm.io/gorm"
 	gormSchema "gorm.io/gorm/schema"
@@ -88,13 +87,16 @@ func CreateMany(db *gorm.DB, models interface{}) error {
 
 	table := sdkModels.Table(tableName)
 	// Participate in an open interactive transaction when CreateMany is called
-	// inside db.Transaction(...); otherwise use the shared connection.
-	var result *[]interface{}
+	// inside db.Transaction(...); otherwise use a pooled connection.
+	var target rpcTarget
+	release := func() {}
 	if txConn, ok := txFromStatement(db); ok {
-		result, err = surrealdb.Insert[interface{}](ctx, txConn.SDKTx(), table, objects)
-	} else {
-		result, err = surrealdb.Insert[interface{}](ctx, dialector.Conn, table, objects)
+		target = rpcTarget{tx: txConn.SDKTx()}
+	} else if target, release, err = dialector.acquire(ctx); err != nil {
+		return err
 	}
+	defer release()
+	result, err := insertOn[interface{}](ctx, target, table, objects)
 	if err != nil {
 		return err
 	}
@@ -1,6 +1,7 @@
 package surrealdb
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"github.com/surrealdb/surrealdb.go"
 	sdkModels "github.com/surrealdb/surrealdb.go/pkg/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 
 	localModels "github.com/dailaim/surrealdb-gorm/models"
 	TypesM "github.com/dailaim/surrealdb-gorm/types"
@@ -29,6 +31,13 @@ func CreateCallback(db *gorm.DB) {
 		db.AddError(errors.New("surrealdb connection not initialized"))
 		return
 	}
+	// Every path below runs on the open transaction or one pooled connection.
+	target, release, err := dialector.statementTarget(db)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	defer release()
 
 	// Trace the create to GORM's logger so it shows under db.Debug() like the
 	// query/update/delete paths. Creates go through the SDK, not executeSQL, so
@@ -70,78 +79,7 @@ func CreateCallback(db *gorm.DB) {
 			hasTimestamps := false
 			if db.Statement.Schema != nil {
 				hasTimestamps = db.Statement.Schema.LookUpField("CreatedAt") != nil
-				skipFields := map[string]bool{"id": true, "in": true, "out": true,
-					"created_at": true, "updated_at": true, "deleted_at": true}
-				for _, field := range db.Statement.Schema.Fields {
-					if field.DBName == "" || skipFields[field.DBName] {
-						continue
-					}
-					if field.StructField.Anonymous {
-						continue
-					}
-					val, isZero := field.ValueOf(db.Statement.Context, reflectValue)
-					if !isZero {
-						extraData[field.DBName] = val
-					}
-				}
-			}
-
-			// If timestamps are present, use native RELATE with time::now() instead
-			// of InsertRelation which ignores extra fields like created_at.
-			if hasTimestamps {
-				params := make(map[string]interface{})
-				setParts := []string{"created_at = time::now()", "updated_at = time::now()"}
-				i := 0
-				for k, v := range extraData {
-					paramKey := fmt.Sprintf("p%d", i)
-					params[paramKey] = TypesM.ToSDKValue(v)
-					setParts = append(setParts, fmt.Sprintf("%s = $%s", k, paramKey))
-					i++
-				}
-				sql := fmt.Sprintf("RELATE %s -> %s -> %s SET %s",
-					inID.String(), db.Statement.Table, outID.String(),
-					strings.Join(setParts, ", "))
-				var results *[]surrealdb.QueryResult[interface{}]
-				var err error
-				if txConn, ok := db.Statement.ConnPool.(*SurrealTx); ok {
-					results, err = surrealdb.Query[interface{}](db.Statement.Context, txConn.SDKTx(), sql, params)
-				} else {
-					results, err = surrealdb.Query[interface{}](db.Statement.Context, dialector.Conn, sql, params)
-				}
-				if err != nil {
-					db.AddError(err)
-					return
-				}
-				if len(*results) > 0 && (*results)[0].Status != "OK" {
-					db.AddError(fmt.Errorf("relate error: %v", (*results)[0]))
-					return
-				}
-				// Write the created edge (id, in, out, timestamps) back into dest
-				// so callers see the populated ID after db.Create(&edge).
-				if len(*results) > 0 && (*results)[0].Result != nil {
-					val := reflect.ValueOf((*results)[0].Result)
-					for val.Kind() == reflect.Pointer || val.Kind() == reflect.Interface {
-						if val.IsNil() {
-							break
-						}
-						val = val.Elem()
-					}
-					var single interface{}
-					if val.IsValid() && (val.Kind() == reflect.Slice || val.Kind() == reflect.Array) {
-						if val.Len() > 0 {
-							single = val.Index(0).Interface()
-						}
-					} else if val.IsValid() {
-						single = val.Interface()
-					}
-					if single != nil {
-						if b, err := json.Marshal(single); err == nil {
-							_ = json.Unmarshal(b, db.Statement.Dest)
-						}
-					}
-				}
-				db.RowsAffected = 1
-				return
+				extraData = edgeData(db.Statement.Context, db.Statement.Schema, reflectValue)
 			}
 
 			rel := &surrealdb.Relationship{
@@ -150,38 +88,24 @@ func CreateCallback(db *gorm.DB) {
 				Relation: sdkModels.Table(db.Statement.Table),
 				Data:     extraData,
 			}
-			var result *interface{}
-			var err error
-			if txConn, ok := db.Statement.ConnPool.(*SurrealTx); ok {
-				result, err = surrealdb.InsertRelation[interface{}](db.Statement.Context, txConn.SDKTx(), rel)
-			} else {
-				result, err = surrealdb.InsertRelation[interface{}](db.Statement.Context, dialector.Conn, rel)
+			var model reflect.Type
+			if db.Statement.Schema != nil {
+				model = db.Statement.Schema.ModelType
 			}
+			result, err := dialector.relateEdge(db.Statement.Context, target, model, rel, func() (interface{}, error) {
+				// If timestamps are present, use native RELATE with time::now() instead
+				// of InsertRelation which ignores extra fields like created_at.
+				if hasTimestamps {
+					return relateSet(db.Statement.Context, target, &inID.RecordID, db.Statement.Table, &outID.RecordID, extraData, true)
+				}
+				return insertRelationOn[interface{}](db.Statement.Context, target, rel)
+			})
 			if err != nil {
 				db.AddError(err)
 				return
 			}
-			if result != nil {
-				val := reflect.ValueOf(result)
-				if val.Kind() == reflect.Pointer {
-					val = val.Elem()
-				}
-				if val.Kind() == reflect.Interface {
-					val = val.Elem()
-				}
-				var single interface{}
-				if val.Kind() == reflect.Slice && val.Len() > 0 {
-					single = val.Index(0).Interface()
-				} else if val.IsValid() {
-					single = val.Interface()
-				}
-				if single != nil {
-					b, err := json.Marshal(single)
-					if err == nil {
-						_ = json.Unmarshal(b, db.Statement.Dest)
-					}
-				}
-			}
+			setEdgeDest(db, result)
+			db.RowsAffected = 1
 			return
 		}
 	}
@@ -189,6 +113,7 @@ func CreateCallback(db *gorm.DB) {
 	// Path B: GORM auto-generated join table struct (Association.Append for many2many edges).
 	if registeredName, isEdge := dialector.FindEdgeTable(db.Statement.Table); isEdge && db.Statement.Schema != nil {
 		var fkVals []*sdkModels.RecordID
+		ends := map[string]*sdkModels.RecordID{}
 		for _, field := range db.Statement.Schema.Fields {
 			if field.DBName == "" || field.DBName == "id" {
 				continue
@@ -198,12 +123,17 @@ func CreateCallback(db *gorm.DB) {
 				continue
 			}
 			if rid := extractRecordID(val); rid != nil {
-				fkVals = append(fkVals, rid)
-				if len(fkVals) == 2 {
-					break
+				ends[field.DBName] = rid
+				if len(fkVals) < 2 {
+					fkVals = append(fkVals, rid)
 				}
 			}
 		}
+		// Join columns named in and out say which end is which; the owner's
+		// foreign key comes first otherwise.
+		if ends["in"] != nil && ends["out"] != nil {
+			fkVals = []*sdkModels.RecordID{ends["in"], ends["out"]}
+		}
 		if len(fkVals) == 2 {
 			// Collect extra (non-FK, non-timestamp) fields from the struct.
 			skipDBNames := map[string]bool{
@@ -225,56 +155,22 @@ func CreateCallback(db *gorm.DB) {
 				extraData[field.DBName] = TypesM.ToSDKValue(v)
 			}
 
-			hasTimestamps := db.Statement.Schema.LookUpField("CreatedAt") != nil
-
-			params := make(map[string]interface{})
-			var setParts []string
-			if hasTimestamps {
-				setParts = append(setParts, "created_at = time::now()", "updated_at = time::now()")
-			}
-			i := 0
-			for k, v := range extraData {
-				paramKey := fmt.Sprintf("p%d", i)
-				params[paramKey] = v
-				setParts = append(setParts, fmt.Sprintf("%s = $%s", k, paramKey))
-				i++
+			rel := &surrealdb.Relationship{
+				In:       *fkVals[0],
+				Out:      *fkVals[1],
+				Relation: sdkModels.Table(registeredName),
+				Data:     extraData,
 			}
-
-			if len(setParts) > 0 {
-				sql := fmt.Sprintf("RELATE %s -> %s -> %s SET %s",
-					fkVals[0].String(), registeredName, fkVals[1].String(),
-					strings.Join(setParts, ", "))
-				var results *[]surrealdb.QueryResult[interface{}]
-				var err error
-				if txConn, ok := db.Statement.ConnPool.(*SurrealTx); ok {
-					results, err = surrealdb.Query[interface{}](db.Statement.Context, txConn.SDKTx(), sql, params)
-				} else {
-					results, err = surrealdb.Query[interface{}](db.Statement.Context, dialector.Conn, sql, params)
-				}
-				if err != nil {
-					db.AddError(err)
-					return
-				}
-				if len(*results) > 0 && (*results)[0].Status != "OK" {
-					db.AddError(fmt.Errorf("relate error: %v", (*results)[0]))
-					return
-				}
-			} else {
-				rel := &surrealdb.Relationship{
-					In:       *fkVals[0],
-					Out:      *fkVals[1],
-					Relation: sdkModels.Table(registeredName),
-				}
-				var err error
-				if txConn, ok := db.Statement.ConnPool.(*SurrealTx); ok {
-					_, err = surrealdb.InsertRelation[interface{}](db.Statement.Context, txConn.SDKTx(), rel)
-				} else {
-					_, err = surrealdb.InsertRelation[interface{}](db.Statement.Context, dialector.Conn, rel)
-				}
-				if err != nil {
-					db.AddError(err)
-					return
+			hasTimestamps := db.Statement.Schema.LookUpField("CreatedAt") != nil
+			_, err := dialector.relateEdge(db.Statement.Context, target, db.Statement.Schema.ModelType, rel, func() (interface{}, error) {
+				if hasTimestamps || len(extraData) > 0 {
+					return relateSet(db.Statement.Context, target, fkVals[0], registeredName, fkVals[1], extraData, hasTimestamps)
 				}
+				return insertRelationOn[interface{}](db.Statement.Context, target, rel)
+			})
+			if err != nil {
+				db.AddError(err)
+				return
 			}
 			db.RowsAffected = 1
 			return
@@ -326,13 +222,7 @@ func CreateCallback(db *gorm.DB) {
 
 		// Route through the interactive transaction if one is open so bulk
 		// inserts participate in db.Transaction(...).
-		var created *[]interface{}
-		var err error
-		if txConn, ok := db.Statement.ConnPool.(*SurrealTx); ok {
-			created, err = surrealdb.Insert[interface{}](db.Statement.Context, txConn.SDKTx(), table, objects)
-		} else {
-			created, err = surrealdb.Insert[interface{}](db.Statement.Context, dialector.Conn, table, objects)
-		}
+		created, err := insertOn[interface{}](db.Statement.Context, target, table, objects)
 		if err != nil {
 			db.AddError(err)
 			return
@@ -402,20 +292,13 @@ func CreateCallback(db *gorm.DB) {
 			createData = dataMap
 		}
 
-		// Use sdkTx if inside a GORM transaction so the CREATE participates in
-		// the open transaction (read-your-own-writes).
+		// target is the sdkTx if inside a GORM transaction so the CREATE
+		// participates in the open transaction (read-your-own-writes).
 		var created *interface{}
-		var err error
-		if txConn, ok := db.Statement.ConnPool.(*SurrealTx); ok {
-			if whatRecord != nil {
-				created, err = surrealdb.Update[interface{}](db.Statement.Context, txConn.SDKTx(), *whatRecord, createData)
-			} else {
-				created, err = surrealdb.Create[interface{}](db.Statement.Context, txConn.SDKTx(), sdkModels.Table(whatTable), createData)
-			}
-		} else if whatRecord != nil {
-			created, err = surrealdb.Update[interface{}](db.Statement.Context, dialector.Conn, *whatRecord, createData)
+		if whatRecord != nil {
+			created, err = updateOn[interface{}](db.Statement.Context, target, *whatRecord, createData)
 		} else {
-			created, err = surrealdb.Create[interface{}](db.Statement.Context, dialector.Conn, sdkModels.Table(whatTable), createData)
+			created, err = createOn[interface{}](db.Statement.Context, target, sdkModels.Table(whatTable), createData)
 		}
 
 		if err != nil {
@@ -478,3 +361,79 @@ func CreateCallback(db *gorm.DB) {
 
 	db.RowsAffected = 1
 }
+
+// setEdgeDest writes the edge a statement returned (id, in, out,
+// timestamps) back into dest, so callers see the populated ID after
+// db.Create(&edge).
+func setEdgeDest(db *gorm.DB, result interface{}) {
+	val := reflect.ValueOf(result)
+	for val.Kind() == reflect.Pointer || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return
+		}
+		val = val.Elem()
+	}
+	var single interface{}
+	if val.Kind() == reflect.Slice || val.Kind() == reflect.Array {
+		if val.Len() > 0 {
+			single = val.Index(0).Interface()
+		}
+	} else if val.IsValid() {
+		single = val.Interface()
+	}
+	if single != nil {
+		if b, err := json.Marshal(single); err == nil {
+			_ = json.Unmarshal(b, db.Statement.Dest)
+		}
+	}
+}
+
+// edgeData returns the payload of an edge model value rv: its non-zero
+// fields other than id, in, out, the timestamps and embedded structs.
+func edgeData(ctx context.Context, s *schema.Schema, rv reflect.Value) map[string]interface{} {
+	data := make(map[string]interface{})
+	skipFields := map[string]bool{"id": true, "in": true, "out": true,
+		"created_at": true, "updated_at": true, "deleted_at": true}
+	for _, field := range s.Fields {
+		if field.DBName == "" || skipFields[field.DBName] || field.StructField.Anonymous {
+			continue
+		}
+		if val, isZero := field.ValueOf(ctx, rv); !isZero {
+			data[field.DBName] = val
+		}
+	}
+	return data
+}
+
+// relateSet creates the edge in->edge->out with RELATE ... SET, setting data
+// and, with timestamps, created_at and updated_at to the server's time. It
+// returns the statement's result, the created edge.
+func relateSet(ctx context.Context, target rpcTarget, in *sdkModels.RecordID, edge string, out *sdkModels.RecordID, data map[string]interface{}, timestamps bool) (interface{}, error) {
+	params := make(map[string]interface{})
+	var setParts []string
+	if timestamps {
+		setParts = append(setParts, "created_at = time::now()", "updated_at = time::now()")
+	}
+	i := 0
+	for k, v := range data {
+		paramKey := fmt.Sprintf("p%d", i)
+		params[paramKey] = TypesM.ToSDKValue(v)
+		setParts = append(setParts, fmt.Sprintf("%s = $%s", k, paramKey))
+		i++
+	}
+	sql := fmt.Sprintf("RELATE %s -> `%s` -> %s", in.String(), edge, out.String())
+	if len(setParts) > 0 {
+		sql += " SET " + strings.Join(setParts, ", ")
+	}
+	results, err := queryOn[interface{}](ctx, target, sql, params)
+	if err != nil {
+		return nil, err
+	}
+	if len(*results) == 0 {
+		return nil, nil
+	}
+	if (*results)[0].Status != "OK" {
+		return nil, fmt.Errorf("relate error: %v", (*results)[0])
+	}
+	return (*results)[0].Result, nil
+}
@@ -1,256 +1,91 @@
 package surrealdb
 
 import (
-	"fmt"
 	"reflect"
 
 	"github.com/surrealdb/surrealdb.go"
 	sdkModels "github.com/surrealdb/surrealdb.go/pkg/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
-// edgeAssocDeleteCallback handles Association("X").Delete(&y) for edge tables.
-func edgeAssocDeleteCallback(db *gorm.DB) {
-	if db.Error != nil {
-		return
-	}
-	assocMode, _ := db.Statement.Settings.Load("gorm:association:delete")
-	if assocMode == nil {
-		return
-	}
-	dialector := db.Dialector.(*Dialector)
-	if db.Statement.Schema == nil {
-		return
-	}
-	for _, rel := range db.Statement.Schema.Relationships.Many2Many {
-		if rel.JoinTable == nil {
-			continue
-		}
-		registeredEdge, ok := dialector.FindEdgeTable(rel.JoinTable.Table)
-		if !ok {
-			continue
-		}
-		rv := db.Statement.ReflectValue
-		for rv.Kind() == reflect.Pointer {
-			rv = rv.Elem()
-		}
-		var inID *sdkModels.RecordID
-		for _, ref := range rel.References {
-			if ref.OwnPrimaryKey {
-				v, isZero := ref.PrimaryKey.ValueOf(db.Statement.Context, rv)
-				if !isZero {
-					inID = extractRecordID(v)
-				}
-				break
-			}
-		}
-		if inID == nil {
-			continue
-		}
-
-		destVal := reflect.ValueOf(db.Statement.Dest)
-		for destVal.Kind() == reflect.Pointer {
-			destVal = destVal.Elem()
-		}
-		var outIDs []*sdkModels.RecordID
-		if destVal.Kind() == reflect.Slice {
-			for i := 0; i < destVal.Len(); i++ {
-				elem := destVal.Index(i)
-				for elem.Kind() == reflect.Pointer {
-					elem = elem.Elem()
-				}
-				for _, ref := range rel.References {
-					if !ref.OwnPrimaryKey && ref.PrimaryValue == "" {
-						v, isZero := ref.PrimaryKey.ValueOf(db.Statement.Context, elem)
-						if !isZero {
-							if rid := extractRecordID(v); rid != nil {
-								outIDs = append(outIDs, rid)
-							}
-						}
-						break
-					}
-				}
-			}
-		} else if destVal.IsValid() {
-			for _, ref := range rel.References {
-				if !ref.OwnPrimaryKey && ref.PrimaryValue == "" {
-					v, isZero := ref.PrimaryKey.ValueOf(db.Statement.Context, destVal)
-					if !isZero {
-						if rid := extractRecordID(v); rid != nil {
-							outIDs = append(outIDs, rid)
-						}
-					}
-					break
-				}
-			}
-		}
-		for _, outID := range outIDs {
-			results, err := surrealdb.Query[interface{}](
-				db.Statement.Context, dialector.Conn,
-				fmt.Sprintf("DELETE %s WHERE in = $in AND out = $out", registeredEdge),
-				map[string]interface{}{"in": inID, "out": outID},
-			)
-			if err != nil {
-				db.AddError(err)
-				return
-			}
-			if len(*results) > 0 && (*results)[0].Status != "OK" {
-				db.AddError(fmt.Errorf("edge assoc delete error: %v", (*results)[0]))
-				return
+// edgeSides returns the edge columns holding the owner and the associated
+// records of a many2many relation stored in an edge table: in and out, or
+// out and in when the owner's join foreign key is out, as in a reverse
+// relation such as a product's buyers.
+func edgeSides(rel *schema.Relationship) (owner, other string) {
+	for _, ref := range rel.References {
+		if ref.OwnPrimaryKey {
+			if ref.ForeignKey != nil && ref.ForeignKey.DBName == "out" {
+				return "out", "in"
 			}
+			break
 		}
-		db.Statement.SQL.WriteString("-- edge assoc delete handled")
-		return
 	}
+	return "in", "out"
 }
 
-// edgeAssocReplaceCallback handles Association("X").Replace(&y) for edge tables.
-func edgeAssocReplaceCallback(db *gorm.DB) {
-	if db.Error != nil {
-		return
-	}
-	assocMode, _ := db.Statement.Settings.Load("gorm:association:replace")
-	if assocMode == nil {
-		return
-	}
-	dialector := db.Dialector.(*Dialector)
-	if db.Statement.Schema == nil {
-		return
+// edgeRelationship is the edge joining owner to other in rel's direction.
+func edgeRelationship(rel *schema.Relationship, edge string, owner, other *sdkModels.RecordID) *surrealdb.Relationship {
+	r := &surrealdb.Relationship{In: *owner, Out: *other, Relation: sdkModels.Table(edge)}
+	if side, _ := edgeSides(rel); side == "out" {
+		r.In, r.Out = *other, *owner
 	}
-	for _, rel := range db.Statement.Schema.Relationships.Many2Many {
-		if rel.JoinTable == nil {
-			continue
-		}
-		registeredEdge, ok := dialector.FindEdgeTable(rel.JoinTable.Table)
-		if !ok {
-			continue
-		}
-		rv := db.Statement.ReflectValue
-		for rv.Kind() == reflect.Pointer {
-			rv = rv.Elem()
-		}
-		var inID *sdkModels.RecordID
-		for _, ref := range rel.References {
-			if ref.OwnPrimaryKey {
-				v, isZero := ref.PrimaryKey.ValueOf(db.Statement.Context, rv)
-				if !isZero {
-					inID = extractRecordID(v)
-				}
-				break
-			}
-		}
-		if inID == nil {
-			continue
-		}
-
-		results, err := surrealdb.Query[interface{}](
-			db.Statement.Context, dialector.Conn,
-			fmt.Sprintf("DELETE %s WHERE in = $in", registeredEdge),
-			map[string]interface{}{"in": inID},
-		)
-		if err != nil {
-			db.AddError(err)
-			return
-		}
-		if len(*results) > 0 && (*results)[0].Status != "OK" {
-			db.AddError(fmt.Errorf("edge assoc replace (delete phase) error: %v", (*results)[0]))
-			return
-		}
+	return r
+}
 
-		destVal := reflect.ValueOf(db.Statement.Dest)
-		for destVal.Kind() == reflect.Pointer {
-			destVal = destVal.Elem()
-		}
-		var newOutIDs []*sdkModels.RecordID
-		if destVal.Kind() == reflect.Slice {
-			for i := 0; i < destVal.Len(); i++ {
-				elem := destVal.Index(i)
-				for elem.Kind() == reflect.Pointer {
-					elem = elem.Elem()
-				}
-				for _, ref := range rel.References {
-					if !ref.OwnPrimaryKey && ref.PrimaryValue == "" {
-						v, isZero := ref.PrimaryKey.ValueOf(db.Statement.Context, elem)
-						if !isZero {
-							if rid := extractRecordID(v); rid != nil {
-								newOutIDs = append(newOutIDs, rid)
-							}
-						}
-						break
-					}
-				}
-			}
-		}
-		for _, outID := range newOutIDs {
-			rel2 := &surrealdb.Relationship{
-				In:       *inID,
-				Out:      *outID,
-				Relation: sdkModels.Table(registeredEdge),
-			}
-			if _, err := surrealdb.InsertRelation[interface{}](db.Statement.Context, dialector.Conn, rel2); err != nil {
-				db.AddError(err)
-				return
+// edgeOwnerID returns the record id of rel's owner rv, or nil.
+func edgeOwnerID(db *gorm.DB, rel *schema.Relationship, rv reflect.Value) *sdkModels.RecordID {
+	for rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	for _, ref := range rel.References {
+		if ref.OwnPrimaryKey {
+			if v, isZero := ref.PrimaryKey.ValueOf(db.Statement.Context, rv); !isZero {
+				return extractRecordID(v)
 			}
+			break
 		}
-		db.Statement.SQL.WriteString("-- edge assoc replace handled")
-		return
 	}
+	return nil
 }
 
-// edgeAssocCountCallback handles Association("X").Count() for edge tables.
-func edgeAssocCountCallback(db *gorm.DB) {
-	if db.Error != nil {
-		return
+// edgeRecords lists the records in v, a record or a slice of them.
+func edgeRecords(v reflect.Value) []reflect.Value {
+	for v.Kind() == reflect.Pointer && v.Elem().Kind() != reflect.Struct {
+		v = v.Elem()
 	}
-	_, isAssocCount := db.Statement.Settings.Load("gorm:association:count")
-	if !isAssocCount {
-		return
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		if v.IsValid() {
+			return []reflect.Value{v}
+		}
+		return nil
 	}
-	dialector := db.Dialector.(*Dialector)
-	if db.Statement.Schema == nil {
-		return
+	elems := make([]reflect.Value, v.Len())
+	for i := range elems {
+		elems[i] = v.Index(i)
 	}
-	for _, rel := range db.Statement.Schema.Relationships.Many2Many {
-		if rel.JoinTable == nil {
-			continue
-		}
-		registeredEdge, ok := dialector.FindEdgeTable(rel.JoinTable.Table)
-		if !ok {
-			continue
-		}
-		rv := db.Statement.ReflectValue
-		for rv.Kind() == reflect.Pointer {
-			rv = rv.Elem()
+	return elems
+}
+
+// edgeTargetIDs returns the record ids of the associated records in v, a
+// record or a slice of them.
+func edgeTargetIDs(db *gorm.DB, rel *schema.Relationship, v reflect.Value) []*sdkModels.RecordID {
+	var ids []*sdkModels.RecordID
+	for _, elem := range edgeRecords(v) {
+		for elem.Kind() == reflect.Pointer {
+			elem = elem.Elem()
 		}
-		var inID *sdkModels.RecordID
 		for _, ref := range rel.References {
-			if ref.OwnPrimaryKey {
-				v, isZero := ref.PrimaryKey.ValueOf(db.Statement.Context, rv)
-				if !isZero {
-					inID = extractRecordID(v)
+			if !ref.OwnPrimaryKey && ref.PrimaryValue == "" {
+				if v, isZero := ref.PrimaryKey.ValueOf(db.Statement.Context, elem); !isZero {
+					if rid := extractRecordID(v); rid != nil {
+						ids = append(ids, rid)
+					}
 				}
 				break
 			}
 		}
-		if inID == nil {
-			return
-		}
-		type countResult struct {
-			Count int64 `json:"count"`
-		}
-		results, err := surrealdb.Query[[]countResult](
-			db.Statement.Context, dialector.Conn,
-			fmt.Sprintf("SELECT count() FROM %s WHERE in = $in GROUP ALL", registeredEdge),
-			map[string]interface{}{"in": inID},
-		)
-		if err != nil {
-			db.AddError(err)
-			return
-		}
-		if len(*results) > 0 && (*results)[0].Status == "OK" && len((*results)[0].Result) > 0 {
-			db.RowsAffected = (*results)[0].Result[0].Count
-		}
-		db.Statement.SQL.WriteString("-- edge count handled")
-		return
 	}
+	return ids
 }
@@ -0,0 +1,184 @@
+package surrealdb
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"github.com/dailaim/surrealdb-gorm/clauses"
+	TypesM "github.com/dailaim/surrealdb-gorm/types"
+)
+
+// handleJoins converts db.Joins("Assoc") and db.InnerJoins("Assoc") to
+// SurrealQL. SurrealDB has no JOIN; related records are reached through the
+// record itself:
+//
+//   - a belongs-to relation or a types.Link field projects the linked
+//     record: author_id.* AS Author
+//   - a has-one relation selects the record pointing back:
+//     (SELECT * FROM profiles WHERE user_id = $parent.id LIMIT 1)[0] AS Profile
+//   - a many2many relation through a registered edge table traverses the
+//     graph: ->wishlists->products.* AS Products
+//
+// Join conditions filter the related record (or the traversal); InnerJoins
+// additionally drops records without a match.
+func handleJoins(db *gorm.DB) {
+	if db.Error != nil || len(db.Statement.Joins) == 0 {
+		return
+	}
+	if db.Statement.Schema == nil {
+		db.AddError(fmt.Errorf("surrealdb: Joins requires a model"))
+		return
+	}
+	dialector, _ := db.Dialector.(*Dialector)
+	counting := isCountSelect(db.Statement)
+
+	var fields []string
+	var vars []interface{}
+	for _, j := range db.Statement.Joins {
+		cond, condVars := joinCondition(db, j.Name, j.On)
+		value, alias, err := joinValue(db, dialector, j.Name, cond)
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+		if !counting {
+			fields = append(fields, fmt.Sprintf("%s AS %s", value, alias))
+			vars = append(vars, condVars...)
+		}
+		if j.JoinType == clause.InnerJoin {
+			db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+				clause.Expr{SQL: joinExists(value), Vars: condVars},
+			}})
+		}
+	}
+	if len(fields) > 0 {
+		db.Statement.AddClause(clauses.GraphSelect{Fields: fields, Vars: vars})
+	}
+	db.Statement.Joins = nil
+}
+
+// joinValue returns the SurrealQL expression for the records the
+// association name reaches from the current record, and the key it is
+// selected as.
+func joinValue(db *gorm.DB, dialector *Dialector, name, cond string) (value, alias string, err error) {
+	s := db.Statement.Schema
+	unsupported := func(reason string) error {
+		return fmt.Errorf("surrealdb: Joins(%q): %s", name, reason)
+	}
+
+	rel, ok := s.Relationships.Relations[name]
+	if !ok {
+		// A record link stored on the model itself.
+		field := s.LookUpField(name)
+		if field == nil || field.DBName == "" || !isLinkField(field) {
+			return "", "", unsupported("not an association, a types.Link field or an edge relation; raw JOINs are not SurrealQL")
+		}
+		return linkValue(db.Statement.Quote(field.DBName), cond), jsonKey(field), nil
+	}
+
+	alias = jsonKey(rel.Field)
+	switch rel.Type {
+	case schema.BelongsTo:
+		for _, ref := range rel.References {
+			if !ref.OwnPrimaryKey && ref.ForeignKey != nil {
+				return linkValue(db.Statement.Quote(ref.ForeignKey.DBName), cond), alias, nil
+			}
+		}
+	case schema.HasOne:
+		for _, ref := range rel.References {
+			if ref.OwnPrimaryKey && ref.ForeignKey != nil && ref.PrimaryKey != nil {
+				where := fmt.Sprintf("%s = $parent.%s", db.Statement.Quote(ref.ForeignKey.DBName), db.Statement.Quote(ref.PrimaryKey.DBName))
+				if cond != "" {
+					where += " AND (" + cond + ")"
+				}
+				return fmt.Sprintf("(SELECT * FROM %s WHERE %s LIMIT 1)[0]", db.Statement.Quote(rel.FieldSchema.Table), where), alias, nil
+			}
+		}
+	case schema.Many2Many:
+		if rel.JoinTable != nil && dialector != nil {
+			if edge, found := dialector.FindEdgeTable(rel.JoinTable.Table); found {
+				return edgePath(db, rel, edge, cond) + ".*", alias, nil
+			}
+		}
+		return "", "", unsupported("many2many joins need the join table registered as an edge table")
+	}
+	return "", "", unsupported(fmt.Sprintf("%s relations cannot be joined", rel.Type))
+}
+
+// linkValue dereferences the record link in column, or when cond is set
+// selects the linked record only if it matches.
+func linkValue(column, cond string) string {
+	if cond == "" {
+		return column + ".*"
+	}
+	return fmt.Sprintf("(SELECT * FROM $parent.%s WHERE %s)[0]", column, cond)
+}
+
+// joinExists is the WHERE condition InnerJoins adds: the joined value
+// exists, or for a traversal is not empty.
+func joinExists(value string) string {
+	if strings.HasPrefix(value, "->") || strings.HasPrefix(value, "<-") {
+		return fmt.Sprintf("array::len(%s) > 0", strings.TrimSuffix(value, ".*"))
+	}
+	return fmt.Sprintf("%s IS NOT NONE", strings.TrimSuffix(value, ".*"))
+}
+
+// joinCondition renders the conditions passed as Joins("Assoc",
+// db.Where(...)) with `?` placeholders, unqualified, to be evaluated on the
+// related records.
+func joinCondition(db *gorm.DB, name string, on *clause.Where) (string, []interface{}) {
+	if on == nil || len(on.Exprs) == 0 {
+		return "", nil
+	}
+	stmt := &gorm.Statement{DB: db, Table: name, Clauses: map[string]clause.Clause{}}
+	if rel, ok := db.Statement.Schema.Relationships.Relations[name]; ok && rel.FieldSchema != nil {
+		stmt.Table, stmt.Schema = rel.FieldSchema.Table, rel.FieldSchema
+	}
+	on.Build(stmt)
+
+	toks := (&translator{table: stmt.Table}).unqualify(tokenizeSQL(stmt.SQL.String()))
+	var vars []interface{}
+	for i, t := range toks {
+		if t.kind != tokParam || !strings.HasPrefix(t.text, "$p") {
+			continue
+		}
+		n, err := strconv.Atoi(t.text[2:])
+		if err != nil || n < 1 || n > len(stmt.Vars) {
+			continue
+		}
+		vars = append(vars, stmt.Vars[n-1])
+		toks[i] = sqlToken{kind: tokPunct, text: "?", pre: t.pre}
+	}
+	return strings.TrimSpace(renderSQL(toks)), vars
+}
+
+// isLinkField reports whether field is a types.Link[T]. Instantiated
+// generic types are named Link[pkg.T].
+func isLinkField(field *schema.Field) bool {
+	t := field.IndirectFieldType
+	return t.Kind() == reflect.Struct && strings.HasPrefix(t.Name(), "Link[") && t.PkgPath() == reflect.TypeOf(TypesM.RecordID{}).PkgPath()
+}
+
+// jsonKey is the key encoding/json decodes into field.
+func jsonKey(field *schema.Field) string {
+	if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag != "" && tag != "-" {
+		return tag
+	}
+	return field.Name
+}
+
+// isCountSelect reports whether the statement is GORM's Count.
+func isCountSelect(stmt *gorm.Statement) bool {
+	sel, ok := stmt.Clauses["SELECT"].Expression.(clause.Select)
+	if !ok {
+		return false
+	}
+	expr, ok := sel.Expression.(clause.Expr)
+	return ok && strings.HasPrefix(strings.ToLower(expr.SQL), "count(")
+}
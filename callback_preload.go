@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 
 	"github.com/dailaim/surrealdb-gorm/clauses"
 )
@@ -29,32 +30,8 @@ func handlePreloadAsFetch(db *gorm.DB) {
 			if rel, ok := db.Statement.Schema.Relationships.Relations[name]; ok {
 				if rel.Type == "many_to_many" && rel.JoinTable != nil {
 					if registeredEdge, found := dialector.FindEdgeTable(rel.JoinTable.Table); found {
-						relatedTable := ""
-						if rel.FieldSchema != nil {
-							relatedTable = rel.FieldSchema.Table
-						} else {
-							relatedTable = db.NamingStrategy.TableName(name)
-						}
 						fieldAlias := db.NamingStrategy.ColumnName("", name)
-
-						forward := true
-						if rel.FieldSchema != nil && db.Statement.Schema != nil {
-							for _, ref := range rel.References {
-								if ref.OwnPrimaryKey {
-									if ref.ForeignKey != nil && ref.ForeignKey.DBName == "out" {
-										forward = false
-									}
-									break
-								}
-							}
-						}
-
-						var expr string
-						if forward {
-							expr = fmt.Sprintf("->%s->%s AS %s", registeredEdge, relatedTable, fieldAlias)
-						} else {
-							expr = fmt.Sprintf("<-%s<-%s AS %s", registeredEdge, relatedTable, fieldAlias)
-						}
+						expr := fmt.Sprintf("%s AS %s", edgePath(db, rel, registeredEdge, ""), fieldAlias)
 						graphFields = append(graphFields, expr)
 						continue
 					}
@@ -116,3 +93,23 @@ func handlePreloadAsFetch(db *gorm.DB) {
 	}
 	db.Statement.Preloads = nil
 }
+
+// edgePath returns the graph traversal from a record to the records of a
+// many2many relation stored in edge: ->edge->table, or <-edge<-table when
+// the record is the edge's out. A non-empty cond filters the related records.
+func edgePath(db *gorm.DB, rel *schema.Relationship, edge, cond string) string {
+	relatedTable := ""
+	if rel.FieldSchema != nil {
+		relatedTable = rel.FieldSchema.Table
+	} else {
+		relatedTable = db.NamingStrategy.TableName(rel.Name)
+	}
+	if cond != "" {
+		relatedTable = fmt.Sprintf("(%s WHERE %s)", relatedTable, cond)
+	}
+
+	if owner, _ := edgeSides(rel); owner == "in" {
+		return fmt.Sprintf("->%s->%s", edge, relatedTable)
+	}
+	return fmt.Sprintf("<-%s<-%s", edge, relatedTable)
+}
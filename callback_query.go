@@ -68,6 +68,14 @@ func optimizeFindByIDList(db *gorm.DB) {
 	db.Statement.SQL.WriteString(sql)
 }
 
+// queryClauses are the clauses a SELECT is built from, in SurrealQL's
+// order; see the clauses package for OMIT, WITH, SPLIT, TIMEOUT, PARALLEL
+// and TEMPFILES.
+var queryClauses = []string{
+	"SELECT", "OMIT", "FROM", "WITH", "WHERE", "SPLIT", "GROUP BY", "ORDER BY", "LIMIT",
+	"FOR", "INFO", "FETCH", "TIMEOUT", "PARALLEL", "TEMPFILES",
+}
+
 func QueryCallback(db *gorm.DB) {
 	if db.Error != nil {
 		return
@@ -84,27 +92,32 @@ func QueryCallback(db *gorm.DB) {
 
 	// Ensure default clauses for SELECT if missing
 	if len(db.Statement.BuildClauses) == 0 {
-		db.Statement.BuildClauses = []string{"SELECT", "FROM", "WHERE", "GROUP BY", "ORDER BY", "LIMIT", "FOR", "INFO", "FETCH"}
+		db.Statement.BuildClauses = queryClauses
 	}
 	if _, ok := db.Statement.Clauses["SELECT"]; !ok {
 		selectSQL := "*"
+		if cols := selectColumns(db.Statement); cols != "" {
+			selectSQL = cols
+		}
+		var vars []interface{}
 		if gs, ok := db.Statement.Clauses["GRAPH_SELECT"]; ok {
 			if gsExpr, ok := gs.Expression.(clauses.GraphSelect); ok {
 				for _, f := range gsExpr.Fields {
 					selectSQL += ", " + f
 				}
+				vars = gsExpr.Vars
 			}
 		}
-		db.Statement.AddClause(clause.Select{Expression: clause.Expr{SQL: selectSQL}})
+		db.Statement.AddClause(clause.Select{Expression: clause.Expr{SQL: selectSQL, Vars: vars}})
 	} else if gs, ok := db.Statement.Clauses["GRAPH_SELECT"]; ok {
 		if gsExpr, ok := gs.Expression.(clauses.GraphSelect); ok && len(gsExpr.Fields) > 0 {
 			extra := strings.Join(gsExpr.Fields, ", ")
 			selClause := db.Statement.Clauses["SELECT"]
 			if expr, ok := selClause.Expression.(clause.Select); ok {
 				if sqlExpr, ok := expr.Expression.(clause.Expr); ok {
-					expr.Expression = clause.Expr{SQL: sqlExpr.SQL + ", " + extra}
+					expr.Expression = clause.Expr{SQL: sqlExpr.SQL + ", " + extra, Vars: append(sqlExpr.Vars, gsExpr.Vars...)}
 				} else {
-					expr.Expression = clause.Expr{SQL: "*, " + extra}
+					expr.Expression = clause.Expr{SQL: "*, " + extra, Vars: gsExpr.Vars}
 				}
 				selClause.Expression = expr
 				db.Statement.Clauses["SELECT"] = selClause
@@ -125,6 +138,35 @@ func QueryCallback(db *gorm.DB) {
 	executeSQL(db)
 }
 
+// bareColumnRe matches a plain column name.
+var bareColumnRe = regexp.MustCompile("^`?[A-Za-z_][A-Za-z0-9_]*`?$")
+
+// selectColumns renders Select("a", "b") when every entry is a model field
+// or a plain column name, as in a subquery's Select("id"). A grouped query
+// selects its entries as written, aggregates included. Anything else
+// returns "" and the query keeps selecting *.
+func selectColumns(stmt *gorm.Statement) string {
+	_, grouped := stmt.Clauses["GROUP BY"]
+	cols := make([]string, len(stmt.Selects))
+	for i, name := range stmt.Selects {
+		if stmt.Schema != nil {
+			if f := stmt.Schema.LookUpField(name); f != nil && f.DBName != "" {
+				cols[i] = stmt.Quote(f.DBName)
+				continue
+			}
+		}
+		if !bareColumnRe.MatchString(name) {
+			if !grouped {
+				return ""
+			}
+			cols[i] = name
+			continue
+		}
+		cols[i] = stmt.Quote(strings.Trim(name, "`"))
+	}
+	return strings.Join(cols, ", ")
+}
+
 func optimizeFindByID(db *gorm.DB) {
 	if len(db.Statement.Vars) >= 1 {
 		isRecordID := false
@@ -1,6 +1,8 @@
 package surrealdb
 
 import (
+	"database/sql"
+
 	"gorm.io/gorm"
 	"gorm.io/gorm/callbacks"
 )
@@ -8,35 +10,48 @@ import (
 // RowCallback backs db.Row() and db.Rows(). Now that QueryContext/QueryRowContext
 // return real *sql.Row / *sql.Rows (see sqldriver.go), this executes the built
 // statement and stores the result in Statement.Dest — mirroring GORM's default
-// RowQuery, with one addition: it yields to edgeAssocCountCallback, which already
-// produced the result for many2many association counts.
+// RowQuery.
 func RowCallback(db *gorm.DB) {
 	if db.Error != nil {
 		return
 	}
 
-	// Edge association count is fully handled upstream by edgeAssocCountCallback.
-	if _, isAssocCount := db.Statement.Settings.Load("gorm:association:count"); isAssocCount {
+	// db.Raw(...) has already populated Statement.SQL and runs as written; for
+	// db.Model(...).Rows() and Scan build the SELECT here.
+	if db.Statement.SQL.Len() > 0 {
+		if db.DryRun {
+			return
+		}
+		rowQuery(db, db.Statement.SQL.String(), db.Statement.Vars)
 		return
 	}
-
-	// db.Raw(...) has already populated Statement.SQL; for db.Model(...).Rows()
-	// build the SELECT here. (Raw is the primary supported path; built SELECTs do
-	// not receive the SurrealQL rewrites that executeSQL applies.)
-	if db.Statement.SQL.Len() == 0 {
-		callbacks.BuildQuerySQL(db)
+	if len(db.Statement.BuildClauses) == 0 {
+		db.Statement.BuildClauses = queryClauses
 	}
+	callbacks.BuildQuerySQL(db)
 	if db.DryRun || db.Error != nil {
 		return
 	}
 
-	sql := db.Statement.SQL.String()
+	// Translate the built SELECT like executeSQL does. The translator may drop
+	// or rewrite params, so they are passed by name rather than position.
+	params := statementParams(db.Statement.Vars)
+	query := db.Dialector.(*Dialector).newTranslator(db.Statement.Table, params).translate(db.Statement.SQL.String())
+	args := make([]interface{}, 0, len(params))
+	for name, v := range params {
+		args = append(args, sql.Named(name, v))
+	}
+	rowQuery(db, query, args)
+}
+
+// rowQuery runs query and stores the *sql.Rows or *sql.Row in Statement.Dest.
+func rowQuery(db *gorm.DB, query string, args []interface{}) {
 	if isRows, ok := db.Get("rows"); ok && isRows.(bool) {
 		db.Statement.Settings.Delete("rows")
 		db.Statement.Dest, db.Error = db.Statement.ConnPool.QueryContext(
-			db.Statement.Context, sql, db.Statement.Vars...)
+			db.Statement.Context, query, args...)
 	} else {
 		db.Statement.Dest = db.Statement.ConnPool.QueryRowContext(
-			db.Statement.Context, sql, db.Statement.Vars...)
+			db.Statement.Context, query, args...)
 	}
 }
@@ -3,8 +3,6 @@ package surrealdb
 import (
 	"reflect"
 
-	"github.com/surrealdb/surrealdb.go"
-	sdkModels "github.com/surrealdb/surrealdb.go/pkg/models"
 	"gorm.io/gorm"
 	"gorm.io/gorm/callbacks"
 	"gorm.io/gorm/clause"
@@ -21,10 +19,15 @@ func UpdateCallback(db *gorm.DB) {
 			rv = rv.Elem()
 		}
 		if rv.Kind() == reflect.Struct {
+			// Association.Append selects the one association it saves.
+			selectColumns, restricted := db.Statement.SelectAndOmitColumns(false, true)
 			for _, rel := range db.Statement.Schema.Relationships.Many2Many {
 				if rel.JoinTable == nil {
 					continue
 				}
+				if v, ok := selectColumns[rel.Name]; (ok && !v) || (!ok && restricted) {
+					continue
+				}
 				registeredEdge, ok := dialector.FindEdgeTable(rel.JoinTable.Table)
 				if !ok {
 					continue
@@ -37,48 +40,19 @@ func UpdateCallback(db *gorm.DB) {
 					continue
 				}
 
-				var inID *sdkModels.RecordID
-				for _, ref := range rel.References {
-					if ref.OwnPrimaryKey {
-						v, isZero := ref.PrimaryKey.ValueOf(db.Statement.Context, rv)
-						if !isZero {
-							inID = extractRecordID(v)
-						}
-						break
-					}
-				}
-				if inID == nil {
+				ownerID := edgeOwnerID(db, rel, rv)
+				if ownerID == nil {
 					continue
 				}
 
-				for i := 0; i < fieldVal.Len(); i++ {
-					elem := fieldVal.Index(i)
-					for elem.Kind() == reflect.Pointer {
-						elem = elem.Elem()
-					}
-					var outID *sdkModels.RecordID
-					for _, ref := range rel.References {
-						if !ref.OwnPrimaryKey && ref.PrimaryValue == "" {
-							v, isZero := ref.PrimaryKey.ValueOf(db.Statement.Context, elem)
-							if !isZero {
-								outID = extractRecordID(v)
-							}
-							break
-						}
-					}
-					if outID == nil {
-						continue
-					}
-					rel2 := &surrealdb.Relationship{
-						In:       *inID,
-						Out:      *outID,
-						Relation: sdkModels.Table(registeredEdge),
-					}
-					var relErr error
-					if txConn, ok := db.Statement.ConnPool.(*SurrealTx); ok {
-						_, relErr = surrealdb.InsertRelation[interface{}](db.Statement.Context, txConn.SDKTx(), rel2)
-					} else {
-						_, relErr = surrealdb.InsertRelation[interface{}](db.Statement.Context, dialector.Conn, rel2)
+				for _, otherID := range edgeTargetIDs(db, rel, fieldVal) {
+					rel2 := edgeRelationship(rel, registeredEdge, ownerID, otherID)
+					target, release, relErr := dialector.statementTarget(db)
+					if relErr == nil {
+						_, relErr = dialector.relateEdge(db.Statement.Context, target, joinEdgeModel(rel), rel2, func() (interface{}, error) {
+							return insertRelationOn[interface{}](db.Statement.Context, target, rel2)
+						})
+						release()
 					}
 					if relErr != nil {
 						db.AddError(relErr)
@@ -0,0 +1,90 @@
+package clauses
+
+import (
+	"strconv"
+
+	"gorm.io/gorm/clause"
+)
+
+// GraphFrom selects the records a graph path reaches from a record, e.g.
+//
+//	FROM $p1->follows->users[WHERE age > $p2]
+//
+// From is the record the path starts at and is bound as a var. With
+// Recurse set, Steps are repeated:
+//
+//	FROM $p1.{1..3+collect}(->manages->?)
+type GraphFrom struct {
+	From    interface{}
+	Steps   []GraphStep
+	Recurse *Recursion
+}
+
+// Recursion is the depth range and algorithm of a recursive graph path.
+// Max 0 leaves the depth unbounded. Collect returns every record reached
+// once; Shortest, a record id, the records on the shortest path to it.
+// Inclusive adds the starting record to either.
+type Recursion struct {
+	Min, Max  int
+	Collect   bool
+	Shortest  interface{}
+	Inclusive bool
+}
+
+// GraphStep is one hop of a graph path: Dir is "->", "<-" or "<->", Table
+// the edge or record table reached ("?" for any), and Where filters the
+// records of the hop.
+type GraphStep struct {
+	Dir   string
+	Table string
+	Where []clause.Expression
+}
+
+func (g GraphFrom) Name() string {
+	return "FROM"
+}
+
+func (g GraphFrom) Build(builder clause.Builder) {
+	builder.AddVar(builder, g.From)
+	if r := g.Recurse; r != nil {
+		builder.WriteString(".{")
+		r.build(builder)
+		builder.WriteString("}(")
+		defer builder.WriteByte(')')
+	}
+	for _, s := range g.Steps {
+		builder.WriteString(s.Dir)
+		builder.WriteString(s.Table)
+		if len(s.Where) > 0 {
+			builder.WriteString("[WHERE ")
+			clause.Where{Exprs: s.Where}.Build(builder)
+			builder.WriteByte(']')
+		}
+	}
+}
+
+func (g GraphFrom) MergeClause(c *clause.Clause) {
+	c.Expression = g
+}
+
+func (r Recursion) build(builder clause.Builder) {
+	if r.Min > 0 {
+		builder.WriteString(strconv.Itoa(r.Min))
+	}
+	if r.Max != r.Min || r.Max == 0 {
+		builder.WriteString("..")
+		if r.Max > 0 {
+			builder.WriteString(strconv.Itoa(r.Max))
+		}
+	}
+	if r.Collect {
+		builder.WriteString("+collect")
+	}
+	if r.Shortest != nil {
+		builder.WriteString("+shortest=")
+		builder.AddVar(builder, r.Shortest)
+	}
+	if r.Inclusive {
+		builder.WriteString("+inclusive")
+	}
+}
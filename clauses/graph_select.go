@@ -8,8 +8,11 @@ import (
 // the SELECT list.  Each entry is a raw expression, e.g.:
 //
 //	->wishlist->product AS products
+//
+// Vars bind the `?` placeholders of the fields, in order.
 type GraphSelect struct {
 	Fields []string
+	Vars   []interface{}
 }
 
 func (g GraphSelect) Name() string {
@@ -23,6 +26,7 @@ func (g GraphSelect) Build(builder clause.Builder) {
 func (g GraphSelect) MergeClause(c *clause.Clause) {
 	if v, ok := c.Expression.(GraphSelect); ok {
 		g.Fields = append(v.Fields, g.Fields...)
+		g.Vars = append(v.Vars, g.Vars...)
 	}
 	c.Expression = g
 }
@@ -0,0 +1,76 @@
+package clauses
+
+import (
+	"gorm.io/gorm/clause"
+)
+
+// OrderByColumn is an ORDER BY column with SurrealQL's sort modifiers.
+// Collate compares strings by Unicode collation (locale-aware, case
+// folded); Numeric compares the numbers embedded in strings, so "item2"
+// sorts before "item10".
+type OrderByColumn struct {
+	Column  clause.Column
+	Collate bool
+	Numeric bool
+	Desc    bool
+	// Reorder drops the columns ordered by before, as in GORM.
+	Reorder bool
+}
+
+// OrderBy is ORDER BY with SurrealQL's modifiers:
+//
+//	db.Clauses(clauses.OrderBy{Columns: []clauses.OrderByColumn{
+//		{Column: clause.Column{Name: "name"}, Collate: true},
+//	}}).Find(&users) // ORDER BY `name` COLLATE
+//
+// Rand orders randomly (ORDER BY RAND()) and replaces any other order. The
+// clause merges with db.Order in either sequence.
+type OrderBy struct {
+	Columns []OrderByColumn
+	Rand    bool
+}
+
+func (o OrderBy) Name() string {
+	return "ORDER BY"
+}
+
+func (o OrderBy) Build(builder clause.Builder) {
+	o.orderBy().Build(builder)
+}
+
+// MergeClause merges as the GORM clause.OrderBy it renders to, so db.Order
+// and this clause extend each other.
+func (o OrderBy) MergeClause(c *clause.Clause) {
+	o.orderBy().MergeClause(c)
+}
+
+// orderBy renders the modifiers into raw GORM order columns.
+func (o OrderBy) orderBy() clause.OrderBy {
+	if o.Rand {
+		return clause.OrderBy{Columns: []clause.OrderByColumn{
+			{Column: clause.Column{Name: "RAND()", Raw: true}, Reorder: true},
+		}}
+	}
+	columns := make([]clause.OrderByColumn, len(o.Columns))
+	for i, col := range o.Columns {
+		name := col.Column.Name
+		if !col.Column.Raw {
+			name = "`" + name + "`"
+			if t := col.Column.Table; t != "" && t != clause.CurrentTable {
+				name = "`" + t + "`." + name
+			}
+		}
+		if col.Collate {
+			name += " COLLATE"
+		}
+		if col.Numeric {
+			name += " NUMERIC"
+		}
+		columns[i] = clause.OrderByColumn{
+			Column:  clause.Column{Name: name, Raw: true},
+			Desc:    col.Desc,
+			Reorder: col.Reorder,
+		}
+	}
+	return clause.OrderBy{Columns: columns}
+}
@@ -0,0 +1,179 @@
+package clauses
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// SurrealQL SELECT clauses beyond GORM's own. QueryCallback builds them in
+// grammar order:
+//
+//	SELECT ... OMIT ... FROM ... WITH ... WHERE ... SPLIT ... GROUP BY ...
+//	ORDER BY ... LIMIT ... FETCH ... TIMEOUT ... PARALLEL TEMPFILES
+//
+// Use them through db.Clauses, e.g.
+//
+//	db.Clauses(clauses.Omit{Fields: []string{"password"}}).Find(&users)
+
+// Omit leaves fields out of the selected records, e.g. a password hash.
+type Omit struct {
+	Fields []string
+}
+
+func (o Omit) Name() string {
+	return "OMIT"
+}
+
+func (o Omit) Build(builder clause.Builder) {
+	builder.WriteString(strings.Join(o.Fields, ", "))
+}
+
+// MergeClause merges multiple OMIT clauses
+func (o Omit) MergeClause(c *clause.Clause) {
+	if v, ok := c.Expression.(Omit); ok {
+		o.Fields = append(v.Fields, o.Fields...)
+	}
+	c.Expression = o
+}
+
+// Split returns one record per value of each of the array fields.
+type Split struct {
+	Fields []string
+}
+
+func (s Split) Name() string {
+	return "SPLIT"
+}
+
+func (s Split) Build(builder clause.Builder) {
+	builder.WriteString(strings.Join(s.Fields, ", "))
+}
+
+// MergeClause merges multiple SPLIT clauses
+func (s Split) MergeClause(c *clause.Clause) {
+	if v, ok := c.Expression.(Split); ok {
+		s.Fields = append(v.Fields, s.Fields...)
+	}
+	c.Expression = s
+}
+
+// Timeout cancels the statement on the server when it runs longer than
+// Duration.
+type Timeout struct {
+	Duration time.Duration
+}
+
+func (t Timeout) Name() string {
+	return "TIMEOUT"
+}
+
+func (t Timeout) Build(builder clause.Builder) {
+	builder.WriteString(duration(t.Duration))
+}
+
+func (t Timeout) MergeClause(c *clause.Clause) {
+	c.Expression = t
+}
+
+// Parallel fetches the records of the statement in parallel.
+type Parallel struct{}
+
+func (Parallel) Name() string {
+	return "PARALLEL"
+}
+
+func (Parallel) Build(builder clause.Builder) {
+	builder.WriteString("PARALLEL")
+}
+
+// MergeClause clears the clause name: the keyword is the whole clause and
+// is written by Build, without the space GORM puts after a name.
+func (p Parallel) MergeClause(c *clause.Clause) {
+	c.Name = ""
+	c.Expression = p
+}
+
+// TempFiles lets the server process the statement on disk rather than in
+// memory.
+type TempFiles struct{}
+
+func (TempFiles) Name() string {
+	return "TEMPFILES"
+}
+
+func (TempFiles) Build(builder clause.Builder) {
+	builder.WriteString("TEMPFILES")
+}
+
+// MergeClause clears the clause name, see Parallel.
+func (t TempFiles) MergeClause(c *clause.Clause) {
+	c.Name = ""
+	c.Expression = t
+}
+
+// WithIndex restricts the query planner to the named indexes.
+type WithIndex struct {
+	Names []string
+}
+
+func (w WithIndex) Name() string {
+	return "WITH"
+}
+
+func (w WithIndex) Build(builder clause.Builder) {
+	builder.WriteString("INDEX ")
+	builder.WriteString(strings.Join(w.Names, ", "))
+}
+
+// MergeClause merges multiple WithIndex clauses; it replaces WithNoIndex.
+func (w WithIndex) MergeClause(c *clause.Clause) {
+	if v, ok := c.Expression.(WithIndex); ok {
+		w.Names = append(v.Names, w.Names...)
+	}
+	c.Expression = w
+}
+
+// WithNoIndex makes the query planner scan the table instead of using
+// indexes.
+type WithNoIndex struct{}
+
+func (WithNoIndex) Name() string {
+	return "WITH"
+}
+
+func (WithNoIndex) Build(builder clause.Builder) {
+	builder.WriteString("NOINDEX")
+}
+
+func (w WithNoIndex) MergeClause(c *clause.Clause) {
+	c.Expression = w
+}
+
+// durationUnits are SurrealQL's duration units, largest first.
+var durationUnits = []struct {
+	suffix string
+	d      time.Duration
+}{
+	{"h", time.Hour}, {"m", time.Minute}, {"s", time.Second},
+	{"ms", time.Millisecond}, {"us", time.Microsecond}, {"ns", time.Nanosecond},
+}
+
+// duration formats d as a SurrealQL duration literal, e.g. 1m30s or
+// 1s500ms.
+func duration(d time.Duration) string {
+	if d <= 0 {
+		return "0s"
+	}
+	var b strings.Builder
+	for _, u := range durationUnits {
+		if n := d / u.d; n > 0 {
+			b.WriteString(strconv.FormatInt(int64(n), 10))
+			b.WriteString(u.suffix)
+			d -= n * u.d
+		}
+	}
+	return b.String()
+}
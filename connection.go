@@ -2,15 +2,16 @@ package surrealdb
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/surrealdb/surrealdb.go"
 	"github.com/surrealdb/surrealdb.go/contrib/rews"
 	"github.com/surrealdb/surrealdb.go/pkg/connection"
-	"github.com/surrealdb/surrealdb.go/pkg/connection/gws"
 	"github.com/surrealdb/surrealdb.go/pkg/logger"
 )
 
@@ -29,20 +30,33 @@ const defaultReconnectInterval = 5 * time.Second
 // HTTP/embedded DSNs fall back to the standard connection since rews only wraps
 // WebSocket connections.
 func (dialector *Dialector) dialConn(ctx context.Context) (*surrealdb.DB, error) {
+	return dialector.dialEndpoint(ctx, dialector.DSN)
+}
+
+// dialEndpoint is dialConn for an arbitrary endpoint, e.g. a read replica.
+func (dialector *Dialector) dialEndpoint(ctx context.Context, endpoint string) (*surrealdb.DB, error) {
 	interval := dialector.ReconnectInterval
 	if interval == 0 {
 		interval = defaultReconnectInterval
 	}
 
-	u, err := url.ParseRequestURI(dialector.DSN)
+	u, err := url.ParseRequestURI(endpoint)
 	if err != nil {
 		return nil, err
 	}
 
+	// A replay answers from the golden file; there is nothing to dial.
+	if dialector.tape != nil && dialector.tape.replay {
+		return surrealdb.FromConnection(ctx, newReplayConn(connection.NewConfig(u), dialector.tape))
+	}
+
 	// rews only applies to WebSocket connections and only when reconnection is
 	// enabled; otherwise use the plain connection.
 	if interval < 0 || (u.Scheme != "ws" && u.Scheme != "wss") {
-		return surrealdb.FromEndpointURLString(ctx, dialector.DSN)
+		if dialector.TLSConfig != nil || dialector.tape != nil {
+			return dialector.dialPlain(ctx, u)
+		}
+		return surrealdb.FromEndpointURLString(ctx, endpoint)
 	}
 
 	conf := connection.NewConfig(u)
@@ -50,8 +64,12 @@ func (dialector *Dialector) dialConn(ctx context.Context) (*surrealdb.DB, error)
 	// query errors instead of noisy logs.
 	conf.Logger = logger.New(slog.NewTextHandler(io.Discard, nil))
 
+	watch := &connWatch{}
 	rewsConn := rews.New(
-		func(context.Context) (*gws.Connection, error) { return gws.New(conf), nil },
+		func(context.Context) (*watchedConn, error) {
+			socket := dialector.recorded(dialector.newSocket(conf), conf).(connection.WebSocketConnection)
+			return &watchedConn{WebSocketConnection: socket, watch: watch}, nil
+		},
 		interval,
 		conf.Unmarshaler,
 		conf.Logger,
@@ -59,5 +77,137 @@ func (dialector *Dialector) dialConn(ctx context.Context) (*surrealdb.DB, error)
 	if err := rewsConn.Connect(ctx); err != nil {
 		return nil, err
 	}
-	return surrealdb.FromConnection(ctx, rewsConn)
+	db, err := surrealdb.FromConnection(ctx, rewsConn)
+	if err != nil {
+		return nil, err
+	}
+	dialector.watches.Store(db, watch)
+	watch.setHandler(func(ev connEvent) { dialector.handleConnEvent(db, endpoint, ev) })
+	return db, nil
+}
+
+// ============================================================================
+// Reconnect detection
+// ============================================================================
+
+// connEvent is a lifecycle change of one logical (auto-reconnecting) connection.
+type connEvent int
+
+const (
+	connDropped connEvent = iota
+	connReconnected
+)
+
+// watchedConn is the raw WebSocket rews (re)dials. It reports connects and
+// drops to the connWatch shared by every socket of one logical connection, so
+// the driver can restore what rews does not: live queries issued by the
+// driver and attached sessions.
+type watchedConn struct {
+	connection.WebSocketConnection
+	watch *connWatch
+}
+
+func (c *watchedConn) Connect(ctx context.Context) error {
+	if err := c.WebSocketConnection.Connect(ctx); err != nil {
+		return err
+	}
+	c.watch.connected(c)
+	return nil
+}
+
+// IsClosed is polled by the rews reconnection loop, which makes it the point
+// where a dropped socket is first noticed.
+func (c *watchedConn) IsClosed() bool {
+	closed := c.WebSocketConnection.IsClosed()
+	if closed {
+		c.watch.dropped(c)
+	}
+	return closed
+}
+
+// connWatch tracks the current socket of one logical connection.
+type connWatch struct {
+	mu      sync.Mutex
+	current *watchedConn
+	down    bool
+	handler func(connEvent)
+}
+
+func (w *connWatch) setHandler(fn func(connEvent)) {
+	w.mu.Lock()
+	w.handler = fn
+	w.mu.Unlock()
+}
+
+// socket returns the WebSocket currently serving the connection.
+func (w *connWatch) socket() *watchedConn {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+func (w *connWatch) connected(c *watchedConn) {
+	w.mu.Lock()
+	reconnect := w.current != nil
+	w.current = c
+	w.down = false
+	fn := w.handler
+	w.mu.Unlock()
+	// The handler runs outside the rews reconnection loop, which still has to
+	// replay USE and the token on this goroutine.
+	if reconnect && fn != nil {
+		go fn(connReconnected)
+	}
+}
+
+func (w *connWatch) dropped(c *watchedConn) {
+	w.mu.Lock()
+	if w.current != c || w.down {
+		w.mu.Unlock()
+		return
+	}
+	w.down = true
+	fn := w.handler
+	w.mu.Unlock()
+	if fn != nil {
+		go fn(connDropped)
+	}
+}
+
+// handleConnEvent reacts to conn's socket dropping or being replaced. After a
+// reconnect it restores per-connection state: attached sessions died with the
+// old socket, and live queries are re-issued on the new one.
+func (dialector *Dialector) handleConnEvent(conn *surrealdb.DB, endpoint string, ev connEvent) {
+	if dialector.closed.Load() {
+		return
+	}
+	if ev == connDropped {
+		dialector.health.recordError(fmt.Errorf("surrealdb: connection to %s lost", redactEndpoint(endpoint)))
+		if dialector.OnDisconnect != nil {
+			dialector.OnDisconnect(redactEndpoint(endpoint))
+		}
+		return
+	}
+	dialector.health.reconnects.Add(1)
+	ctx := context.Background()
+	// rews replays USE and the last token concurrently; signing in again here
+	// makes sure the socket is ready before anything is re-issued on it, and
+	// picks up rotated credentials once that token has expired.
+	if dialector.ownsCredentials() {
+		if _, _, err := dialector.signIn(ctx, conn, true); err != nil {
+			dialector.authFailed(endpoint, err)
+			return
+		}
+		if err := conn.Use(ctx, dialector.namespace, dialector.database); err != nil {
+			dialector.health.recordError(err)
+			return
+		}
+	}
+	if pc := dialector.findPoolConn(conn); pc != nil {
+		pc.sessions.reset()
+	}
+	dialector.resubscribeLive(ctx, conn)
+	if dialector.OnReconnect != nil {
+		dialector.OnReconnect(redactEndpoint(endpoint))
+	}
 }
@@ -2,13 +2,16 @@ package surrealdb
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
 	"errors"
 	"fmt"
 	"net/url"
 	"reflect"
+	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	TypesM "github.com/dailaim/surrealdb-gorm/types"
@@ -28,8 +31,66 @@ type Dialector struct {
 	// 0 uses the default (5s), a positive value tunes the check interval, and a
 	// negative value disables reconnection (plain connection).
 	ReconnectInterval time.Duration
-	sqlDB             *sql.DB  // backs QueryContext/QueryRowContext with real *sql.Rows
-	edgeTables        sync.Map // map[string]string — canonical edge table names; key = any alias, value = canonical name
+	// TLSConfig is used for wss:// and https:// endpoints, primary and
+	// replicas alike: a private CA in RootCAs, a client certificate for mTLS,
+	// or a ServerName. nil uses Go's defaults.
+	TLSConfig *tls.Config
+	// MinConns and MaxConns size the connection pool: MinConns connections are
+	// opened up front and up to MaxConns are dialed while every open connection
+	// is busy. Both default to 1 (a single shared connection).
+	MinConns int
+	MaxConns int
+	// ConnMaxIdleTime closes connections above MinConns once they have been
+	// idle this long (default 5m).
+	ConnMaxIdleTime time.Duration
+
+	// Auth selects how connections authenticate. When nil, Initialize signs in
+	// as the root user named by the DSN's username/password parameters.
+	Auth Auth
+	// Credentials, when set, is asked for credentials on the first connect,
+	// whenever a connection is dialed or reconnects, and when the server
+	// reports an expired session (the failed statement is then retried once).
+	// Use it for secrets that rotate; it takes precedence over Auth.
+	Credentials func(ctx context.Context) (Auth, error)
+
+	// Replicas lists secondary endpoints (e.g. "ws://replica-1:8000/rpc") that
+	// serve reads. Each gets its own pool sized like the primary's; SELECTs
+	// outside a transaction are round-robined across them, and everything else
+	// goes to the primary. See UsePrimary.
+	Replicas []string
+
+	// OnDisconnect, OnReconnect and OnAuthFailure are called (from a
+	// background goroutine) when an auto-reconnecting connection drops, when it
+	// is back and its sessions and live queries are restored, and when signing
+	// a connection in fails. endpoint has credentials stripped.
+	OnDisconnect  func(endpoint string)
+	OnReconnect   func(endpoint string)
+	OnAuthFailure func(endpoint string, err error)
+
+	// Record names a golden file that every RPC exchange (method, SurrealQL,
+	// CBOR params and result) is appended to; Initialize truncates it.
+	// Replay names a recorded file to answer from instead of dialing a
+	// server, with the same DSN as the recording. Credentials and tokens
+	// are redacted, and live query notifications are not recorded.
+	Record string
+	Replay string
+
+	namespace  string
+	database   string
+	token      string // session token issued to the first connection
+	ownsConn   bool   // Conn was dialed by Initialize rather than supplied by the caller
+	closed     atomic.Bool
+	pool       *connPool
+	replicas   []*connPool
+	nextRead   atomic.Uint64 // round-robin cursor over replicas
+	liveConns  sync.Map      // map[string]*liveHandle — live query ID → where it runs
+	txs        sync.Map      // map[*SurrealTx]struct{} — open interactive transactions
+	watches    sync.Map      // map[*surrealdb.DB]*connWatch — reconnect tracking per dialed connection
+	health     healthState
+	sqlDB      *sql.DB  // backs QueryContext/QueryRowContext with real *sql.Rows
+	edgeTables sync.Map // map[string]string — canonical edge table names; key = any alias, value = canonical name
+	edgeModels sync.Map // map[string]reflect.Type — the model AutoMigrate defined each canonical edge table from
+	tape       *tape    // Record/Replay golden file
 }
 
 // RegisterEdgeTable marks a table name as a SurrealDB graph edge table.
@@ -60,6 +121,22 @@ func (d *Dialector) FindEdgeTable(table string) (string, bool) {
 	return "", false
 }
 
+// registerEdgeModel records the model an edge table was migrated from, for
+// the association paths that only know the table.
+func (d *Dialector) registerEdgeModel(table string, model reflect.Type) {
+	d.edgeModels.Store(table, model)
+}
+
+// edgeModel returns the model registered for an edge table, or nil.
+func (d *Dialector) edgeModel(table string) reflect.Type {
+	if canonical, ok := d.FindEdgeTable(table); ok {
+		if t, ok := d.edgeModels.Load(canonical); ok {
+			return t.(reflect.Type)
+		}
+	}
+	return nil
+}
+
 // IsEdgeTable reports whether the given table name is a registered graph edge table.
 func (d *Dialector) IsEdgeTable(table string) bool {
 	_, ok := d.FindEdgeTable(table)
@@ -71,58 +148,58 @@ func (dialector *Dialector) Name() string {
 }
 
 func (dialector *Dialector) Initialize(db *gorm.DB) (err error) {
-	if dialector.Conn != nil {
-		db.ConnPool = dialector
-	} else {
-		u, err := url.Parse(dialector.DSN)
-		if err != nil {
+	if dialector.tape == nil && (dialector.Record != "" || dialector.Replay != "") {
+		if dialector.tape, err = openTape(dialector.Record, dialector.Replay); err != nil {
 			return err
 		}
-
-		conn, err := dialector.dialConn(context.Background())
+	}
+	if dialector.Conn == nil {
+		u, err := url.Parse(dialector.DSN)
 		if err != nil {
 			return err
 		}
 
 		q := u.Query()
-		ns := q.Get("namespace")
-		dbName := q.Get("database")
-		if ns == "" || dbName == "" {
+		dialector.namespace = q.Get("namespace")
+		dialector.database = q.Get("database")
+		if dialector.namespace == "" || dialector.database == "" {
 			return errors.New("namespace and database must be provided")
 		}
 
-		user := q.Get("username")
-		pass := q.Get("password")
-		if user == "" || pass == "" {
-			return errors.New("username and password must be provided")
-		}
-		// Sign in first: root-level auth is namespace-independent and is required
-		// before defining namespaces/databases.
-		if _, err := conn.SignIn(context.Background(), surrealdb.Auth{
-			Username: user,
-			Password: pass,
-		}); err != nil {
-			return err
+		if !dialector.ownsCredentials() {
+			user := q.Get("username")
+			pass := q.Get("password")
+			if user == "" || pass == "" {
+				return errors.New("username and password must be provided")
+			}
+			dialector.Auth = RootAuth{Username: user, Password: pass}
 		}
 
-		// SurrealDB v3 does not implicitly create the namespace/database on USE
-		// (v2 tolerated it). Create them idempotently so AutoMigrate and queries
-		// work out of the box on both versions.
-		ctx := context.Background()
-		if _, err := surrealdb.Query[interface{}](ctx, conn,
-			fmt.Sprintf("DEFINE NAMESPACE IF NOT EXISTS `%s`", ns), nil); err != nil {
+		conn, err := dialector.bootstrapConn(context.Background())
+		if err != nil {
 			return err
 		}
-		if err := conn.Use(ctx, ns, dbName); err != nil {
-			return err
+		dialector.Conn = conn
+		dialector.ownsConn = true
+	}
+	db.ConnPool = dialector
+
+	// Spread statements over a pool of connections. A caller-supplied Conn has
+	// no credentials to dial siblings with, so it stays a pool of one.
+	if dialector.pool == nil && dialector.Conn != nil {
+		var open func(context.Context) (*surrealdb.DB, error)
+		if dialector.ownsCredentials() {
+			open = dialector.openConn
 		}
-		if _, err := surrealdb.Query[interface{}](ctx, conn,
-			fmt.Sprintf("DEFINE DATABASE IF NOT EXISTS `%s`", dbName), nil); err != nil {
+		dialector.pool = newConnPool(dialector.Conn, open,
+			dialector.MinConns, dialector.MaxConns, dialector.ConnMaxIdleTime)
+		dialector.pool.closeConn = dialector.closeConn
+		if err := dialector.pool.fill(context.Background()); err != nil {
 			return err
 		}
-
-		dialector.Conn = conn
-		db.ConnPool = dialector
+	}
+	if err := dialector.openReplicas(context.Background()); err != nil {
+		return err
 	}
 
 	// Open the internal *sql.DB that backs the raw-row query paths. It reuses the
@@ -138,6 +215,102 @@ func (dialector *Dialector) Initialize(db *gorm.DB) (err error) {
 	return nil
 }
 
+// bootstrapConn opens the first connection. Besides authenticating and
+// selecting the namespace/database, a root connection creates both
+// idempotently: SurrealDB v3 does not implicitly create them on USE (v2
+// tolerated it), so AutoMigrate and queries work out of the box on both
+// versions. Least-privilege modes expect them to exist.
+func (dialector *Dialector) bootstrapConn(ctx context.Context) (*surrealdb.DB, error) {
+	conn, err := dialector.dialConn(ctx)
+	if err != nil {
+		dialector.health.recordError(err)
+		return nil, err
+	}
+	// Sign in first: root-level auth is namespace-independent and is required
+	// before defining namespaces/databases.
+	token, isRoot, err := dialector.signIn(ctx, conn, false)
+	if err != nil {
+		dialector.closeConn(ctx, conn)
+		dialector.authFailed(dialector.DSN, err)
+		return nil, err
+	}
+	dialector.token = token
+	if err := useDatabase(ctx, conn, dialector.namespace, dialector.database, isRoot); err != nil {
+		dialector.closeConn(ctx, conn)
+		return nil, err
+	}
+	return conn, nil
+}
+
+// closeConn closes a connection the dialector dialed and forgets its
+// reconnect tracking. A caller-supplied Conn is left for the caller to close.
+func (dialector *Dialector) closeConn(ctx context.Context, conn *surrealdb.DB) {
+	dialector.watches.Delete(conn)
+	if conn == dialector.Conn && !dialector.ownsConn {
+		return
+	}
+	_ = conn.Close(ctx)
+}
+
+// databaseNameRe matches the namespace and database names useDatabase
+// accepts, so they can be quoted into DEFINE statements.
+var databaseNameRe = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_-]*$`)
+
+// useDatabase switches conn to ns/db. With define set (root credentials) the
+// namespace and database are created first if they do not exist yet.
+func useDatabase[S interface {
+	*surrealdb.DB | *surrealdb.Session
+	Use(ctx context.Context, ns, db string) error
+}](ctx context.Context, conn S, ns, db string, define bool) error {
+	for _, name := range []string{ns, db} {
+		if !databaseNameRe.MatchString(name) {
+			return fmt.Errorf("surrealdb: invalid namespace or database name %q", name)
+		}
+	}
+	if define {
+		if _, err := surrealdb.Query[interface{}](ctx, conn,
+			fmt.Sprintf("DEFINE NAMESPACE IF NOT EXISTS `%s`", ns), nil); err != nil {
+			return err
+		}
+	}
+	if err := conn.Use(ctx, ns, db); err != nil {
+		return err
+	}
+	if define {
+		if _, err := surrealdb.Query[interface{}](ctx, conn,
+			fmt.Sprintf("DEFINE DATABASE IF NOT EXISTS `%s`", db), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openConn dials an additional pooled connection with the same credentials and
+// namespace/database as the first one.
+func (dialector *Dialector) openConn(ctx context.Context) (*surrealdb.DB, error) {
+	return dialector.openEndpoint(ctx, dialector.DSN)
+}
+
+// openEndpoint dials and authenticates a connection to endpoint, which may be
+// the primary DSN or a read replica.
+func (dialector *Dialector) openEndpoint(ctx context.Context, endpoint string) (*surrealdb.DB, error) {
+	conn, err := dialector.dialEndpoint(ctx, endpoint)
+	if err != nil {
+		dialector.health.recordError(err)
+		return nil, err
+	}
+	if _, _, err := dialector.signIn(ctx, conn, true); err != nil {
+		dialector.closeConn(ctx, conn)
+		dialector.authFailed(endpoint, err)
+		return nil, err
+	}
+	if err := conn.Use(ctx, dialector.namespace, dialector.database); err != nil {
+		dialector.closeConn(ctx, conn)
+		return nil, err
+	}
+	return conn, nil
+}
+
 func (dialector *Dialector) Migrator(db *gorm.DB) gorm.Migrator {
 	return Migrator{
 		Migrator: migrator.Migrator{
@@ -296,7 +469,15 @@ func inferRecordTable(field *schema.Field) string {
 			return rel.FieldSchema.Table
 		}
 		for _, ref := range rel.References {
-			if ref.ForeignKey == field && rel.FieldSchema != nil {
+			if ref.ForeignKey != field {
+				continue
+			}
+			// A has-one/has-many foreign key lives on the related model and
+			// links back to the owner.
+			if ref.OwnPrimaryKey && rel.Schema != nil {
+				return rel.Schema.Table
+			}
+			if rel.FieldSchema != nil {
 				return rel.FieldSchema.Table
 			}
 		}
@@ -379,7 +560,12 @@ func (d *Dialector) ExplainQuery(ctx context.Context, query string, vars ...inte
 	for i, v := range vars {
 		params[fmt.Sprintf("p%d", i+1)] = TypesM.ToSDKValue(v)
 	}
-	results, err := surrealdb.Query[ExplainResult](ctx, d.Conn, fmt.Sprintf("EXPLAIN %s", query), params)
+	target, release, err := d.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	results, err := queryOn[ExplainResult](ctx, target, fmt.Sprintf("EXPLAIN %s", query), params)
 	if err != nil {
 		return nil, err
 	}
@@ -420,20 +606,44 @@ func (dialector *Dialector) QueryRowContext(ctx context.Context, query string, a
 }
 
 // BeginTx implements gorm.ConnPoolBeginner. It opens a native interactive
-// transaction on the SurrealDB WebSocket connection (requires SurrealDB v3+).
+// transaction on one pooled SurrealDB WebSocket connection (requires SurrealDB v3+).
 // All subsequent operations on the returned ConnPool run inside that transaction,
 // so read-your-own-writes works transparently.
 func (dialector *Dialector) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
-	if dialector.Conn == nil {
+	if dialector.pool == nil {
 		return nil, errors.New("surrealdb: connection not initialized")
 	}
-	sdkTx, err := dialector.Conn.Begin(ctx)
+	// The transaction lives on one WebSocket, so pin that connection for the
+	// transaction's whole lifetime.
+	pc, err := dialector.pool.acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("surrealdb BeginTx: %w", err)
+	}
+	// Under AsToken or WithDatabase the transaction opens inside that session.
+	target, unlease, err := dialector.connTarget(ctx, pc)
+	if err != nil {
+		pc.release()
+		return nil, fmt.Errorf("surrealdb BeginTx: %w", err)
+	}
+	var sdkTx *surrealdb.Transaction
+	if target.session != nil {
+		sdkTx, err = target.session.Begin(ctx)
+	} else {
+		sdkTx, err = pc.db.Begin(ctx)
+	}
 	if err != nil {
+		unlease()
+		pc.release()
 		return nil, fmt.Errorf("surrealdb BeginTx: %w", err)
 	}
-	return &SurrealTx{
+	pc.pin()
+	tx := &SurrealTx{
 		dialector: dialector,
 		ctx:       ctx,
 		sdkTx:     sdkTx,
-	}, nil
+		conn:      pc,
+		unlease:   unlease,
+	}
+	dialector.txs.Store(tx, struct{}{})
+	return tx, nil
 }
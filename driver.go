@@ -26,6 +26,31 @@ func (d DriverResult) RowsAffected() (int64, error) {
 	return d.Rows, nil
 }
 
+// insertEnds returns the argument positions of the in and out columns of
+// an INSERT INTO edge (...) VALUES (...): the first two when the column
+// list does not name them.
+func insertEnds(query string) (in, out int) {
+	in, out = 0, 1
+	open, end := strings.IndexByte(query, '('), strings.IndexByte(query, ')')
+	if open < 0 || end < open {
+		return in, out
+	}
+	cols := strings.Split(query[open+1:end], ",")
+	var found int
+	for i, c := range cols {
+		switch strings.Trim(strings.TrimSpace(c), "`\"") {
+		case "in":
+			in, found = i, found+1
+		case "out":
+			out, found = i, found+1
+		}
+	}
+	if found != 2 {
+		return 0, 1
+	}
+	return in, out
+}
+
 func (dialector *Dialector) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
 	// Intercept INSERT INTO <edge_table> generated by GORM's many2many association handler.
 	upperQuery := strings.ToUpper(strings.TrimSpace(query))
@@ -36,16 +61,24 @@ func (dialector *Dialector) ExecContext(ctx context.Context, query string, args
 		})
 		if len(tbl) > 0 {
 			if registeredName, ok := dialector.FindEdgeTable(tbl[0]); ok {
-				if len(args) >= 2 {
-					inID := extractRecordID(args[0])
-					outID := extractRecordID(args[1])
+				if in, out := insertEnds(query); len(args) > max(in, out) {
+					inID := extractRecordID(args[in])
+					outID := extractRecordID(args[out])
 					if inID != nil && outID != nil {
 						rel := &surrealdb.Relationship{
 							In:       *inID,
 							Out:      *outID,
 							Relation: sdkModels.Table(registeredName),
 						}
-						if _, err := surrealdb.InsertRelation[interface{}](ctx, dialector.Conn, rel); err != nil {
+						target, release, err := dialector.acquire(ctx)
+						if err != nil {
+							return nil, &Error{Op: "relate", Query: query, Err: err}
+						}
+						_, err = dialector.relateEdge(ctx, target, nil, rel, func() (interface{}, error) {
+							return insertRelationOn[interface{}](ctx, target, rel)
+						})
+						release()
+						if err != nil {
 							return nil, &Error{Op: "relate", Query: query, Err: err}
 						}
 						return DriverResult{Rows: 1}, nil
@@ -64,7 +97,12 @@ func (dialector *Dialector) ExecContext(ctx context.Context, query string, args
 		params[fmt.Sprintf("p%d", i+1)] = TypesM.ToSDKValue(v)
 	}
 
-	results, err := surrealdb.Query[interface{}](ctx, dialector.Conn, query, params)
+	target, release, err := dialector.acquire(ctx)
+	if err != nil {
+		return nil, &Error{Op: "exec", Query: query, Err: err}
+	}
+	defer release()
+	results, err := queryOn[interface{}](ctx, target, query, params)
 	if err != nil {
 		return nil, &Error{Op: "exec", Query: query, Err: err}
 	}
@@ -94,43 +132,9 @@ func (dialector *Dialector) ExecContext(ctx context.Context, query string, args
 //     id is immutable and lives in the UPDATE target, not the SET body)
 //   - rewrites standard-SQL "DELETE FROM x" into SurrealQL "DELETE x"
 func rewriteExecSQL(query string) string {
-	// Sanitize UPDATE SQL (remove id from SET).
-	if len(query) > 6 && strings.HasPrefix(strings.ToUpper(query), "UPDATE") {
-		lowerSql := strings.ToLower(query)
-		setIdx := strings.Index(lowerSql, " set ")
-		whereIdx := strings.LastIndex(lowerSql, " where ")
-		if setIdx != -1 && (whereIdx == -1 || setIdx < whereIdx) {
-			beforeSet := query[:setIdx+5]
-			var setPart, afterSet string
-			if whereIdx != -1 {
-				setPart = query[setIdx+5 : whereIdx]
-				afterSet = query[whereIdx:]
-			} else {
-				setPart = query[setIdx+5:]
-			}
-			parts := strings.Split(setPart, ",")
-			var cleanParts []string
-			for _, p := range parts {
-				trimmed := strings.TrimSpace(p)
-				if strings.HasPrefix(trimmed, "`id` =") || strings.HasPrefix(trimmed, "id =") || strings.HasPrefix(trimmed, "\"id\" =") {
-					continue
-				}
-				cleanParts = append(cleanParts, p)
-			}
-			newSetPart := strings.Join(cleanParts, ", ")
-			query = beforeSet + newSetPart + " " + afterSet
-		}
-	}
-
-	// Translate standard SQL DELETE FROM to SurrealQL DELETE.
-	if len(query) > 12 && strings.HasPrefix(strings.ToUpper(strings.TrimSpace(query)), "DELETE FROM ") {
-		idx := strings.Index(strings.ToUpper(query), "DELETE FROM ")
-		if idx != -1 {
-			query = query[:idx] + "DELETE " + strings.TrimSpace(query[idx+12:])
-		}
-	}
-
-	return query
+	return eachStatement(query, func(toks []sqlToken) []sqlToken {
+		return deleteFrom(updateSetID(toks))
+	})
 }
 
 // extractRecordID coerces an interface{} arg value into a *sdkModels.RecordID.
@@ -0,0 +1,269 @@
+package surrealdb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/surrealdb/surrealdb.go"
+	sdkModels "github.com/surrealdb/surrealdb.go/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	localModels "github.com/dailaim/surrealdb-gorm/models"
+	TypesM "github.com/dailaim/surrealdb-gorm/types"
+)
+
+// edgeFieldRe matches the payload field names AppendEdge sets.
+var edgeFieldRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// AppendEdge appends values to a many2many association stored in an edge
+// table, as Association.Append does, and sets data on each new edge:
+//
+//	surrealdb.AppendEdge(db.Model(&store).Association("Products"), &product,
+//		map[string]any{"price": 9.99})
+//
+// The edges are created with RELATE ... SET in the relation's direction,
+// with created_at and updated_at when the edge model has them, inside the
+// transaction the association's db runs in. values must already be saved.
+func AppendEdge(assoc *gorm.Association, values interface{}, data map[string]interface{}) error {
+	return appendEdge(assoc, values, data, nil)
+}
+
+// AppendEdgeModel is AppendEdge with the payload taken from edge, a value of
+// the edge model: its non-zero fields other than id, in, out and the
+// timestamps.
+//
+//	surrealdb.AppendEdgeModel(db.Model(&store).Association("Products"), &product,
+//		&Stock{Price: 9.99})
+func AppendEdgeModel[E any](assoc *gorm.Association, values interface{}, edge *E) error {
+	if assoc.Error != nil {
+		return assoc.Error
+	}
+	stmt := &gorm.Statement{DB: assoc.DB}
+	if err := stmt.Parse(edge); err != nil {
+		return err
+	}
+	data := edgeData(assoc.DB.Statement.Context, stmt.Schema, reflect.ValueOf(edge).Elem())
+	return appendEdge(assoc, values, data, stmt.Schema)
+}
+
+func appendEdge(assoc *gorm.Association, values interface{}, data map[string]interface{}, edgeSchema *schema.Schema) error {
+	if assoc.Error != nil {
+		return assoc.Error
+	}
+	db, rel := assoc.DB, assoc.Relationship
+	dialector, ok := db.Dialector.(*Dialector)
+	if !ok {
+		return fmt.Errorf("surrealdb: AppendEdge needs a surrealdb dialector")
+	}
+	table := ""
+	if rel.Type == schema.Many2Many && rel.JoinTable != nil {
+		table, ok = dialector.FindEdgeTable(rel.JoinTable.Table)
+	}
+	if table == "" || !ok {
+		return fmt.Errorf("surrealdb: association %s is not stored in an edge table", rel.Name)
+	}
+	if edgeSchema == nil {
+		model := joinEdgeModel(rel)
+		if model == nil {
+			model = dialector.edgeModel(table)
+		}
+		if model != nil {
+			stmt := &gorm.Statement{DB: db}
+			if err := stmt.Parse(reflect.New(model).Interface()); err != nil {
+				return err
+			}
+			edgeSchema = stmt.Schema
+		}
+	} else if edgeSchema.Table != table {
+		return fmt.Errorf("surrealdb: %s is not the edge model of %s", edgeSchema.Name, table)
+	}
+	for k := range data {
+		if !edgeFieldRe.MatchString(k) {
+			return fmt.Errorf("surrealdb: invalid edge field %q", k)
+		}
+	}
+
+	owner := db.Statement.ReflectValue
+	if owner.Kind() != reflect.Struct {
+		return fmt.Errorf("surrealdb: AppendEdge needs a single owner record")
+	}
+	ownerID := edgeOwnerID(db, rel, owner)
+	if ownerID == nil {
+		return fmt.Errorf("surrealdb: AppendEdge needs an owner with a record id")
+	}
+	records := edgeRecords(reflect.ValueOf(values))
+	ids := edgeTargetIDs(db, rel, reflect.ValueOf(values))
+	if len(ids) != len(records) {
+		return fmt.Errorf("surrealdb: AppendEdge needs saved records with record ids")
+	}
+
+	target, release, err := dialector.statementTarget(db)
+	if err != nil {
+		return err
+	}
+	defer release()
+	timestamps := edgeSchema != nil && edgeSchema.LookUpField("CreatedAt") != nil
+	var model reflect.Type
+	if edgeSchema != nil {
+		model = edgeSchema.ModelType
+	}
+	for _, id := range ids {
+		r := edgeRelationship(rel, table, ownerID, id)
+		r.Data = data
+		if _, err := dialector.relateEdge(db.Statement.Context, target, model, r, func() (interface{}, error) {
+			return relateSet(db.Statement.Context, target, &r.In, table, &r.Out, data, timestamps)
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Like Append, add the records to the owner's field.
+	field := rel.Field.ReflectValueOf(db.Statement.Context, owner)
+	if field.Kind() == reflect.Slice && field.CanSet() {
+		elemType := field.Type().Elem()
+		for _, rec := range records {
+			for rec.Kind() == reflect.Pointer && rec.Type() != elemType {
+				rec = rec.Elem()
+			}
+			switch {
+			case rec.Type() == elemType:
+				field.Set(reflect.Append(field, rec))
+			case rec.CanAddr() && rec.Addr().Type() == elemType:
+				field.Set(reflect.Append(field, rec.Addr()))
+			}
+		}
+	}
+	return nil
+}
+
+// uniqueEdgeModel reports whether an edge model type has UniqueEdges.
+func uniqueEdgeModel(model reflect.Type) bool {
+	if model == nil {
+		return false
+	}
+	u, ok := reflect.New(model).Interface().(localModels.UniqueEdgeRelation)
+	return ok && u.UniqueEdges()
+}
+
+// edgeIndexName is the name of the unique [in, out] index AutoMigrate
+// defines on an edge table whose model has UniqueEdges.
+func edgeIndexName(table string) string {
+	return fmt.Sprintf("idx_%s_in_out", table)
+}
+
+// isEdgeIndexError reports whether err is the database refusing a second
+// edge between the same records on table's [in, out] index.
+func isEdgeIndexError(err error, table string) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "`"+edgeIndexName(table)+"`") && strings.Contains(msg, "already contains")
+}
+
+// isEdgeModel reports whether model is an edge model rather than, say, the
+// join table struct GORM generates for a many2many relation.
+func isEdgeModel(model reflect.Type) bool {
+	if model == nil {
+		return false
+	}
+	_, ok := reflect.New(model).Interface().(localModels.EdgeRelation)
+	return ok
+}
+
+// joinEdgeModel returns the model of rel's join table when it is an edge
+// model, as after db.SetupJoinTable(&Reader{}, "Novels", &Like{}), or nil
+// for the join table GORM generates.
+func joinEdgeModel(rel *schema.Relationship) reflect.Type {
+	if rel.JoinTable != nil && isEdgeModel(rel.JoinTable.ModelType) {
+		return rel.JoinTable.ModelType
+	}
+	return nil
+}
+
+// relateEdge creates the edge rel describes with create, unless its table
+// keeps a single edge per pair of records: then an existing edge is updated
+// by relateUnique instead. model is the edge model of the caller's statement
+// or relationship; when it is not one, as for nil or GORM's generated join
+// struct, the model AutoMigrate registered is used.
+//
+// The lookup and create are separate statements, so a concurrent writer may
+// create the edge in between; the [in, out] index refuses the second create,
+// which is retried as an update. The same holds for a schema whose index
+// this process did not define.
+func (d *Dialector) relateEdge(ctx context.Context, target rpcTarget, model reflect.Type, rel *surrealdb.Relationship, create func() (interface{}, error)) (interface{}, error) {
+	table := string(rel.Relation)
+	if !isEdgeModel(model) {
+		model = d.edgeModel(table)
+	}
+	if uniqueEdgeModel(model) {
+		if edge, found, err := d.relateUnique(ctx, target, model, rel); err != nil {
+			return nil, err
+		} else if found {
+			return edge, nil
+		}
+	}
+	result, err := create()
+	if err != nil && isEdgeIndexError(err, table) {
+		if edge, found, uerr := d.relateUnique(ctx, target, model, rel); uerr == nil && found {
+			return edge, nil
+		}
+	}
+	return result, err
+}
+
+// relateUnique looks for the edge rel would create. If there is one, rel.Data
+// is set on it, and when model has them updated_at bumped and a soft delete
+// undone, and found is true.
+func (d *Dialector) relateUnique(ctx context.Context, target rpcTarget, model reflect.Type, rel *surrealdb.Relationship) (edge map[string]interface{}, found bool, err error) {
+	table := string(rel.Relation)
+	ids, err := queryOn[[]sdkModels.RecordID](ctx, target,
+		"SELECT VALUE id FROM type::table($tb) WHERE in = $in AND out = $out LIMIT 1",
+		map[string]interface{}{"tb": table, "in": rel.In, "out": rel.Out})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(*ids) > 0 && (*ids)[0].Status != "OK" {
+		return nil, false, fmt.Errorf("edge lookup error: %v", (*ids)[0])
+	}
+	if len(*ids) == 0 || len((*ids)[0].Result) == 0 {
+		return nil, false, nil
+	}
+
+	params := map[string]interface{}{"id": (*ids)[0].Result[0]}
+	var setParts []string
+	if model != nil {
+		if _, ok := model.FieldByName("UpdatedAt"); ok {
+			setParts = append(setParts, "updated_at = time::now()")
+		}
+		if _, ok := model.FieldByName("DeletedAt"); ok {
+			setParts = append(setParts, "deleted_at = NONE")
+		}
+	}
+	i := 0
+	for k, v := range rel.Data {
+		if !edgeFieldRe.MatchString(k) {
+			return nil, false, fmt.Errorf("surrealdb: invalid edge field %q", k)
+		}
+		paramKey := fmt.Sprintf("p%d", i)
+		params[paramKey] = TypesM.ToSDKValue(v)
+		setParts = append(setParts, fmt.Sprintf("%s = $%s", k, paramKey))
+		i++
+	}
+	sql := "SELECT * FROM $id"
+	if len(setParts) > 0 {
+		sql = "UPDATE $id SET " + strings.Join(setParts, ", ")
+	}
+	results, err := queryOn[[]map[string]interface{}](ctx, target, sql, params)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(*results) > 0 && (*results)[0].Status != "OK" {
+		return nil, false, fmt.Errorf("edge update error: %v", (*results)[0])
+	}
+	if len(*results) == 0 || len((*results)[0].Result) == 0 {
+		return nil, true, nil
+	}
+	return (*results)[0].Result[0], true, nil
+}
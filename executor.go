@@ -4,8 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
-	"regexp"
-	"strings"
 	"time"
 
 	"github.com/surrealdb/surrealdb.go"
@@ -26,168 +24,34 @@ func txFromStatement(db *gorm.DB) (*SurrealTx, bool) {
 }
 
 // execTxQuery runs a SurrealQL statement against the interactive transaction
-// bound to the current GORM statement, if one is open, otherwise against the
-// shared connection. Centralizing this here ensures every write path (edges,
+// bound to the current GORM statement, if one is open, otherwise against a
+// pooled connection. Centralizing this here ensures every write path (edges,
 // bulk inserts, soft-deletes) participates in db.Transaction(...) and gets
-// read-your-own-writes, instead of silently escaping to the shared connection.
+// read-your-own-writes, instead of silently escaping to another connection.
 func execTxQuery(db *gorm.DB, d *Dialector, sql string, params map[string]interface{}) (*[]surrealdb.QueryResult[interface{}], error) {
-	if txConn, ok := txFromStatement(db); ok {
-		return surrealdb.Query[interface{}](db.Statement.Context, txConn.SDKTx(), sql, params)
+	target, release, err := d.statementTarget(db)
+	if err != nil {
+		return nil, err
 	}
-	return surrealdb.Query[interface{}](db.Statement.Context, d.Conn, sql, params)
+	defer release()
+	return queryOn[interface{}](db.Statement.Context, target, sql, params)
 }
 
-func executeSQL(db *gorm.DB) {
-	dialector := db.Dialector.(*Dialector)
-	sql := db.Statement.SQL.String()
-
-	// Emit the final SurrealQL to GORM's logger so db.Debug(), slow-query
-	// logging, and error logging all work. `sql` is captured by reference, so
-	// the closure reports the fully-rewritten statement regardless of which
-	// return path fires.
-	begin := time.Now()
-	defer func() {
-		db.Logger.Trace(db.Statement.Context, begin, func() (string, int64) {
-			return sql, db.RowsAffected
-		}, db.Error)
-	}()
-
-	// Map table name to canonical form
-	actualTable := db.Statement.Table
-	if actualTable != "" {
-		if canonical, ok := dialector.FindEdgeTable(actualTable); ok {
-			sql = strings.ReplaceAll(sql, fmt.Sprintf("`%s`", actualTable), fmt.Sprintf("`%s`", canonical))
-			sql = strings.ReplaceAll(sql, fmt.Sprintf("FROM %s", actualTable), fmt.Sprintf("FROM `%s`", canonical))
-			actualTable = canonical
-		}
-	}
-
-	// Replace <> with !=
-	sql = strings.ReplaceAll(sql, "<>", "!=")
-
-	// Rewrite count queries
-	if strings.Contains(sql, "SELECT count(*)") || strings.Contains(sql, "SELECT count(1)") {
-		sql = strings.ReplaceAll(sql, "count(*)", "count()")
-		sql = strings.ReplaceAll(sql, "count(1)", "count()")
-
-		if !strings.Contains(strings.ToUpper(sql), "GROUP ALL") {
-			sql = sql + " GROUP ALL"
-		}
-
-		// Edge table association count rewrite
-		if strings.Contains(sql, " JOIN ") {
-			if dialector != nil {
-				rewritten := false
-
-				// Case 1: FROM table is the edge table
-				parts := strings.Split(sql, " FROM `")
-				if len(parts) == 2 {
-					tablePart := strings.Split(parts[1], "`")[0]
-					if canonical, ok := dialector.FindEdgeTable(tablePart); ok {
-						var ownerField string
-						for _, param := range []string{"in", "out"} {
-							if strings.Contains(sql, ".`"+param+"` = $p") {
-								ownerField = param
-								break
-							}
-						}
-						if ownerField != "" {
-							idx := strings.Index(sql, ".`"+ownerField+"` = $p")
-							if idx != -1 {
-								pstart := idx + len(".`"+ownerField+"` = ")
-								pend := pstart
-								for pend < len(sql) && (sql[pend] == '$' || sql[pend] == 'p' || (sql[pend] >= '0' && sql[pend] <= '9')) {
-									pend++
-								}
-								param := sql[pstart:pend]
-								sql = fmt.Sprintf("SELECT count() FROM `%s` WHERE `%s` = %s GROUP ALL", canonical, ownerField, param)
-								rewritten = true
-							}
-						}
-					}
-				}
-
-				// Case 2: edge table is in JOIN clause → graph traversal
-				if !rewritten {
-					joinRe := regexp.MustCompile("JOIN `([^`]+)` ON")
-					matches := joinRe.FindStringSubmatch(sql)
-					if len(matches) >= 2 {
-						joinTable := matches[1]
-						if canonical, ok := dialector.FindEdgeTable(joinTable); ok {
-							var ownerField, param string
-							for _, field := range []string{"in", "out"} {
-								paramRe := regexp.MustCompile(fmt.Sprintf("`%s`\\.`%s` = (\\$p\\d+)", regexp.QuoteMeta(canonical), field))
-								pm := paramRe.FindStringSubmatch(sql)
-								if len(pm) >= 2 {
-									ownerField = field
-									param = pm[1]
-									break
-								}
-							}
-							if ownerField != "" && param != "" {
-								fromRe := regexp.MustCompile("FROM `([^`]+)`")
-								fm := fromRe.FindStringSubmatch(sql)
-								targetTable := ""
-								if len(fm) >= 2 {
-									targetTable = fm[1]
-								}
-								if targetTable != "" {
-									var traversal string
-									if ownerField == "in" {
-										traversal = fmt.Sprintf("%s->%s->%s", param, canonical, targetTable)
-									} else {
-										traversal = fmt.Sprintf("%s<-%s<-%s", param, canonical, targetTable)
-									}
-									whereClause := ""
-									whereIdx := strings.Index(strings.ToUpper(sql), " WHERE ")
-									if whereIdx != -1 {
-										end := len(sql)
-										groupIdx := strings.Index(strings.ToUpper(sql[whereIdx:]), " GROUP ALL")
-										if groupIdx != -1 {
-											end = whereIdx + groupIdx
-										}
-										whereClause = sql[whereIdx:end]
-									}
-									sql = fmt.Sprintf("SELECT count() FROM %s%s GROUP ALL", traversal, whereClause)
-								}
-							}
-						}
-					}
-				}
-			}
-		}
-	}
-
-	// Translate OFFSET to START
-	sql = strings.ReplaceAll(sql, " OFFSET ", " START ")
-
-	// Remove table prefixes
-	if actualTable != "" {
-		sql = strings.ReplaceAll(sql, fmt.Sprintf("`%s`.", actualTable), "")
-		sql = strings.ReplaceAll(sql, fmt.Sprintf("%s.", actualTable), "")
-	}
-
-	// Translate DELETE FROM → DELETE (SurrealQL has no FROM in DELETE).
-	if len(sql) > 12 && strings.HasPrefix(strings.ToUpper(strings.TrimSpace(sql)), "DELETE FROM ") {
-		idx := strings.Index(strings.ToUpper(sql), "DELETE FROM ")
-		if idx != -1 {
-			sql = sql[:idx] + "DELETE " + strings.TrimSpace(sql[idx+12:])
-		}
-	}
-
-	// Remove soft-delete IS NULL check
-	sql = strings.ReplaceAll(sql, " AND (`deleted_at` IS NULL)", "")
-
-	// SurrealDB soft delete syntax fix
-	if strings.HasPrefix(strings.TrimSpace(sql), "UPDATE") {
-		sql = strings.ReplaceAll(sql, "`deleted_at` IS NULL", "(`deleted_at` IS NULL OR `deleted_at` IS NONE)")
-		if actualTable != "" {
-			sql = strings.ReplaceAll(sql, fmt.Sprintf("`%s`.`deleted_at` IS NULL", actualTable), fmt.Sprintf("(`%s`.`deleted_at` IS NULL OR `%s`.`deleted_at` IS NONE)", actualTable, actualTable))
-		}
+// execReadQuery runs a read-only statement, on a replica when Replicas are
+// configured and the statement is not part of a transaction.
+func execReadQuery(db *gorm.DB, d *Dialector, sql string, params map[string]interface{}) (*[]surrealdb.QueryResult[interface{}], error) {
+	target, release, err := d.readTarget(db)
+	if err != nil {
+		return nil, err
 	}
+	defer release()
+	return queryOn[interface{}](db.Statement.Context, target, sql, params)
+}
 
-	vars := db.Statement.Vars
-	params := make(map[string]interface{})
+// statementParams binds a statement's vars as $p1..$pN, converted to the
+// SDK's values.
+func statementParams(vars []interface{}) map[string]interface{} {
+	params := make(map[string]interface{}, len(vars))
 	for i, v := range vars {
 		if rid, ok := v.(*TypesM.RecordID); ok && rid != nil {
 			native := rid.RecordID
@@ -205,32 +69,43 @@ func executeSQL(db *gorm.DB) {
 		}
 		params[fmt.Sprintf("p%d", i+1)] = TypesM.ToSDKValue(v)
 	}
+	return params
+}
 
-	// Inline LIMIT and START params
-	inlineRegex := regexp.MustCompile(`(LIMIT|START)\s+\$p(\d+)`)
-	sql = inlineRegex.ReplaceAllStringFunc(sql, func(match string) string {
-		parts := strings.Split(match, "$p")
-		if len(parts) == 2 {
-			paramKey := "p" + parts[1]
-			if val, ok := params[paramKey]; ok {
-				delete(params, paramKey)
-				return fmt.Sprintf("%s %v", strings.TrimSpace(parts[0]), val)
-			}
-		}
-		return match
-	})
+func executeSQL(db *gorm.DB) {
+	dialector := db.Dialector.(*Dialector)
+	sql := db.Statement.SQL.String()
+
+	// Emit the final SurrealQL to GORM's logger so db.Debug(), slow-query
+	// logging, and error logging all work. `sql` is captured by reference, so
+	// the closure reports the fully-rewritten statement regardless of which
+	// return path fires.
+	begin := time.Now()
+	defer func() {
+		db.Logger.Trace(db.Statement.Context, begin, func() (string, int64) {
+			return sql, db.RowsAffected
+		}, db.Error)
+	}()
+
+	params := statementParams(db.Statement.Vars)
+
+	// Translate GORM's SQL to SurrealQL: edge table names, operators, counts,
+	// table qualifiers, DELETE FROM, soft-delete checks and paging values.
+	sql = dialector.newTranslator(db.Statement.Table, params).translate(sql)
 
 	// Execute
 	//
 	// If we're inside a GORM transaction, db.Statement.ConnPool is a *SurrealTx.
 	// Route the query through the SDK transaction so every statement (read or
 	// write) participates in the same open transaction — giving read-your-own-writes.
+	// Otherwise the statement runs on the least-loaded pooled connection; plain
+	// SELECTs may be served by a read replica.
 	var results *[]surrealdb.QueryResult[interface{}]
 	var err error
-	if txConn, ok := db.Statement.ConnPool.(*SurrealTx); ok {
-		results, err = surrealdb.Query[interface{}](db.Statement.Context, txConn.SDKTx(), sql, params)
+	if isReadOnlySQL(sql) {
+		results, err = execReadQuery(db, dialector, sql, params)
 	} else {
-		results, err = surrealdb.Query[interface{}](db.Statement.Context, dialector.Conn, sql, params)
+		results, err = execTxQuery(db, dialector, sql, params)
 	}
 	if err != nil {
 		db.AddError(&Error{Op: "query", Query: sql, Err: err})
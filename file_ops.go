@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/surrealdb/surrealdb.go"
 	"gorm.io/gorm"
 
 	TypesM "github.com/dailaim/surrealdb-gorm/types"
@@ -24,26 +23,32 @@ type FileInfo struct {
 // actual bytes in and out of the bucket via the file:: functions. The bucket
 // must exist first — see Migrator.DefineBucket.
 
-func fileConn(db *gorm.DB) (*Dialector, context.Context, error) {
+// fileConn resolves where a file operation runs, honoring an open transaction
+// and an AsToken end-user session. The returned release func must be called.
+func fileConn(db *gorm.DB) (rpcTarget, func(), context.Context, error) {
 	d, ok := db.Dialector.(*Dialector)
 	if !ok || d.Conn == nil {
-		return nil, nil, fmt.Errorf("surrealdb: connection not initialized")
+		return rpcTarget{}, nil, nil, fmt.Errorf("surrealdb: connection not initialized")
 	}
-	ctx := context.Background()
-	if db.Statement != nil && db.Statement.Context != nil {
-		ctx = db.Statement.Context
+	if db.Statement.Context == nil {
+		db.Statement.Context = context.Background()
 	}
-	return d, ctx, nil
+	target, release, err := d.statementTarget(db)
+	if err != nil {
+		return rpcTarget{}, nil, nil, err
+	}
+	return target, release, db.Statement.Context, nil
 }
 
 // PutFile stores content at the file pointer f. The file's bucket must be
 // defined (see DefineBucket).
 func PutFile(db *gorm.DB, f TypesM.File, content []byte) error {
-	d, ctx, err := fileConn(db)
+	target, release, ctx, err := fileConn(db)
 	if err != nil {
 		return err
 	}
-	res, err := surrealdb.Query[interface{}](ctx, d.Conn, "file::put($f, $c)",
+	defer release()
+	res, err := queryOn[interface{}](ctx, target, "file::put($f, $c)",
 		map[string]interface{}{"f": f, "c": content})
 	if err != nil {
 		return &Error{Op: "file::put", Err: err}
@@ -56,11 +61,12 @@ func PutFile(db *gorm.DB, f TypesM.File, content []byte) error {
 
 // GetFile retrieves the content stored at the file pointer f.
 func GetFile(db *gorm.DB, f TypesM.File) ([]byte, error) {
-	d, ctx, err := fileConn(db)
+	target, release, ctx, err := fileConn(db)
 	if err != nil {
 		return nil, err
 	}
-	res, err := surrealdb.Query[[]byte](ctx, d.Conn, "RETURN file::get($f)",
+	defer release()
+	res, err := queryOn[[]byte](ctx, target, "RETURN file::get($f)",
 		map[string]interface{}{"f": f})
 	if err != nil {
 		return nil, &Error{Op: "file::get", Err: err}
@@ -76,11 +82,12 @@ func GetFile(db *gorm.DB, f TypesM.File) ([]byte, error) {
 
 // FileExists reports whether the file pointer f has stored content.
 func FileExists(db *gorm.DB, f TypesM.File) (bool, error) {
-	d, ctx, err := fileConn(db)
+	target, release, ctx, err := fileConn(db)
 	if err != nil {
 		return false, err
 	}
-	res, err := surrealdb.Query[bool](ctx, d.Conn, "RETURN file::exists($f)",
+	defer release()
+	res, err := queryOn[bool](ctx, target, "RETURN file::exists($f)",
 		map[string]interface{}{"f": f})
 	if err != nil {
 		return false, &Error{Op: "file::exists", Err: err}
@@ -96,11 +103,12 @@ func FileExists(db *gorm.DB, f TypesM.File) (bool, error) {
 
 // DeleteFile removes the content stored at the file pointer f.
 func DeleteFile(db *gorm.DB, f TypesM.File) error {
-	d, ctx, err := fileConn(db)
+	target, release, ctx, err := fileConn(db)
 	if err != nil {
 		return err
 	}
-	res, err := surrealdb.Query[interface{}](ctx, d.Conn, "file::delete($f)",
+	defer release()
+	res, err := queryOn[interface{}](ctx, target, "file::delete($f)",
 		map[string]interface{}{"f": f})
 	if err != nil {
 		return &Error{Op: "file::delete", Err: err}
@@ -113,11 +121,12 @@ func DeleteFile(db *gorm.DB, f TypesM.File) error {
 
 // CopyFile copies the content of f to targetKey within the same bucket.
 func CopyFile(db *gorm.DB, f TypesM.File, targetKey string) error {
-	d, ctx, err := fileConn(db)
+	target, release, ctx, err := fileConn(db)
 	if err != nil {
 		return err
 	}
-	res, err := surrealdb.Query[interface{}](ctx, d.Conn, "$f.copy($k)",
+	defer release()
+	res, err := queryOn[interface{}](ctx, target, "$f.copy($k)",
 		map[string]interface{}{"f": f, "k": targetKey})
 	if err != nil {
 		return &Error{Op: "file::copy", Err: err}
@@ -130,11 +139,12 @@ func CopyFile(db *gorm.DB, f TypesM.File, targetKey string) error {
 
 // RenameFile renames f to targetKey within the same bucket.
 func RenameFile(db *gorm.DB, f TypesM.File, targetKey string) error {
-	d, ctx, err := fileConn(db)
+	target, release, ctx, err := fileConn(db)
 	if err != nil {
 		return err
 	}
-	res, err := surrealdb.Query[interface{}](ctx, d.Conn, "$f.rename($k)",
+	defer release()
+	res, err := queryOn[interface{}](ctx, target, "$f.rename($k)",
 		map[string]interface{}{"f": f, "k": targetKey})
 	if err != nil {
 		return &Error{Op: "file::rename", Err: err}
@@ -147,11 +157,12 @@ func RenameFile(db *gorm.DB, f TypesM.File, targetKey string) error {
 
 // FileHead returns metadata (size, updated) for the file pointer f.
 func FileHead(db *gorm.DB, f TypesM.File) (*FileInfo, error) {
-	d, ctx, err := fileConn(db)
+	target, release, ctx, err := fileConn(db)
 	if err != nil {
 		return nil, err
 	}
-	res, err := surrealdb.Query[FileInfo](ctx, d.Conn, "RETURN file::head($f)",
+	defer release()
+	res, err := queryOn[FileInfo](ctx, target, "RETURN file::head($f)",
 		map[string]interface{}{"f": f})
 	if err != nil {
 		return nil, &Error{Op: "file::head", Err: err}
@@ -169,17 +180,18 @@ func FileHead(db *gorm.DB, f TypesM.File) (*FileInfo, error) {
 // ListFiles lists the files in a bucket. opts is an optional SurrealQL options
 // object, e.g. map[string]any{"prefix": "/img/", "limit": 100}.
 func ListFiles(db *gorm.DB, bucket string, opts map[string]interface{}) ([]FileInfo, error) {
-	d, ctx, err := fileConn(db)
+	target, release, ctx, err := fileConn(db)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 	query := "RETURN file::list($b)"
 	params := map[string]interface{}{"b": bucket}
 	if opts != nil {
 		query = "RETURN file::list($b, $o)"
 		params["o"] = opts
 	}
-	res, err := surrealdb.Query[[]FileInfo](ctx, d.Conn, query, params)
+	res, err := queryOn[[]FileInfo](ctx, target, query, params)
 	if err != nil {
 		return nil, &Error{Op: "file::list", Err: err}
 	}
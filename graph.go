@@ -0,0 +1,255 @@
+package surrealdb
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	sdkModels "github.com/surrealdb/surrealdb.go/pkg/models"
+	"gorm.io/gorm"
+
+	"github.com/dailaim/surrealdb-gorm/clauses"
+	localModels "github.com/dailaim/surrealdb-gorm/models"
+)
+
+// graphTableRe matches the table names a graph step may name.
+var graphTableRe = regexp.MustCompile(`^(?:[A-Za-z_][A-Za-z0-9_]*|\?)$`)
+
+// GraphQuery is a graph traversal from a record of T, built step by step
+// and run as SELECT * FROM $start->edge->table[WHERE ...]:
+//
+//	var friends []User
+//	surrealdb.From[User](db, aliceID).Out("follows").Out("users").
+//		Where("age > ?", 30).Find(&friends)
+//
+// Each method returns a new GraphQuery, so a partial path can be reused.
+type GraphQuery[T any] struct {
+	db   *gorm.DB
+	from clauses.GraphFrom
+	err  error
+}
+
+// From starts a graph traversal at the record id of T's table. id is a
+// record id (types.RecordID, models.RecordID or pointers to them) or the
+// key of the record in T's table.
+func From[T any](db *gorm.DB, id interface{}) *GraphQuery[T] {
+	q := &GraphQuery[T]{db: db}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		q.err = err
+		return q
+	}
+	start := extractRecordID(id)
+	if start == nil || start.Table == "" {
+		rid := sdkModels.NewRecordID(stmt.Schema.Table, id)
+		start = &rid
+	} else if start.Table != stmt.Schema.Table {
+		q.err = fmt.Errorf("surrealdb: From[%s] cannot start at a record of %s", stmt.Schema.Name, start.Table)
+	}
+	q.from.From = start
+	return q
+}
+
+// Out follows the outgoing edges, or reaches the records at their out end
+// after an edge step: ->table.
+func (q *GraphQuery[T]) Out(table string) *GraphQuery[T] {
+	return q.step("->", table)
+}
+
+// In follows the incoming edges, or reaches the records at their in end
+// after an edge step: <-table.
+func (q *GraphQuery[T]) In(table string) *GraphQuery[T] {
+	return q.step("<-", table)
+}
+
+// Both follows edges in either direction: <->table.
+func (q *GraphQuery[T]) Both(table string) *GraphQuery[T] {
+	return q.step("<->", table)
+}
+
+// step appends a hop. Edge names are resolved through the dialector's
+// edge table registry, so Out("follow") reaches the follows table; "?"
+// matches any table.
+func (q *GraphQuery[T]) step(dir, table string) *GraphQuery[T] {
+	next := q.clone()
+	if !graphTableRe.MatchString(table) {
+		next.fail(fmt.Errorf("surrealdb: invalid graph table %q", table))
+		return next
+	}
+	if d, ok := q.db.Dialector.(*Dialector); ok {
+		if edge, found := d.FindEdgeTable(table); found {
+			table = edge
+		}
+	}
+	next.from.Steps = append(next.from.Steps, clauses.GraphStep{Dir: dir, Table: table})
+	return next
+}
+
+// Where filters the records reached by the last step, as db.Where does;
+// the condition's vars are bound like any other GORM vars.
+func (q *GraphQuery[T]) Where(query interface{}, args ...interface{}) *GraphQuery[T] {
+	next := q.clone()
+	n := len(next.from.Steps)
+	if n == 0 {
+		next.fail(fmt.Errorf("surrealdb: graph Where needs a step before it"))
+		return next
+	}
+	last := &next.from.Steps[n-1]
+	conds := q.db.Statement.BuildCondition(query, args...)
+	last.Where = append(last.Where[:len(last.Where):len(last.Where)], conds...)
+	return next
+}
+
+// DB returns the traversal as a query, for GORM's Limit, Order, Count and
+// the like.
+func (q *GraphQuery[T]) DB() *gorm.DB {
+	tx := q.db.Clauses(q.from)
+	if q.err != nil {
+		tx.AddError(q.err)
+	}
+	return tx
+}
+
+// Find loads the records the traversal reaches into dest.
+func (q *GraphQuery[T]) Find(dest interface{}) *gorm.DB {
+	return q.DB().Find(dest)
+}
+
+func (q *GraphQuery[T]) clone() *GraphQuery[T] {
+	next := *q
+	next.from.Steps = append([]clauses.GraphStep(nil), q.from.Steps...)
+	return &next
+}
+
+func (q *GraphQuery[T]) fail(err error) {
+	if q.err == nil {
+		q.err = err
+	}
+}
+
+// Traverse returns db reading the records reached from start by following
+// edge between minDepth and maxDepth times, each record once, nearest
+// first: SELECT * FROM $start.{min..max+collect}(->edge->?). It suits
+// hierarchies such as org charts and category trees:
+//
+//	var reports []Employee
+//	surrealdb.Traverse(db, bossID, "manages", 1, 3).Find(&reports)
+//
+// edge is a registered edge table, optionally prefixed with "<-" to follow
+// edges backwards (up a tree) or "<->" for both directions; maxDepth 0
+// goes as deep as the server allows.
+func Traverse(db *gorm.DB, start interface{}, edge string, minDepth, maxDepth int) *gorm.DB {
+	from, err := recursiveFrom(db, start, edge)
+	if err == nil && (minDepth < 1 || maxDepth != 0 && maxDepth < minDepth) {
+		err = fmt.Errorf("surrealdb: Traverse depth %d..%d is not a valid range", minDepth, maxDepth)
+	}
+	from.Recurse = &clauses.Recursion{Min: minDepth, Max: maxDepth, Collect: true}
+	tx := db.Clauses(from)
+	if err != nil {
+		tx.AddError(err)
+	}
+	return tx
+}
+
+// Path is a graph path: Edges[i] joins Nodes[i] and Nodes[i+1].
+type Path[N, E any] struct {
+	Nodes []N
+	Edges []E
+}
+
+// ShortestPath returns the shortest path from one record to another along
+// edge (see Traverse for its direction prefixes), with the records at both
+// ends and the edges between them. E is the edge model:
+//
+//	path, err := surrealdb.ShortestPath[User, Follow](db, aliceID, daveID, "follows")
+//
+// It returns gorm.ErrRecordNotFound when to cannot be reached.
+func ShortestPath[N, E any](db *gorm.DB, from, to interface{}, edge string) (Path[N, E], error) {
+	var path Path[N, E]
+	graph, err := recursiveFrom(db, from, edge)
+	if err != nil {
+		return path, err
+	}
+	target := extractRecordID(to)
+	if target == nil {
+		return path, fmt.Errorf("surrealdb: ShortestPath needs a record id to reach")
+	}
+	graph.Recurse = &clauses.Recursion{Shortest: target, Inclusive: true}
+	tx := db.Clauses(graph).Find(&path.Nodes)
+	if tx.Error != nil {
+		return path, tx.Error
+	}
+	if len(path.Nodes) < 2 {
+		return Path[N, E]{}, gorm.ErrRecordNotFound
+	}
+
+	// The edges between consecutive nodes, in path order.
+	pk := tx.Statement.Schema.PrioritizedPrimaryField
+	ids := make([]*sdkModels.RecordID, len(path.Nodes))
+	for i := range path.Nodes {
+		v, _ := pk.ValueOf(tx.Statement.Context, reflect.ValueOf(&path.Nodes[i]).Elem())
+		if ids[i] = extractRecordID(v); ids[i] == nil {
+			return path, fmt.Errorf("surrealdb: ShortestPath node %d has no record id", i)
+		}
+	}
+	var edges []E
+	if err := db.Table(graph.Steps[0].Table).Where("in IN ? AND out IN ?", ids, ids).Find(&edges).Error; err != nil {
+		return path, err
+	}
+	dir := graph.Steps[0].Dir
+	for i := 1; i < len(ids); i++ {
+		found := false
+		for j := range edges {
+			rel, ok := any(&edges[j]).(localModels.EdgeRelation)
+			if !ok {
+				return path, fmt.Errorf("surrealdb: ShortestPath edge type %T is not an edge model", edges[j])
+			}
+			in, out := extractRecordID(rel.EdgeIn()), extractRecordID(rel.EdgeOut())
+			if in == nil || out == nil {
+				continue
+			}
+			forward := sameRecord(in, ids[i-1]) && sameRecord(out, ids[i])
+			backward := sameRecord(in, ids[i]) && sameRecord(out, ids[i-1])
+			if dir == "->" && forward || dir == "<-" && backward || dir == "<->" && (forward || backward) {
+				path.Edges = append(path.Edges, edges[j])
+				found = true
+				break
+			}
+		}
+		if !found {
+			return path, fmt.Errorf("surrealdb: ShortestPath found no %s edge between %s and %s", graph.Steps[0].Table, ids[i-1], ids[i])
+		}
+	}
+	return path, nil
+}
+
+// recursiveFrom is the one-hop path Traverse and ShortestPath repeat:
+// $start->edge->? for edge, <-edge<-? for "<-edge", <->edge<->? for
+// "<->edge".
+func recursiveFrom(db *gorm.DB, start interface{}, edge string) (clauses.GraphFrom, error) {
+	from := clauses.GraphFrom{From: extractRecordID(start)}
+	dir := "->"
+	for _, d := range []string{"<->", "<-", "->"} {
+		if strings.HasPrefix(edge, d) {
+			dir, edge = d, strings.TrimPrefix(edge, d)
+			break
+		}
+	}
+	from.Steps = []clauses.GraphStep{{Dir: dir, Table: edge}, {Dir: dir, Table: "?"}}
+	if from.From == nil {
+		return from, fmt.Errorf("surrealdb: a graph path needs a record id to start at")
+	}
+	if d, ok := db.Dialector.(*Dialector); ok {
+		if table, found := d.FindEdgeTable(edge); found {
+			from.Steps[0].Table = table
+			return from, nil
+		}
+	}
+	return from, fmt.Errorf("surrealdb: %q is not a registered edge table", edge)
+}
+
+// sameRecord reports whether a and b are the same record id.
+func sameRecord(a, b *sdkModels.RecordID) bool {
+	return a.Table == b.Table && fmt.Sprint(a.ID) == fmt.Sprint(b.ID)
+}
@@ -0,0 +1,174 @@
+package surrealdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/surrealdb/surrealdb.go"
+)
+
+// Stats is a point-in-time snapshot of the dialector's connections, meant for
+// readiness probes, metrics and alerts.
+type Stats struct {
+	// OpenConns counts connections open across the primary and replica pools.
+	OpenConns int
+	// InFlight counts statements currently running.
+	InFlight int
+	// Reconnects counts automatic reconnects since Initialize.
+	Reconnects int64
+	// LiveQueries counts live queries currently registered.
+	LiveQueries int
+	// LiveQueriesLost counts live queries dropped because they could not be
+	// re-issued after a reconnect.
+	LiveQueriesLost int64
+	// LastError is the most recent connection-level error (dial,
+	// authentication, reconnect or failed Ping), nil if there was none.
+	LastError   error
+	LastErrorAt time.Time
+}
+
+// healthState backs Stats.
+type healthState struct {
+	reconnects atomic.Int64
+	liveLost   atomic.Int64
+
+	mu        sync.Mutex
+	lastErr   error
+	lastErrAt time.Time
+}
+
+func (h *healthState) recordError(err error) {
+	if err == nil {
+		return
+	}
+	h.mu.Lock()
+	h.lastErr, h.lastErrAt = err, time.Now()
+	h.mu.Unlock()
+}
+
+// Stats reports the current connection statistics.
+func (d *Dialector) Stats() Stats {
+	var st Stats
+	for _, p := range append([]*connPool{d.pool}, d.replicas...) {
+		if p == nil {
+			continue
+		}
+		p.mu.Lock()
+		st.OpenConns += len(p.conns)
+		for _, c := range p.conns {
+			st.InFlight += int(c.inFlight.Load())
+		}
+		p.mu.Unlock()
+	}
+	st.Reconnects = d.health.reconnects.Load()
+	st.LiveQueriesLost = d.health.liveLost.Load()
+	d.liveConns.Range(func(_, _ any) bool {
+		st.LiveQueries++
+		return true
+	})
+	d.health.mu.Lock()
+	st.LastError, st.LastErrorAt = d.health.lastErr, d.health.lastErrAt
+	d.health.mu.Unlock()
+	return st
+}
+
+// Ping runs a trivial query on the primary and on every replica, verifying
+// that each is reachable, authenticated and has a namespace/database selected.
+func (d *Dialector) Ping(ctx context.Context) error {
+	target, release, err := d.acquire(ctx)
+	if err != nil {
+		d.health.recordError(err)
+		return err
+	}
+	_, err = queryOn[interface{}](ctx, target, "RETURN true", nil)
+	release()
+	if err != nil {
+		err = &Error{Op: "ping", Err: err}
+	}
+	errs := []error{err}
+	for i, p := range d.replicas {
+		pc, rerr := p.acquire(ctx)
+		if rerr == nil {
+			_, rerr = surrealdb.Query[interface{}](ctx, pc.db, "RETURN true", nil)
+			pc.release()
+		}
+		if rerr != nil {
+			errs = append(errs, &Error{Op: "ping", Detail: fmt.Sprintf("replica %s", redactEndpoint(d.Replicas[i])), Err: rerr})
+		}
+	}
+	err = errors.Join(errs...)
+	d.health.recordError(err)
+	return err
+}
+
+// Close shuts the dialector down: it kills registered live queries, cancels
+// open transactions, closes the internal *sql.DB and then every pooled
+// connection, which stops their reconnection. Statements issued afterwards
+// fail. Closing the *sql.DB returned by gorm's db.DB() has the same effect.
+//
+// A Conn supplied by the caller is left open. Close is idempotent.
+func (d *Dialector) Close(ctx context.Context) error {
+	if !d.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	errs := []error{d.killAllLive(ctx)}
+	d.txs.Range(func(k, _ any) bool {
+		if err := k.(*SurrealTx).cancel(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("cancel transaction: %w", err))
+		}
+		return true
+	})
+	if d.sqlDB != nil {
+		errs = append(errs, d.sqlDB.Close())
+	}
+	for _, p := range append([]*connPool{d.pool}, d.replicas...) {
+		if p != nil {
+			p.close(ctx)
+		}
+	}
+	if d.pool == nil && d.Conn != nil && d.ownsConn {
+		d.closeConn(ctx, d.Conn)
+	}
+	if err := d.tape.close(); err != nil {
+		errs = append(errs, fmt.Errorf("close golden file: %w", err))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return &Error{Op: "close", Err: err}
+	}
+	return nil
+}
+
+// GetDBConn implements gorm.GetDBConnector, so db.DB() returns the internal
+// *sql.DB and db.DB().Close() shuts the dialector down.
+func (d *Dialector) GetDBConn() (*sql.DB, error) {
+	if d.sqlDB == nil {
+		return nil, errors.New("surrealdb: connection not initialized")
+	}
+	return d.sqlDB, nil
+}
+
+// authFailed reports an authentication error to Stats and OnAuthFailure.
+func (d *Dialector) authFailed(endpoint string, err error) {
+	d.health.recordError(err)
+	if d.OnAuthFailure != nil {
+		d.OnAuthFailure(redactEndpoint(endpoint), err)
+	}
+}
+
+// redactEndpoint strips credentials and query parameters from an endpoint so
+// it is safe to hand to hooks and logs.
+func redactEndpoint(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	u.RawQuery = ""
+	return u.String()
+}
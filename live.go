@@ -1,7 +1,11 @@
 package surrealdb
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/surrealdb/surrealdb.go"
 	"github.com/surrealdb/surrealdb.go/pkg/connection"
@@ -9,66 +13,487 @@ import (
 	"gorm.io/gorm"
 )
 
-// getSurrealDB extracts the underlying *surrealdb.DB from a GORM instance.
-func getSurrealDB(db *gorm.DB) (*surrealdb.DB, error) {
+// ActionResubscribed is the Action of the synthetic notification a live query
+// delivers after it was re-issued on a reconnected WebSocket. Changes made
+// while the connection was down are not replayed, so consumers that need a
+// consistent view should re-read the table when they see it.
+const ActionResubscribed connection.Action = "RESUBSCRIBED"
+
+// ActionResubscribeFailed is the Action of the last notification a live query
+// delivers when it could not be re-issued after a reconnect. Its Result is the
+// error. The channel is closed after it and the live query is dropped.
+const ActionResubscribeFailed connection.Action = "RESUBSCRIBE_FAILED"
+
+// maxResubscribeAttempts bounds how often a live query is re-issued after a
+// reconnect before the driver gives up on it.
+const maxResubscribeAttempts = 10
+
+// getDialector extracts the SurrealDB dialector from a GORM instance.
+func getDialector(db *gorm.DB) (*Dialector, error) {
 	dialector, ok := db.Dialector.(*Dialector)
 	if !ok {
 		return nil, fmt.Errorf("db is not using surrealdb dialector")
 	}
-	if dialector.Conn == nil {
+	if dialector.pool == nil && dialector.Conn == nil {
 		return nil, fmt.Errorf("surrealdb connection is nil")
 	}
-	return dialector.Conn, nil
+	return dialector, nil
+}
+
+// liveHandle is a live query owned by the driver. The ID handed to callers
+// stays the same for the query's whole life, while the server-side ID changes
+// every time the query is re-issued after a reconnect. Notifications flow
+// through a forwarder so they keep arriving on the same channel.
+type liveHandle struct {
+	d      *Dialector
+	id     *models.UUID // stable ID returned to the caller
+	table  string
+	diff   bool
+	key    sessionKey // session the query runs in, when scoped
+	scoped bool
+	conn   *poolConn     // nil when the dialector has no pool
+	sdb    *surrealdb.DB // connection the query is pinned to
+
+	mu       sync.Mutex
+	serverID string
+	session  *surrealdb.Session
+	unlease  func()       // releases session's lease in the connection's cache
+	sock     *watchedConn // socket serverID lives on; nil without auto-reconnect
+	fwd      *liveForwarder
+	killed   bool
+}
+
+// liveFor returns the registered live query with the given ID.
+func liveFor(db *gorm.DB, liveQueryID string) (*liveHandle, error) {
+	dialector, err := getDialector(db)
+	if err != nil {
+		return nil, err
+	}
+	h, ok := dialector.liveConns.Load(liveQueryID)
+	if !ok {
+		return nil, fmt.Errorf("surrealdb: live query %s not found", liveQueryID)
+	}
+	return h.(*liveHandle), nil
 }
 
 // LiveSelect starts a live query on the given table and returns the live query UUID.
 // If diff is true, notifications will contain only the changed fields (diff mode).
+// The query stays pinned to the pooled connection that started it until killed.
+// Under AsToken the query runs in the end user's session, so table PERMISSIONS
+// filter the notifications.
+//
+// On an auto-reconnecting connection the query is re-issued after every
+// reconnect and keeps its UUID and notification channel; an
+// ActionResubscribed notification marks the gap.
 func LiveSelect(db *gorm.DB, table string, diff bool) (*string, error) {
-	sdb, err := getSurrealDB(db)
+	dialector, err := getDialector(db)
 	if err != nil {
 		return nil, err
 	}
-	uuid, err := surrealdb.Live(db.Statement.Context, sdb, models.Table(table), diff)
-	if err != nil {
+	ctx := db.Statement.Context
+	h := &liveHandle{d: dialector, table: table, diff: diff, sdb: dialector.Conn}
+	h.key, h.scoped = dialector.sessionKeyFor(ctx)
+	if dialector.pool != nil {
+		if h.conn, err = dialector.pool.acquire(ctx); err != nil {
+			return nil, err
+		}
+		defer h.conn.release()
+		h.sdb = h.conn.db
+	} else if h.scoped {
+		return nil, errors.New("surrealdb: sessions need an initialized dialector")
+	}
+	if err := h.start(ctx); err != nil {
 		return nil, err
 	}
-	if uuid == nil {
-		return nil, fmt.Errorf("live query returned nil UUID")
+	s := h.serverID
+	if h.conn != nil {
+		h.conn.pin()
 	}
-	s := uuid.String()
+	dialector.liveConns.Store(s, h)
 	return &s, nil
 }
 
-// LiveNotifications returns a channel that receives real-time change notifications
-// for the given live query ID. The channel must be closed with CloseLiveNotifications.
-func LiveNotifications(db *gorm.DB, liveQueryID string) (<-chan connection.Notification, error) {
-	sdb, err := getSurrealDB(db)
+// start issues LIVE SELECT and records the server-side ID. A scoped query
+// keeps its session leased until it is killed, so the session is never
+// evicted under it. Callers hold no lock.
+func (h *liveHandle) start(ctx context.Context) (err error) {
+	var session *surrealdb.Session
+	var unlease func()
+	if h.scoped {
+		s, release, serr := h.conn.sessions.session(ctx, h.d, h.sdb, h.key)
+		if serr != nil {
+			return serr
+		}
+		session, unlease = s, release
+		defer func() {
+			if err != nil {
+				release()
+			}
+		}()
+	}
+
+	var uuid *models.UUID
+	var sock *watchedConn
+	if w, ok := h.d.watches.Load(h.sdb); ok {
+		// Send on the raw socket so rews does not also replay the query on
+		// reconnect; the driver re-issues it itself, with the right session.
+		sock = w.(*connWatch).socket()
+		req := &connection.RPCRequest{Method: "live", Params: []any{models.Table(h.table), h.diff}}
+		if session != nil {
+			req.Session = session.ID()
+		}
+		resp, err := sock.Call(ctx, req)
+		if err != nil {
+			return err
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if resp.Result == nil {
+			return fmt.Errorf("live query returned nil UUID")
+		}
+		uuid = &models.UUID{}
+		if err := sock.GetUnmarshaler().Unmarshal(*resp.Result, uuid); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if session != nil {
+			uuid, err = surrealdb.Live(ctx, session, models.Table(h.table), h.diff)
+		} else {
+			uuid, err = surrealdb.Live(ctx, h.sdb, models.Table(h.table), h.diff)
+		}
+		if err != nil {
+			return err
+		}
+		if uuid == nil {
+			return fmt.Errorf("live query returned nil UUID")
+		}
+	}
+
+	h.mu.Lock()
+	prev := h.unlease
+	h.serverID, h.session, h.unlease, h.sock = uuid.String(), session, unlease, sock
+	if h.id == nil {
+		h.id = uuid
+	}
+	h.mu.Unlock()
+	if prev != nil {
+		prev()
+	}
+	return nil
+}
+
+// unpin releases the connection and session the query held once it is gone.
+func (h *liveHandle) unpin() {
+	h.mu.Lock()
+	unlease := h.unlease
+	h.unlease = nil
+	h.mu.Unlock()
+	if unlease != nil {
+		unlease()
+	}
+	h.conn.unpin()
+}
+
+// openChannel opens the SDK notification channel for the current server ID.
+// Callers hold h.mu.
+func (h *liveHandle) openChannel() (chan connection.Notification, error) {
+	if h.sock != nil {
+		return h.sock.LiveNotifications(h.serverID)
+	}
+	return h.sdb.LiveNotifications(h.serverID)
+}
+
+// closeChannel closes the SDK notification channel for the current server ID.
+// Callers hold h.mu.
+func (h *liveHandle) closeChannel() error {
+	if h.sock != nil {
+		return h.sock.CloseLiveNotifications(h.serverID)
+	}
+	return h.sdb.CloseLiveNotifications(h.serverID)
+}
+
+// notifications returns the caller-facing channel, starting the forwarder on
+// first use.
+func (h *liveHandle) notifications() (<-chan connection.Notification, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.fwd != nil {
+		return h.fwd.out, nil
+	}
+	in, err := h.openChannel()
 	if err != nil {
 		return nil, err
 	}
-	ch, err := sdb.LiveNotifications(liveQueryID)
+	h.fwd = newLiveForwarder()
+	go h.fwd.run(in, h.id)
+	return h.fwd.out, nil
+}
+
+// closeNotifications stops delivery without killing the query.
+func (h *liveHandle) closeNotifications() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.fwd == nil {
+		return nil
+	}
+	h.fwd.halt()
+	h.fwd = nil
+	return h.closeChannel()
+}
+
+// kill terminates the query on the server and stops delivery.
+func (h *liveHandle) kill(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.sock != nil {
+		req := &connection.RPCRequest{Method: "kill", Params: []any{h.serverID}}
+		if h.session != nil {
+			req.Session = h.session.ID()
+		}
+		resp, err := h.sock.Call(ctx, req)
+		if err != nil {
+			return err
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+	} else {
+		var err error
+		if h.session != nil {
+			err = surrealdb.Kill(ctx, h.session, h.serverID)
+		} else {
+			err = surrealdb.Kill(ctx, h.sdb, h.serverID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	h.killed = true
+	if h.fwd != nil {
+		h.fwd.halt()
+		h.fwd = nil
+		if h.sock != nil {
+			_ = h.closeChannel()
+		}
+	}
+	return nil
+}
+
+// resubscribe re-issues the query after its connection reconnected and swaps
+// the forwarder onto the new notification channel. If that cannot be done the
+// query is dropped with fail and the error returned.
+func (h *liveHandle) resubscribe(ctx context.Context) error {
+	interval := h.d.ReconnectInterval
+	if interval <= 0 {
+		interval = defaultReconnectInterval
+	}
+	var err error
+	for attempt := 0; attempt < maxResubscribeAttempts; attempt++ {
+		h.mu.Lock()
+		killed := h.killed
+		h.mu.Unlock()
+		if killed {
+			return nil
+		}
+		if err = h.start(ctx); err == nil {
+			h.mu.Lock()
+			fwd := h.fwd
+			var in chan connection.Notification
+			if fwd != nil {
+				in, err = h.openChannel()
+			}
+			h.mu.Unlock()
+			if err != nil {
+				err = fmt.Errorf("surrealdb: live query %s: open notifications: %w", h.id, err)
+				h.fail(err)
+				_ = h.kill(ctx) // the re-issued query has nowhere to deliver
+				return err
+			}
+			// Hand over outside the lock: the forwarder may be blocked on a slow
+			// consumer, and Close/Kill must still be able to stop it.
+			if fwd != nil {
+				fwd.resume(in)
+			}
+			return nil
+		}
+		time.Sleep(interval)
+	}
+	err = fmt.Errorf("surrealdb: live query %s not re-issued after %d attempts: %w", h.id, maxResubscribeAttempts, err)
+	h.fail(err)
+	return err
+}
+
+// fail drops a query that could not be re-issued: it is unregistered and its
+// connection unpinned, the loss counted in Stats, and its consumer receives an
+// ActionResubscribeFailed notification carrying err before the channel closes.
+func (h *liveHandle) fail(err error) {
+	h.mu.Lock()
+	h.killed = true
+	fwd := h.fwd
+	h.fwd = nil
+	h.mu.Unlock()
+	h.d.health.liveLost.Add(1)
+	h.d.health.recordError(err)
+	if h.id != nil {
+		if _, ok := h.d.liveConns.LoadAndDelete(h.id.String()); ok && h.conn != nil {
+			h.unpin()
+		}
+	}
+	if fwd != nil {
+		fwd.abort(err)
+	}
+}
+
+// resubscribeLive re-issues every live query pinned to conn and waits until
+// each has been restored or given up on; a query given up on is reported to
+// its consumer and in Stats by fail.
+func (d *Dialector) resubscribeLive(ctx context.Context, conn *surrealdb.DB) {
+	var wg sync.WaitGroup
+	d.liveConns.Range(func(_, v any) bool {
+		if h := v.(*liveHandle); h.sdb == conn {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_ = h.resubscribe(ctx)
+			}()
+		}
+		return true
+	})
+	wg.Wait()
+}
+
+// liveForwarder copies notifications from the current SDK channel to the
+// caller's channel, which outlives any single WebSocket.
+type liveForwarder struct {
+	out  chan connection.Notification
+	swap chan chan connection.Notification
+	dead chan error
+	stop chan struct{}
+	once sync.Once
+}
+
+func newLiveForwarder() *liveForwarder {
+	return &liveForwarder{
+		out:  make(chan connection.Notification),
+		swap: make(chan chan connection.Notification),
+		dead: make(chan error),
+		stop: make(chan struct{}),
+	}
+}
+
+func (f *liveForwarder) run(in chan connection.Notification, id *models.UUID) {
+	defer close(f.out)
+	for {
+		select {
+		case <-f.stop:
+			return
+		case next := <-f.swap:
+			in = next
+			if !f.emit(connection.Notification{ID: id, Action: ActionResubscribed}) {
+				return
+			}
+		case err := <-f.dead:
+			f.emit(connection.Notification{ID: id, Action: ActionResubscribeFailed, Result: err})
+			return
+		case n, ok := <-in:
+			if !ok {
+				// The SDK closed the old channel; wait for a resubscribe.
+				in = nil
+				continue
+			}
+			n.ID = id
+			if !f.emit(n) {
+				return
+			}
+		}
+	}
+}
+
+func (f *liveForwarder) emit(n connection.Notification) bool {
+	select {
+	case f.out <- n:
+		return true
+	case <-f.stop:
+		return false
+	}
+}
+
+// resume switches the forwarder onto in and announces the resubscription.
+func (f *liveForwarder) resume(in chan connection.Notification) {
+	select {
+	case f.swap <- in:
+	case <-f.stop:
+	}
+}
+
+// abort delivers the ActionResubscribeFailed notification for err and closes
+// the out channel.
+func (f *liveForwarder) abort(err error) {
+	select {
+	case f.dead <- err:
+	case <-f.stop:
+	}
+}
+
+func (f *liveForwarder) halt() {
+	f.once.Do(func() { close(f.stop) })
+}
+
+// LiveNotifications returns a channel that receives real-time change notifications
+// for the given live query ID. The channel must be closed with CloseLiveNotifications.
+func LiveNotifications(db *gorm.DB, liveQueryID string) (<-chan connection.Notification, error) {
+	h, err := liveFor(db, liveQueryID)
 	if err != nil {
 		return nil, err
 	}
-	return ch, nil
+	return h.notifications()
 }
 
 // CloseLiveNotifications closes the notification channel for a live query.
 func CloseLiveNotifications(db *gorm.DB, liveQueryID string) error {
-	sdb, err := getSurrealDB(db)
+	h, err := liveFor(db, liveQueryID)
 	if err != nil {
 		return err
 	}
-	return sdb.CloseLiveNotifications(liveQueryID)
+	return h.closeNotifications()
+}
+
+// killAllLive kills every registered live query. A query whose kill fails
+// (e.g. its connection is already gone) still stops delivering.
+func (d *Dialector) killAllLive(ctx context.Context) error {
+	var errs []error
+	d.liveConns.Range(func(id, v any) bool {
+		h := v.(*liveHandle)
+		if err := h.kill(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("kill live query %v: %w", id, err))
+			h.mu.Lock()
+			h.killed = true
+			h.mu.Unlock()
+			_ = h.closeNotifications()
+		}
+		if _, ok := d.liveConns.LoadAndDelete(id); ok && h.conn != nil {
+			h.unpin()
+		}
+		return true
+	})
+	return errors.Join(errs...)
 }
 
 // KillLiveQuery terminates a live query and closes its notification channel.
 func KillLiveQuery(db *gorm.DB, liveQueryID string) error {
-	sdb, err := getSurrealDB(db)
+	h, err := liveFor(db, liveQueryID)
 	if err != nil {
 		return err
 	}
-	return surrealdb.Kill(db.Statement.Context, sdb, liveQueryID)
+	if err := h.kill(db.Statement.Context); err != nil {
+		return err
+	}
+	if _, ok := h.d.liveConns.LoadAndDelete(liveQueryID); ok && h.conn != nil {
+		h.unpin()
+	}
+	return nil
 }
 
 // LiveQuery is a convenience wrapper for managing a single live query subscription.
@@ -88,7 +513,8 @@ func NewLiveQuery(db *gorm.DB, table string, diff bool) (*LiveQuery, error) {
 	return &LiveQuery{DB: db, ID: *id, Diff: diff, Table: table}, nil
 }
 
-// Notifications returns the notification channel for this live query.
+// Notifications returns the notification channel for this live query. It is
+// the same channel across reconnects.
 func (l *LiveQuery) Notifications() (<-chan connection.Notification, error) {
 	return LiveNotifications(l.DB, l.ID)
 }
@@ -7,7 +7,6 @@ import (
 	"strings"
 
 	localModels "github.com/dailaim/surrealdb-gorm/models"
-	"github.com/surrealdb/surrealdb.go"
 	"gorm.io/gorm"
 	"gorm.io/gorm/migrator"
 	"gorm.io/gorm/schema"
@@ -54,6 +53,7 @@ func (m Migrator) AutoMigrate(dst ...interface{}) error {
 			if modelType.Implements(edgeRelType) || reflect.PointerTo(modelType).Implements(edgeRelType) {
 				if d, ok := m.DB.Dialector.(*Dialector); ok {
 					d.RegisterEdgeTable(tableName)
+					d.registerEdgeModel(tableName, modelType)
 				}
 			}
 		}
@@ -85,6 +85,11 @@ func (m Migrator) AutoMigrate(dst ...interface{}) error {
 			if err := m.defineIndexes(stmt); err != nil {
 				return err
 			}
+			if isEdge {
+				if err := m.defineEdgeIndex(stmt); err != nil {
+					return err
+				}
+			}
 			// For existing tables, clean up fields that no longer exist in the model.
 			if !isNewTable {
 				return m.removeObsoleteFields(stmt, isEdge)
@@ -131,7 +136,12 @@ func (m Migrator) getExistingTables() (map[string]string, error) {
 		Tables map[string]string `json:"tables"`
 	}
 
-	results, err := surrealdb.Query[InfoForDB](m.ctx(), dialector.Conn, "INFO FOR DB", nil)
+	target, release, err := dialector.acquire(m.ctx())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	results, err := queryOn[InfoForDB](m.ctx(), target, "INFO FOR DB", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -156,7 +166,12 @@ func (m Migrator) tableInfo(table string) (fields, indexes map[string]string, er
 		Fields  map[string]string `json:"fields"`
 		Indexes map[string]string `json:"indexes"`
 	}
-	res, err := surrealdb.Query[info](m.ctx(), dialector.Conn,
+	target, release, err := dialector.acquire(m.ctx())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+	res, err := queryOn[info](m.ctx(), target,
 		fmt.Sprintf("INFO FOR TABLE `%s`", table), nil)
 	if err != nil || res == nil || len(*res) == 0 {
 		return map[string]string{}, map[string]string{}, err
@@ -405,7 +420,12 @@ func (m Migrator) removeObsoleteFields(stmt *gorm.Statement, isEdge bool) error
 	type InfoForTable struct {
 		Fields map[string]string `json:"fields"`
 	}
-	res, err := surrealdb.Query[InfoForTable](m.ctx(), dialector.Conn,
+	target, release, err := dialector.acquire(m.ctx())
+	if err != nil {
+		return nil
+	}
+	defer release()
+	res, err := queryOn[InfoForTable](m.ctx(), target,
 		fmt.Sprintf("INFO FOR TABLE `%s`", tableName), nil)
 	if err != nil || res == nil || len(*res) == 0 {
 		return nil // best-effort cleanup
@@ -506,6 +526,22 @@ func (m Migrator) defineIndexes(stmt *gorm.Statement) error {
 	return nil
 }
 
+// defineEdgeIndex defines a unique index on [in, out] for an edge model
+// with UniqueEdges, so the database refuses a second edge between the same
+// records.
+func (m Migrator) defineEdgeIndex(stmt *gorm.Statement) error {
+	if !uniqueEdgeModel(stmt.Schema.ModelType) {
+		return nil
+	}
+	tableName := stmt.Schema.Table
+	name := edgeIndexName(tableName)
+	sql := fmt.Sprintf("DEFINE INDEX IF NOT EXISTS `%s` ON `%s` FIELDS `in`, `out` UNIQUE", name, tableName)
+	if err := m.DB.Exec(sql).Error; err != nil {
+		return fmt.Errorf("define index %s on %s: %w", name, tableName, err)
+	}
+	return nil
+}
+
 // buildVectorIndexParams turns an index option string of space-separated
 // `key=value` pairs into the SurrealDB vector-index parameter clause, e.g.
 // "dimension=4 dist=euclidean efc=150 m=12" -> " DIMENSION 4 DIST EUCLIDEAN EFC 150 M 12".
@@ -20,17 +20,28 @@ type EdgeRelation interface {
 	EdgeOut() *types.RecordID
 }
 
+// UniqueEdgeRelation is implemented by edge models that allow at most one
+// edge between the same in and out records:
+//
+//	func (Follows) UniqueEdges() bool { return true }
+//
+// AutoMigrate then defines a unique index on [in, out], and creating an
+// edge that already exists (db.Create, Relate, Association.Append,
+// AppendEdge) updates it instead of adding a duplicate.
+type UniqueEdgeRelation interface {
+	UniqueEdges() bool
+}
+
 // Edge is the base embedded type for SurrealDB graph edge models.
 // Embed it in your own struct together with BaseModel if you need IDs / timestamps.
 //
 // # Extra fields and Association.Append
 //
 // GORM's standard Association.Append API does not provide a way to pass
-// additional data to the join table. If you need an edge with custom fields
-// (e.g. Name, Year), use db.Create(&MyEdge{Edge: ..., Name: "x"}) directly.
-// Association.Append will create the edge but leave extra fields at their zero
-// value. This is a limitation of the GORM association API and cannot be worked
-// around without a custom helper.
+// additional data to the join table: it creates the edge but leaves extra
+// fields at their zero value. To set them (e.g. Name, Year), use
+// surrealdb.AppendEdge or AppendEdgeModel instead, or create the edge with
+// db.Create(&MyEdge{Edge: ..., Name: "x"}).
 type Edge[T any, U any] struct {
 	ID  *types.RecordID `gorm:"primaryKey;type:record;<-:create" json:"id,omitempty"`
 	In  *types.Link[T]  `gorm:"column:in" json:"in,omitempty"`
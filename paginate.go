@@ -0,0 +1,270 @@
+package surrealdb
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	sdkModels "github.com/surrealdb/surrealdb.go/pkg/models"
+	"github.com/surrealdb/surrealdb.go/surrealcbor"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	TypesM "github.com/dailaim/surrealdb-gorm/types"
+)
+
+// paginateKey is the statement setting Paginate stores its *page under.
+const paginateKey = "surrealdb:paginate"
+
+// page is a keyset page request.
+type page struct {
+	limit int
+	// after is the last record of the previous page, key its sort key.
+	after *sdkModels.RecordID
+	key   interface{}
+	// sortField is the field ordered by, nil for record id order; it is
+	// resolved when the query runs.
+	sortField *schema.Field
+}
+
+// Paginate returns db limited to the limit records after cursor, "" for
+// the first page. Pages follow record id order, read as a record range
+// (FROM users:⟨last⟩>..) so each page costs the same however deep it is.
+// With one Order column set on db before Paginate, pages follow that
+// column, the record id breaking ties; an index on the column keeps pages
+// cheap.
+//
+//	tx := surrealdb.Paginate(db.Where("active = ?", true), cursor, 100).Find(&users)
+//	next := surrealdb.NextCursor(tx) // "" after the last page
+//
+// Cursors are opaque and only valid for the same query.
+func Paginate(db *gorm.DB, cursor string, limit int) *gorm.DB {
+	p := &page{limit: limit}
+	tx := db.Set(paginateKey, p)
+	if limit <= 0 {
+		tx.AddError(fmt.Errorf("surrealdb: Paginate limit must be positive, got %d", limit))
+		return tx
+	}
+	if cursor == "" {
+		return tx
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	var parts []interface{}
+	if err == nil {
+		err = surrealcbor.Unmarshal(data, &parts)
+	}
+	if err == nil && len(parts) > 0 {
+		p.after = extractRecordID(parts[0])
+	}
+	if err != nil || p.after == nil {
+		tx.AddError(fmt.Errorf("surrealdb: invalid pagination cursor"))
+		return tx
+	}
+	if len(parts) > 1 {
+		p.key = parts[1]
+	}
+	return tx
+}
+
+// NextCursor returns the cursor of the page after the one tx, a Find on a
+// Paginate query, loaded, or "" when it was the last page.
+func NextCursor(tx *gorm.DB) string {
+	v, ok := tx.Get(paginateKey)
+	if !ok || tx.Error != nil || tx.Statement.Schema == nil {
+		return ""
+	}
+	p := v.(*page)
+	rows := reflect.Indirect(tx.Statement.ReflectValue)
+	if rows.Kind() != reflect.Slice || rows.Len() < p.limit || rows.Len() == 0 {
+		return ""
+	}
+	last := reflect.Indirect(rows.Index(rows.Len() - 1))
+	pk := tx.Statement.Schema.PrioritizedPrimaryField
+	if pk == nil || last.Kind() != reflect.Struct {
+		return ""
+	}
+	id, _ := pk.ValueOf(tx.Statement.Context, last)
+	rid := extractRecordID(id)
+	if rid == nil {
+		return ""
+	}
+	parts := []interface{}{*rid}
+	if p.sortField != nil {
+		key, _ := p.sortField.ValueOf(tx.Statement.Context, last)
+		parts = append(parts, TypesM.ToSDKValue(key))
+	}
+	data, err := surrealcbor.Marshal(parts)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// handlePagination applies Paginate to the query, and turns GORM's
+// FindInBatches (ORDER BY id, then WHERE id > last) into record ranges.
+func handlePagination(db *gorm.DB) {
+	if db.Error != nil || isCountSelect(db.Statement) {
+		return
+	}
+	if v, ok := db.Get(paginateKey); ok {
+		if db.Statement.Schema == nil {
+			db.AddError(fmt.Errorf("surrealdb: Paginate requires a model"))
+			return
+		}
+		applyPage(db, v.(*page))
+		return
+	}
+	batchRange(db)
+}
+
+func applyPage(db *gorm.DB, p *page) {
+	stmt := db.Statement
+	limit := p.limit
+	stmt.AddClause(clause.Limit{Limit: &limit})
+
+	column, desc, err := pageOrder(stmt)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	p.sortField = nil
+	if column != "" {
+		if p.sortField = stmt.Schema.LookUpField(column); p.sortField == nil {
+			db.AddError(fmt.Errorf("surrealdb: Paginate cannot order by %q: not a field of %s", column, stmt.Schema.Name))
+			return
+		}
+	}
+
+	if p.sortField == nil && !desc {
+		if from := recordRange(stmt.Table, p.after); from != "" {
+			delete(stmt.Clauses, "ORDER BY")
+			stmt.AddClause(clause.From{Tables: []clause.Table{{Name: from, Raw: true}}})
+			return
+		}
+	}
+
+	// Keyset condition on (column, id).
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	if p.after != nil {
+		expr := clause.Expr{SQL: "id " + op + " ?", Vars: []interface{}{p.after}}
+		if p.sortField != nil {
+			col := stmt.Quote(p.sortField.DBName)
+			expr = clause.Expr{
+				SQL:  fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", col, op, col, op),
+				Vars: []interface{}{p.key, p.key, p.after},
+			}
+		}
+		stmt.AddClause(clause.Where{Exprs: []clause.Expression{expr}})
+	}
+	var order []clause.OrderByColumn
+	if p.sortField != nil {
+		order = append(order, clause.OrderByColumn{Column: clause.Column{Name: p.sortField.DBName}, Desc: desc})
+	}
+	order = append(order, clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: desc})
+	order[0].Reorder = true
+	stmt.AddClause(clause.OrderBy{Columns: order})
+}
+
+// pageOrder returns the column a paginated query is ordered by, "" for
+// the record id.
+func pageOrder(stmt *gorm.Statement) (column string, desc bool, err error) {
+	c, ok := stmt.Clauses["ORDER BY"]
+	if !ok {
+		return "", false, nil
+	}
+	orderBy, _ := c.Expression.(clause.OrderBy)
+	if orderBy.Expression != nil || len(orderBy.Columns) > 1 {
+		return "", false, fmt.Errorf("surrealdb: Paginate supports one Order column")
+	}
+	if len(orderBy.Columns) == 0 {
+		return "", false, nil
+	}
+	col := orderBy.Columns[0]
+	name, desc := col.Column.Name, col.Desc
+	if col.Column.Raw {
+		fields := strings.Fields(name)
+		if len(fields) == 0 || len(fields) > 2 || len(fields) == 2 && !strings.EqualFold(fields[1], "ASC") && !strings.EqualFold(fields[1], "DESC") {
+			return "", false, fmt.Errorf("surrealdb: Paginate cannot order by %q", name)
+		}
+		name = fields[0]
+		desc = len(fields) == 2 && strings.EqualFold(fields[1], "DESC")
+	}
+	name = strings.Trim(name, "`")
+	if name == "id" || name == clause.PrimaryKey {
+		name = ""
+	}
+	return name, desc, nil
+}
+
+// batchRange rewrites the queries of GORM's FindInBatches, ordered by the
+// primary key and continuing after the last one seen (WHERE id > last), to
+// read the next record range instead.
+func batchRange(db *gorm.DB) {
+	stmt := db.Statement
+	c, ok := stmt.Clauses["ORDER BY"]
+	if !ok {
+		return
+	}
+	orderBy, _ := c.Expression.(clause.OrderBy)
+	pk := clause.Column{Table: clause.CurrentTable, Name: clause.PrimaryKey}
+	if len(orderBy.Columns) != 1 || orderBy.Columns[0].Column != pk || orderBy.Columns[0].Desc {
+		return
+	}
+
+	// The first batch orders like First does and is left alone.
+	w := stmt.Clauses["WHERE"]
+	where, _ := w.Expression.(clause.Where)
+	for i, e := range where.Exprs {
+		gt, ok := e.(clause.Gt)
+		if !ok || gt.Column != pk {
+			continue
+		}
+		after := extractRecordID(gt.Value)
+		if after == nil {
+			return
+		}
+		from := recordRange(stmt.Table, after)
+		if from == "" {
+			return
+		}
+		where.Exprs = append(where.Exprs[:i:i], where.Exprs[i+1:]...)
+		w.Expression = where
+		stmt.Clauses["WHERE"] = w
+		delete(stmt.Clauses, "ORDER BY")
+		stmt.AddClause(clause.From{Tables: []clause.Table{{Name: from, Raw: true}}})
+		return
+	}
+}
+
+// recordRange returns the record range of table after the record id,
+// all of it for nil, or "" when the id's key cannot be written as a range
+// bound.
+func recordRange(table string, after *sdkModels.RecordID) string {
+	if table == "" {
+		return ""
+	}
+	prefix := "`" + strings.ReplaceAll(table, "`", "\\`") + "`:"
+	if after == nil {
+		return prefix + ".."
+	}
+	var key string
+	switch k := after.ID.(type) {
+	case string:
+		key = "⟨" + strings.ReplaceAll(strings.ReplaceAll(k, `\`, `\\`), "⟩", `\⟩`) + "⟩"
+	case int:
+		key = strconv.Itoa(k)
+	case int64:
+		key = strconv.FormatInt(k, 10)
+	case uint64:
+		key = strconv.FormatUint(k, 10)
+	default:
+		return ""
+	}
+	return prefix + key + ">.."
+}
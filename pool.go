@@ -0,0 +1,460 @@
+package surrealdb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/surrealdb/surrealdb.go"
+	sdkModels "github.com/surrealdb/surrealdb.go/pkg/models"
+	"gorm.io/gorm"
+)
+
+// defaultConnMaxIdleTime is how long a connection above MinConns may sit idle
+// before the pool closes it.
+const defaultConnMaxIdleTime = 5 * time.Minute
+
+// errPoolClosed is returned when a statement is issued after the pool shut down.
+var errPoolClosed = errors.New("surrealdb: connection pool is closed")
+
+// ============================================================================
+// rpcTarget — where a statement is sent
+// ============================================================================
+
+// rpcTarget is the SDK handle a statement runs against: a pooled *surrealdb.DB,
+// an attached *surrealdb.Session, or an open *surrealdb.Transaction. The SDK
+// expresses these as a generic type-set constraint, which can't be stored in a
+// variable, so the helpers below dispatch on whichever field is set (the most
+// specific one wins).
+type rpcTarget struct {
+	db      *surrealdb.DB
+	session *surrealdb.Session
+	tx      *surrealdb.Transaction
+	// reauth signs the target in again with fresh credentials. It is nil
+	// where that is impossible: transactions, end-user sessions, and a
+	// caller-supplied Conn.
+	reauth func(ctx context.Context) error
+}
+
+// retryExpired runs call and, when the server rejects it because the target's
+// session expired, re-authenticates the target and runs call once more.
+func retryExpired[R any](ctx context.Context, t rpcTarget, call func() (R, error)) (R, error) {
+	res, err := call()
+	if err == nil || t.reauth == nil || !isSessionExpired(err) {
+		return res, err
+	}
+	if rerr := t.reauth(ctx); rerr != nil {
+		return res, errors.Join(err, rerr)
+	}
+	return call()
+}
+
+func queryOn[T any](ctx context.Context, t rpcTarget, sql string, vars map[string]interface{}) (*[]surrealdb.QueryResult[T], error) {
+	return retryExpired(ctx, t, func() (*[]surrealdb.QueryResult[T], error) {
+		switch {
+		case t.tx != nil:
+			return surrealdb.Query[T](ctx, t.tx, sql, vars)
+		case t.session != nil:
+			return surrealdb.Query[T](ctx, t.session, sql, vars)
+		default:
+			return surrealdb.Query[T](ctx, t.db, sql, vars)
+		}
+	})
+}
+
+func createOn[T any, W surrealdb.TableOrRecord](ctx context.Context, t rpcTarget, what W, data interface{}) (*T, error) {
+	return retryExpired(ctx, t, func() (*T, error) {
+		switch {
+		case t.tx != nil:
+			return surrealdb.Create[T](ctx, t.tx, what, data)
+		case t.session != nil:
+			return surrealdb.Create[T](ctx, t.session, what, data)
+		default:
+			return surrealdb.Create[T](ctx, t.db, what, data)
+		}
+	})
+}
+
+func updateOn[T any, W surrealdb.TableOrRecord](ctx context.Context, t rpcTarget, what W, data interface{}) (*T, error) {
+	return retryExpired(ctx, t, func() (*T, error) {
+		switch {
+		case t.tx != nil:
+			return surrealdb.Update[T](ctx, t.tx, what, data)
+		case t.session != nil:
+			return surrealdb.Update[T](ctx, t.session, what, data)
+		default:
+			return surrealdb.Update[T](ctx, t.db, what, data)
+		}
+	})
+}
+
+func insertOn[T any](ctx context.Context, t rpcTarget, table sdkModels.Table, data interface{}) (*[]T, error) {
+	return retryExpired(ctx, t, func() (*[]T, error) {
+		switch {
+		case t.tx != nil:
+			return surrealdb.Insert[T](ctx, t.tx, table, data)
+		case t.session != nil:
+			return surrealdb.Insert[T](ctx, t.session, table, data)
+		default:
+			return surrealdb.Insert[T](ctx, t.db, table, data)
+		}
+	})
+}
+
+func insertRelationOn[T any](ctx context.Context, t rpcTarget, rel *surrealdb.Relationship) (*T, error) {
+	return retryExpired(ctx, t, func() (*T, error) {
+		switch {
+		case t.tx != nil:
+			return surrealdb.InsertRelation[T](ctx, t.tx, rel)
+		case t.session != nil:
+			return surrealdb.InsertRelation[T](ctx, t.session, rel)
+		default:
+			return surrealdb.InsertRelation[T](ctx, t.db, rel)
+		}
+	})
+}
+
+func relateOn[T any](ctx context.Context, t rpcTarget, rel *surrealdb.Relationship) (*T, error) {
+	return retryExpired(ctx, t, func() (*T, error) {
+		switch {
+		case t.tx != nil:
+			return surrealdb.Relate[T](ctx, t.tx, rel)
+		case t.session != nil:
+			return surrealdb.Relate[T](ctx, t.session, rel)
+		default:
+			return surrealdb.Relate[T](ctx, t.db, rel)
+		}
+	})
+}
+
+// ============================================================================
+// connPool — N WebSocket connections behind the Dialector
+// ============================================================================
+
+// poolConn is one connection owned by the pool.
+type poolConn struct {
+	db *surrealdb.DB
+	// inFlight counts statements currently running on this connection.
+	inFlight atomic.Int64
+	// pinned counts open transactions and live queries bound to this
+	// connection; a pinned connection is never reaped.
+	pinned atomic.Int64
+	// lastUsed is the UnixNano time the connection last went idle.
+	lastUsed atomic.Int64
+	// sessions holds the end-user and tenant sessions attached to this
+	// connection.
+	sessions sessionCache
+}
+
+func (c *poolConn) release() {
+	c.inFlight.Add(-1)
+	c.lastUsed.Store(time.Now().UnixNano())
+}
+
+func (c *poolConn) pin()   { c.pinned.Add(1) }
+func (c *poolConn) unpin() { c.pinned.Add(-1) }
+
+// connPool spreads statements across up to max authenticated connections. It
+// keeps at least min connections open, dials more on demand while every open
+// connection is busy, and closes surplus connections after they sit idle for
+// maxIdle.
+type connPool struct {
+	open func(ctx context.Context) (*surrealdb.DB, error)
+	// closeConn, when set, closes connections the pool drops; it lets the
+	// dialector forget per-connection state. Defaults to db.Close.
+	closeConn func(ctx context.Context, db *surrealdb.DB)
+	min       int
+	max       int
+	maxIdle   time.Duration
+
+	mu      sync.Mutex
+	conns   []*poolConn
+	dialing int
+	closed  bool
+	done    chan struct{}
+}
+
+// newConnPool builds a pool around an already-established first connection.
+// open dials and authenticates additional connections; it may be nil, in which
+// case the pool never grows past the first connection.
+func newConnPool(first *surrealdb.DB, open func(ctx context.Context) (*surrealdb.DB, error), minConns, maxConns int, maxIdle time.Duration) *connPool {
+	if minConns < 1 {
+		minConns = 1
+	}
+	if maxConns < minConns {
+		maxConns = minConns
+	}
+	if open == nil {
+		minConns, maxConns = 1, 1
+	}
+	if maxIdle <= 0 {
+		maxIdle = defaultConnMaxIdleTime
+	}
+	p := &connPool{
+		open:    open,
+		min:     minConns,
+		max:     maxConns,
+		maxIdle: maxIdle,
+		done:    make(chan struct{}),
+	}
+	pc := &poolConn{db: first}
+	pc.lastUsed.Store(time.Now().UnixNano())
+	p.conns = append(p.conns, pc)
+	if p.max > p.min {
+		go p.reapLoop()
+	}
+	return p
+}
+
+// fill dials connections until the pool holds min of them.
+func (p *connPool) fill(ctx context.Context) error {
+	for {
+		p.mu.Lock()
+		if p.closed || len(p.conns) >= p.min {
+			p.mu.Unlock()
+			return nil
+		}
+		p.mu.Unlock()
+
+		db, err := p.open(ctx)
+		if err != nil {
+			return err
+		}
+		p.add(ctx, db)
+	}
+}
+
+// add registers a freshly dialed connection, or closes it if the pool shut
+// down in the meantime.
+func (p *connPool) add(ctx context.Context, db *surrealdb.DB) *poolConn {
+	pc := &poolConn{db: db}
+	pc.lastUsed.Store(time.Now().UnixNano())
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		p.closeDB(ctx, db)
+		return nil
+	}
+	p.conns = append(p.conns, pc)
+	return pc
+}
+
+// acquire returns the least-loaded connection and marks a statement in flight
+// on it. When every open connection is busy and the pool is below max, a new
+// connection is dialed instead. Callers must release the connection.
+func (p *connPool) acquire(ctx context.Context) (*poolConn, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, errPoolClosed
+	}
+	best := p.leastLoadedLocked()
+	if (best == nil || best.inFlight.Load() > 0) && p.open != nil && len(p.conns)+p.dialing < p.max {
+		p.dialing++
+		p.mu.Unlock()
+		db, err := p.open(ctx)
+		var pc *poolConn
+		if err == nil {
+			pc = p.add(ctx, db)
+		}
+		p.mu.Lock()
+		p.dialing--
+		if pc != nil {
+			best = pc
+		} else {
+			// A failed dial falls back to the least-loaded open connection; the
+			// statement still runs, just without extra parallelism.
+			best = p.leastLoadedLocked()
+			if best == nil && err != nil {
+				p.mu.Unlock()
+				return nil, err
+			}
+		}
+	}
+	if p.closed || best == nil {
+		p.mu.Unlock()
+		return nil, errPoolClosed
+	}
+	best.inFlight.Add(1)
+	p.mu.Unlock()
+	return best, nil
+}
+
+func (p *connPool) leastLoadedLocked() *poolConn {
+	var best *poolConn
+	for _, c := range p.conns {
+		if best == nil || c.inFlight.Load() < best.inFlight.Load() {
+			best = c
+		}
+	}
+	return best
+}
+
+// reapLoop periodically closes surplus idle connections until the pool closes.
+func (p *connPool) reapLoop() {
+	ticker := time.NewTicker(p.maxIdle / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.reapIdle(time.Now())
+		}
+	}
+}
+
+// reapIdle closes connections that have been idle longer than maxIdle while
+// keeping at least min open. The first connection (Dialector.Conn) is never
+// reaped, nor is any connection pinned by a transaction or live query.
+func (p *connPool) reapIdle(now time.Time) {
+	p.mu.Lock()
+	var victims []*poolConn
+	kept := p.conns[:1]
+	for _, c := range p.conns[1:] {
+		idle := now.Sub(time.Unix(0, c.lastUsed.Load()))
+		if len(p.conns)-len(victims) > p.min &&
+			c.inFlight.Load() == 0 && c.pinned.Load() == 0 && idle > p.maxIdle {
+			victims = append(victims, c)
+			continue
+		}
+		kept = append(kept, c)
+	}
+	p.conns = kept
+	p.mu.Unlock()
+
+	for _, c := range victims {
+		p.closeDB(context.Background(), c.db)
+	}
+}
+
+func (p *connPool) closeDB(ctx context.Context, db *surrealdb.DB) {
+	switch {
+	case db == nil:
+	case p.closeConn != nil:
+		p.closeConn(ctx, db)
+	default:
+		_ = db.Close(ctx)
+	}
+}
+
+// close shuts the pool down: acquire fails from then on, the reaper stops and
+// every connection is closed.
+func (p *connPool) close(ctx context.Context) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.done)
+	conns := p.conns
+	p.conns = nil
+	p.mu.Unlock()
+
+	for _, c := range conns {
+		p.closeDB(ctx, c.db)
+	}
+}
+
+// find returns the pooled connection wrapping db, if the pool owns it.
+func (p *connPool) find(db *surrealdb.DB) *poolConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range p.conns {
+		if c.db == db {
+			return c
+		}
+	}
+	return nil
+}
+
+// size reports how many connections are currently open.
+func (p *connPool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.conns)
+}
+
+// ============================================================================
+// Statement routing
+// ============================================================================
+
+// acquire picks a pooled connection for a statement that is not bound to a
+// transaction. The returned release func must be called when the statement is
+// done.
+//
+// When ctx carries an end-user token (AsToken) or another namespace/database
+// (WithDatabase), the statement runs in a matching session attached to the
+// chosen connection rather than in the connection's own session.
+func (d *Dialector) acquire(ctx context.Context) (rpcTarget, func(), error) {
+	if d.pool == nil {
+		if d.Conn == nil {
+			return rpcTarget{}, nil, errors.New("surrealdb: connection not initialized")
+		}
+		if _, ok := d.sessionKeyFor(ctx); ok {
+			return rpcTarget{}, nil, errors.New("surrealdb: sessions need an initialized dialector")
+		}
+		return rpcTarget{db: d.Conn}, func() {}, nil
+	}
+	pc, err := d.pool.acquire(ctx)
+	if err != nil {
+		return rpcTarget{}, nil, err
+	}
+	target, unlease, err := d.connTarget(ctx, pc)
+	if err != nil {
+		pc.release()
+		return rpcTarget{}, nil, err
+	}
+	return target, func() { unlease(); pc.release() }, nil
+}
+
+// connTarget returns the handle a statement issued under ctx uses on pc: the
+// connection itself, or the session ctx asks for. The returned func hands
+// back the session's lease once the statement is done with it.
+func (d *Dialector) connTarget(ctx context.Context, pc *poolConn) (rpcTarget, func(), error) {
+	key, ok := d.sessionKeyFor(ctx)
+	if !ok {
+		t := rpcTarget{db: pc.db}
+		if d.ownsCredentials() {
+			t.reauth = func(ctx context.Context) error { return d.reauthConn(ctx, pc.db) }
+		}
+		return t, func() {}, nil
+	}
+	s, release, err := pc.sessions.session(ctx, d, pc.db, key)
+	if err != nil {
+		return rpcTarget{}, nil, err
+	}
+	t := rpcTarget{session: s}
+	if key.token == "" {
+		// Tenant sessions run as the driver; an end user's expired token is
+		// theirs to renew.
+		t.reauth = func(ctx context.Context) error { return d.prepareSession(ctx, s, key) }
+	}
+	return t, release, nil
+}
+
+// findPoolConn returns the pooled connection (primary or replica) wrapping db.
+func (d *Dialector) findPoolConn(db *surrealdb.DB) *poolConn {
+	for _, p := range append([]*connPool{d.pool}, d.replicas...) {
+		if p == nil {
+			continue
+		}
+		if pc := p.find(db); pc != nil {
+			return pc
+		}
+	}
+	return nil
+}
+
+// statementTarget resolves where the current GORM statement runs: the open
+// interactive transaction if there is one (so every read and write inside
+// db.Transaction shares it), otherwise a pooled connection.
+func (d *Dialector) statementTarget(db *gorm.DB) (rpcTarget, func(), error) {
+	if txConn, ok := txFromStatement(db); ok {
+		return rpcTarget{tx: txConn.SDKTx()}, func() {}, nil
+	}
+	return d.acquire(db.Statement.Context)
+}
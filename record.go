@@ -0,0 +1,354 @@
+package surrealdb
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/surrealdb/surrealdb.go/pkg/connection"
+	"github.com/surrealdb/surrealdb.go/pkg/connection/gws"
+	"github.com/surrealdb/surrealdb.go/pkg/connection/rpc"
+)
+
+// ============================================================================
+// Record/replay transport
+// ============================================================================
+
+// redacted replaces credentials and tokens in golden files.
+const redacted = "[redacted]"
+
+// exchange is one RPC round trip, a line of a golden file. Params is CBOR
+// diagnostic notation (canonical key order, so files diff cleanly) and
+// Result the raw CBOR result, base64 in the file.
+type exchange struct {
+	Method string         `json:"method"`
+	Query  string         `json:"query,omitempty"`
+	Params string         `json:"params,omitempty"`
+	Result []byte         `json:"result,omitempty"`
+	Error  *exchangeError `json:"error,omitempty"`
+
+	used bool
+}
+
+// exchangeError is a recorded server error, in the shape the SDK decodes.
+type exchangeError struct {
+	Code        int    `json:"code"`
+	Message     string `json:"message,omitempty"`
+	Description string `json:"description,omitempty"`
+	Kind        string `json:"kind,omitempty"`
+	Details     any    `json:"details,omitempty"`
+}
+
+// rpcError rebuilds the *connection.RPCError the server returned, so
+// errors.As to *connection.ServerError keeps working on replay.
+func (e *exchangeError) rpcError() error {
+	data, err := cbor.Marshal(e)
+	if err != nil {
+		return err
+	}
+	rpcErr := &connection.RPCError{}
+	if err := rpcErr.UnmarshalCBOR(data); err != nil {
+		return err
+	}
+	return rpcErr
+}
+
+// tape is the golden file of a recording or replaying dialector. Recorded
+// exchanges are appended as they complete, as JSON lines.
+type tape struct {
+	replay bool
+
+	mu        sync.Mutex
+	file      *os.File
+	enc       *json.Encoder
+	exchanges []*exchange
+}
+
+// openTape truncates record for writing, or loads replay.
+func openTape(record, replay string) (*tape, error) {
+	if record != "" && replay != "" {
+		return nil, errors.New("surrealdb: Record and Replay are mutually exclusive")
+	}
+	if record != "" {
+		f, err := os.Create(record)
+		if err != nil {
+			return nil, &Error{Op: "record", Err: err}
+		}
+		enc := json.NewEncoder(f)
+		enc.SetEscapeHTML(false)
+		return &tape{file: f, enc: enc}, nil
+	}
+
+	f, err := os.Open(replay)
+	if err != nil {
+		return nil, &Error{Op: "replay", Err: err}
+	}
+	defer f.Close()
+	t := &tape{replay: true}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		ex := &exchange{}
+		if err := json.Unmarshal(scanner.Bytes(), ex); err != nil {
+			return nil, &Error{Op: "replay", Err: fmt.Errorf("%s:%d: %w", replay, line, err)}
+		}
+		t.exchanges = append(t.exchanges, ex)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &Error{Op: "replay", Err: err}
+	}
+	return t, nil
+}
+
+func (t *tape) close() error {
+	if t == nil || t.file == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	err := t.file.Close()
+	t.file = nil
+	return err
+}
+
+// record appends the outcome of req. Transport failures (timeouts, closed
+// sockets) are not the server's answer and are left out.
+func (t *tape) record(m marshaler, req *connection.RPCRequest, res *connection.RPCResponse[cbor.RawMessage], err error) {
+	ex := &exchange{Method: req.Method}
+	ex.Query, ex.Params = describe(m, req.Method, req.Params)
+	var rpcErr *connection.RPCError
+	switch {
+	case err == nil && res != nil:
+		if res.Result != nil {
+			ex.Result = *res.Result
+		}
+		if isAuthMethod(req.Method) {
+			ex.Result = redactTokens(ex.Result)
+		}
+	case errors.As(err, &rpcErr):
+		ex.Error = &exchangeError{Code: rpcErr.Code, Message: rpcErr.Message, Description: rpcErr.Description}
+		var serverErr connection.ServerError
+		if errors.As(err, &serverErr) {
+			ex.Error.Kind = serverErr.Kind
+			ex.Error.Details = serverErr.Details
+		}
+	default:
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file != nil {
+		_ = t.enc.Encode(ex)
+	}
+}
+
+// next returns the first unused exchange for the request: one with the same
+// method, SurrealQL and params if there is one, else the same method and
+// SurrealQL, so values that differ between runs (random ids, timestamps) do
+// not break a replay.
+func (t *tape) next(method, query, params string) (*exchange, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var loose *exchange
+	for _, ex := range t.exchanges {
+		if ex.used || ex.Method != method || ex.Query != query {
+			continue
+		}
+		if ex.Params == params {
+			ex.used = true
+			return ex, nil
+		}
+		if loose == nil {
+			loose = ex
+		}
+	}
+	if loose == nil {
+		if query != "" {
+			return nil, fmt.Errorf("surrealdb: replay: no recorded %s response for %q", method, query)
+		}
+		return nil, fmt.Errorf("surrealdb: replay: no recorded %s response", method)
+	}
+	loose.used = true
+	return loose, nil
+}
+
+// marshaler is the SDK codec's encoding half.
+type marshaler interface {
+	Marshal(v any) ([]byte, error)
+}
+
+// canonical re-encodes params with sorted map keys before they are rendered.
+var canonical, _ = cbor.CanonicalEncOptions().EncMode()
+
+// describe renders a request for the golden file: the SurrealQL of a query
+// on its own, and the remaining params in diagnostic notation. Credentials
+// are never written.
+func describe(m marshaler, method string, params []any) (query, diag string) {
+	if isAuthMethod(method) || method == "authenticate" {
+		return "", redacted
+	}
+	if method == "query" && len(params) > 0 {
+		if sql, ok := params[0].(string); ok {
+			query, params = sql, params[1:]
+		}
+	}
+	if len(params) == 0 {
+		return query, ""
+	}
+	data, err := m.Marshal(params)
+	if err != nil {
+		return query, ""
+	}
+	var v any
+	if err := cbor.Unmarshal(data, &v); err == nil {
+		if sorted, err := canonical.Marshal(v); err == nil {
+			data = sorted
+		}
+	}
+	diag, _ = cbor.Diagnose(data)
+	return query, diag
+}
+
+func isAuthMethod(method string) bool {
+	return method == "signin" || method == "signup"
+}
+
+// redactTokens replaces the token, or the access/refresh pair, that signin
+// and signup return.
+func redactTokens(result []byte) []byte {
+	var v any
+	if err := cbor.Unmarshal(result, &v); err != nil {
+		return nil
+	}
+	switch x := v.(type) {
+	case string:
+		v = redacted
+	case map[any]any:
+		for k := range x {
+			x[k] = redacted
+		}
+	}
+	data, _ := cbor.Marshal(v)
+	return data
+}
+
+// tapeConn records every request its connection makes to the dialector's
+// tape, or with a replaying tape answers them from it without a server. The
+// SDK's helpers (Use, SignIn, ...) call Send on the connection they belong
+// to, so they are re-implemented here to pass through the tape.
+type tapeConn struct {
+	connection.Connection
+	tape      *tape
+	marshaler marshaler
+	closed    atomic.Bool
+}
+
+var _ connection.WebSocketConnection = (*tapeConn)(nil)
+
+// recorded wraps conn when the dialector records.
+func (dialector *Dialector) recorded(conn connection.Connection, conf *connection.Config) connection.Connection {
+	if dialector.tape == nil {
+		return conn
+	}
+	return &tapeConn{Connection: conn, tape: dialector.tape, marshaler: conf.Marshaler}
+}
+
+// newReplayConn answers from t. The embedded SDK transport is never
+// connected; it only contributes its codec and notification channels.
+func newReplayConn(conf *connection.Config, t *tape) *tapeConn {
+	return &tapeConn{Connection: gws.New(conf), tape: t, marshaler: conf.Marshaler}
+}
+
+func (c *tapeConn) Connect(ctx context.Context) error {
+	if c.tape.replay {
+		return nil
+	}
+	return c.Connection.Connect(ctx)
+}
+
+func (c *tapeConn) Close(ctx context.Context) error {
+	if c.tape.replay {
+		c.closed.Store(true)
+		return nil
+	}
+	return c.Connection.Close(ctx)
+}
+
+func (c *tapeConn) IsClosed() bool {
+	if ws, ok := c.Connection.(connection.WebSocketConnection); ok && !c.tape.replay {
+		return ws.IsClosed()
+	}
+	return c.closed.Load()
+}
+
+func (c *tapeConn) Send(ctx context.Context, method string, params ...any) (*connection.RPCResponse[cbor.RawMessage], error) {
+	return c.Call(ctx, &connection.RPCRequest{Method: method, Params: params})
+}
+
+func (c *tapeConn) Call(ctx context.Context, req *connection.RPCRequest) (*connection.RPCResponse[cbor.RawMessage], error) {
+	if !c.tape.replay {
+		res, err := c.Connection.Call(ctx, req)
+		c.tape.record(c.marshaler, req, res, err)
+		return res, err
+	}
+	query, params := describe(c.marshaler, req.Method, req.Params)
+	ex, err := c.tape.next(req.Method, query, params)
+	if err != nil {
+		return nil, err
+	}
+	if ex.Error != nil {
+		return nil, ex.Error.rpcError()
+	}
+	res := &connection.RPCResponse[cbor.RawMessage]{ID: req.ID}
+	if ex.Result != nil {
+		result := cbor.RawMessage(ex.Result)
+		res.Result = &result
+	}
+	return res, nil
+}
+
+func (c *tapeConn) Use(ctx context.Context, namespace, database string) error {
+	return connection.Send[any](c, ctx, nil, "use", namespace, database)
+}
+
+func (c *tapeConn) Let(ctx context.Context, key string, value any) error {
+	return connection.Send[any](c, ctx, nil, "let", key, value)
+}
+
+func (c *tapeConn) Unset(ctx context.Context, key string) error {
+	return connection.Send[any](c, ctx, nil, "unset", key)
+}
+
+func (c *tapeConn) Authenticate(ctx context.Context, token string) error {
+	return rpc.Authenticate(c, ctx, token)
+}
+
+func (c *tapeConn) SignIn(ctx context.Context, authData any) (string, error) {
+	return rpc.SignIn(c, ctx, authData)
+}
+
+func (c *tapeConn) SignUp(ctx context.Context, authData any) (string, error) {
+	return rpc.SignUp(c, ctx, authData)
+}
+
+func (c *tapeConn) SignInWithRefresh(ctx context.Context, authData any) (*connection.Tokens, error) {
+	return rpc.SignInWithRefresh(c, ctx, authData)
+}
+
+func (c *tapeConn) SignUpWithRefresh(ctx context.Context, authData any) (*connection.Tokens, error) {
+	return rpc.SignUpWithRefresh(c, ctx, authData)
+}
+
+func (c *tapeConn) Invalidate(ctx context.Context) error {
+	return rpc.Invalidate(c, ctx)
+}
@@ -28,18 +28,17 @@ func RegisterCallbacks(db *gorm.DB) {
 
 	// ── Delete ──────────────────────────────────────────────────────────────
 	db.Callback().Delete().Register("gorm:before_delete", callbacks.BeforeDelete)
-	db.Callback().Delete().After("gorm:before_delete").Register("surreal:edge_assoc_delete", edgeAssocDeleteCallback)
-	db.Callback().Delete().After("surreal:edge_assoc_delete").Register("surreal:edge_assoc_replace", edgeAssocReplaceCallback)
-	db.Callback().Delete().After("surreal:edge_assoc_replace").Register("gorm:delete", DeleteCallback)
+	db.Callback().Delete().After("gorm:before_delete").Register("gorm:delete", DeleteCallback)
 	db.Callback().Delete().After("gorm:delete").Register("gorm:after_delete", callbacks.AfterDelete)
 
 	// ── Row (used internally by GORM for association counts, etc.) ───────────
-	db.Callback().Row().Register("surreal:edge_assoc_count", edgeAssocCountCallback)
-	db.Callback().Row().After("surreal:edge_assoc_count").Register("gorm:row", RowCallback)
+	db.Callback().Row().Register("gorm:row", RowCallback)
 
 	// ── Query ────────────────────────────────────────────────────────────────
 	db.Callback().Query().Register("surreal:handle_preload", handlePreloadAsFetch)
-	db.Callback().Query().After("surreal:handle_preload").Register("gorm:query", QueryCallback)
+	db.Callback().Query().After("surreal:handle_preload").Register("surreal:handle_joins", handleJoins)
+	db.Callback().Query().After("surreal:handle_joins").Register("surreal:paginate", handlePagination)
+	db.Callback().Query().After("surreal:paginate").Register("gorm:query", QueryCallback)
 	db.Callback().Query().After("gorm:query").Register("gorm:after_query", callbacks.AfterQuery)
 
 	// ── Raw ──────────────────────────────────────────────────────────────────
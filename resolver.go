@@ -0,0 +1,101 @@
+package surrealdb
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/surrealdb/surrealdb.go"
+	"gorm.io/gorm"
+)
+
+// UsePrimary returns a context whose reads go to the primary endpoint even
+// when Replicas are configured. Use it for read-your-own-writes sections that
+// can't tolerate replication lag:
+//
+//	db.Create(&order)
+//	db.WithContext(surrealdb.UsePrimary(ctx)).First(&order, order.ID)
+//
+// Statements inside db.Transaction always run on the primary.
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryCtxKey, true)
+}
+
+func primaryFromContext(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	v, _ := ctx.Value(primaryCtxKey).(bool)
+	return v
+}
+
+// openReplicas dials a pool per configured replica endpoint. Replicas share
+// the primary's credentials and namespace/database.
+func (d *Dialector) openReplicas(ctx context.Context) error {
+	if len(d.replicas) > 0 || len(d.Replicas) == 0 {
+		return nil
+	}
+	if !d.ownsCredentials() {
+		return fmt.Errorf("surrealdb: Replicas need the dialector to own its credentials")
+	}
+	for _, endpoint := range d.Replicas {
+		endpoint := endpoint
+		open := func(ctx context.Context) (*surrealdb.DB, error) {
+			return d.openEndpoint(ctx, endpoint)
+		}
+		first, err := open(ctx)
+		if err != nil {
+			return fmt.Errorf("surrealdb: replica %s: %w", endpoint, err)
+		}
+		p := newConnPool(first, open, d.MinConns, d.MaxConns, d.ConnMaxIdleTime)
+		p.closeConn = d.closeConn
+		d.replicas = append(d.replicas, p)
+		if err := p.fill(ctx); err != nil {
+			return fmt.Errorf("surrealdb: replica %s: %w", endpoint, err)
+		}
+	}
+	return nil
+}
+
+// readTarget resolves where a read-only statement runs: a replica, picked
+// round-robin, unless the statement is inside a transaction, the context asks
+// for the primary, or no replica can serve it right now.
+func (d *Dialector) readTarget(db *gorm.DB) (rpcTarget, func(), error) {
+	ctx := db.Statement.Context
+	if _, inTx := txFromStatement(db); inTx || len(d.replicas) == 0 || primaryFromContext(ctx) {
+		return d.statementTarget(db)
+	}
+	start := d.nextRead.Add(1)
+	for i := range d.replicas {
+		p := d.replicas[(start+uint64(i))%uint64(len(d.replicas))]
+		pc, err := p.acquire(ctx)
+		if err != nil {
+			continue
+		}
+		target, unlease, err := d.connTarget(ctx, pc)
+		if err != nil {
+			pc.release()
+			return rpcTarget{}, nil, err
+		}
+		return target, func() { unlease(); pc.release() }, nil
+	}
+	return d.statementTarget(db)
+}
+
+// writeKeywordRe matches SurrealQL keywords that make a statement a write.
+var writeKeywordRe = regexp.MustCompile(`\b(CREATE|UPDATE|UPSERT|DELETE|RELATE|INSERT|DEFINE|REMOVE|ALTER|LET|BEGIN|COMMIT)\b`)
+
+// isReadOnlySQL reports whether sql is a single SELECT that may be served by a
+// replica. Anything else — including a SELECT over a mutating subquery or a
+// multi-statement script — goes to the primary.
+func isReadOnlySQL(sql string) bool {
+	s := strings.TrimSpace(sql)
+	if fields := strings.Fields(s); len(fields) == 0 || !strings.EqualFold(fields[0], "SELECT") {
+		return false
+	}
+	if strings.Contains(strings.TrimRight(s, "; \n\t"), ";") {
+		return false
+	}
+	return !writeKeywordRe.MatchString(strings.ToUpper(s))
+}
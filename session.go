@@ -0,0 +1,263 @@
+package surrealdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/surrealdb/surrealdb.go"
+)
+
+// maxSessionsPerConn bounds how many sessions a pooled connection
+// keeps attached; the least recently used idle one is detached beyond that.
+const maxSessionsPerConn = 256
+
+type ctxKey int
+
+const (
+	tokenCtxKey ctxKey = iota
+	databaseCtxKey
+	primaryCtxKey
+)
+
+// AsToken returns a context under which GORM statements run as the end user
+// identified by token (a JWT issued by SIGNIN/SIGNUP or an external identity
+// provider), instead of as the user the driver connected with. Table and field
+// PERMISSIONS are then enforced by the database:
+//
+//	db.WithContext(surrealdb.AsToken(ctx, jwt)).Find(&posts)
+//
+// Each token gets its own server-side session, attached to a pooled connection
+// and kept separate from the driver's own session. Requires SurrealDB v3+ over
+// WebSocket.
+func AsToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenCtxKey, token)
+}
+
+// tokenFromContext returns the end-user token set by AsToken, if any.
+func tokenFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	token, ok := ctx.Value(tokenCtxKey).(string)
+	return token, ok && token != ""
+}
+
+// tenantDatabase is the namespace/database pair set by WithDatabase.
+type tenantDatabase struct {
+	ns, db string
+}
+
+// WithDatabase returns a context under which GORM statements target namespace
+// ns and database db instead of the ones the driver connected to, e.g. one
+// database per tenant:
+//
+//	db.WithContext(surrealdb.WithDatabase(ctx, "acme", "tenant_42")).Find(&orders)
+//
+// Statements run in a session attached to a pooled connection and bound to
+// that namespace/database, so concurrent requests for different tenants never
+// race on a shared USE. It composes with AsToken and db.Transaction. With
+// RootAuth, a namespace or database that does not exist yet is created on
+// first use. ns and db may hold letters, digits, _ and -; statements under
+// any other name fail. Requires SurrealDB v3+ over WebSocket.
+func WithDatabase(ctx context.Context, ns, db string) context.Context {
+	return context.WithValue(ctx, databaseCtxKey, tenantDatabase{ns: ns, db: db})
+}
+
+// databaseFromContext returns the namespace/database set by WithDatabase, if any.
+func databaseFromContext(ctx context.Context) (ns, db string, ok bool) {
+	if ctx == nil {
+		return "", "", false
+	}
+	t, ok := ctx.Value(databaseCtxKey).(tenantDatabase)
+	return t.ns, t.db, ok && t.ns != "" && t.db != ""
+}
+
+// sessionKey identifies one attached session on a connection: who it is
+// authenticated as (an end-user token, or the driver's own credentials when
+// empty) and which namespace/database it uses.
+type sessionKey struct {
+	token  string
+	ns, db string
+}
+
+// sessionKeyFor returns the session a statement issued under ctx must run in,
+// or ok == false when it should use the connection's own session.
+func (d *Dialector) sessionKeyFor(ctx context.Context) (sessionKey, bool) {
+	key := sessionKey{ns: d.namespace, db: d.database}
+	token, hasToken := tokenFromContext(ctx)
+	if hasToken {
+		key.token = token
+	}
+	if ns, db, ok := databaseFromContext(ctx); ok {
+		key.ns, key.db = ns, db
+	}
+	if !hasToken && key.ns == d.namespace && key.db == d.database {
+		return sessionKey{}, false
+	}
+	return key, true
+}
+
+// connSession is an attached session cached on a pooled connection.
+type connSession struct {
+	session  *surrealdb.Session
+	lastUsed time.Time
+	// leases counts the statements, transactions and live queries running
+	// in the session; a leased session is never detached.
+	leases int
+	// ready is closed once the session is attached and prepared, or err set.
+	ready chan struct{}
+	err   error
+}
+
+// sessionCache holds the end-user and tenant sessions attached to one
+// connection. Its lock is never held across a round trip: a session is
+// attached and prepared outside it while an in-progress entry makes other
+// callers for the same key wait for the result.
+type sessionCache struct {
+	mu       sync.Mutex
+	sessions map[sessionKey]*connSession
+}
+
+// session returns the attached session for key on conn, attaching and
+// authenticating a new one on first use. The session is leased to the caller
+// until release is called, and is not detached before then.
+func (c *sessionCache) session(ctx context.Context, d *Dialector, conn *surrealdb.DB, key sessionKey) (s *surrealdb.Session, release func(), err error) {
+	c.mu.Lock()
+	if c.sessions == nil {
+		c.sessions = make(map[sessionKey]*connSession)
+	}
+	cs, ok := c.sessions[key]
+	if !ok {
+		cs = &connSession{ready: make(chan struct{})}
+		c.sessions[key] = cs
+	}
+	cs.leases++
+	c.mu.Unlock()
+	release = c.releaser(cs)
+
+	if ok {
+		select {
+		case <-cs.ready:
+		case <-ctx.Done():
+			release()
+			return nil, nil, ctx.Err()
+		}
+		if cs.err != nil {
+			release()
+			return nil, nil, cs.err
+		}
+		return cs.session, release, nil
+	}
+
+	s, err = attachSession(ctx, d, conn, key)
+	c.mu.Lock()
+	if err != nil {
+		if c.sessions[key] == cs {
+			delete(c.sessions, key)
+		}
+		cs.err = err
+		close(cs.ready)
+		c.mu.Unlock()
+		return nil, nil, err
+	}
+	cs.session, cs.lastUsed = s, time.Now()
+	close(cs.ready)
+	var evicted []*surrealdb.Session
+	for len(c.sessions) > maxSessionsPerConn {
+		victim := c.evictOldestLocked()
+		if victim == nil {
+			break
+		}
+		evicted = append(evicted, victim)
+	}
+	c.mu.Unlock()
+	for _, v := range evicted {
+		_ = v.Detach(ctx)
+	}
+	return s, release, nil
+}
+
+// attachSession attaches a new session to conn and prepares it for key.
+func attachSession(ctx context.Context, d *Dialector, conn *surrealdb.DB, key sessionKey) (*surrealdb.Session, error) {
+	s, err := conn.Attach(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("surrealdb: sessions require SurrealDB v3+ over WebSocket: %w", err)
+	}
+	if err := d.prepareSession(ctx, s, key); err != nil {
+		_ = s.Detach(ctx)
+		return nil, err
+	}
+	return s, nil
+}
+
+// releaser returns the func that hands back one lease on cs.
+func (c *sessionCache) releaser(cs *connSession) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			cs.leases--
+			cs.lastUsed = time.Now()
+			c.mu.Unlock()
+		})
+	}
+}
+
+// evictOldestLocked removes the least recently used session no one holds a
+// lease on and returns it for the caller to detach once the lock is
+// released, or nil. While every session is in use the cache grows past
+// maxSessionsPerConn.
+func (c *sessionCache) evictOldestLocked() *surrealdb.Session {
+	var oldestKey sessionKey
+	var oldest *connSession
+	for k, cs := range c.sessions {
+		if cs.leases > 0 {
+			continue
+		}
+		if oldest == nil || cs.lastUsed.Before(oldest.lastUsed) {
+			oldestKey, oldest = k, cs
+		}
+	}
+	if oldest == nil {
+		return nil
+	}
+	delete(c.sessions, oldestKey)
+	return oldest.session
+}
+
+// prepareSession authenticates a freshly attached session and selects its
+// namespace/database. End-user sessions authenticate with their token; tenant
+// sessions reuse the driver's own credentials.
+func (d *Dialector) prepareSession(ctx context.Context, s *surrealdb.Session, key sessionKey) error {
+	define := false
+	if key.token != "" {
+		if err := s.Authenticate(ctx, key.token); err != nil {
+			return fmt.Errorf("surrealdb: authenticate session: %w", err)
+		}
+	} else {
+		if !d.ownsCredentials() {
+			return errors.New("surrealdb: WithDatabase needs the dialector to own its credentials")
+		}
+		var err error
+		if _, define, err = d.signIn(ctx, s, true); err != nil {
+			return fmt.Errorf("surrealdb: authenticate session: %w", err)
+		}
+	}
+	// Record and JWT tokens already carry their namespace and database; a
+	// caller-supplied Conn leaves both unknown.
+	if key.ns == "" || key.db == "" {
+		return nil
+	}
+	return useDatabase(ctx, s, key.ns, key.db, define)
+}
+
+// reset forgets every cached session. Sessions live on the server side of one
+// WebSocket, so they are gone once that socket is replaced.
+func (c *sessionCache) reset() {
+	c.mu.Lock()
+	c.sessions = nil
+	c.mu.Unlock()
+}
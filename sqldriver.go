@@ -9,8 +9,6 @@ import (
 	"sort"
 	"time"
 
-	"github.com/surrealdb/surrealdb.go"
-
 	TypesM "github.com/dailaim/surrealdb-gorm/types"
 )
 
@@ -42,6 +40,12 @@ func (c *sdConnector) Connect(_ context.Context) (driver.Conn, error) {
 
 func (c *sdConnector) Driver() driver.Driver { return sdDriver{} }
 
+// Close is called by (*sql.DB).Close, so closing the handle returned by
+// gorm's db.DB() shuts the whole dialector down.
+func (c *sdConnector) Close() error {
+	return c.dialector.Close(context.Background())
+}
+
 // sdDriver exists to satisfy driver.Connector.Driver(). Open is unused because
 // connections are created via sql.OpenDB(connector), not sql.Open(name, dsn).
 type sdDriver struct{}
@@ -50,8 +54,9 @@ func (sdDriver) Open(string) (driver.Conn, error) {
 	return nil, fmt.Errorf("surrealdb: use sql.OpenDB with a connector, not sql.Open")
 }
 
-// sdConn wraps the shared SurrealDB connection. It implements QueryerContext and
-// ExecerContext so database/sql never needs Prepare, and NamedValueChecker so
+// sdConn runs each statement on a connection borrowed from the Dialector's
+// pool. It implements QueryerContext and ExecerContext so database/sql never
+// needs Prepare, and NamedValueChecker so
 // GORM's custom argument types pass through untouched (we convert them to
 // SDK-native CBOR values ourselves via ToSDKValue).
 type sdConn struct {
@@ -89,7 +94,12 @@ func (c *sdConn) QueryContext(ctx context.Context, query string, args []driver.N
 	if c.dialector == nil || c.dialector.Conn == nil {
 		return nil, fmt.Errorf("surrealdb: connection not initialized")
 	}
-	results, err := surrealdb.Query[interface{}](ctx, c.dialector.Conn, query, c.params(args))
+	target, release, err := c.dialector.acquire(ctx)
+	if err != nil {
+		return nil, &Error{Op: "query", Query: query, Err: err}
+	}
+	results, err := queryOn[interface{}](ctx, target, query, c.params(args))
+	release()
 	if err != nil {
 		return nil, &Error{Op: "query", Query: query, Err: err}
 	}
@@ -107,7 +117,12 @@ func (c *sdConn) ExecContext(ctx context.Context, query string, args []driver.Na
 	if c.dialector == nil || c.dialector.Conn == nil {
 		return nil, fmt.Errorf("surrealdb: connection not initialized")
 	}
-	results, err := surrealdb.Query[interface{}](ctx, c.dialector.Conn, query, c.params(args))
+	target, release, err := c.dialector.acquire(ctx)
+	if err != nil {
+		return nil, &Error{Op: "exec", Query: query, Err: err}
+	}
+	results, err := queryOn[interface{}](ctx, target, query, c.params(args))
+	release()
 	if err != nil {
 		return nil, &Error{Op: "exec", Query: query, Err: err}
 	}
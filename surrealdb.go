@@ -2,6 +2,7 @@ package surrealdb
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net/url"
 	"time"
@@ -25,13 +26,46 @@ type Config struct {
 	// Namespace and Database select the working namespace/database (USE).
 	Namespace string
 	Database  string
-	// Username and Password are the signin credentials.
+	// Username and Password are root signin credentials. They are ignored when
+	// Auth is set.
 	Username string
 	Password string
+	// Auth selects a least-privilege authentication mode: NamespaceAuth,
+	// DatabaseAuth, RecordAuth or TokenAuth (RootAuth is equivalent to
+	// Username/Password).
+	Auth Auth
+	// Credentials supplies rotating credentials: it is called on first
+	// connect, on every new or reconnected connection and when the server
+	// reports an expired session, and takes precedence over Auth and
+	// Username/Password.
+	Credentials func(ctx context.Context) (Auth, error)
 	// ReconnectInterval tunes the auto-reconnecting WebSocket connection: 0 uses
 	// the default (5s), a positive value sets the reconnect check interval, and a
 	// negative value disables reconnection.
 	ReconnectInterval time.Duration
+	// TLSConfig configures TLS for wss:// and https:// endpoints, e.g. a
+	// private CA or a client certificate. nil uses Go's defaults.
+	TLSConfig *tls.Config
+	// MinConns and MaxConns size the connection pool. MinConns connections are
+	// opened on connect; up to MaxConns are dialed on demand while every open
+	// connection is busy. Both default to 1.
+	MinConns int
+	MaxConns int
+	// ConnMaxIdleTime closes connections above MinConns after they have been
+	// idle this long. 0 uses the default (5m).
+	ConnMaxIdleTime time.Duration
+	// Replicas lists secondary endpoints that serve reads. Writes, transactions
+	// and UsePrimary contexts always use Endpoint.
+	Replicas []string
+	// OnDisconnect, OnReconnect and OnAuthFailure observe the connection
+	// lifecycle; see the matching Dialector fields.
+	OnDisconnect  func(endpoint string)
+	OnReconnect   func(endpoint string)
+	OnAuthFailure func(endpoint string, err error)
+	// Record and Replay name golden files to record RPC exchanges to, or to
+	// answer from without a server; see the matching Dialector fields.
+	Record string
+	Replay string
 }
 
 // New returns a GORM dialector from an explicit Config. Prefer this over Open
@@ -45,12 +79,28 @@ type Config struct {
 //	    Password:  "root",
 //	}), &gorm.Config{})
 func New(cfg Config) gorm.Dialector {
-	return &Dialector{DSN: cfg.dsn(), ReconnectInterval: cfg.ReconnectInterval}
+	return &Dialector{
+		DSN:               cfg.dsn(),
+		Auth:              cfg.Auth,
+		Credentials:       cfg.Credentials,
+		ReconnectInterval: cfg.ReconnectInterval,
+		TLSConfig:         cfg.TLSConfig,
+		MinConns:          cfg.MinConns,
+		MaxConns:          cfg.MaxConns,
+		ConnMaxIdleTime:   cfg.ConnMaxIdleTime,
+		Replicas:          cfg.Replicas,
+		OnDisconnect:      cfg.OnDisconnect,
+		OnReconnect:       cfg.OnReconnect,
+		OnAuthFailure:     cfg.OnAuthFailure,
+		Record:            cfg.Record,
+		Replay:            cfg.Replay,
+	}
 }
 
 // dsn assembles the DSN string that Dialector.Initialize expects, URL-encoding
 // the credentials. Namespace/database/username/password are carried as query
-// parameters, matching the format accepted by Open.
+// parameters, matching the format accepted by Open; an explicit Auth travels
+// on the Dialector instead.
 func (c Config) dsn() string {
 	u, err := url.Parse(c.Endpoint)
 	if err != nil || u.Scheme == "" {
@@ -60,8 +110,10 @@ func (c Config) dsn() string {
 	q := u.Query()
 	q.Set("namespace", c.Namespace)
 	q.Set("database", c.Database)
-	q.Set("username", c.Username)
-	q.Set("password", c.Password)
+	if c.Auth == nil && c.Credentials == nil {
+		q.Set("username", c.Username)
+		q.Set("password", c.Password)
+	}
 	u.RawQuery = q.Encode()
 	return u.String()
 }
@@ -98,9 +150,21 @@ func Relate(ctx context.Context, d *Dialector, in, relation, out string, data ..
 		Relation: sdkModels.Table(relation),
 		Data:     payload,
 	}
-	res, err := surrealdb.Relate[map[string]interface{}](ctx, d.Conn, rel)
+	target, release, err := d.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	edge, err := d.relateEdge(ctx, target, nil, rel, func() (interface{}, error) {
+		res, err := relateOn[map[string]interface{}](ctx, target, rel)
+		if err != nil {
+			return nil, err
+		}
+		return *res, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	return *res, nil
+	m, _ := edge.(map[string]interface{})
+	return m, nil
 }
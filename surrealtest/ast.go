@@ -0,0 +1,288 @@
+package surrealtest
+
+// Expressions.
+
+type expr interface{}
+
+type litExpr struct{ v any }
+
+type paramExpr struct{ name string }
+
+// tableExpr is a bare identifier in a FROM/CREATE/UPDATE target position.
+type tableExpr struct{ name string }
+
+type recordExpr struct {
+	table string
+	id    expr
+	// gen is a generated id: rand, ulid or uuid.
+	gen string
+}
+
+// rangeExpr is a record range, table:from..to, as a FROM target. A nil
+// bound is open.
+type rangeExpr struct {
+	table            string
+	from, to         expr
+	fromExcl, toIncl bool
+}
+
+type arrayExpr struct{ elems []expr }
+
+type objectExpr struct {
+	keys []string
+	vals []expr
+}
+
+type unaryExpr struct {
+	op string
+	x  expr
+}
+
+type binaryExpr struct {
+	op   string
+	l, r expr
+}
+
+// knnExpr is x <|k|> vec. It holds for every document; SELECT then keeps
+// the k documents whose x is nearest to vec.
+type knnExpr struct {
+	x, vec expr
+	k      int
+}
+
+type callExpr struct {
+	name string
+	args []expr
+}
+
+type castExpr struct {
+	typ string
+	x   expr
+}
+
+type subqueryExpr struct{ stmt stmt }
+
+type blockExpr struct{ stmts []stmt }
+
+type ifExpr struct {
+	conds []expr
+	thens []expr
+	els   expr
+}
+
+// idiomExpr is a path: an optional base value followed by parts. A nil base
+// starts at the current document.
+type idiomExpr struct {
+	base  expr
+	parts []part
+	text  string
+}
+
+type part interface{}
+
+type fieldPart struct{ name string }
+
+type allPart struct{}
+
+type lastPart struct{}
+
+type indexPart struct{ x expr }
+
+type wherePart struct{ cond expr }
+
+type methodPart struct {
+	name string
+	args []expr
+}
+
+// recursePart repeats path between min and max times: .{min..max}(path).
+// instr is "", "collect", "path" or "shortest" (to target).
+type recursePart struct {
+	min, max  int
+	instr     string
+	target    expr
+	inclusive bool
+	path      []part
+}
+
+// maxRecursion is the deepest a recursive path goes.
+const maxRecursion = 256
+
+type graphPart struct {
+	dir    string // "->", "<-" or "<->"
+	tables []string
+	cond   expr
+}
+
+// Statements.
+
+type stmt interface{}
+
+type field struct {
+	all   bool
+	x     expr
+	alias *idiomExpr
+	text  string
+}
+
+type orderItem struct {
+	x                      expr
+	desc, collate, numeric bool
+}
+
+type selectStmt struct {
+	value    bool
+	fields   []field
+	omit     []expr
+	only     bool
+	from     []expr
+	where    expr
+	split    []expr
+	group    []expr
+	groupAll bool
+	order    []orderItem
+	rand     bool
+	limit    expr
+	start    expr
+	fetch    []expr
+	explain  bool
+}
+
+type assignment struct {
+	target *idiomExpr
+	op     string
+	x      expr
+}
+
+type dataClause struct {
+	set                            []assignment
+	unset                          []*idiomExpr
+	content, merge, replace, patch expr
+}
+
+type returnClause struct {
+	kind   string // "", none, null, before, after, diff, fields
+	fields []field
+}
+
+type createStmt struct {
+	only bool
+	what []expr
+	data *dataClause
+	ret  returnClause
+}
+
+type updateStmt struct {
+	upsert bool
+	only   bool
+	what   []expr
+	data   *dataClause
+	where  expr
+	ret    returnClause
+}
+
+type deleteStmt struct {
+	only  bool
+	what  []expr
+	where expr
+	ret   returnClause
+}
+
+type insertStmt struct {
+	relation bool
+	ignore   bool
+	into     expr
+	data     expr
+	update   []assignment
+	ret      returnClause
+}
+
+type relateStmt struct {
+	only     bool
+	from, to expr
+	edge     expr
+	data     *dataClause
+	ret      returnClause
+}
+
+type returnStmt struct{ x expr }
+
+type letStmt struct {
+	name string
+	x    expr
+}
+
+type exprStmt struct{ x expr }
+
+type throwStmt struct{ x expr }
+
+type beginStmt struct{}
+
+type commitStmt struct{}
+
+type cancelStmt struct{}
+
+type useStmt struct{ ns, db string }
+
+type infoStmt struct {
+	level string // root, ns, db, table, user
+	name  string
+}
+
+type liveStmt struct {
+	diff   bool
+	fields []field
+	table  expr
+	where  expr
+}
+
+type killStmt struct{ id expr }
+
+type removeStmt struct {
+	kind     string
+	name     string
+	table    string
+	level    string
+	ifExists bool
+}
+
+// alterTableStmt and alterFieldStmt keep their clauses as source text, which
+// is applied on top of the existing definition when the statement runs.
+type alterTableStmt struct {
+	name     string
+	ifExists bool
+	rename   string
+	clauses  string
+}
+
+type alterFieldStmt struct {
+	name, table string
+	ifExists    bool
+	clauses     string
+}
+
+// defineStmt carries the definition built by the parser; exactly one of the
+// pointers is set.
+type defineStmt struct {
+	ifNotExists, overwrite bool
+
+	ns       string
+	db       string
+	table    *tableDef
+	field    *fieldDef
+	index    *indexDef
+	event    *eventDef
+	user     *userDef
+	access   *accessDef
+	param    *paramDef
+	function *funcDef
+	// other is any definition that is recorded but has no behaviour here,
+	// e.g. analyzers, buckets and sequences.
+	other *otherDef
+}
+
+type ifStmt struct{ x *ifExpr }
+
+type explainStmt struct{ stmt stmt }
+
+type noopStmt struct{}
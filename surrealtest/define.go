@@ -0,0 +1,538 @@
+package surrealtest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/surrealdb/surrealdb.go/pkg/models"
+)
+
+// ============================================================================
+// DEFINE
+// ============================================================================
+
+// exists reports what a DEFINE should do about a definition that is already
+// there: skip it (IF NOT EXISTS), replace it (OVERWRITE) or fail.
+func exists(s *defineStmt, found bool, kind, name string) (skip bool, err error) {
+	switch {
+	case !found, s.overwrite:
+		return false, nil
+	case s.ifNotExists:
+		return true, nil
+	}
+	return true, fmt.Errorf("The %s '%s' already exists", kind, name)
+}
+
+func (ex *executor) execDefine(e *env, s *defineStmt) error {
+	if s.ns != "" {
+		if skip, err := exists(s, ex.srv.namespace(s.ns) != nil, "namespace", s.ns); skip {
+			return err
+		}
+		ex.srv.namespaces[s.ns] = newNamespace(s.ns)
+		return nil
+	}
+	if s.db != "" {
+		ns := ex.srv.namespace(ex.sess.ns)
+		if ns == nil {
+			if ex.sess.ns == "" {
+				return fmt.Errorf("Specify a namespace to use")
+			}
+			return fmt.Errorf("The namespace '%s' does not exist", ex.sess.ns)
+		}
+		if skip, err := exists(s, ns.dbs[s.db] != nil, "database", s.db); skip {
+			return err
+		}
+		ns.dbs[s.db] = newDatabase(s.db)
+		return nil
+	}
+	if s.user != nil {
+		return ex.defineUser(s)
+	}
+	if s.access != nil {
+		return ex.defineAccess(s)
+	}
+
+	db, err := ex.database()
+	if err != nil {
+		return err
+	}
+	switch {
+	case s.table != nil:
+		d := s.table
+		t := ex.tx.table(db, d.name)
+		if skip, err := exists(s, t != nil && t.def != nil, "table", d.name); skip {
+			return err
+		}
+		t = ex.tx.writable(db, d.name, true)
+		t.def, t.defsDirty = d, true
+	case s.field != nil:
+		d := s.field
+		t := ex.tx.table(db, d.table)
+		if skip, err := exists(s, t != nil && t.field(d.name) != nil, "field", d.name); skip {
+			return err
+		}
+		t = ex.tx.writable(db, d.table, true)
+		t.setField(d)
+		t.defsDirty = true
+	case s.index != nil:
+		d := s.index
+		t := ex.tx.table(db, d.table)
+		if skip, err := exists(s, t != nil && t.index(d.name) >= 0, "index", d.name); skip {
+			return err
+		}
+		t = ex.tx.writable(db, d.table, true)
+		if d.unique {
+			seen := map[string]bool{}
+			for _, doc := range t.scan() {
+				vals, ok, err := ex.indexKey(e, d, doc)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					continue
+				}
+				k := valueKey(vals)
+				if seen[k] {
+					return uniqueError(d, vals, doc["id"])
+				}
+				seen[k] = true
+			}
+		}
+		if i := t.index(d.name); i >= 0 {
+			t.indexes[i] = d
+		} else {
+			t.indexes = append(t.indexes, d)
+		}
+		t.defsDirty = true
+	case s.event != nil:
+		d := s.event
+		t := ex.tx.table(db, d.table)
+		if skip, err := exists(s, t != nil && t.event(d.name) >= 0, "event", d.name); skip {
+			return err
+		}
+		t = ex.tx.writable(db, d.table, true)
+		if i := t.event(d.name); i >= 0 {
+			t.events[i] = d
+		} else {
+			t.events = append(t.events, d)
+		}
+		t.defsDirty = true
+	case s.param != nil:
+		d := s.param
+		if skip, err := exists(s, db.params[d.name] != nil, "param", "$"+d.name); skip {
+			return err
+		}
+		v, err := ex.eval(e, d.value)
+		if err != nil {
+			return err
+		}
+		db.params[d.name], db.paramVals[d.name] = d, v
+	case s.function != nil:
+		d := s.function
+		if skip, err := exists(s, db.functions[d.name] != nil, "function", d.name); skip {
+			return err
+		}
+		db.functions[d.name] = d
+	case s.other != nil:
+		d := s.other
+		key := d.kind + " " + d.name
+		if skip, err := exists(s, db.others[key] != nil, d.kind, d.name); skip {
+			return err
+		}
+		db.others[key] = d
+	}
+	return nil
+}
+
+// users returns the user map for a level of the session's namespace and
+// database.
+func (ex *executor) users(level string) (map[string]*userDef, error) {
+	switch level {
+	case "ROOT":
+		return ex.srv.rootUsers, nil
+	case "NAMESPACE":
+		ns := ex.srv.namespace(ex.sess.ns)
+		if ns == nil {
+			return nil, fmt.Errorf("The namespace '%s' does not exist", ex.sess.ns)
+		}
+		return ns.users, nil
+	}
+	db, err := ex.database()
+	if err != nil {
+		return nil, err
+	}
+	return db.users, nil
+}
+
+func (ex *executor) accesses(level string) (map[string]*accessDef, error) {
+	if level == "NAMESPACE" {
+		ns := ex.srv.namespace(ex.sess.ns)
+		if ns == nil {
+			return nil, fmt.Errorf("The namespace '%s' does not exist", ex.sess.ns)
+		}
+		return ns.access, nil
+	}
+	db, err := ex.database()
+	if err != nil {
+		return nil, err
+	}
+	return db.access, nil
+}
+
+func (ex *executor) defineUser(s *defineStmt) error {
+	users, err := ex.users(s.user.level)
+	if err != nil {
+		return err
+	}
+	if skip, err := exists(s, users[s.user.name] != nil, "user", s.user.name); skip {
+		return err
+	}
+	users[s.user.name] = s.user
+	return nil
+}
+
+func (ex *executor) defineAccess(s *defineStmt) error {
+	access, err := ex.accesses(s.access.level)
+	if err != nil {
+		return err
+	}
+	if skip, err := exists(s, access[s.access.name] != nil, "access method", s.access.name); skip {
+		return err
+	}
+	access[s.access.name] = s.access
+	return nil
+}
+
+// ============================================================================
+// REMOVE
+// ============================================================================
+
+func (ex *executor) execRemove(s removeStmt) error {
+	missing := func(found bool, kind string) error {
+		if found || s.ifExists {
+			return nil
+		}
+		return fmt.Errorf("The %s '%s' does not exist", kind, s.name)
+	}
+	switch s.kind {
+	case "NAMESPACE", "NS":
+		found := ex.srv.namespace(s.name) != nil
+		delete(ex.srv.namespaces, s.name)
+		return missing(found, "namespace")
+	case "DATABASE", "DB":
+		ns := ex.srv.namespace(ex.sess.ns)
+		found := ns != nil && ns.dbs[s.name] != nil
+		if found {
+			delete(ns.dbs, s.name)
+		}
+		return missing(found, "database")
+	case "USER":
+		users, err := ex.users(s.level)
+		if err != nil {
+			return err
+		}
+		found := users[s.name] != nil
+		delete(users, s.name)
+		return missing(found, "user")
+	case "ACCESS":
+		access, err := ex.accesses(s.level)
+		if err != nil {
+			return err
+		}
+		found := access[s.name] != nil
+		delete(access, s.name)
+		return missing(found, "access method")
+	}
+
+	db, err := ex.database()
+	if err != nil {
+		return err
+	}
+	switch s.kind {
+	case "TABLE":
+		found := ex.tx.table(db, s.name) != nil
+		if found {
+			ex.tx.drop(db, s.name)
+		}
+		return missing(found, "table")
+	case "FIELD", "INDEX", "EVENT":
+		t := ex.tx.table(db, s.table)
+		found := false
+		if t != nil {
+			switch s.kind {
+			case "FIELD":
+				found = t.field(s.name) != nil
+			case "INDEX":
+				found = t.index(s.name) >= 0
+			default:
+				found = t.event(s.name) >= 0
+			}
+		}
+		if !found {
+			return missing(false, strings.ToLower(s.kind))
+		}
+		t = ex.tx.writable(db, s.table, false)
+		switch s.kind {
+		case "FIELD":
+			kept := t.fields[:0:0]
+			for _, f := range t.fields {
+				if f.name != s.name {
+					kept = append(kept, f)
+				}
+			}
+			t.fields = kept
+		case "INDEX":
+			i := t.index(s.name)
+			t.indexes = append(t.indexes[:i:i], t.indexes[i+1:]...)
+		default:
+			i := t.event(s.name)
+			t.events = append(t.events[:i:i], t.events[i+1:]...)
+		}
+		t.defsDirty = true
+		return nil
+	case "PARAM":
+		found := db.params[s.name] != nil
+		delete(db.params, s.name)
+		delete(db.paramVals, s.name)
+		if !found && !s.ifExists {
+			return fmt.Errorf("The param '$%s' does not exist", s.name)
+		}
+		return nil
+	case "FUNCTION":
+		found := db.functions[s.name] != nil
+		delete(db.functions, s.name)
+		return missing(found, "function")
+	}
+	key := strings.ToLower(s.kind) + " " + s.name
+	found := db.others[key] != nil
+	delete(db.others, key)
+	return missing(found, strings.ToLower(s.kind))
+}
+
+// ============================================================================
+// ALTER
+// ============================================================================
+
+func (ex *executor) execAlterTable(s *alterTableStmt) error {
+	db, err := ex.database()
+	if err != nil {
+		return err
+	}
+	t := ex.tx.table(db, s.name)
+	if t == nil {
+		if s.ifExists {
+			return nil
+		}
+		return fmt.Errorf("The table '%s' does not exist", s.name)
+	}
+	if s.rename != "" {
+		return ex.renameTable(db, t, s.rename)
+	}
+	d := &tableDef{name: s.name, kind: "ANY"}
+	if t.def != nil {
+		c := *t.def
+		d = &c
+	}
+	if err := applyClauses(s.clauses, func(p *parser) error { return p.parseTableClauses(d) }); err != nil {
+		return err
+	}
+	t = ex.tx.writable(db, s.name, false)
+	t.def, t.defsDirty = d, true
+	return nil
+}
+
+// renameTable moves the records and definitions of t to a table named to.
+func (ex *executor) renameTable(db *database, t *table, to string) error {
+	if ex.tx.table(db, to) != nil {
+		return fmt.Errorf("The table '%s' already exists", to)
+	}
+	n := ex.tx.writable(db, to, true)
+	if t.def != nil {
+		d := *t.def
+		d.name = to
+		n.def = &d
+	}
+	for _, f := range t.fields {
+		c := *f
+		c.table = to
+		n.fields = append(n.fields, &c)
+	}
+	for _, ix := range t.indexes {
+		c := *ix
+		c.table = to
+		n.indexes = append(n.indexes, &c)
+	}
+	for _, ev := range t.events {
+		c := *ev
+		c.table = to
+		n.events = append(n.events, &c)
+	}
+	for _, doc := range t.scan() {
+		doc = copyDoc(doc)
+		rid := doc["id"].(models.RecordID)
+		rid.Table = to
+		doc["id"] = rid
+		n.put(doc)
+	}
+	ex.tx.drop(db, t.name)
+	return nil
+}
+
+func (ex *executor) execAlterField(s *alterFieldStmt) error {
+	db, err := ex.database()
+	if err != nil {
+		return err
+	}
+	t := ex.tx.table(db, s.table)
+	var f *fieldDef
+	if t != nil {
+		for _, x := range t.fields {
+			if x.name == s.name {
+				f = x
+			}
+		}
+	}
+	if f == nil {
+		if s.ifExists {
+			return nil
+		}
+		return fmt.Errorf("The field '%s' does not exist", s.name)
+	}
+	c := *f
+	if err := applyClauses(s.clauses, func(p *parser) error { return p.parseFieldClauses(&c) }); err != nil {
+		return err
+	}
+	t = ex.tx.writable(db, s.table, false)
+	t.setField(&c)
+	t.defsDirty = true
+	return nil
+}
+
+// ============================================================================
+// INFO
+// ============================================================================
+
+func (ex *executor) execInfo(s infoStmt) (any, error) {
+	switch s.level {
+	case "root":
+		namespaces := map[string]any{}
+		for name := range ex.srv.namespaces {
+			namespaces[name] = "DEFINE NAMESPACE " + escapeIdent(name)
+		}
+		return map[string]any{
+			"namespaces": namespaces,
+			"users":      userTexts(ex.srv.rootUsers),
+			"accesses":   map[string]any{},
+			"nodes":      map[string]any{},
+		}, nil
+	case "ns":
+		ns := ex.srv.namespace(ex.sess.ns)
+		if ns == nil {
+			return nil, fmt.Errorf("The namespace '%s' does not exist", ex.sess.ns)
+		}
+		databases := map[string]any{}
+		for name := range ns.dbs {
+			databases[name] = "DEFINE DATABASE " + escapeIdent(name)
+		}
+		return map[string]any{
+			"databases": databases,
+			"users":     userTexts(ns.users),
+			"accesses":  accessTexts(ns.access),
+		}, nil
+	case "user":
+		for _, level := range []string{"DATABASE", "NAMESPACE", "ROOT"} {
+			users, err := ex.users(level)
+			if err != nil {
+				continue
+			}
+			if u := users[s.name]; u != nil {
+				return u.text(), nil
+			}
+		}
+		return nil, fmt.Errorf("The user '%s' does not exist", s.name)
+	}
+
+	db, err := ex.database()
+	if err != nil {
+		return nil, err
+	}
+	if s.level == "table" {
+		fields, indexes, events := map[string]any{}, map[string]any{}, map[string]any{}
+		if t := ex.tx.table(db, s.name); t != nil {
+			for _, f := range t.fields {
+				fields[f.name] = f.text()
+			}
+			for _, ix := range t.indexes {
+				indexes[ix.name] = ix.text()
+			}
+			for _, ev := range t.events {
+				events[ev.name] = ev.text
+			}
+		}
+		lives := map[string]any{}
+		for id, lq := range ex.srv.lives {
+			if lq.ns == ex.sess.ns && lq.db == ex.sess.db && lq.table == s.name {
+				lives[id] = "LIVE SELECT * FROM " + escapeIdent(lq.table)
+			}
+		}
+		return map[string]any{
+			"fields":  fields,
+			"indexes": indexes,
+			"events":  events,
+			"tables":  map[string]any{},
+			"lives":   lives,
+		}, nil
+	}
+
+	tables := map[string]any{}
+	for _, name := range ex.tx.tableNames(db) {
+		t := ex.tx.table(db, name)
+		if t.def != nil {
+			tables[name] = t.def.text()
+		} else {
+			tables[name] = fmt.Sprintf("DEFINE TABLE %s TYPE ANY SCHEMALESS PERMISSIONS NONE", escapeIdent(name))
+		}
+	}
+	params := map[string]any{}
+	for name, d := range db.params {
+		params[name] = d.text
+	}
+	functions := map[string]any{}
+	for name, d := range db.functions {
+		functions[strings.TrimPrefix(name, "fn::")] = d.text
+	}
+	out := map[string]any{
+		"tables":    tables,
+		"users":     userTexts(db.users),
+		"accesses":  accessTexts(db.access),
+		"params":    params,
+		"functions": functions,
+		"analyzers": map[string]any{},
+		"models":    map[string]any{},
+		"configs":   map[string]any{},
+		"buckets":   map[string]any{},
+		"sequences": map[string]any{},
+	}
+	for _, d := range db.others {
+		if m, ok := out[d.kind+"s"].(map[string]any); ok {
+			m[d.name] = d.text
+		}
+	}
+	return out, nil
+}
+
+func userTexts(users map[string]*userDef) map[string]any {
+	out := make(map[string]any, len(users))
+	for name, u := range users {
+		out[name] = u.text()
+	}
+	return out
+}
+
+func accessTexts(access map[string]*accessDef) map[string]any {
+	out := make(map[string]any, len(access))
+	for name, a := range access {
+		out[name] = a.text
+	}
+	return out
+}
@@ -0,0 +1,999 @@
+package surrealtest
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/surrealdb/surrealdb.go/pkg/models"
+)
+
+// env is a variable scope. Scopes that carry a document make it $this; the
+// document of the next such scope up is $parent.
+type env struct {
+	ex     *executor
+	up     *env
+	vars   map[string]any
+	doc    any
+	hasDoc bool
+	// group holds the documents of the current GROUP BY group while its
+	// projection is evaluated.
+	group []any
+}
+
+func (e *env) child() *env { return &env{ex: e.ex, up: e} }
+
+func (e *env) withDoc(doc any) *env {
+	return &env{ex: e.ex, up: e, doc: doc, hasDoc: true}
+}
+
+func (e *env) with(vars map[string]any) *env {
+	return &env{ex: e.ex, up: e, vars: vars}
+}
+
+func (e *env) set(name string, v any) {
+	if e.vars == nil {
+		e.vars = map[string]any{}
+	}
+	e.vars[name] = v
+}
+
+// this returns the current document.
+func (e *env) this() any {
+	for s := e; s != nil; s = s.up {
+		if s.hasDoc {
+			return s.doc
+		}
+	}
+	return nil
+}
+
+// groupDocs returns the documents of the enclosing group, or nil when the
+// nearest document in scope is not a group's.
+func (e *env) groupDocs() []any {
+	for s := e; s != nil; s = s.up {
+		if s.group != nil {
+			return s.group
+		}
+		if s.hasDoc {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (e *env) lookup(name string) any {
+	for s := e; s != nil; s = s.up {
+		if v, ok := s.vars[name]; ok {
+			return v
+		}
+	}
+	ex := e.ex
+	switch name {
+	case "this":
+		return e.this()
+	case "parent":
+		found := false
+		for s := e; s != nil; s = s.up {
+			if s.hasDoc {
+				if found {
+					return s.doc
+				}
+				found = true
+			}
+		}
+		return nil
+	case "auth":
+		if r, ok := ex.sess.auth.authValue().(models.RecordID); ok {
+			if doc := ex.fetch(r); doc != nil {
+				return doc
+			}
+			return r
+		}
+		return nil
+	case "session":
+		return ex.sessionValue()
+	case "token":
+		return ex.tokenValue()
+	case "access":
+		if a := ex.sess.auth; a != nil && a.access != "" {
+			return a.access
+		}
+		return nil
+	}
+	if v, ok := ex.sess.vars[name]; ok {
+		return v
+	}
+	if db, err := ex.database(); err == nil {
+		return db.paramVals[name]
+	}
+	return nil
+}
+
+func (ex *executor) sessionValue() any {
+	m := map[string]any{"ns": ex.sess.ns, "db": ex.sess.db}
+	if a := ex.sess.auth; a != nil {
+		if a.access != "" {
+			m["ac"] = a.access
+		}
+		if a.level == "RECORD" {
+			m["rd"] = a.record
+		}
+	}
+	return normalize(m)
+}
+
+func (ex *executor) tokenValue() any {
+	a := ex.sess.auth
+	if a == nil || a.level == "" {
+		return nil
+	}
+	m := map[string]any{"NS": a.ns, "DB": a.db, "AC": a.access}
+	if a.level == "RECORD" {
+		m["ID"] = a.record
+	}
+	if !a.tokenExp.IsZero() {
+		m["exp"] = a.tokenExp.Unix()
+	}
+	return normalize(m)
+}
+
+// returnSignal unwinds a block or function body on RETURN.
+type returnSignal struct{ v any }
+
+func (returnSignal) Error() string { return "RETURN outside of a block" }
+
+func (ex *executor) eval(e *env, x expr) (any, error) {
+	switch x := x.(type) {
+	case nil:
+		return nil, nil
+	case litExpr:
+		return x.v, nil
+	case paramExpr:
+		return e.lookup(x.name), nil
+	case tableExpr:
+		return models.Table(x.name), nil
+	case *recordExpr:
+		return ex.evalRecord(e, x)
+	case *rangeExpr:
+		return nil, fmt.Errorf("surrealtest: record ranges are only supported as FROM targets")
+	case *arrayExpr:
+		out := make([]any, 0, len(x.elems))
+		for _, el := range x.elems {
+			v, err := ex.eval(e, el)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	case *objectExpr:
+		out := make(map[string]any, len(x.keys))
+		for i, k := range x.keys {
+			v, err := ex.eval(e, x.vals[i])
+			if err != nil {
+				return nil, err
+			}
+			if v != nil {
+				out[k] = v
+			}
+		}
+		return out, nil
+	case *unaryExpr:
+		v, err := ex.eval(e, x.x)
+		if err != nil {
+			return nil, err
+		}
+		if x.op == "!" {
+			return !truthy(v), nil
+		}
+		return arith("-", int64(0), v)
+	case *binaryExpr:
+		return ex.evalBinary(e, x)
+	case *knnExpr:
+		return true, nil
+	case *callExpr:
+		return ex.call(e, x)
+	case *castExpr:
+		v, err := ex.eval(e, x.x)
+		if err != nil {
+			return nil, err
+		}
+		return cast(v, x.typ)
+	case subqueryExpr:
+		return ex.exec(e.child(), x.stmt)
+	case *blockExpr:
+		return ex.runBlock(e, x)
+	case *ifExpr:
+		for i, c := range x.conds {
+			v, err := ex.eval(e, c)
+			if err != nil {
+				return nil, err
+			}
+			if truthy(v) {
+				return ex.eval(e, x.thens[i])
+			}
+		}
+		return ex.eval(e, x.els)
+	case *idiomExpr:
+		return ex.evalIdiom(e, x)
+	}
+	return nil, fmt.Errorf("surrealtest: cannot evaluate %T", x)
+}
+
+// runBlock runs the statements of a block in a new scope, returning the value
+// of a RETURN or of the last statement.
+func (ex *executor) runBlock(e *env, b *blockExpr) (any, error) {
+	inner := e.child()
+	var last any
+	for _, s := range b.stmts {
+		v, err := ex.exec(inner, s)
+		if err != nil {
+			if r, ok := err.(returnSignal); ok {
+				return r.v, nil
+			}
+			return nil, err
+		}
+		last = v
+	}
+	return last, nil
+}
+
+func (ex *executor) evalRecord(e *env, x *recordExpr) (any, error) {
+	if x.gen != "" {
+		return models.RecordID{Table: x.table, ID: generateID(x.gen)}, nil
+	}
+	id, err := ex.eval(e, x.id)
+	if err != nil {
+		return nil, err
+	}
+	return models.RecordID{Table: x.table, ID: id}, nil
+}
+
+// ============================================================================
+// Idioms
+// ============================================================================
+
+func (ex *executor) evalIdiom(e *env, x *idiomExpr) (any, error) {
+	var cur any
+	if x.base != nil {
+		v, err := ex.eval(e, x.base)
+		if err != nil {
+			return nil, err
+		}
+		cur = v
+	} else {
+		cur = e.this()
+		// A path starting with a graph step starts at the document's id.
+		if len(x.parts) > 0 {
+			if _, ok := x.parts[0].(graphPart); ok {
+				if doc, ok := cur.(map[string]any); ok {
+					cur = doc["id"]
+				}
+			}
+		}
+	}
+	return ex.walk(e, cur, x.parts, false)
+}
+
+// walk applies idiom parts to cur. onEdge tracks whether the previous graph
+// step arrived at edge records, so the next step continues to nodes.
+func (ex *executor) walk(e *env, cur any, parts []part, onEdge bool) (any, error) {
+	for i, p := range parts {
+		switch p := p.(type) {
+		case fieldPart:
+			if arr, ok := cur.([]any); ok {
+				out := make([]any, 0, len(arr))
+				for _, el := range arr {
+					v, err := ex.walk(e, el, parts[i:i+1], false)
+					if err != nil {
+						return nil, err
+					}
+					out = append(out, v)
+				}
+				cur = out
+				continue
+			}
+			cur = ex.getField(cur, p.name)
+		case allPart:
+			switch v := cur.(type) {
+			case models.RecordID:
+				if doc := ex.fetch(v); doc != nil {
+					cur = doc
+				} else {
+					cur = nil
+				}
+			case map[string]any:
+				vals := make([]any, 0, len(v))
+				for _, k := range sortedKeys(v) {
+					vals = append(vals, v[k])
+				}
+				cur = vals
+			case []any:
+				// An array of links, such as a graph path's result, fetches
+				// each record.
+				vals := make([]any, len(v))
+				for j, el := range v {
+					vals[j] = el
+					if r, ok := el.(models.RecordID); ok {
+						vals[j] = nil
+						if doc := ex.fetch(r); doc != nil {
+							vals[j] = doc
+						}
+					}
+				}
+				cur = vals
+			}
+			if arr, ok := cur.([]any); ok && i+1 < len(parts) {
+				out := make([]any, 0, len(arr))
+				for _, el := range arr {
+					v, err := ex.walk(e, el, parts[i+1:], onEdge)
+					if err != nil {
+						return nil, err
+					}
+					out = append(out, v)
+				}
+				return out, nil
+			}
+		case lastPart:
+			arr, _ := cur.([]any)
+			if len(arr) == 0 {
+				cur = nil
+			} else {
+				cur = arr[len(arr)-1]
+			}
+		case indexPart:
+			ix, err := ex.eval(e, p.x)
+			if err != nil {
+				return nil, err
+			}
+			switch k := ix.(type) {
+			case string:
+				cur = ex.getField(cur, k)
+			default:
+				n, ok := toInt(ix)
+				arr, isArr := cur.([]any)
+				if !ok || !isArr || n < 0 || int(n) >= len(arr) {
+					cur = nil
+				} else {
+					cur = arr[n]
+				}
+			}
+		case wherePart:
+			arr, ok := cur.([]any)
+			if !ok {
+				if cur == nil {
+					arr = nil
+				} else {
+					arr = []any{cur}
+				}
+			}
+			out := []any{}
+			for _, el := range arr {
+				doc := el
+				if r, ok := el.(models.RecordID); ok {
+					if d := ex.fetch(r); d != nil {
+						doc = d
+					}
+				}
+				v, err := ex.eval(e.withDoc(doc), p.cond)
+				if err != nil {
+					return nil, err
+				}
+				if truthy(v) {
+					out = append(out, el)
+				}
+			}
+			cur = out
+		case methodPart:
+			args := make([]any, 0, len(p.args)+1)
+			args = append(args, cur)
+			for _, a := range p.args {
+				v, err := ex.eval(e, a)
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, v)
+			}
+			v, err := ex.callMethod(e, p.name, args)
+			if err != nil {
+				return nil, err
+			}
+			cur = v
+		case recursePart:
+			v, err := ex.recurse(e, cur, p)
+			if err != nil {
+				return nil, err
+			}
+			cur = v
+		case graphPart:
+			v, err := ex.traverse(e, cur, p, onEdge)
+			if err != nil {
+				return nil, err
+			}
+			cur = v
+			onEdge = !onEdge
+		}
+	}
+	return cur, nil
+}
+
+// recurse walks r.path from start level by level. +collect returns the
+// unique records first reached between r.min and r.max levels down,
+// +shortest the records along the shortest path to the target; either
+// skips start unless +inclusive. Without an instruction the path repeats
+// until r.max or until a level reaches nothing, returning the last level
+// reached at r.min or deeper.
+func (ex *executor) recurse(e *env, start any, r recursePart) (any, error) {
+	if doc, ok := start.(map[string]any); ok && doc["id"] != nil {
+		start = doc["id"]
+	}
+	step := func(v any) ([]any, error) {
+		out, err := ex.walk(e, v, r.path, false)
+		if err != nil {
+			return nil, err
+		}
+		var flat []any
+		for _, el := range asArray(out) {
+			if el != nil {
+				flat = append(flat, el)
+			}
+		}
+		return flat, nil
+	}
+	var target any
+	if r.instr == "shortest" {
+		v, err := ex.eval(e, r.target)
+		if err != nil {
+			return nil, err
+		}
+		target = v
+	}
+
+	switch r.instr {
+	case "collect", "shortest":
+		seen := map[string]bool{valueKey(start): true}
+		parent := map[string]any{}
+		frontier := []any{start}
+		out := []any{}
+		for depth := 1; depth <= r.max && len(frontier) > 0; depth++ {
+			var next []any
+			for _, node := range frontier {
+				vals, err := step(node)
+				if err != nil {
+					return nil, err
+				}
+				for _, v := range vals {
+					k := valueKey(v)
+					if seen[k] {
+						continue
+					}
+					seen[k] = true
+					parent[k] = node
+					next = append(next, v)
+					if r.instr == "collect" && depth >= r.min {
+						out = append(out, v)
+					}
+					if r.instr == "shortest" && equalValues(v, target) {
+						if depth < r.min {
+							return []any{}, nil
+						}
+						path := []any{v}
+						for k != valueKey(start) {
+							p := parent[k]
+							path = append([]any{p}, path...)
+							k = valueKey(p)
+						}
+						if !r.inclusive {
+							path = path[1:]
+						}
+						return path, nil
+					}
+				}
+			}
+			frontier = next
+		}
+		if r.instr == "collect" && r.inclusive {
+			out = append([]any{start}, out...)
+		}
+		return out, nil
+	case "":
+		cur := []any{start}
+		var last any
+		for depth := 1; depth <= r.max; depth++ {
+			var next []any
+			for _, node := range cur {
+				vals, err := step(node)
+				if err != nil {
+					return nil, err
+				}
+				next = append(next, vals...)
+			}
+			if len(next) == 0 {
+				break
+			}
+			cur = next
+			if depth >= r.min {
+				last = cur
+			}
+		}
+		return last, nil
+	}
+	return nil, fmt.Errorf("recursion instruction +%s is not supported", r.instr)
+}
+
+// getField reads name from an object, fetching the document first when cur
+// is a record id.
+func (ex *executor) getField(cur any, name string) any {
+	switch v := cur.(type) {
+	case map[string]any:
+		return v[name]
+	case models.RecordID:
+		if name == "id" {
+			return v
+		}
+		if doc := ex.fetch(v); doc != nil {
+			return doc[name]
+		}
+	case []any:
+		out := make([]any, 0, len(v))
+		for _, el := range v {
+			out = append(out, ex.getField(el, name))
+		}
+		return out
+	}
+	return nil
+}
+
+// traverse follows one graph step. From nodes it finds the edges whose in
+// (->), out (<-) or either (<->) is the node; from edges it continues to
+// the records at the other end.
+func (ex *executor) traverse(e *env, cur any, g graphPart, onEdge bool) (any, error) {
+	var from []models.RecordID
+	collect := func(v any) {
+		switch r := v.(type) {
+		case models.RecordID:
+			from = append(from, r)
+		case map[string]any:
+			if id, ok := r["id"].(models.RecordID); ok {
+				from = append(from, id)
+			}
+		}
+	}
+	if arr, ok := cur.([]any); ok {
+		for _, el := range arr {
+			collect(el)
+		}
+	} else {
+		collect(cur)
+	}
+	matchTable := func(table string) bool {
+		if len(g.tables) == 0 {
+			return true
+		}
+		for _, t := range g.tables {
+			if t == table {
+				return true
+			}
+		}
+		return false
+	}
+	out := []any{}
+	keep := func(id models.RecordID) error {
+		if !matchTable(id.Table) {
+			return nil
+		}
+		if g.cond != nil {
+			doc := ex.fetch(id)
+			if doc == nil {
+				return nil
+			}
+			v, err := ex.eval(e.withDoc(doc), g.cond)
+			if err != nil || !truthy(v) {
+				return err
+			}
+		}
+		out = append(out, id)
+		return nil
+	}
+
+	if onEdge {
+		for _, edge := range from {
+			doc := ex.fetch(edge)
+			if doc == nil {
+				continue
+			}
+			var ends []any
+			switch g.dir {
+			case "->":
+				ends = []any{doc["out"]}
+			case "<-":
+				ends = []any{doc["in"]}
+			default:
+				ends = []any{doc["in"], doc["out"]}
+			}
+			for _, end := range ends {
+				if r, ok := end.(models.RecordID); ok {
+					if err := keep(r); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+		return out, nil
+	}
+
+	db, err := ex.database()
+	if err != nil {
+		return nil, err
+	}
+	tables := g.tables
+	if len(tables) == 0 {
+		tables = ex.tx.tableNames(db)
+	}
+	for _, node := range from {
+		for _, name := range tables {
+			t := ex.tx.table(db, name)
+			if t == nil {
+				continue
+			}
+			for _, doc := range ex.visible(t, t.scan()) {
+				in, _ := doc["in"].(models.RecordID)
+				outID, _ := doc["out"].(models.RecordID)
+				hit := false
+				switch g.dir {
+				case "->":
+					hit = equalValues(in, node)
+				case "<-":
+					hit = equalValues(outID, node)
+				default:
+					hit = equalValues(in, node) || equalValues(outID, node)
+				}
+				if _, isEdge := doc["in"].(models.RecordID); !isEdge || !hit {
+					continue
+				}
+				if err := keep(doc["id"].(models.RecordID)); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// ============================================================================
+// Operators
+// ============================================================================
+
+func (ex *executor) evalBinary(e *env, x *binaryExpr) (any, error) {
+	l, err := ex.eval(e, x.l)
+	if err != nil {
+		return nil, err
+	}
+	switch x.op {
+	case "||":
+		if truthy(l) {
+			return l, nil
+		}
+		return ex.eval(e, x.r)
+	case "&&":
+		if !truthy(l) {
+			return l, nil
+		}
+		return ex.eval(e, x.r)
+	case "??":
+		if l != nil {
+			return l, nil
+		}
+		return ex.eval(e, x.r)
+	case "?:":
+		if truthy(l) {
+			return l, nil
+		}
+		return ex.eval(e, x.r)
+	}
+	r, err := ex.eval(e, x.r)
+	if err != nil {
+		return nil, err
+	}
+	return binaryOp(x.op, l, r)
+}
+
+func binaryOp(op string, l, r any) (any, error) {
+	switch op {
+	case "=", "==":
+		return equalValues(l, r), nil
+	case "!=", "!==":
+		return !equalValues(l, r), nil
+	case "?=":
+		for _, el := range asArray(l) {
+			if equalValues(el, r) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "*=":
+		arr := asArray(l)
+		for _, el := range arr {
+			if !equalValues(el, r) {
+				return false, nil
+			}
+		}
+		return len(arr) > 0, nil
+	case "~", "!~":
+		ls, _ := l.(string)
+		rs, _ := r.(string)
+		match := strings.Contains(strings.ToLower(ls), strings.ToLower(rs))
+		return match == (op == "~"), nil
+	case "<":
+		return compareValues(l, r) < 0, nil
+	case "<=":
+		return compareValues(l, r) <= 0, nil
+	case ">":
+		return compareValues(l, r) > 0, nil
+	case ">=":
+		return compareValues(l, r) >= 0, nil
+	case "CONTAINS":
+		return contains(l, r), nil
+	case "CONTAINSNOT":
+		return !contains(l, r), nil
+	case "CONTAINSALL":
+		for _, el := range asArray(r) {
+			if !contains(l, el) {
+				return false, nil
+			}
+		}
+		return true, nil
+	case "CONTAINSANY":
+		for _, el := range asArray(r) {
+			if contains(l, el) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "CONTAINSNONE":
+		for _, el := range asArray(r) {
+			if contains(l, el) {
+				return false, nil
+			}
+		}
+		return true, nil
+	case "INSIDE":
+		return contains(r, l), nil
+	case "NOTINSIDE":
+		return !contains(r, l), nil
+	case "ALLINSIDE":
+		for _, el := range asArray(l) {
+			if !contains(r, el) {
+				return false, nil
+			}
+		}
+		return true, nil
+	case "ANYINSIDE":
+		for _, el := range asArray(l) {
+			if contains(r, el) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "NONEINSIDE":
+		for _, el := range asArray(l) {
+			if contains(r, el) {
+				return false, nil
+			}
+		}
+		return true, nil
+	case "@@":
+		text := strings.ToLower(fmt.Sprint(l))
+		for _, term := range strings.Fields(strings.ToLower(fmt.Sprint(r))) {
+			if !strings.Contains(text, term) {
+				return false, nil
+			}
+		}
+		return true, nil
+	case "OUTSIDE", "INTERSECTS":
+		return false, nil
+	}
+	return arith(op, l, r)
+}
+
+func asArray(v any) []any {
+	switch x := v.(type) {
+	case []any:
+		return x
+	case nil:
+		return nil
+	}
+	return []any{v}
+}
+
+// contains reports whether the array, string or object c contains v.
+func contains(c, v any) bool {
+	switch x := c.(type) {
+	case []any:
+		for _, el := range x {
+			if equalValues(el, v) {
+				return true
+			}
+		}
+	case string:
+		s, ok := v.(string)
+		return ok && strings.Contains(x, s)
+	case map[string]any:
+		s, ok := v.(string)
+		if ok {
+			_, found := x[s]
+			return found
+		}
+	}
+	return false
+}
+
+func arith(op string, l, r any) (any, error) {
+	switch op {
+	case "×":
+		op = "*"
+	case "÷":
+		op = "/"
+	}
+	if isNumber(l) && isNumber(r) {
+		return numberOp(op, l, r)
+	}
+	switch lv := l.(type) {
+	case string:
+		if op == "+" {
+			if rs, ok := r.(string); ok {
+				return lv + rs, nil
+			}
+			if r == nil {
+				return lv, nil
+			}
+		}
+	case []any:
+		switch op {
+		case "+":
+			return append(append([]any{}, lv...), asArray(r)...), nil
+		case "-":
+			out := []any{}
+			for _, el := range lv {
+				if !contains(asArray(r), el) {
+					out = append(out, el)
+				}
+			}
+			return out, nil
+		}
+	case map[string]any:
+		if rm, ok := r.(map[string]any); ok && op == "+" {
+			out := copyDoc(lv)
+			for k, v := range rm {
+				out[k] = deepCopy(v)
+			}
+			return out, nil
+		}
+	case time.Time:
+		switch rv := r.(type) {
+		case time.Duration:
+			if op == "+" {
+				return lv.Add(rv), nil
+			}
+			if op == "-" {
+				return lv.Add(-rv), nil
+			}
+		case time.Time:
+			if op == "-" {
+				return lv.Sub(rv), nil
+			}
+		}
+	case time.Duration:
+		switch rv := r.(type) {
+		case time.Duration:
+			if op == "+" {
+				return lv + rv, nil
+			}
+			if op == "-" {
+				return lv - rv, nil
+			}
+		case time.Time:
+			if op == "+" {
+				return rv.Add(lv), nil
+			}
+		case int64:
+			if op == "*" {
+				return lv * time.Duration(rv), nil
+			}
+			if op == "/" && rv != 0 {
+				return lv / time.Duration(rv), nil
+			}
+		}
+	case nil:
+		if isNumber(r) && (op == "+" || op == "-") {
+			return numberOp(op, int64(0), r)
+		}
+		if op == "+" {
+			return r, nil
+		}
+	}
+	if l == nil && r == nil {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("Cannot perform %s operation on '%s' and '%s'", opName(op), render(l), render(r))
+}
+
+func opName(op string) string {
+	switch op {
+	case "+":
+		return "addition"
+	case "-":
+		return "subtraction"
+	case "*":
+		return "multiplication"
+	case "/":
+		return "division"
+	case "**":
+		return "power"
+	}
+	return op
+}
+
+func numberOp(op string, l, r any) (any, error) {
+	_, ld := l.(models.DecimalString)
+	_, rd := r.(models.DecimalString)
+	li, lok := l.(int64)
+	ri, rok := r.(int64)
+	if lok && rok {
+		switch op {
+		case "+":
+			return li + ri, nil
+		case "-":
+			return li - ri, nil
+		case "*":
+			return li * ri, nil
+		case "/":
+			if ri == 0 {
+				return math.NaN(), nil
+			}
+			if li%ri == 0 {
+				return li / ri, nil
+			}
+			return float64(li) / float64(ri), nil
+		case "%":
+			if ri == 0 {
+				return math.NaN(), nil
+			}
+			return li % ri, nil
+		case "**":
+			if ri >= 0 {
+				return int64(math.Pow(float64(li), float64(ri))), nil
+			}
+			return math.Pow(float64(li), float64(ri)), nil
+		}
+	}
+	lf, _ := toFloat(l)
+	rf, _ := toFloat(r)
+	var out float64
+	switch op {
+	case "+":
+		out = lf + rf
+	case "-":
+		out = lf - rf
+	case "*":
+		out = lf * rf
+	case "/":
+		out = lf / rf
+	case "%":
+		out = math.Mod(lf, rf)
+	case "**":
+		out = math.Pow(lf, rf)
+	default:
+		return nil, fmt.Errorf("surrealtest: unsupported operator %s", op)
+	}
+	if ld || rd {
+		return models.DecimalString(formatFloat(out)), nil
+	}
+	return out, nil
+}
+
+func formatFloat(f float64) string {
+	return fmt.Sprintf("%v", f)
+}
@@ -0,0 +1,1822 @@
+package surrealtest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/surrealdb/surrealdb.go/pkg/models"
+)
+
+// executor runs statements for one session inside one transaction.
+type executor struct {
+	srv  *Server
+	sess *session
+	tx   *txn
+	// depth guards against events and functions recursing without end.
+	depth int
+	// inPerm is set while a permission clause is evaluated, which itself
+	// runs unrestricted.
+	inPerm int
+}
+
+const maxDepth = 32
+
+func (ex *executor) database() (*database, error) {
+	return ex.srv.database(ex.sess.ns, ex.sess.db)
+}
+
+// queryResult is one entry of a query RPC response.
+type queryResult struct {
+	result any
+	err    error
+	dur    time.Duration
+}
+
+// Errors reported for the statements of a transaction that did not commit.
+var (
+	errFailedTxn    = fmt.Errorf("The query was not executed due to a failed transaction")
+	errCancelledTxn = fmt.Errorf("The query was not executed due to a cancelled transaction")
+)
+
+// run executes a parsed query. Outside an RPC transaction each statement
+// commits on its own, except between BEGIN and COMMIT, where the statements
+// commit or fail together.
+func (ex *executor) run(stmts []stmt, vars map[string]any) []queryResult {
+	root := &env{ex: ex, vars: map[string]any{}}
+	for k, v := range vars {
+		root.vars[k] = v
+	}
+	rpcTx := ex.tx
+	var results []queryResult
+	var block *txn
+	blockStart, failed := 0, false
+	for _, s := range stmts {
+		switch s.(type) {
+		case beginStmt:
+			if rpcTx == nil && block == nil {
+				block, blockStart, failed = newTxn(), len(results), false
+			}
+			continue
+		case commitStmt:
+			if block != nil {
+				if failed {
+					for i := blockStart; i < len(results); i++ {
+						if results[i].err == nil {
+							results[i] = queryResult{err: errFailedTxn}
+						}
+					}
+				} else {
+					ex.srv.commit(block)
+				}
+				block = nil
+			}
+			continue
+		case cancelStmt:
+			if block != nil {
+				for i := blockStart; i < len(results); i++ {
+					results[i] = queryResult{err: errCancelledTxn}
+				}
+				block = nil
+			}
+			continue
+		}
+
+		start := time.Now()
+		var res queryResult
+		switch {
+		case block != nil && failed:
+			res.err = errFailedTxn
+		case block != nil:
+			ex.tx = block
+			res.result, res.err = ex.execTop(root, s)
+			failed = res.err != nil
+		case rpcTx != nil:
+			res.result, res.err = ex.execTop(root, s)
+		default:
+			tx := newTxn()
+			ex.tx = tx
+			res.result, res.err = ex.execTop(root, s)
+			if res.err == nil {
+				ex.srv.commit(tx)
+			}
+		}
+		res.dur = time.Since(start)
+		results = append(results, res)
+	}
+	if block != nil {
+		// A transaction left open at the end of the query is cancelled.
+		for i := blockStart; i < len(results); i++ {
+			results[i] = queryResult{err: errCancelledTxn}
+		}
+	}
+	ex.tx = rpcTx
+	return results
+}
+
+func (ex *executor) execTop(e *env, s stmt) (any, error) {
+	v, err := ex.exec(e, s)
+	if r, ok := err.(returnSignal); ok {
+		return r.v, nil
+	}
+	return v, err
+}
+
+// commit applies tx and delivers its live query notifications.
+func (s *Server) commit(tx *txn) {
+	tx.commit()
+	s.deliver(tx)
+}
+
+func (ex *executor) exec(e *env, s stmt) (any, error) {
+	switch s := s.(type) {
+	case *selectStmt:
+		return ex.execSelect(e, s)
+	case *createStmt:
+		return ex.execCreate(e, s)
+	case *updateStmt:
+		return ex.execUpdate(e, s)
+	case *deleteStmt:
+		return ex.execDelete(e, s)
+	case *insertStmt:
+		return ex.execInsert(e, s)
+	case *relateStmt:
+		return ex.execRelate(e, s)
+	case returnStmt:
+		v, err := ex.eval(e, s.x)
+		if err != nil {
+			return nil, err
+		}
+		return nil, returnSignal{v}
+	case letStmt:
+		v, err := ex.eval(e, s.x)
+		if err != nil {
+			return nil, err
+		}
+		e.set(s.name, v)
+		return nil, nil
+	case exprStmt:
+		return ex.eval(e, s.x)
+	case ifStmt:
+		return ex.eval(e, s.x)
+	case throwStmt:
+		v, err := ex.eval(e, s.x)
+		if err != nil {
+			return nil, err
+		}
+		if str, ok := v.(string); ok {
+			return nil, fmt.Errorf("An error occurred: %s", str)
+		}
+		return nil, fmt.Errorf("An error occurred: %s", render(v))
+	case beginStmt, commitStmt, cancelStmt, noopStmt:
+		return nil, nil
+	case useStmt:
+		if s.ns != "" {
+			ex.sess.ns = s.ns
+		}
+		if s.db != "" {
+			ex.sess.db = s.db
+		}
+		return nil, nil
+	case infoStmt:
+		return ex.execInfo(s)
+	case *liveStmt:
+		return ex.execLive(e, s)
+	case killStmt:
+		v, err := ex.eval(e, s.id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, ex.srv.kill(v)
+	case *defineStmt:
+		return nil, ex.execDefine(e, s)
+	case removeStmt:
+		return nil, ex.execRemove(s)
+	case *alterTableStmt:
+		return nil, ex.execAlterTable(s)
+	case *alterFieldStmt:
+		return nil, ex.execAlterField(s)
+	case explainStmt:
+		return ex.execExplain(s)
+	}
+	return nil, fmt.Errorf("surrealtest: unsupported statement %T", s)
+}
+
+// ============================================================================
+// Permissions
+// ============================================================================
+
+// restricted reports whether table permissions apply to the session: they
+// do for record users and anonymous sessions, not for system users.
+func (ex *executor) restricted() bool {
+	return ex.inPerm == 0 && !ex.sess.auth.isSystem()
+}
+
+func (ex *executor) allowed(t *table, op string, doc map[string]any) (bool, error) {
+	if !ex.restricted() {
+		return true, nil
+	}
+	if t.def == nil {
+		return false, nil
+	}
+	p := t.def.perms[op]
+	if p.full || p.cond == nil {
+		return p.full, nil
+	}
+	ex.inPerm++
+	defer func() { ex.inPerm-- }()
+	v, err := ex.eval((&env{ex: ex}).withDoc(doc), p.cond)
+	return truthy(v), err
+}
+
+// visible filters docs down to those the session may select.
+func (ex *executor) visible(t *table, docs []map[string]any) []map[string]any {
+	if !ex.restricted() {
+		return docs
+	}
+	out := docs[:0:0]
+	for _, doc := range docs {
+		if ok, err := ex.allowed(t, "select", doc); ok && err == nil {
+			out = append(out, doc)
+		}
+	}
+	return out
+}
+
+// fetch returns the document for a record id, or nil when it does not exist
+// or is not visible.
+func (ex *executor) fetch(r models.RecordID) map[string]any {
+	db, err := ex.database()
+	if err != nil {
+		return nil
+	}
+	t := ex.tx.table(db, r.Table)
+	if t == nil {
+		return nil
+	}
+	doc := t.get(r.ID)
+	if doc == nil {
+		return nil
+	}
+	if ok, err := ex.allowed(t, "select", doc); !ok || err != nil {
+		return nil
+	}
+	return doc
+}
+
+// ============================================================================
+// SELECT
+// ============================================================================
+
+// findKNN returns the nearest-neighbour condition of a WHERE clause, which
+// may be joined to others with AND.
+func findKNN(x expr) *knnExpr {
+	switch x := x.(type) {
+	case *knnExpr:
+		return x
+	case *binaryExpr:
+		if x.op == "&&" {
+			if k := findKNN(x.l); k != nil {
+				return k
+			}
+			return findKNN(x.r)
+		}
+	}
+	return nil
+}
+
+// nearest keeps the knn.k rows closest to knn.vec by Euclidean distance,
+// nearest first. Rows without a vector are dropped.
+func (ex *executor) nearest(e *env, knn *knnExpr, rows []any) ([]any, error) {
+	target, err := ex.eval(e, knn.vec)
+	if err != nil {
+		return nil, err
+	}
+	to := numbers(target)
+	type scored struct {
+		row  any
+		dist float64
+	}
+	var found []scored
+	for _, row := range rows {
+		v, err := ex.eval(e.withDoc(row), knn.x)
+		if err != nil {
+			return nil, err
+		}
+		from := numbers(v)
+		if len(from) == 0 || len(from) != len(to) {
+			continue
+		}
+		var sum float64
+		for i := range from {
+			sum += (from[i] - to[i]) * (from[i] - to[i])
+		}
+		found = append(found, scored{row, sum})
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].dist < found[j].dist })
+	if len(found) > knn.k {
+		found = found[:knn.k]
+	}
+	out := make([]any, len(found))
+	for i, f := range found {
+		out[i] = f.row
+	}
+	return out, nil
+}
+
+func (ex *executor) execSelect(e *env, s *selectStmt) (any, error) {
+	// In a subquery the targets are evaluated with the outer document as
+	// $parent, as the rest of the statement is: FROM $parent.author.
+	from := e
+	if doc := e.this(); doc != nil {
+		from = e.with(map[string]any{"parent": doc})
+	}
+	var rows []any
+	for _, target := range s.from {
+		vals, err := ex.source(from, target)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, vals...)
+	}
+	if s.explain {
+		return ex.plan(s), nil
+	}
+
+	if s.where != nil {
+		kept := rows[:0:0]
+		for _, row := range rows {
+			v, err := ex.eval(e.withDoc(row), s.where)
+			if err != nil {
+				return nil, err
+			}
+			if truthy(v) {
+				kept = append(kept, row)
+			}
+		}
+		rows = kept
+		if knn := findKNN(s.where); knn != nil {
+			nearest, err := ex.nearest(e, knn, rows)
+			if err != nil {
+				return nil, err
+			}
+			rows = nearest
+		}
+	}
+
+	for _, sp := range s.split {
+		path := idiomPath(sp.(*idiomExpr))
+		var split []any
+		for _, row := range rows {
+			doc, ok := row.(map[string]any)
+			arr, isArr := getPath(doc, path).([]any)
+			if !ok || !isArr {
+				split = append(split, row)
+				continue
+			}
+			for _, el := range arr {
+				split = append(split, setPath(copyDoc(doc), path, el))
+			}
+		}
+		rows = split
+	}
+
+	type outRow struct {
+		doc, proj any
+	}
+	var out []outRow
+	if len(s.group) > 0 || s.groupAll {
+		keys := map[string][]any{}
+		var order []string
+		keyVals := map[string][]any{}
+		for _, row := range rows {
+			vals := make([]any, len(s.group))
+			for i, g := range s.group {
+				v, err := ex.eval(e.withDoc(row), g)
+				if err != nil {
+					return nil, err
+				}
+				vals[i] = v
+			}
+			k := valueKey(vals)
+			if _, ok := keys[k]; !ok {
+				order = append(order, k)
+				keyVals[k] = vals
+			}
+			keys[k] = append(keys[k], row)
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return compareValues(keyVals[order[i]], keyVals[order[j]]) < 0
+		})
+		for _, k := range order {
+			group := keys[k]
+			ge := e.withDoc(group[0])
+			ge.group = group
+			proj, err := ex.project(ge, s, group[0])
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, outRow{group[0], proj})
+		}
+	} else {
+		for _, row := range rows {
+			proj, err := ex.project(e.withDoc(row), s, row)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, outRow{row, proj})
+		}
+	}
+
+	if s.rand {
+		shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+	} else if len(s.order) > 0 {
+		keys := make([][]any, len(out))
+		for i, r := range out {
+			keys[i] = make([]any, len(s.order))
+			for j, o := range s.order {
+				v, err := ex.eval(e.withDoc(r.proj), o.x)
+				if err != nil {
+					return nil, err
+				}
+				if v == nil && r.doc != nil {
+					if v, err = ex.eval(e.withDoc(r.doc), o.x); err != nil {
+						return nil, err
+					}
+				}
+				keys[i][j] = v
+			}
+		}
+		idx := make([]int, len(out))
+		for i := range idx {
+			idx[i] = i
+		}
+		sort.SliceStable(idx, func(a, b int) bool {
+			for j, o := range s.order {
+				c := compareOrder(keys[idx[a]][j], keys[idx[b]][j], o)
+				if c != 0 {
+					return c < 0
+				}
+			}
+			return false
+		})
+		sorted := make([]outRow, len(out))
+		for i, k := range idx {
+			sorted[i] = out[k]
+		}
+		out = sorted
+	}
+
+	if s.start != nil {
+		v, err := ex.eval(e, s.start)
+		if err != nil {
+			return nil, err
+		}
+		n, _ := toInt(v)
+		if int(n) >= len(out) {
+			out = nil
+		} else if n > 0 {
+			out = out[n:]
+		}
+	}
+	if s.limit != nil {
+		v, err := ex.eval(e, s.limit)
+		if err != nil {
+			return nil, err
+		}
+		if n, ok := toInt(v); ok && n >= 0 && int(n) < len(out) {
+			out = out[:n]
+		}
+	}
+
+	result := make([]any, len(out))
+	for i, r := range out {
+		result[i] = r.proj
+		for _, f := range s.fetch {
+			result[i] = ex.fetchPath(result[i], idiomPath(f.(*idiomExpr)))
+		}
+	}
+	return only(s.only, result)
+}
+
+// source expands one FROM target into rows.
+func (ex *executor) source(e *env, target expr) ([]any, error) {
+	switch t := target.(type) {
+	case tableExpr:
+		return ex.scan(t.name)
+	case *rangeExpr:
+		return ex.scanRange(e, t)
+	}
+	v, err := ex.eval(e, target)
+	if err != nil {
+		return nil, err
+	}
+	return ex.expand(v)
+}
+
+func (ex *executor) scan(name string) ([]any, error) {
+	db, err := ex.database()
+	if err != nil {
+		return nil, err
+	}
+	t := ex.tx.table(db, name)
+	if t == nil {
+		return nil, nil
+	}
+	docs := ex.visible(t, t.scan())
+	out := make([]any, len(docs))
+	for i, d := range docs {
+		out[i] = d
+	}
+	return out, nil
+}
+
+// scanRange returns the records of a record range in key order.
+func (ex *executor) scanRange(e *env, r *rangeExpr) ([]any, error) {
+	var from, to any
+	var err error
+	if r.from != nil {
+		if from, err = ex.eval(e, r.from); err != nil {
+			return nil, err
+		}
+	}
+	if r.to != nil {
+		if to, err = ex.eval(e, r.to); err != nil {
+			return nil, err
+		}
+	}
+	rows, err := ex.scan(r.table)
+	if err != nil {
+		return nil, err
+	}
+	key := func(row any) any {
+		id, _ := row.(map[string]any)["id"].(models.RecordID)
+		return id.ID
+	}
+	var out []any
+	for _, row := range rows {
+		k := key(row)
+		if r.from != nil {
+			if c := compareValues(k, from); c < 0 || c == 0 && r.fromExcl {
+				continue
+			}
+		}
+		if r.to != nil {
+			if c := compareValues(k, to); c > 0 || c == 0 && !r.toIncl {
+				continue
+			}
+		}
+		out = append(out, row)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return compareValues(key(out[i]), key(out[j])) < 0 })
+	return out, nil
+}
+
+func (ex *executor) expand(v any) ([]any, error) {
+	switch x := v.(type) {
+	case nil:
+		return nil, nil
+	case models.Table:
+		return ex.scan(string(x))
+	case models.RecordID:
+		if doc := ex.fetch(x); doc != nil {
+			return []any{doc}, nil
+		}
+		return nil, nil
+	case []any:
+		var out []any
+		for _, el := range x {
+			switch el.(type) {
+			case models.RecordID, models.Table:
+				rows, err := ex.expand(el)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, rows...)
+			default:
+				out = append(out, el)
+			}
+		}
+		return out, nil
+	}
+	return []any{v}, nil
+}
+
+func only(single bool, result []any) (any, error) {
+	if !single {
+		return result, nil
+	}
+	switch len(result) {
+	case 0:
+		return nil, nil
+	case 1:
+		return result[0], nil
+	}
+	return nil, fmt.Errorf("Expected a single result output when using the ONLY keyword")
+}
+
+// project builds the output row for one document or group.
+func (ex *executor) project(e *env, s *selectStmt, row any) (any, error) {
+	if s.value {
+		return ex.eval(e, s.fields[0].x)
+	}
+	return ex.projectFields(e, s.fields, s.omit, row)
+}
+
+func (ex *executor) projectFields(e *env, fields []field, omit []expr, row any) (any, error) {
+	out := map[string]any{}
+	for _, f := range fields {
+		if f.all {
+			doc, ok := row.(map[string]any)
+			if !ok {
+				if len(fields) == 1 {
+					return row, nil
+				}
+				continue
+			}
+			for k, v := range doc {
+				out[k] = v
+			}
+			continue
+		}
+		v, err := ex.eval(e, f.x)
+		if err != nil {
+			return nil, err
+		}
+		out = setPath(out, fieldName(f), v)
+	}
+	for _, o := range omit {
+		out = removePath(out, idiomPath(o.(*idiomExpr)))
+	}
+	return out, nil
+}
+
+// fieldName is the output path of a projected field.
+func fieldName(f field) []string {
+	if f.alias != nil {
+		return idiomPath(f.alias)
+	}
+	switch x := f.x.(type) {
+	case *idiomExpr:
+		if x.base == nil {
+			if path := idiomPath(x); path != nil {
+				return path
+			}
+		}
+	case *callExpr:
+		return []string{x.name}
+	}
+	return []string{f.text}
+}
+
+// idiomPath returns the field names of a plain field path, or nil when the
+// idiom has other parts.
+func idiomPath(x *idiomExpr) []string {
+	if x.base != nil {
+		return nil
+	}
+	path := make([]string, 0, len(x.parts))
+	for _, p := range x.parts {
+		f, ok := p.(fieldPart)
+		if !ok {
+			if _, graph := p.(graphPart); graph {
+				return []string{x.text}
+			}
+			return nil
+		}
+		path = append(path, f.name)
+	}
+	return path
+}
+
+func getPath(doc map[string]any, path []string) any {
+	var cur any = doc
+	for _, p := range path {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = m[p]
+	}
+	return cur
+}
+
+// setPath sets path in doc, creating intermediate objects. A nil value
+// removes the field. doc may be nil.
+func setPath(doc map[string]any, path []string, v any) map[string]any {
+	if len(path) == 0 {
+		return doc
+	}
+	if doc == nil {
+		if v == nil {
+			return nil
+		}
+		doc = map[string]any{}
+	}
+	if len(path) == 1 {
+		if v == nil {
+			delete(doc, path[0])
+		} else {
+			doc[path[0]] = v
+		}
+		return doc
+	}
+	child, _ := doc[path[0]].(map[string]any)
+	if child != nil {
+		child = copyDoc(child)
+	}
+	child = setPath(child, path[1:], v)
+	if child == nil {
+		return doc
+	}
+	doc[path[0]] = child
+	return doc
+}
+
+func removePath(doc map[string]any, path []string) map[string]any {
+	return setPath(doc, path, nil)
+}
+
+// fetchPath replaces the record ids found at path with their documents.
+func (ex *executor) fetchPath(v any, path []string) any {
+	switch x := v.(type) {
+	case models.RecordID:
+		if len(path) > 0 {
+			return v
+		}
+		if doc := ex.fetch(x); doc != nil {
+			return doc
+		}
+		return v
+	case []any:
+		out := make([]any, len(x))
+		for i, el := range x {
+			out[i] = ex.fetchPath(el, path)
+		}
+		return out
+	case map[string]any:
+		if len(path) == 0 {
+			return v
+		}
+		child, ok := x[path[0]]
+		if !ok {
+			return v
+		}
+		out := copyDoc(x)
+		out[path[0]] = ex.fetchPath(child, path[1:])
+		return out
+	}
+	return v
+}
+
+func compareOrder(a, b any, o orderItem) int {
+	var c int
+	as, aok := a.(string)
+	bs, bok := b.(string)
+	switch {
+	case aok && bok && o.numeric:
+		c = compareNatural(as, bs)
+	case aok && bok && o.collate:
+		c = strings.Compare(strings.ToLower(as), strings.ToLower(bs))
+	default:
+		c = compareValues(a, b)
+	}
+	if o.desc {
+		return -c
+	}
+	return c
+}
+
+// compareNatural compares strings with embedded numbers by their value, so
+// "item2" sorts before "item10".
+func compareNatural(a, b string) int {
+	for a != "" && b != "" {
+		da, db := digitPrefix(a), digitPrefix(b)
+		if da > 0 && db > 0 {
+			na, _ := strconv.ParseFloat(a[:da], 64)
+			nb, _ := strconv.ParseFloat(b[:db], 64)
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+			a, b = a[da:], b[db:]
+			continue
+		}
+		if a[0] != b[0] {
+			return cmpInt(int(a[0]), int(b[0]))
+		}
+		a, b = a[1:], b[1:]
+	}
+	return cmpInt(len(a), len(b))
+}
+
+func digitPrefix(s string) int {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return i
+}
+
+func (ex *executor) plan(s *selectStmt) any {
+	var out []any
+	for _, f := range s.from {
+		detail := map[string]any{}
+		op := "Iterate Value"
+		if t, ok := f.(tableExpr); ok {
+			detail["table"] = t.name
+			op = "Iterate Table"
+		}
+		out = append(out, map[string]any{"detail": detail, "operation": op})
+	}
+	return out
+}
+
+func (ex *executor) execExplain(s explainStmt) (any, error) {
+	sel, ok := s.stmt.(*selectStmt)
+	if !ok {
+		return map[string]any{"plan": "Execute", "detail": ""}, nil
+	}
+	var ops []string
+	for _, f := range sel.from {
+		if t, ok := f.(tableExpr); ok {
+			ops = append(ops, "Iterate Table "+t.name)
+		} else {
+			ops = append(ops, "Iterate Value")
+		}
+	}
+	return map[string]any{"plan": strings.Join(ops, ", "), "detail": "full table scan"}, nil
+}
+
+// ============================================================================
+// Writes
+// ============================================================================
+
+// thing is a write target: a table, or a record when id is set.
+type thing struct {
+	table string
+	id    any
+}
+
+func (ex *executor) things(e *env, verb string, targets []expr) ([]thing, error) {
+	var out []thing
+	var add func(v any) error
+	add = func(v any) error {
+		switch x := v.(type) {
+		case models.Table:
+			out = append(out, thing{table: string(x)})
+		case string:
+			out = append(out, thing{table: x})
+		case models.RecordID:
+			out = append(out, thing{table: x.Table, id: x.ID})
+		case map[string]any:
+			if id, ok := x["id"].(models.RecordID); ok {
+				out = append(out, thing{table: id.Table, id: id.ID})
+				return nil
+			}
+			return fmt.Errorf("Can not execute %s statement using value '%s'", verb, render(v))
+		case []any:
+			for _, el := range x {
+				if err := add(el); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("Can not execute %s statement using value '%s'", verb, render(v))
+		}
+		return nil
+	}
+	for _, t := range targets {
+		if te, ok := t.(tableExpr); ok {
+			out = append(out, thing{table: te.name})
+			continue
+		}
+		v, err := ex.eval(e, t)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(v); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (ex *executor) execCreate(e *env, s *createStmt) (any, error) {
+	targets, err := ex.things(e, "CREATE", s.what)
+	if err != nil {
+		return nil, err
+	}
+	out := []any{}
+	for _, t := range targets {
+		doc, err := ex.applyData(e, s.data, nil)
+		if err != nil {
+			return nil, err
+		}
+		id := t.id
+		if id == nil {
+			id = recordKey(t.table, doc["id"])
+		}
+		after, err := ex.create(e, t.table, id, doc)
+		if err != nil {
+			return nil, err
+		}
+		if v, ok, err := ex.output(e, s.ret, nil, after, "after"); err != nil {
+			return nil, err
+		} else if ok {
+			out = append(out, v)
+		}
+	}
+	return only(s.only, out)
+}
+
+// recordKey returns the id part for a record in table given the id field
+// of its data, generating one when there is none.
+func recordKey(table string, id any) any {
+	switch x := id.(type) {
+	case nil:
+		return generateID("rand")
+	case models.RecordID:
+		if x.Table == table {
+			return x.ID
+		}
+	}
+	return id
+}
+
+func (ex *executor) execUpdate(e *env, s *updateStmt) (any, error) {
+	verb := "UPDATE"
+	if s.upsert {
+		verb = "UPSERT"
+	}
+	targets, err := ex.things(e, verb, s.what)
+	if err != nil {
+		return nil, err
+	}
+	db, err := ex.database()
+	if err != nil {
+		return nil, err
+	}
+	out := []any{}
+	emit := func(before, after map[string]any) error {
+		v, ok, err := ex.output(e, s.ret, before, after, "after")
+		if ok {
+			out = append(out, v)
+		}
+		return err
+	}
+	for _, target := range targets {
+		var docs []map[string]any
+		if t := ex.tx.table(db, target.table); t != nil {
+			if target.id != nil {
+				if doc := t.get(target.id); doc != nil {
+					docs = ex.visible(t, []map[string]any{doc})
+				}
+			} else {
+				docs = ex.visible(t, t.scan())
+			}
+		}
+		matched := 0
+		for _, before := range docs {
+			if s.where != nil {
+				v, err := ex.eval(e.withDoc(before), s.where)
+				if err != nil {
+					return nil, err
+				}
+				if !truthy(v) {
+					continue
+				}
+			}
+			matched++
+			after, err := ex.applyData(e.withDoc(before), s.data, before)
+			if err != nil {
+				return nil, err
+			}
+			after, err = ex.update(e, target.table, before, after)
+			if err != nil {
+				return nil, err
+			}
+			if after == nil {
+				continue
+			}
+			if err := emit(before, after); err != nil {
+				return nil, err
+			}
+		}
+		if matched == 0 && s.upsert && (target.id != nil || len(docs) == 0) {
+			doc, err := ex.applyData(e, s.data, nil)
+			if err != nil {
+				return nil, err
+			}
+			id := target.id
+			if id == nil {
+				id = recordKey(target.table, doc["id"])
+			}
+			after, err := ex.create(e, target.table, id, doc)
+			if err != nil {
+				return nil, err
+			}
+			if err := emit(nil, after); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return only(s.only, out)
+}
+
+func (ex *executor) execDelete(e *env, s *deleteStmt) (any, error) {
+	targets, err := ex.things(e, "DELETE", s.what)
+	if err != nil {
+		return nil, err
+	}
+	db, err := ex.database()
+	if err != nil {
+		return nil, err
+	}
+	out := []any{}
+	for _, target := range targets {
+		t := ex.tx.table(db, target.table)
+		if t == nil {
+			continue
+		}
+		var docs []map[string]any
+		if target.id != nil {
+			if doc := t.get(target.id); doc != nil {
+				docs = ex.visible(t, []map[string]any{doc})
+			}
+		} else {
+			docs = ex.visible(t, t.scan())
+		}
+		for _, before := range docs {
+			if s.where != nil {
+				v, err := ex.eval(e.withDoc(before), s.where)
+				if err != nil {
+					return nil, err
+				}
+				if !truthy(v) {
+					continue
+				}
+			}
+			deleted, err := ex.delete(e, target.table, before)
+			if err != nil {
+				return nil, err
+			}
+			if !deleted {
+				continue
+			}
+			if v, ok, err := ex.output(e, s.ret, before, nil, "none"); err != nil {
+				return nil, err
+			} else if ok {
+				out = append(out, v)
+			}
+		}
+	}
+	return only(s.only, out)
+}
+
+func (ex *executor) execInsert(e *env, s *insertStmt) (any, error) {
+	table := ""
+	if s.into != nil {
+		if te, ok := s.into.(tableExpr); ok {
+			table = te.name
+		} else {
+			v, err := ex.eval(e, s.into)
+			if err != nil {
+				return nil, err
+			}
+			switch x := v.(type) {
+			case models.Table:
+				table = string(x)
+			case string:
+				table = x
+			default:
+				return nil, fmt.Errorf("Can not execute INSERT statement using value '%s'", render(v))
+			}
+		}
+	}
+	data, err := ex.eval(e, s.data)
+	if err != nil {
+		return nil, err
+	}
+	return ex.insert(e, table, data, s.relation, s.ignore, s.update, s.ret)
+}
+
+func (ex *executor) insert(e *env, table string, data any, relation, ignore bool, update []assignment, ret returnClause) (any, error) {
+	var objs []map[string]any
+	switch x := data.(type) {
+	case map[string]any:
+		objs = []map[string]any{x}
+	case []any:
+		for _, el := range x {
+			m, ok := el.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("Can not execute INSERT statement using value '%s'", render(el))
+			}
+			objs = append(objs, m)
+		}
+	default:
+		return nil, fmt.Errorf("Can not execute INSERT statement using value '%s'", render(data))
+	}
+	db, err := ex.database()
+	if err != nil {
+		return nil, err
+	}
+	out := []any{}
+	for _, obj := range objs {
+		obj = copyDoc(obj)
+		tbl := table
+		if r, ok := obj["id"].(models.RecordID); ok && tbl == "" {
+			tbl = r.Table
+		}
+		if tbl == "" {
+			return nil, fmt.Errorf("Can not execute INSERT statement without a table")
+		}
+		if relation {
+			_, inOK := obj["in"].(models.RecordID)
+			_, outOK := obj["out"].(models.RecordID)
+			if !inOK || !outOK {
+				return nil, fmt.Errorf("Can not execute INSERT RELATION statement: the in and out fields must be record ids, found '%s'", render(obj))
+			}
+		}
+		id := recordKey(tbl, obj["id"])
+		if t := ex.tx.table(db, tbl); t != nil {
+			if before := t.get(id); before != nil {
+				switch {
+				case update != nil:
+					ue := e.with(map[string]any{"input": obj}).withDoc(before)
+					after, err := ex.assign(ue, copyDoc(before), update)
+					if err != nil {
+						return nil, err
+					}
+					if after, err = ex.update(e, tbl, before, after); err != nil {
+						return nil, err
+					}
+					if after != nil {
+						if v, ok, err := ex.output(e, ret, before, after, "after"); err != nil {
+							return nil, err
+						} else if ok {
+							out = append(out, v)
+						}
+					}
+					continue
+				case ignore:
+					continue
+				}
+				return nil, fmt.Errorf("Database record `%s` already exists", render(models.RecordID{Table: tbl, ID: id}))
+			}
+		}
+		after, err := ex.create(e, tbl, id, obj)
+		if err != nil {
+			return nil, err
+		}
+		if v, ok, err := ex.output(e, ret, nil, after, "after"); err != nil {
+			return nil, err
+		} else if ok {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+func (ex *executor) execRelate(e *env, s *relateStmt) (any, error) {
+	from, err := ex.records(e, s.from)
+	if err != nil {
+		return nil, err
+	}
+	to, err := ex.records(e, s.to)
+	if err != nil {
+		return nil, err
+	}
+	var edge thing
+	if te, ok := s.edge.(tableExpr); ok {
+		edge.table = te.name
+	} else {
+		targets, err := ex.things(e, "RELATE", []expr{s.edge})
+		if err != nil {
+			return nil, err
+		}
+		if len(targets) != 1 {
+			return nil, fmt.Errorf("Can not execute RELATE statement using multiple edge tables")
+		}
+		edge = targets[0]
+	}
+	out := []any{}
+	for _, f := range from {
+		for _, t := range to {
+			doc, err := ex.applyData(e, s.data, nil)
+			if err != nil {
+				return nil, err
+			}
+			if doc == nil {
+				doc = map[string]any{}
+			}
+			doc["in"], doc["out"] = f, t
+			id := edge.id
+			if id == nil {
+				id = recordKey(edge.table, doc["id"])
+			}
+			after, err := ex.create(e, edge.table, id, doc)
+			if err != nil {
+				return nil, err
+			}
+			if v, ok, err := ex.output(e, s.ret, nil, after, "after"); err != nil {
+				return nil, err
+			} else if ok {
+				out = append(out, v)
+			}
+		}
+	}
+	return only(s.only, out)
+}
+
+// records evaluates a RELATE endpoint into record ids.
+func (ex *executor) records(e *env, x expr) ([]models.RecordID, error) {
+	v, err := ex.eval(e, x)
+	if err != nil {
+		return nil, err
+	}
+	var out []models.RecordID
+	for _, el := range asArray(v) {
+		switch r := el.(type) {
+		case models.RecordID:
+			out = append(out, r)
+		case map[string]any:
+			id, ok := r["id"].(models.RecordID)
+			if !ok {
+				return nil, fmt.Errorf("Can not execute RELATE statement using value '%s'", render(el))
+			}
+			out = append(out, id)
+		default:
+			return nil, fmt.Errorf("Can not execute RELATE statement using value '%s'", render(el))
+		}
+	}
+	return out, nil
+}
+
+// applyData applies a SET/UNSET/CONTENT/MERGE/REPLACE/PATCH clause to a copy
+// of before, which is nil for a new record.
+func (ex *executor) applyData(e *env, d *dataClause, before map[string]any) (map[string]any, error) {
+	doc := map[string]any{}
+	if before != nil {
+		doc = copyDoc(before)
+	}
+	if d == nil {
+		return doc, nil
+	}
+	for _, x := range []expr{d.content, d.replace} {
+		if x == nil {
+			continue
+		}
+		v, err := ex.eval(e, x)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := v.(map[string]any)
+		if !ok && v != nil {
+			return nil, fmt.Errorf("Can not use '%s' in a CONTENT clause", render(v))
+		}
+		doc = copyDoc(m)
+		if before != nil {
+			for _, k := range []string{"id", "in", "out"} {
+				if v, ok := before[k]; ok {
+					doc[k] = v
+				}
+			}
+		}
+	}
+	if d.merge != nil {
+		v, err := ex.eval(e, d.merge)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := v.(map[string]any)
+		if !ok && v != nil {
+			return nil, fmt.Errorf("Can not use '%s' in a MERGE clause", render(v))
+		}
+		doc = mergeDoc(doc, m)
+	}
+	if d.patch != nil {
+		v, err := ex.eval(e, d.patch)
+		if err != nil {
+			return nil, err
+		}
+		if doc, err = applyPatch(doc, v); err != nil {
+			return nil, err
+		}
+	}
+	if d.set != nil {
+		var err error
+		if doc, err = ex.assign(e, doc, d.set); err != nil {
+			return nil, err
+		}
+	}
+	for _, u := range d.unset {
+		if path := idiomPath(u); path != nil {
+			doc = removePath(doc, path)
+		}
+	}
+	return doc, nil
+}
+
+// mergeDoc deep-merges m into doc.
+func mergeDoc(doc, m map[string]any) map[string]any {
+	for k, v := range m {
+		if sub, ok := v.(map[string]any); ok {
+			if cur, ok := doc[k].(map[string]any); ok {
+				doc[k] = mergeDoc(copyDoc(cur), sub)
+				continue
+			}
+		}
+		if v == nil {
+			delete(doc, k)
+		} else {
+			doc[k] = deepCopy(v)
+		}
+	}
+	return doc
+}
+
+// applyPatch applies JSON Patch add, replace and remove operations.
+func applyPatch(doc map[string]any, ops any) (map[string]any, error) {
+	for _, op := range asArray(ops) {
+		m, ok := op.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("Invalid JSON Patch operation '%s'", render(op))
+		}
+		path := strings.Split(strings.TrimPrefix(fmt.Sprint(m["path"]), "/"), "/")
+		switch m["op"] {
+		case "add", "replace":
+			doc = setPath(doc, path, deepCopy(m["value"]))
+		case "remove":
+			doc = removePath(doc, path)
+		default:
+			return nil, fmt.Errorf("Unsupported JSON Patch operation '%v'", m["op"])
+		}
+	}
+	return doc, nil
+}
+
+// assign applies SET assignments to doc; each value sees the document as
+// updated by the assignments before it.
+func (ex *executor) assign(e *env, doc map[string]any, set []assignment) (map[string]any, error) {
+	for _, a := range set {
+		v, err := ex.eval(e.withDoc(doc), a.x)
+		if err != nil {
+			return nil, err
+		}
+		path := idiomPath(a.target)
+		if path == nil {
+			return nil, fmt.Errorf("Can not assign to '%s'", a.target.text)
+		}
+		cur := getPath(doc, path)
+		switch a.op {
+		case "+=":
+			if arr, ok := cur.([]any); ok {
+				v = append(append([]any{}, arr...), asArray(v)...)
+			} else if cur == nil && !isNumber(v) {
+				v = asArray(v)
+			} else if v, err = arith("+", cur, v); err != nil {
+				return nil, err
+			}
+		case "-=":
+			if arr, ok := cur.([]any); ok {
+				kept := []any{}
+				for _, el := range arr {
+					if !contains(asArray(v), el) {
+						kept = append(kept, el)
+					}
+				}
+				v = kept
+			} else if v, err = arith("-", cur, v); err != nil {
+				return nil, err
+			}
+		case "+?=":
+			arr, _ := cur.([]any)
+			arr = append([]any{}, arr...)
+			for _, el := range asArray(v) {
+				if !contains(arr, el) {
+					arr = append(arr, el)
+				}
+			}
+			v = arr
+		}
+		if doc = setPath(doc, path, v); doc == nil {
+			doc = map[string]any{}
+		}
+	}
+	return doc, nil
+}
+
+// output renders a changed record for a RETURN clause; ok is false when
+// nothing is returned. def is the statement's default clause.
+func (ex *executor) output(e *env, ret returnClause, before, after map[string]any, def string) (any, bool, error) {
+	kind := ret.kind
+	if kind == "" {
+		kind = def
+	}
+	switch kind {
+	case "none":
+		return nil, false, nil
+	case "null":
+		return nil, true, nil
+	case "before":
+		if before == nil {
+			return nil, true, nil
+		}
+		return before, true, nil
+	case "after":
+		if after == nil {
+			return nil, true, nil
+		}
+		return after, true, nil
+	case "diff":
+		return diff(before, after), true, nil
+	}
+	doc := after
+	if doc == nil {
+		doc = before
+	}
+	re := e.with(map[string]any{"before": asValue(before), "after": asValue(after)}).withDoc(doc)
+	v, err := ex.projectFields(re, ret.fields, nil, doc)
+	return v, err == nil, err
+}
+
+// asValue turns a nil document into a nil interface.
+func asValue(doc map[string]any) any {
+	if doc == nil {
+		return nil
+	}
+	return doc
+}
+
+// diff is the JSON Patch from before to after.
+func diff(before, after map[string]any) any {
+	ops := []any{}
+	for _, k := range sortedKeys(after) {
+		if b, ok := before[k]; !ok {
+			ops = append(ops, map[string]any{"op": "add", "path": "/" + k, "value": after[k]})
+		} else if !equalValues(b, after[k]) {
+			ops = append(ops, map[string]any{"op": "replace", "path": "/" + k, "value": after[k]})
+		}
+	}
+	for _, k := range sortedKeys(before) {
+		if _, ok := after[k]; !ok {
+			ops = append(ops, map[string]any{"op": "remove", "path": "/" + k})
+		}
+	}
+	return ops
+}
+
+// create stores a new record in table with the given id and data.
+func (ex *executor) create(e *env, table string, id any, data map[string]any) (map[string]any, error) {
+	db, err := ex.database()
+	if err != nil {
+		return nil, err
+	}
+	if err := ex.checkWritable(db, table); err != nil {
+		return nil, err
+	}
+	t := ex.tx.writable(db, table, true)
+	rid := models.RecordID{Table: table, ID: id}
+	if t.get(id) != nil {
+		return nil, fmt.Errorf("Database record `%s` already exists", render(rid))
+	}
+	doc := copyDoc(data)
+	doc["id"] = rid
+	if doc, err = ex.processFields(e, t, nil, doc); err != nil {
+		return nil, err
+	}
+	if ok, err := ex.allowed(t, "create", doc); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, fmt.Errorf("Not enough permissions to perform this action")
+	}
+	if err := ex.checkIndexes(e, t, doc); err != nil {
+		return nil, err
+	}
+	t.put(doc)
+	ex.srv.liveChanges(ex.tx, ex.sess.ns, ex.sess.db, table, rid, nil, doc)
+	if err := ex.runEvents(t, "CREATE", nil, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// update replaces before with after, returning nil when the session may not
+// update the record.
+func (ex *executor) update(e *env, table string, before, after map[string]any) (map[string]any, error) {
+	db, err := ex.database()
+	if err != nil {
+		return nil, err
+	}
+	t := ex.tx.writable(db, table, true)
+	if ok, err := ex.allowed(t, "update", before); err != nil || !ok {
+		return nil, err
+	}
+	rid := before["id"].(models.RecordID)
+	after["id"] = rid
+	for _, k := range []string{"in", "out"} {
+		if v, ok := before[k].(models.RecordID); ok {
+			after[k] = v
+		}
+	}
+	if after, err = ex.processFields(e, t, before, after); err != nil {
+		return nil, err
+	}
+	if err := ex.checkIndexes(e, t, after); err != nil {
+		return nil, err
+	}
+	t.put(after)
+	ex.srv.liveChanges(ex.tx, ex.sess.ns, ex.sess.db, table, rid, before, after)
+	if err := ex.runEvents(t, "UPDATE", before, after); err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+// delete removes a record and the edges attached to it.
+func (ex *executor) delete(e *env, table string, before map[string]any) (bool, error) {
+	db, err := ex.database()
+	if err != nil {
+		return false, err
+	}
+	t := ex.tx.writable(db, table, false)
+	if t == nil {
+		return false, nil
+	}
+	if ok, err := ex.allowed(t, "delete", before); err != nil || !ok {
+		return false, err
+	}
+	rid := before["id"].(models.RecordID)
+	if t.get(rid.ID) == nil {
+		return false, nil
+	}
+	t.remove(rid.ID)
+	ex.srv.liveChanges(ex.tx, ex.sess.ns, ex.sess.db, table, rid, before, nil)
+	if err := ex.runEvents(t, "DELETE", before, nil); err != nil {
+		return false, err
+	}
+	for _, name := range ex.tx.tableNames(db) {
+		et := ex.tx.table(db, name)
+		for _, doc := range et.scan() {
+			in, isEdge := doc["in"].(models.RecordID)
+			out, _ := doc["out"].(models.RecordID)
+			if !isEdge || !equalValues(in, rid) && !equalValues(out, rid) {
+				continue
+			}
+			if _, err := ex.delete(e, name, doc); err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// checkWritable rejects writes by record users and anonymous sessions to
+// tables that grant them nothing.
+func (ex *executor) checkWritable(db *database, table string) error {
+	if !ex.restricted() {
+		return nil
+	}
+	if t := ex.tx.table(db, table); t == nil || t.def == nil {
+		return fmt.Errorf("Not enough permissions to perform this action")
+	}
+	return nil
+}
+
+// ============================================================================
+// Fields, indexes and events
+// ============================================================================
+
+// processFields applies the field definitions of t to a record being
+// written: defaults, computed values, readonly checks, type coercion,
+// assertions and, for SCHEMAFULL tables, dropping undefined fields.
+func (ex *executor) processFields(e *env, t *table, before, after map[string]any) (map[string]any, error) {
+	rid := render(after["id"])
+	create := before == nil
+	for _, f := range t.fields {
+		if f.path[0] == "id" || containsStr(f.path, "*") {
+			continue
+		}
+		if len(f.path) > 1 {
+			if _, ok := getPath(after, f.path[:len(f.path)-1]).(map[string]any); !ok {
+				continue
+			}
+		}
+		val := getPath(after, f.path)
+		var old any
+		if before != nil {
+			old = getPath(before, f.path)
+		}
+		fe := e.with(map[string]any{"value": val, "before": asValue(before), "after": after, "input": val}).withDoc(after)
+		if f.def != nil && val == nil && (create || f.defAlways) {
+			v, err := ex.eval(fe, f.def)
+			if err != nil {
+				return nil, err
+			}
+			val = v
+			fe.up.vars["value"] = val
+		}
+		if f.value != nil {
+			v, err := ex.eval(fe, f.value)
+			if err != nil {
+				return nil, err
+			}
+			val = v
+			fe.up.vars["value"] = val
+		}
+		if f.readonly && !create && !equalValues(old, val) {
+			return nil, fmt.Errorf("Found changed value for field `%s`, with record `%s`, but field is readonly", f.name, rid)
+		}
+		if f.typ != "" {
+			v, err := coerce(val, f.typ)
+			if err != nil {
+				return nil, fmt.Errorf("Found %s for field `%s`, with record `%s`, but expected a %s", render(val), f.name, rid, f.typ)
+			}
+			val = v
+			fe.up.vars["value"] = val
+		}
+		if f.assert != nil && val != nil {
+			v, err := ex.eval(fe, f.assert)
+			if err != nil {
+				return nil, err
+			}
+			if !truthy(v) {
+				return nil, fmt.Errorf("Found %s for field `%s`, with record `%s`, but field must conform to: %s", render(val), f.name, rid, f.assertText)
+			}
+		}
+		after = setPath(after, f.path, val)
+	}
+	if t.schemafull() {
+		for k := range after {
+			if k == "id" || t.field(k) != nil {
+				continue
+			}
+			if _, edge := after[k].(models.RecordID); edge && (k == "in" || k == "out") {
+				continue
+			}
+			delete(after, k)
+		}
+	}
+	return after, nil
+}
+
+func containsStr(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// indexKey returns the values a record has for the fields of ix; ok is
+// false when they are all NONE, which unique indexes ignore.
+func (ex *executor) indexKey(e *env, ix *indexDef, doc map[string]any) ([]any, bool, error) {
+	vals := make([]any, len(ix.fields))
+	any := false
+	for i, f := range ix.fields {
+		v, err := ex.eval(e.withDoc(doc), f)
+		if err != nil {
+			return nil, false, err
+		}
+		vals[i] = v
+		any = any || v != nil
+	}
+	return vals, any, nil
+}
+
+func (ex *executor) checkIndexes(e *env, t *table, doc map[string]any) error {
+	for _, ix := range t.indexes {
+		if !ix.unique {
+			continue
+		}
+		vals, ok, err := ex.indexKey(e, ix, doc)
+		if err != nil || !ok {
+			return err
+		}
+		key := valueKey(vals)
+		for _, other := range t.records {
+			if equalValues(other["id"], doc["id"]) {
+				continue
+			}
+			ovals, ok, err := ex.indexKey(e, ix, other)
+			if err != nil {
+				return err
+			}
+			if ok && valueKey(ovals) == key {
+				return uniqueError(ix, vals, doc["id"])
+			}
+		}
+	}
+	return nil
+}
+
+func uniqueError(ix *indexDef, vals []any, id any) error {
+	var v any = vals
+	if len(vals) == 1 {
+		v = vals[0]
+	}
+	return fmt.Errorf("Database index `%s` already contains %s, with record `%s`", ix.name, render(v), render(id))
+}
+
+func (ex *executor) runEvents(t *table, event string, before, after map[string]any) error {
+	if len(t.events) == 0 {
+		return nil
+	}
+	if ex.depth >= maxDepth {
+		return fmt.Errorf("Reached excessive computation depth due to functions, subqueries, or futures")
+	}
+	ex.depth++
+	defer func() { ex.depth-- }()
+	value := after
+	if event == "DELETE" {
+		value = before
+	}
+	ee := (&env{ex: ex}).with(map[string]any{
+		"event":  event,
+		"before": asValue(before),
+		"after":  asValue(after),
+		"value":  asValue(value),
+	}).withDoc(value)
+	for _, ev := range t.events {
+		if ev.when != nil {
+			v, err := ex.eval(ee, ev.when)
+			if err != nil {
+				return err
+			}
+			if !truthy(v) {
+				continue
+			}
+		}
+		inner := ee.child()
+		for _, s := range ev.then {
+			if _, err := ex.exec(inner, s); err != nil {
+				if _, ok := err.(returnSignal); ok {
+					break
+				}
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ============================================================================
+// Live queries
+// ============================================================================
+
+func (ex *executor) execLive(e *env, s *liveStmt) (any, error) {
+	if ex.sess.conn == nil {
+		return nil, fmt.Errorf("Live queries are only supported over WebSocket connections")
+	}
+	var table string
+	if te, ok := s.table.(tableExpr); ok {
+		table = te.name
+	} else {
+		v, err := ex.eval(e, s.table)
+		if err != nil {
+			return nil, err
+		}
+		switch x := v.(type) {
+		case models.Table:
+			table = string(x)
+		case string:
+			table = x
+		default:
+			return nil, fmt.Errorf("Can not execute LIVE statement using value '%s'", render(v))
+		}
+	}
+	lq := &liveQuery{
+		id:     newUUID(),
+		conn:   ex.sess.conn,
+		ns:     ex.sess.ns,
+		db:     ex.sess.db,
+		table:  table,
+		diff:   s.diff,
+		fields: s.fields,
+		where:  s.where,
+		sess:   ex.sess,
+	}
+	ex.srv.lives[lq.id.String()] = lq
+	return lq.id, nil
+}
+
+func (s *Server) kill(v any) error {
+	var key string
+	switch x := v.(type) {
+	case models.UUID:
+		key = x.String()
+	case string:
+		key = x
+	}
+	if _, ok := s.lives[key]; !ok {
+		return fmt.Errorf("Can not execute KILL statement using id '%s'", render(v))
+	}
+	delete(s.lives, key)
+	return nil
+}
@@ -0,0 +1,1177 @@
+package surrealtest
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"math"
+	mrand "math/rand"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/surrealdb/surrealdb.go/pkg/models"
+)
+
+// aggregates are the functions that, inside a GROUP BY projection, take
+// their argument from every document of the group.
+var aggregates = map[string]bool{
+	"count":           true,
+	"math::sum":       true,
+	"math::mean":      true,
+	"math::min":       true,
+	"math::max":       true,
+	"math::median":    true,
+	"math::stddev":    true,
+	"math::variance":  true,
+	"array::group":    true,
+	"array::distinct": true,
+	"time::min":       true,
+	"time::max":       true,
+}
+
+func (ex *executor) call(e *env, c *callExpr) (any, error) {
+	if docs := e.groupDocs(); docs != nil && aggregates[c.name] {
+		if c.name == "count" && len(c.args) == 0 {
+			return int64(len(docs)), nil
+		}
+		vals := make([]any, 0, len(docs))
+		for _, doc := range docs {
+			v, err := ex.eval(e.withDoc(doc), c.args[0])
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, v)
+		}
+		if c.name == "count" {
+			n := int64(0)
+			for _, v := range vals {
+				if truthy(v) {
+					n++
+				}
+			}
+			return n, nil
+		}
+		if c.name == "array::group" {
+			var flat []any
+			for _, v := range vals {
+				flat = append(flat, asArray(v)...)
+			}
+			vals = flat
+		}
+		return ex.callFunc(e, c.name, []any{vals})
+	}
+
+	args := make([]any, 0, len(c.args))
+	for _, a := range c.args {
+		v, err := ex.eval(e, a)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	return ex.callFunc(e, c.name, args)
+}
+
+// callMethod runs a method call such as "a".uppercase() or tags.len(),
+// where args[0] is the receiver.
+func (ex *executor) callMethod(e *env, name string, args []any) (any, error) {
+	var prefix string
+	switch kindOf(args[0]) {
+	case "string":
+		prefix = "string::"
+	case "array":
+		prefix = "array::"
+	case "object":
+		prefix = "object::"
+	case "datetime":
+		prefix = "time::"
+	case "duration":
+		prefix = "duration::"
+	case "record":
+		prefix = "record::"
+	case "int", "float", "decimal":
+		prefix = "math::"
+	}
+	name = strings.ToLower(name)
+	if prefix != "" {
+		if _, ok := builtins[prefix+name]; ok {
+			return ex.callFunc(e, prefix+name, args)
+		}
+	}
+	if _, ok := builtins["type::"+name]; ok {
+		return ex.callFunc(e, "type::"+name, args)
+	}
+	return nil, fmt.Errorf("Invalid function/method call: the method '%s' does not exist for %s", name, kindOf(args[0]))
+}
+
+func (ex *executor) callFunc(e *env, name string, args []any) (any, error) {
+	if strings.HasPrefix(name, "fn::") {
+		return ex.callUser(e, name, args)
+	}
+	switch name {
+	case "crypto::argon2::generate", "crypto::bcrypt::generate", "crypto::pbkdf2::generate", "crypto::scrypt::generate":
+		return hashPassword(str(arg(args, 0))), nil
+	case "crypto::argon2::compare", "crypto::bcrypt::compare", "crypto::pbkdf2::compare", "crypto::scrypt::compare":
+		return str(arg(args, 0)) == hashPassword(str(arg(args, 1))), nil
+	case "session::ns":
+		return ex.sess.ns, nil
+	case "session::db":
+		return ex.sess.db, nil
+	case "session::ac":
+		return e.lookup("access"), nil
+	case "session::rd":
+		return ex.sess.auth.authValue(), nil
+	case "session::token":
+		return ex.tokenValue(), nil
+	case "record::exists":
+		r, ok := arg(args, 0).(models.RecordID)
+		return ok && ex.fetch(r) != nil, nil
+	}
+	if strings.HasPrefix(name, "file::") {
+		return nil, fmt.Errorf("surrealtest: file functions are not supported")
+	}
+	f, ok := builtins[name]
+	if !ok {
+		return nil, fmt.Errorf("Invalid function/method call: the function '%s' does not exist", name)
+	}
+	v, err := f(args)
+	if err != nil {
+		return nil, fmt.Errorf("Incorrect arguments for function %s(). %v", name, err)
+	}
+	return v, nil
+}
+
+// callUser runs a function defined with DEFINE FUNCTION.
+func (ex *executor) callUser(e *env, name string, args []any) (any, error) {
+	db, err := ex.database()
+	if err != nil {
+		return nil, err
+	}
+	f := db.functions[name]
+	if f == nil {
+		return nil, fmt.Errorf("The function '%s' does not exist", name)
+	}
+	if len(args) > len(f.args) {
+		return nil, fmt.Errorf("Incorrect arguments for function %s(). The function expects %d arguments.", name, len(f.args))
+	}
+	if ex.depth >= maxDepth {
+		return nil, fmt.Errorf("Reached excessive computation depth due to functions, subqueries, or futures")
+	}
+	ex.depth++
+	defer func() { ex.depth-- }()
+	vars := make(map[string]any, len(f.args))
+	for i, a := range f.args {
+		vars[a] = arg(args, i)
+	}
+	return ex.runBlock((&env{ex: ex}).with(vars), f.body)
+}
+
+func arg(args []any, i int) any {
+	if i < len(args) {
+		return args[i]
+	}
+	return nil
+}
+
+func str(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	if v == nil {
+		return ""
+	}
+	return render(v)
+}
+
+func num(v any) float64 {
+	f, _ := toFloat(v)
+	return f
+}
+
+// builtins are the pure built-in functions, keyed by lower-case name.
+var builtins map[string]func(args []any) (any, error)
+
+func init() {
+	builtins = map[string]func(args []any) (any, error){
+		"count": func(args []any) (any, error) {
+			if len(args) == 0 {
+				return int64(1), nil
+			}
+			if arr, ok := args[0].([]any); ok {
+				n := int64(0)
+				for _, v := range arr {
+					if truthy(v) {
+						n++
+					}
+				}
+				return n, nil
+			}
+			if truthy(args[0]) {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		},
+		"not": func(args []any) (any, error) { return !truthy(arg(args, 0)), nil },
+		"sleep": func(args []any) (any, error) {
+			if d, ok := arg(args, 0).(time.Duration); ok {
+				time.Sleep(d)
+			}
+			return nil, nil
+		},
+
+		// math
+		"math::sum": func(args []any) (any, error) {
+			var total any = int64(0)
+			for _, v := range asArray(arg(args, 0)) {
+				if !isNumber(v) {
+					continue
+				}
+				var err error
+				if total, err = numberOp("+", total, v); err != nil {
+					return nil, err
+				}
+			}
+			return total, nil
+		},
+		"math::mean": func(args []any) (any, error) {
+			vals := numbers(arg(args, 0))
+			if len(vals) == 0 {
+				return math.NaN(), nil
+			}
+			sum := 0.0
+			for _, f := range vals {
+				sum += f
+			}
+			return sum / float64(len(vals)), nil
+		},
+		"math::median": func(args []any) (any, error) {
+			vals := numbers(arg(args, 0))
+			if len(vals) == 0 {
+				return nil, nil
+			}
+			sort.Float64s(vals)
+			n := len(vals)
+			if n%2 == 1 {
+				return vals[n/2], nil
+			}
+			return (vals[n/2-1] + vals[n/2]) / 2, nil
+		},
+		"math::variance": func(args []any) (any, error) { return variance(numbers(arg(args, 0))), nil },
+		"math::stddev": func(args []any) (any, error) {
+			return math.Sqrt(variance(numbers(arg(args, 0)))), nil
+		},
+		"math::min": func(args []any) (any, error) { return extreme(arg(args, 0), -1), nil },
+		"math::max": func(args []any) (any, error) { return extreme(arg(args, 0), 1), nil },
+		"math::abs": func(args []any) (any, error) {
+			if i, ok := arg(args, 0).(int64); ok {
+				if i < 0 {
+					return -i, nil
+				}
+				return i, nil
+			}
+			return math.Abs(num(arg(args, 0))), nil
+		},
+		"math::ceil":  roundFunc(math.Ceil),
+		"math::floor": roundFunc(math.Floor),
+		"math::round": roundFunc(math.Round),
+		"math::sqrt":  func(args []any) (any, error) { return math.Sqrt(num(arg(args, 0))), nil },
+		"math::pow": func(args []any) (any, error) {
+			return numberOp("**", arg(args, 0), arg(args, 1))
+		},
+		"math::fixed": func(args []any) (any, error) {
+			p := math.Pow(10, num(arg(args, 1)))
+			return math.Round(num(arg(args, 0))*p) / p, nil
+		},
+
+		// array
+		"array::len": func(args []any) (any, error) { return int64(len(asArray(arg(args, 0)))), nil },
+		"array::distinct": func(args []any) (any, error) {
+			out := []any{}
+			for _, v := range asArray(arg(args, 0)) {
+				if !contains(out, v) {
+					out = append(out, v)
+				}
+			}
+			return out, nil
+		},
+		"array::group": func(args []any) (any, error) {
+			out := []any{}
+			for _, v := range asArray(arg(args, 0)) {
+				for _, el := range asArray(v) {
+					if !contains(out, el) {
+						out = append(out, el)
+					}
+				}
+			}
+			return out, nil
+		},
+		"array::flatten": func(args []any) (any, error) {
+			out := []any{}
+			for _, v := range asArray(arg(args, 0)) {
+				out = append(out, asArray(v)...)
+			}
+			return out, nil
+		},
+		"array::first": func(args []any) (any, error) { return arg(asArray(arg(args, 0)), 0), nil },
+		"array::last": func(args []any) (any, error) {
+			arr := asArray(arg(args, 0))
+			return arg(arr, len(arr)-1), nil
+		},
+		"array::at": func(args []any) (any, error) {
+			arr := asArray(arg(args, 0))
+			i, _ := toInt(arg(args, 1))
+			if i < 0 {
+				i += int64(len(arr))
+			}
+			if i < 0 {
+				return nil, nil
+			}
+			return arg(arr, int(i)), nil
+		},
+		"array::push": func(args []any) (any, error) {
+			return append(append([]any{}, asArray(arg(args, 0))...), arg(args, 1)), nil
+		},
+		"array::append": func(args []any) (any, error) {
+			return append(append([]any{}, asArray(arg(args, 0))...), arg(args, 1)), nil
+		},
+		"array::prepend": func(args []any) (any, error) {
+			return append([]any{arg(args, 1)}, asArray(arg(args, 0))...), nil
+		},
+		"array::concat": func(args []any) (any, error) {
+			out := []any{}
+			for _, a := range args {
+				out = append(out, asArray(a)...)
+			}
+			return out, nil
+		},
+		"array::union": func(args []any) (any, error) {
+			out := []any{}
+			for _, a := range args {
+				for _, v := range asArray(a) {
+					if !contains(out, v) {
+						out = append(out, v)
+					}
+				}
+			}
+			return out, nil
+		},
+		"array::add": func(args []any) (any, error) {
+			out := append([]any{}, asArray(arg(args, 0))...)
+			for _, v := range asArray(arg(args, 1)) {
+				if !contains(out, v) {
+					out = append(out, v)
+				}
+			}
+			return out, nil
+		},
+		"array::remove": func(args []any) (any, error) {
+			arr := asArray(arg(args, 0))
+			i, _ := toInt(arg(args, 1))
+			if i < 0 || int(i) >= len(arr) {
+				return arr, nil
+			}
+			return append(append([]any{}, arr[:i]...), arr[i+1:]...), nil
+		},
+		"array::complement": func(args []any) (any, error) {
+			out := []any{}
+			for _, v := range asArray(arg(args, 0)) {
+				if !contains(asArray(arg(args, 1)), v) {
+					out = append(out, v)
+				}
+			}
+			return out, nil
+		},
+		"array::difference": func(args []any) (any, error) {
+			a, b := asArray(arg(args, 0)), asArray(arg(args, 1))
+			out := []any{}
+			for _, v := range a {
+				if !contains(b, v) {
+					out = append(out, v)
+				}
+			}
+			for _, v := range b {
+				if !contains(a, v) {
+					out = append(out, v)
+				}
+			}
+			return out, nil
+		},
+		"array::intersect": func(args []any) (any, error) {
+			out := []any{}
+			for _, v := range asArray(arg(args, 0)) {
+				if contains(asArray(arg(args, 1)), v) && !contains(out, v) {
+					out = append(out, v)
+				}
+			}
+			return out, nil
+		},
+		"array::includes": func(args []any) (any, error) { return contains(asArray(arg(args, 0)), arg(args, 1)), nil },
+		"array::join": func(args []any) (any, error) {
+			parts := []string{}
+			for _, v := range asArray(arg(args, 0)) {
+				parts = append(parts, str(v))
+			}
+			return strings.Join(parts, str(arg(args, 1))), nil
+		},
+		"array::reverse": func(args []any) (any, error) {
+			arr := asArray(arg(args, 0))
+			out := make([]any, len(arr))
+			for i, v := range arr {
+				out[len(arr)-1-i] = v
+			}
+			return out, nil
+		},
+		"array::slice": func(args []any) (any, error) {
+			arr := asArray(arg(args, 0))
+			start, _ := toInt(arg(args, 1))
+			end := int64(len(arr))
+			if n, ok := toInt(arg(args, 2)); ok {
+				end = start + n
+			}
+			start, end = clamp(start, len(arr)), clamp(end, len(arr))
+			if start > end {
+				return []any{}, nil
+			}
+			return append([]any{}, arr[start:end]...), nil
+		},
+		"array::sort":       sortFunc(1),
+		"array::sort::asc":  sortFunc(1),
+		"array::sort::desc": sortFunc(-1),
+		"array::max":        func(args []any) (any, error) { return extreme(arg(args, 0), 1), nil },
+		"array::min":        func(args []any) (any, error) { return extreme(arg(args, 0), -1), nil },
+
+		// string
+		"string::len":       func(args []any) (any, error) { return int64(len([]rune(str(arg(args, 0))))), nil },
+		"string::lowercase": func(args []any) (any, error) { return strings.ToLower(str(arg(args, 0))), nil },
+		"string::uppercase": func(args []any) (any, error) { return strings.ToUpper(str(arg(args, 0))), nil },
+		"string::trim":      func(args []any) (any, error) { return strings.TrimSpace(str(arg(args, 0))), nil },
+		"string::reverse": func(args []any) (any, error) {
+			r := []rune(str(arg(args, 0)))
+			for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+				r[i], r[j] = r[j], r[i]
+			}
+			return string(r), nil
+		},
+		"string::starts_with": func(args []any) (any, error) {
+			return strings.HasPrefix(str(arg(args, 0)), str(arg(args, 1))), nil
+		},
+		"string::ends_with": func(args []any) (any, error) {
+			return strings.HasSuffix(str(arg(args, 0)), str(arg(args, 1))), nil
+		},
+		"string::contains": func(args []any) (any, error) {
+			return strings.Contains(str(arg(args, 0)), str(arg(args, 1))), nil
+		},
+		"string::matches": func(args []any) (any, error) {
+			re, err := regexp.Compile(str(arg(args, 1)))
+			if err != nil {
+				return nil, err
+			}
+			return re.MatchString(str(arg(args, 0))), nil
+		},
+		"string::concat": func(args []any) (any, error) {
+			var b strings.Builder
+			for _, a := range args {
+				b.WriteString(str(a))
+			}
+			return b.String(), nil
+		},
+		"string::join": func(args []any) (any, error) {
+			parts := []string{}
+			for _, a := range args[1:] {
+				parts = append(parts, str(a))
+			}
+			return strings.Join(parts, str(arg(args, 0))), nil
+		},
+		"string::split": func(args []any) (any, error) {
+			out := []any{}
+			for _, s := range strings.Split(str(arg(args, 0)), str(arg(args, 1))) {
+				out = append(out, s)
+			}
+			return out, nil
+		},
+		"string::replace": func(args []any) (any, error) {
+			return strings.ReplaceAll(str(arg(args, 0)), str(arg(args, 1)), str(arg(args, 2))), nil
+		},
+		"string::repeat": func(args []any) (any, error) {
+			n, _ := toInt(arg(args, 1))
+			return strings.Repeat(str(arg(args, 0)), int(max(n, 0))), nil
+		},
+		"string::slice": func(args []any) (any, error) {
+			r := []rune(str(arg(args, 0)))
+			start, _ := toInt(arg(args, 1))
+			end := int64(len(r))
+			if n, ok := toInt(arg(args, 2)); ok {
+				end = start + n
+			}
+			start, end = clamp(start, len(r)), clamp(end, len(r))
+			if start > end {
+				return "", nil
+			}
+			return string(r[start:end]), nil
+		},
+		"string::is::numeric": func(args []any) (any, error) {
+			_, err := strconv.ParseFloat(str(arg(args, 0)), 64)
+			return err == nil, nil
+		},
+
+		// time
+		"time::now": func([]any) (any, error) { return time.Now().UTC(), nil },
+		"time::unix": func(args []any) (any, error) {
+			return datetime(args).Unix(), nil
+		},
+		"time::year":   func(args []any) (any, error) { return int64(datetime(args).Year()), nil },
+		"time::month":  func(args []any) (any, error) { return int64(datetime(args).Month()), nil },
+		"time::day":    func(args []any) (any, error) { return int64(datetime(args).Day()), nil },
+		"time::hour":   func(args []any) (any, error) { return int64(datetime(args).Hour()), nil },
+		"time::minute": func(args []any) (any, error) { return int64(datetime(args).Minute()), nil },
+		"time::floor": func(args []any) (any, error) {
+			d, _ := arg(args, 1).(time.Duration)
+			return datetime(args).Truncate(d), nil
+		},
+		"time::min": func(args []any) (any, error) { return extreme(arg(args, 0), -1), nil },
+		"time::max": func(args []any) (any, error) { return extreme(arg(args, 0), 1), nil },
+
+		// duration
+		"duration::secs": func(args []any) (any, error) {
+			d, _ := arg(args, 0).(time.Duration)
+			return int64(d / time.Second), nil
+		},
+		"duration::mins": func(args []any) (any, error) {
+			d, _ := arg(args, 0).(time.Duration)
+			return int64(d / time.Minute), nil
+		},
+		"duration::hours": func(args []any) (any, error) {
+			d, _ := arg(args, 0).(time.Duration)
+			return int64(d / time.Hour), nil
+		},
+
+		// object
+		"object::keys": func(args []any) (any, error) {
+			m, _ := arg(args, 0).(map[string]any)
+			out := []any{}
+			for _, k := range sortedKeys(m) {
+				out = append(out, k)
+			}
+			return out, nil
+		},
+		"object::values": func(args []any) (any, error) {
+			m, _ := arg(args, 0).(map[string]any)
+			out := []any{}
+			for _, k := range sortedKeys(m) {
+				out = append(out, m[k])
+			}
+			return out, nil
+		},
+		"object::entries": func(args []any) (any, error) {
+			m, _ := arg(args, 0).(map[string]any)
+			out := []any{}
+			for _, k := range sortedKeys(m) {
+				out = append(out, []any{k, m[k]})
+			}
+			return out, nil
+		},
+		"object::len": func(args []any) (any, error) {
+			m, _ := arg(args, 0).(map[string]any)
+			return int64(len(m)), nil
+		},
+
+		// record
+		"record::id": func(args []any) (any, error) {
+			r, ok := arg(args, 0).(models.RecordID)
+			if !ok {
+				return nil, fmt.Errorf("Argument 1 was the wrong type. Expected a record but found %s", render(arg(args, 0)))
+			}
+			return r.ID, nil
+		},
+		"record::tb": func(args []any) (any, error) {
+			r, ok := arg(args, 0).(models.RecordID)
+			if !ok {
+				return nil, fmt.Errorf("Argument 1 was the wrong type. Expected a record but found %s", render(arg(args, 0)))
+			}
+			return r.Table, nil
+		},
+
+		// type
+		"type::thing":  recordFunc,
+		"type::record": recordFunc,
+		"type::table": func(args []any) (any, error) {
+			if r, ok := arg(args, 0).(models.RecordID); ok {
+				return models.Table(r.Table), nil
+			}
+			return models.Table(str(arg(args, 0))), nil
+		},
+		"type::string":   castFunc("string"),
+		"type::int":      castFunc("int"),
+		"type::float":    castFunc("float"),
+		"type::decimal":  castFunc("decimal"),
+		"type::number":   castFunc("number"),
+		"type::bool":     castFunc("bool"),
+		"type::datetime": castFunc("datetime"),
+		"type::duration": castFunc("duration"),
+		"type::uuid":     castFunc("uuid"),
+		"type::is::none": func(args []any) (any, error) { return arg(args, 0) == nil, nil },
+		"type::is::null": func(args []any) (any, error) { return arg(args, 0) == nil, nil },
+
+		// rand
+		"rand":           func([]any) (any, error) { return mrand.Float64(), nil },
+		"rand::uuid":     func([]any) (any, error) { return newUUID(), nil },
+		"rand::uuid::v4": func([]any) (any, error) { return newUUID(), nil },
+		"rand::uuid::v7": func([]any) (any, error) { return generateID("uuid"), nil },
+		"rand::ulid":     func([]any) (any, error) { return generateID("ulid"), nil },
+		"rand::id":       func([]any) (any, error) { return generateID("rand"), nil },
+		"rand::bool":     func([]any) (any, error) { return mrand.Intn(2) == 1, nil },
+		"rand::float":    func([]any) (any, error) { return mrand.Float64(), nil },
+		"rand::int": func(args []any) (any, error) {
+			lo, hi := int64(0), int64(math.MaxInt64-1)
+			if len(args) == 2 {
+				lo, _ = toInt(args[0])
+				hi, _ = toInt(args[1])
+			}
+			if hi < lo {
+				lo, hi = hi, lo
+			}
+			return lo + mrand.Int63n(hi-lo+1), nil
+		},
+		"rand::string": func(args []any) (any, error) {
+			n := int64(32)
+			if len(args) > 0 {
+				n, _ = toInt(args[0])
+			}
+			return randomString(int(n)), nil
+		},
+
+		// crypto
+		"crypto::md5": func(args []any) (any, error) {
+			h := md5.Sum([]byte(str(arg(args, 0))))
+			return hex.EncodeToString(h[:]), nil
+		},
+		"crypto::sha1": func(args []any) (any, error) {
+			h := sha1.Sum([]byte(str(arg(args, 0))))
+			return hex.EncodeToString(h[:]), nil
+		},
+		"crypto::sha256": func(args []any) (any, error) {
+			h := sha256.Sum256([]byte(str(arg(args, 0))))
+			return hex.EncodeToString(h[:]), nil
+		},
+		"crypto::sha512": func(args []any) (any, error) {
+			h := sha512.Sum512([]byte(str(arg(args, 0))))
+			return hex.EncodeToString(h[:]), nil
+		},
+	}
+	// The meta:: names predate record::.
+	builtins["meta::id"] = builtins["record::id"]
+	builtins["meta::tb"] = builtins["record::tb"]
+	builtins["record::table"] = builtins["record::tb"]
+}
+
+func numbers(v any) []float64 {
+	var out []float64
+	for _, el := range asArray(v) {
+		if f, ok := toFloat(el); ok {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func variance(vals []float64) float64 {
+	if len(vals) < 2 {
+		return math.NaN()
+	}
+	mean := 0.0
+	for _, f := range vals {
+		mean += f
+	}
+	mean /= float64(len(vals))
+	sum := 0.0
+	for _, f := range vals {
+		sum += (f - mean) * (f - mean)
+	}
+	return sum / float64(len(vals)-1)
+}
+
+// extreme returns the largest (sign 1) or smallest (sign -1) non-NONE value.
+func extreme(v any, sign int) any {
+	var best any
+	for _, el := range asArray(v) {
+		if el == nil {
+			continue
+		}
+		if best == nil || compareValues(el, best)*sign > 0 {
+			best = el
+		}
+	}
+	return best
+}
+
+func roundFunc(f func(float64) float64) func([]any) (any, error) {
+	return func(args []any) (any, error) {
+		if i, ok := arg(args, 0).(int64); ok {
+			return i, nil
+		}
+		return f(num(arg(args, 0))), nil
+	}
+}
+
+func sortFunc(dir int) func([]any) (any, error) {
+	return func(args []any) (any, error) {
+		out := append([]any{}, asArray(arg(args, 0))...)
+		sign := dir
+		if s, ok := arg(args, 1).(string); ok && strings.EqualFold(s, "desc") || arg(args, 1) == false {
+			sign = -sign
+		}
+		sort.SliceStable(out, func(i, j int) bool { return compareValues(out[i], out[j])*sign < 0 })
+		return out, nil
+	}
+}
+
+func clamp(i int64, n int) int64 {
+	if i < 0 {
+		i += int64(n)
+	}
+	return max(0, min(i, int64(n)))
+}
+
+func datetime(args []any) time.Time {
+	if t, ok := arg(args, 0).(time.Time); ok {
+		return t
+	}
+	return time.Now().UTC()
+}
+
+func recordFunc(args []any) (any, error) {
+	if r, ok := arg(args, 0).(models.RecordID); ok && len(args) == 1 {
+		return r, nil
+	}
+	if len(args) == 1 {
+		s := str(args[0])
+		table, id, ok := strings.Cut(s, ":")
+		if !ok {
+			return nil, fmt.Errorf("Expected a record but found %s", render(args[0]))
+		}
+		return models.RecordID{Table: table, ID: parseKey(id)}, nil
+	}
+	table := str(arg(args, 0))
+	if t, ok := arg(args, 0).(models.Table); ok {
+		table = string(t)
+	}
+	id := arg(args, 1)
+	if r, ok := id.(models.RecordID); ok {
+		id = r.ID
+	}
+	return models.RecordID{Table: table, ID: id}, nil
+}
+
+// parseKey reads the id part of a "table:id" string.
+func parseKey(s string) any {
+	s = strings.Trim(s, "`⟨⟩")
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	return s
+}
+
+func castFunc(typ string) func([]any) (any, error) {
+	return func(args []any) (any, error) { return cast(arg(args, 0), typ) }
+}
+
+// ============================================================================
+// Casts and field types
+// ============================================================================
+
+// cast converts v for an explicit <type> cast.
+func cast(v any, typ string) (any, error) {
+	t := parseType(typ)
+	out, ok := convert(v, t, true)
+	if !ok {
+		return nil, fmt.Errorf("Expected a %s but cannot convert %s into a %s", typ, render(v), typ)
+	}
+	return out, nil
+}
+
+// coerce checks v against a field's TYPE, converting only where SurrealDB
+// does so implicitly.
+func coerce(v any, typ string) (any, error) {
+	out, ok := convert(v, parseType(typ), false)
+	if !ok {
+		return nil, fmt.Errorf("Expected a %s but found %s", typ, render(v))
+	}
+	return out, nil
+}
+
+// typeNode is a parsed type such as option<array<record<user>>> or "a" | "b".
+type typeNode struct {
+	name  string
+	args  []*typeNode
+	union []*typeNode
+	lit   any
+	isLit bool
+}
+
+func parseType(s string) *typeNode {
+	s = strings.TrimSpace(s)
+	if parts := splitTop(s, '|'); len(parts) > 1 {
+		n := &typeNode{}
+		for _, p := range parts {
+			n.union = append(n.union, parseType(p))
+		}
+		return n
+	}
+	switch {
+	case s == "":
+		return &typeNode{name: "any"}
+	case s[0] == '"' || s[0] == '\'':
+		return &typeNode{isLit: true, lit: strings.Trim(s, `"'`)}
+	case s[0] >= '0' && s[0] <= '9' || s[0] == '-':
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return &typeNode{isLit: true, lit: i}
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return &typeNode{isLit: true, lit: f}
+		}
+	case s == "true" || s == "false":
+		return &typeNode{isLit: true, lit: s == "true"}
+	}
+	name, rest, generic := strings.Cut(s, "<")
+	n := &typeNode{name: strings.ToLower(strings.Trim(strings.TrimSpace(name), "`"))}
+	if generic {
+		for _, a := range splitTop(strings.TrimSuffix(rest, ">"), ',') {
+			n.args = append(n.args, parseType(a))
+		}
+	}
+	return n
+}
+
+// splitTop splits s on sep outside of <>, (), [], {} and quotes.
+func splitTop(s string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '<' || c == '(' || c == '[' || c == '{':
+			depth++
+		case c == '>' || c == ')' || c == ']' || c == '}':
+			depth--
+		case c == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// convert converts v to type t. Casts (explicit) also parse strings and
+// truncate numbers; coercions only widen numbers and read strings that are
+// unambiguous for the target type.
+func convert(v any, t *typeNode, explicit bool) (any, bool) {
+	if t.union != nil {
+		for _, u := range t.union {
+			if out, ok := convert(v, u, explicit); ok {
+				return out, true
+			}
+		}
+		return nil, false
+	}
+	if t.isLit {
+		return v, equalValues(v, t.lit)
+	}
+	switch t.name {
+	case "any":
+		return v, true
+	case "option":
+		if v == nil {
+			return nil, true
+		}
+		if len(t.args) == 0 {
+			return v, true
+		}
+		return convert(v, t.args[0], explicit)
+	case "none", "null":
+		return nil, v == nil
+	}
+	if v == nil {
+		return nil, false
+	}
+	switch t.name {
+	case "bool":
+		switch x := v.(type) {
+		case bool:
+			return x, true
+		case string:
+			if explicit {
+				b, err := strconv.ParseBool(x)
+				return b, err == nil
+			}
+		}
+	case "int":
+		switch x := v.(type) {
+		case int64:
+			return x, true
+		case float64:
+			if explicit || x == math.Trunc(x) {
+				return int64(x), true
+			}
+		case models.DecimalString:
+			if f, err := strconv.ParseFloat(string(x), 64); err == nil && (explicit || f == math.Trunc(f)) {
+				return int64(f), true
+			}
+		case string:
+			if explicit {
+				i, err := strconv.ParseInt(strings.TrimSpace(x), 10, 64)
+				return i, err == nil
+			}
+		}
+	case "float":
+		switch x := v.(type) {
+		case float64:
+			return x, true
+		case int64:
+			return float64(x), true
+		case models.DecimalString:
+			f, err := strconv.ParseFloat(string(x), 64)
+			return f, err == nil
+		case string:
+			if explicit {
+				f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+				return f, err == nil
+			}
+		}
+	case "decimal":
+		switch x := v.(type) {
+		case models.DecimalString:
+			return x, true
+		case int64:
+			return models.DecimalString(strconv.FormatInt(x, 10)), true
+		case float64:
+			return models.DecimalString(formatFloat(x)), true
+		case string:
+			if _, err := strconv.ParseFloat(x, 64); err == nil {
+				return models.DecimalString(x), true
+			}
+		}
+	case "number":
+		if isNumber(v) {
+			return v, true
+		}
+		if s, ok := v.(string); ok && explicit {
+			if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return i, true
+			}
+			f, err := strconv.ParseFloat(s, 64)
+			return f, err == nil
+		}
+	case "string":
+		if s, ok := v.(string); ok {
+			return s, true
+		}
+		if explicit {
+			switch x := v.(type) {
+			case time.Time:
+				return x.Format(time.RFC3339Nano), true
+			case models.RecordID:
+				return renderRecordID(x), true
+			case models.UUID:
+				return x.String(), true
+			case models.Table:
+				return string(x), true
+			case int64:
+				return strconv.FormatInt(x, 10), true
+			case float64:
+				return formatFloat(x), true
+			case models.DecimalString:
+				return string(x), true
+			}
+			return render(v), true
+		}
+	case "datetime":
+		switch x := v.(type) {
+		case time.Time:
+			return x, true
+		case string:
+			t, err := time.Parse(time.RFC3339Nano, x)
+			return t.UTC(), err == nil
+		}
+	case "duration":
+		switch x := v.(type) {
+		case time.Duration:
+			return x, true
+		case string:
+			d, err := parseDuration(x)
+			return d, err == nil
+		}
+	case "uuid":
+		switch x := v.(type) {
+		case models.UUID:
+			return x, true
+		case string:
+			u, err := uuid.FromString(x)
+			return models.UUID{UUID: u}, err == nil
+		}
+	case "record":
+		r, ok := v.(models.RecordID)
+		if !ok {
+			s, isStr := v.(string)
+			if !isStr {
+				return nil, false
+			}
+			table, id, found := strings.Cut(s, ":")
+			if !found {
+				return nil, false
+			}
+			r = models.RecordID{Table: table, ID: parseKey(id)}
+		}
+		if len(t.args) == 0 {
+			return r, true
+		}
+		for _, a := range t.args {
+			for _, u := range append(a.union, a) {
+				if u.name == strings.ToLower(r.Table) || u.name == r.Table {
+					return r, true
+				}
+			}
+		}
+	case "table":
+		switch x := v.(type) {
+		case models.Table:
+			return x, true
+		case string:
+			return models.Table(x), true
+		}
+	case "array", "set":
+		arr, ok := v.([]any)
+		if !ok {
+			return nil, false
+		}
+		out := make([]any, 0, len(arr))
+		for _, el := range arr {
+			if len(t.args) > 0 {
+				c, ok := convert(el, t.args[0], explicit)
+				if !ok {
+					return nil, false
+				}
+				el = c
+			}
+			if t.name == "set" && contains(out, el) {
+				continue
+			}
+			out = append(out, el)
+		}
+		return out, true
+	case "object":
+		m, ok := v.(map[string]any)
+		return m, ok
+	case "geometry":
+		return v, isGeometry(v)
+	case "bytes":
+		b, ok := v.([]byte)
+		return b, ok
+	default:
+		// Types this server does not model, e.g. references or file, pass.
+		return v, true
+	}
+	return nil, false
+}
+
+// isGeometry reports whether v is a GeoJSON object or a decoded geometry,
+// whose collections may arrive as arrays of their parts.
+func isGeometry(v any) bool {
+	switch x := v.(type) {
+	case map[string]any, models.GeometryPoint, models.GeometryLine, models.GeometryPolygon,
+		models.GeometryMultiPoint, models.GeometryMultiLine, models.GeometryMultiPolygon, models.GeometryCollection:
+		return true
+	case []any:
+		for _, el := range x {
+			if !isGeometry(el) {
+				return false
+			}
+		}
+		return len(x) > 0
+	}
+	return false
+}
+
+// ============================================================================
+// Ids and secrets
+// ============================================================================
+
+const idChars = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	for i := range b {
+		b[i] = idChars[int(b[i])%len(idChars)]
+	}
+	return string(b)
+}
+
+// generateID returns a new record id for a rand(), ulid() or uuid() generator.
+func generateID(gen string) any {
+	switch gen {
+	case "ulid":
+		return newULID()
+	case "uuid":
+		u, err := uuid.NewV7()
+		if err != nil {
+			return newUUID()
+		}
+		return models.UUID{UUID: u}
+	}
+	return randomString(20)
+}
+
+func newUUID() models.UUID {
+	return models.UUID{UUID: uuid.Must(uuid.NewV4())}
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID returns a ULID: 48 bits of milliseconds then 80 random bits,
+// Crockford base32 encoded.
+func newULID() string {
+	var b [16]byte
+	ms := uint64(time.Now().UnixMilli())
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (40 - 8*i))
+	}
+	_, _ = rand.Read(b[6:])
+	out := make([]byte, 26)
+	// 128 bits do not divide into 5-bit groups; pad two leading zero bits.
+	var acc uint64
+	bits, j := 2, 0
+	for _, c := range b {
+		acc = acc<<8 | uint64(c)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			out[j] = crockford[(acc>>bits)&31]
+			j++
+		}
+	}
+	return string(out[:j])
+}
+
+// hashPassword stands in for argon2: it is deterministic so that a stored
+// hash can be compared against a password, which is all the server needs.
+func hashPassword(password string) string {
+	h := sha256.Sum256([]byte("surrealtest:" + password))
+	return "$argon2id$v=19$m=19456,t=2,p=1$" + hex.EncodeToString(h[:])
+}
+
+func shuffle(n int, swap func(i, j int)) { mrand.Shuffle(n, swap) }
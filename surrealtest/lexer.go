@@ -0,0 +1,297 @@
+package surrealtest
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tEOF tokenKind = iota
+	tIdent
+	tQuoted // `escaped` or ⟨escaped⟩ identifier
+	tNumber
+	tDuration
+	tString
+	tParam
+	tOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// prefix is the type prefix of a string literal: r, d, u, s or 0.
+	prefix byte
+	// float marks a number written with a decimal point, exponent or f suffix;
+	// decimal one written with the dec suffix.
+	float, decimal bool
+	pos, end       int
+	// space reports whitespace (or a comment) before the token.
+	space bool
+}
+
+// ops lists the multi-character operators, longest first.
+var ops = []string{
+	"+?=", "<->", "...", "!==",
+	"..", "->", "<-", "::", "==", "!=", "<=", ">=", "&&", "||", "??", "?:",
+	"?=", "*=", "!~", "+=", "-=", "**", "@@", "<|", "|>",
+}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	i := 0
+	space := false
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			space = true
+			continue
+		case c == '-' && strings.HasPrefix(src[i:], "--"),
+			c == '/' && strings.HasPrefix(src[i:], "//"),
+			c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			space = true
+			continue
+		case c == '/' && strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("Parse error: unterminated comment")
+			}
+			i += end + 4
+			space = true
+			continue
+		}
+
+		tok := token{pos: i, space: space}
+		space = false
+		switch {
+		case isIdentStart(c):
+			j := i
+			for j < len(src) && isIdentChar(src[j]) {
+				j++
+			}
+			word := src[i:j]
+			if j < len(src) && (src[j] == '\'' || src[j] == '"') && len(word) == 1 && strings.ContainsAny(word, "rdus") {
+				s, end, err := lexString(src, j)
+				if err != nil {
+					return nil, err
+				}
+				tok.kind, tok.text, tok.prefix, i = tString, s, word[0], end
+				break
+			}
+			tok.kind, tok.text, i = tIdent, word, j
+		case c >= '0' && c <= '9':
+			i = lexNumber(src, i, &tok)
+		case c == '$':
+			j := i + 1
+			for j < len(src) && isIdentChar(src[j]) {
+				j++
+			}
+			if j == i+1 {
+				// A lone $ is the last-element index, as in items[$].
+				tok.kind, tok.text, i = tOp, "$", j
+				break
+			}
+			tok.kind, tok.text, i = tParam, src[i+1:j], j
+		case c == '`':
+			s, end, err := lexDelimited(src, i+1, "`")
+			if err != nil {
+				return nil, err
+			}
+			tok.kind, tok.text, i = tQuoted, s, end
+		case strings.HasPrefix(src[i:], "⟨"):
+			s, end, err := lexDelimited(src, i+len("⟨"), "⟩")
+			if err != nil {
+				return nil, err
+			}
+			tok.kind, tok.text, i = tQuoted, s, end
+		case c == '\'' || c == '"':
+			s, end, err := lexString(src, i)
+			if err != nil {
+				return nil, err
+			}
+			tok.kind, tok.text, i = tString, s, end
+		case c == '@' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			// @1@ is the full-text match operator with a reference.
+			j := i + 1
+			for j < len(src) && src[j] >= '0' && src[j] <= '9' {
+				j++
+			}
+			if j < len(src) && src[j] == '@' {
+				tok.kind, tok.text, i = tOp, "@@", j+1
+				break
+			}
+			tok.kind, tok.text, i = tOp, "@", i+1
+		default:
+			tok.kind = tOp
+			for _, op := range ops {
+				if strings.HasPrefix(src[i:], op) {
+					tok.text = op
+					break
+				}
+			}
+			if tok.text == "" {
+				r, size := utf8.DecodeRuneInString(src[i:])
+				if !strings.ContainsRune("()[]{},;:.=<>+-*/%!~?|@&×÷", r) {
+					return nil, fmt.Errorf("Parse error: unexpected character %q at position %d", r, i)
+				}
+				tok.text = src[i : i+size]
+			}
+			i += len(tok.text)
+		}
+		tok.end = i
+		toks = append(toks, tok)
+	}
+	toks = append(toks, token{kind: tEOF, pos: len(src), end: len(src), space: true})
+	return toks, nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9'
+}
+
+// lexNumber reads an integer, float, decimal or duration starting at i. A
+// number running into letters (123abc) is an identifier, as in record ids.
+func lexNumber(src string, i int, tok *token) int {
+	j := i
+	for j < len(src) && src[j] >= '0' && src[j] <= '9' {
+		j++
+	}
+	if d := durationEnd(src, i); d > j {
+		tok.kind, tok.text = tDuration, src[i:d]
+		return d
+	}
+	tok.kind = tNumber
+	point := false
+	if j+1 < len(src) && src[j] == '.' && src[j+1] >= '0' && src[j+1] <= '9' {
+		tok.float, point = true, true
+		j++
+		for j < len(src) && src[j] >= '0' && src[j] <= '9' {
+			j++
+		}
+	}
+	if j < len(src) && (src[j] == 'e' || src[j] == 'E') {
+		k := j + 1
+		if k < len(src) && (src[k] == '+' || src[k] == '-') {
+			k++
+		}
+		if k < len(src) && src[k] >= '0' && src[k] <= '9' {
+			for k < len(src) && src[k] >= '0' && src[k] <= '9' {
+				k++
+			}
+			tok.float = true
+			j = k
+		}
+	}
+	tok.text = src[i:j]
+	switch {
+	case strings.HasPrefix(src[j:], "dec") && (j+3 == len(src) || !isIdentChar(src[j+3])):
+		tok.decimal = true
+		return j + 3
+	case strings.HasPrefix(src[j:], "f") && (j+1 == len(src) || !isIdentChar(src[j+1])):
+		tok.float = true
+		return j + 1
+	case j < len(src) && isIdentChar(src[j]) && !point:
+		// An identifier starting with digits, such as the record key in
+		// person:6e26zg1h: not a number with an exponent.
+		for j < len(src) && isIdentChar(src[j]) {
+			j++
+		}
+		tok.kind, tok.text, tok.float = tIdent, src[i:j], false
+	}
+	return j
+}
+
+// durationEnd returns the end of a duration literal starting at i, or i when
+// there is none.
+func durationEnd(src string, i int) int {
+	j := i
+	end := i
+	for {
+		k := j
+		for k < len(src) && src[k] >= '0' && src[k] <= '9' {
+			k++
+		}
+		if k == j {
+			break
+		}
+		unit := ""
+		for _, u := range []string{"ns", "us", "µs", "ms", "s", "m", "h", "d", "w", "y"} {
+			if strings.HasPrefix(src[k:], u) && len(u) > len(unit) {
+				unit = u
+			}
+		}
+		if unit == "" {
+			break
+		}
+		k += len(unit)
+		j = k
+		end = k
+	}
+	if end > i && end < len(src) && isIdentChar(src[end]) {
+		return i
+	}
+	return end
+}
+
+func lexString(src string, i int) (string, int, error) {
+	quote := src[i]
+	var b strings.Builder
+	j := i + 1
+	for j < len(src) {
+		c := src[j]
+		switch {
+		case c == quote:
+			return b.String(), j + 1, nil
+		case c == '\\' && j+1 < len(src):
+			j++
+			switch src[j] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '0':
+				b.WriteByte(0)
+			default:
+				b.WriteByte(src[j])
+			}
+		default:
+			b.WriteByte(c)
+		}
+		j++
+	}
+	return "", 0, fmt.Errorf("Parse error: unterminated string at position %d", i)
+}
+
+func lexDelimited(src string, i int, close string) (string, int, error) {
+	var b strings.Builder
+	j := i
+	for j < len(src) {
+		if strings.HasPrefix(src[j:], close) {
+			return b.String(), j + len(close), nil
+		}
+		if src[j] == '\\' && j+1 < len(src) {
+			j++
+			if strings.HasPrefix(src[j:], close) {
+				b.WriteString(close)
+				j += len(close)
+				continue
+			}
+		}
+		b.WriteByte(src[j])
+		j++
+	}
+	return "", 0, fmt.Errorf("Parse error: unterminated identifier at position %d", i)
+}
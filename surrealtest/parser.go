@@ -0,0 +1,2278 @@
+package surrealtest
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/surrealdb/surrealdb.go/pkg/models"
+)
+
+// parser is a recursive-descent parser for the subset of SurrealQL the
+// driver and typical tests emit.
+type parser struct {
+	src  string
+	toks []token
+	i    int
+	// noGraph disables -> and <- as path operators, inside RELATE.
+	noGraph int
+}
+
+func parse(src string) ([]stmt, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: src, toks: toks}
+	return p.parseStatements(func() bool { return p.peek().kind == tEOF })
+}
+
+func (p *parser) parseStatements(done func() bool) ([]stmt, error) {
+	var out []stmt
+	for {
+		for p.isOp(";") {
+			p.next()
+		}
+		if done() {
+			return out, nil
+		}
+		s, err := p.parseStmt()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+		if !p.isOp(";") && !done() {
+			return nil, p.errorf("expected ';'")
+		}
+	}
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) peekN(n int) token {
+	if p.i+n < len(p.toks) {
+		return p.toks[p.i+n]
+	}
+	return p.toks[len(p.toks)-1]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	t := p.peek()
+	found := t.text
+	if t.kind == tEOF {
+		found = "end of query"
+	}
+	return fmt.Errorf("Parse error: %s, found %q at position %d", fmt.Sprintf(format, args...), found, t.pos)
+}
+
+func isKwTok(t token, words ...string) bool {
+	if t.kind != tIdent {
+		return false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.text, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) isKw(words ...string) bool { return isKwTok(p.peek(), words...) }
+
+// acceptKw consumes the keyword sequence words if it is next.
+func (p *parser) acceptKw(words ...string) bool {
+	for n, w := range words {
+		if !isKwTok(p.peekN(n), w) {
+			return false
+		}
+	}
+	p.i += len(words)
+	return true
+}
+
+func (p *parser) expectKw(words ...string) error {
+	if !p.acceptKw(words...) {
+		return p.errorf("expected %s", strings.Join(words, " "))
+	}
+	return nil
+}
+
+func (p *parser) isOp(op string) bool {
+	t := p.peek()
+	return t.kind == tOp && t.text == op
+}
+
+func (p *parser) acceptOp(op string) bool {
+	if p.isOp(op) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectOp(op string) error {
+	if !p.acceptOp(op) {
+		return p.errorf("expected '%s'", op)
+	}
+	return nil
+}
+
+// text returns the source between token from and the last consumed token.
+func (p *parser) text(from int) string {
+	if p.i == 0 || from >= p.i {
+		return ""
+	}
+	return strings.TrimSpace(p.src[p.toks[from].pos:p.toks[p.i-1].end])
+}
+
+func (p *parser) ident() (string, error) {
+	t := p.peek()
+	if t.kind != tIdent && t.kind != tQuoted {
+		return "", p.errorf("expected an identifier")
+	}
+	p.next()
+	return t.text, nil
+}
+
+var statementKeywords = []string{"SELECT", "CREATE", "UPDATE", "UPSERT", "DELETE", "INSERT", "RELATE", "DEFINE", "REMOVE", "INFO", "LIVE", "KILL", "RETURN", "LET", "IF", "THROW"}
+
+func (p *parser) parseStmt() (stmt, error) {
+	t := p.peek()
+	if t.kind != tIdent {
+		x, err := p.parseExpr()
+		return exprStmt{x}, err
+	}
+	switch strings.ToUpper(t.text) {
+	case "SELECT":
+		p.next()
+		return p.parseSelect()
+	case "LIVE":
+		p.next()
+		if err := p.expectKw("SELECT"); err != nil {
+			return nil, err
+		}
+		return p.parseLive()
+	case "CREATE":
+		p.next()
+		return p.parseCreate()
+	case "UPDATE", "UPSERT":
+		p.next()
+		return p.parseUpdate(strings.EqualFold(t.text, "UPSERT"))
+	case "DELETE":
+		p.next()
+		return p.parseDelete()
+	case "INSERT":
+		p.next()
+		return p.parseInsert()
+	case "RELATE":
+		p.next()
+		return p.parseRelate()
+	case "RETURN":
+		p.next()
+		x, err := p.parseValue()
+		return returnStmt{x}, err
+	case "LET":
+		p.next()
+		name := p.peek()
+		if name.kind != tParam {
+			return nil, p.errorf("expected a parameter")
+		}
+		p.next()
+		if err := p.expectOp("="); err != nil {
+			return nil, err
+		}
+		x, err := p.parseValue()
+		return letStmt{name: name.text, x: x}, err
+	case "THROW":
+		p.next()
+		x, err := p.parseExpr()
+		return throwStmt{x}, err
+	case "BEGIN":
+		p.next()
+		p.acceptKw("TRANSACTION")
+		return beginStmt{}, nil
+	case "COMMIT":
+		p.next()
+		p.acceptKw("TRANSACTION")
+		return commitStmt{}, nil
+	case "CANCEL":
+		p.next()
+		p.acceptKw("TRANSACTION")
+		return cancelStmt{}, nil
+	case "USE":
+		p.next()
+		var u useStmt
+		for {
+			switch {
+			case p.acceptKw("NS") || p.acceptKw("NAMESPACE"):
+				name, err := p.ident()
+				if err != nil {
+					return nil, err
+				}
+				u.ns = name
+			case p.acceptKw("DB") || p.acceptKw("DATABASE"):
+				name, err := p.ident()
+				if err != nil {
+					return nil, err
+				}
+				u.db = name
+			default:
+				return u, nil
+			}
+		}
+	case "INFO":
+		p.next()
+		return p.parseInfo()
+	case "KILL":
+		p.next()
+		x, err := p.parseExpr()
+		return killStmt{x}, err
+	case "DEFINE":
+		p.next()
+		return p.parseDefine()
+	case "REMOVE":
+		p.next()
+		return p.parseRemove()
+	case "ALTER":
+		p.next()
+		return p.parseAlter()
+	case "IF":
+		x, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return ifStmt{x.(*ifExpr)}, nil
+	case "EXPLAIN":
+		p.next()
+		s, err := p.parseStmt()
+		return explainStmt{s}, err
+	case "OPTION", "SLEEP":
+		p.next()
+		p.skipClause()
+		return noopStmt{}, nil
+	case "SHOW":
+		return nil, fmt.Errorf("surrealtest: SHOW CHANGES is not supported")
+	}
+	x, err := p.parseExpr()
+	return exprStmt{x}, err
+}
+
+// parseValue parses the value of RETURN or LET, which may be a bare statement.
+func (p *parser) parseValue() (expr, error) {
+	if p.isKw("SELECT", "CREATE", "UPDATE", "UPSERT", "DELETE", "INSERT", "RELATE", "DEFINE", "REMOVE", "INFO", "LIVE") {
+		s, err := p.parseStmt()
+		return subqueryExpr{s}, err
+	}
+	return p.parseExpr()
+}
+
+// skipClause consumes tokens up to the end of the current statement.
+func (p *parser) skipClause() string {
+	from := p.i
+	depth := 0
+	for {
+		t := p.peek()
+		if t.kind == tEOF {
+			break
+		}
+		if t.kind == tOp {
+			switch t.text {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				if depth == 0 {
+					return p.text(from)
+				}
+				depth--
+			case ";":
+				if depth == 0 {
+					return p.text(from)
+				}
+			}
+		}
+		p.next()
+	}
+	return p.text(from)
+}
+
+// ============================================================================
+// SELECT and LIVE SELECT
+// ============================================================================
+
+func (p *parser) parseSelect() (stmt, error) {
+	s := &selectStmt{}
+	if p.isKw("VALUE") && !p.peekN(1).isOpTok(",") && !isKwTok(p.peekN(1), "FROM") {
+		p.next()
+		s.value = true
+	}
+	fields, err := p.parseFields()
+	if err != nil {
+		return nil, err
+	}
+	s.fields = fields
+	if p.acceptKw("OMIT") {
+		if s.omit, err = p.parseIdiomList(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expectKw("FROM"); err != nil {
+		return nil, err
+	}
+	s.only = p.acceptKw("ONLY")
+	if s.from, err = p.parseTargets(); err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.acceptKw("WITH"):
+			if !p.acceptKw("NOINDEX") {
+				if err := p.expectKw("INDEX"); err != nil {
+					return nil, err
+				}
+				if _, err := p.parseIdentList(); err != nil {
+					return nil, err
+				}
+			}
+		case p.acceptKw("WHERE"):
+			if s.where, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+		case p.acceptKw("SPLIT"):
+			p.acceptKw("ON")
+			if s.split, err = p.parseIdiomList(); err != nil {
+				return nil, err
+			}
+		case p.acceptKw("GROUP"):
+			if p.acceptKw("ALL") {
+				s.groupAll = true
+				break
+			}
+			p.acceptKw("BY")
+			if s.group, err = p.parseIdiomList(); err != nil {
+				return nil, err
+			}
+		case p.acceptKw("ORDER"):
+			p.acceptKw("BY")
+			if p.isKw("RAND") && p.peekN(1).isOpTok("(") {
+				p.i += 2
+				if err := p.expectOp(")"); err != nil {
+					return nil, err
+				}
+				s.rand = true
+				break
+			}
+			for {
+				x, err := p.parseIdiom()
+				if err != nil {
+					return nil, err
+				}
+				item := orderItem{x: x}
+				item.collate = p.acceptKw("COLLATE")
+				item.numeric = p.acceptKw("NUMERIC")
+				if p.acceptKw("DESC") {
+					item.desc = true
+				} else {
+					p.acceptKw("ASC")
+				}
+				s.order = append(s.order, item)
+				if !p.acceptOp(",") {
+					break
+				}
+			}
+		case p.acceptKw("LIMIT"):
+			p.acceptKw("BY")
+			if s.limit, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+		case p.acceptKw("START"):
+			p.acceptKw("AT")
+			if s.start, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+		case p.acceptKw("FETCH"):
+			if s.fetch, err = p.parseIdiomList(); err != nil {
+				return nil, err
+			}
+		case p.acceptKw("TIMEOUT"), p.acceptKw("VERSION"):
+			if _, err := p.parseExpr(); err != nil {
+				return nil, err
+			}
+		case p.acceptKw("PARALLEL"), p.acceptKw("TEMPFILES"):
+		case p.acceptKw("EXPLAIN"):
+			p.acceptKw("FULL")
+			s.explain = true
+		default:
+			return s, nil
+		}
+	}
+}
+
+func (t token) isOpTok(op string) bool { return t.kind == tOp && t.text == op }
+
+func (p *parser) parseFields() ([]field, error) {
+	var fields []field
+	for {
+		start := p.i
+		if p.acceptOp("*") {
+			fields = append(fields, field{all: true, text: "*"})
+		} else {
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			f := field{x: x, text: p.text(start)}
+			if p.acceptKw("AS") {
+				alias, err := p.parseIdiom()
+				if err != nil {
+					return nil, err
+				}
+				f.alias = alias
+			}
+			fields = append(fields, f)
+		}
+		if !p.acceptOp(",") {
+			return fields, nil
+		}
+	}
+}
+
+func (p *parser) parseLive() (stmt, error) {
+	s := &liveStmt{}
+	if p.acceptKw("DIFF") {
+		s.diff = true
+	} else {
+		fields, err := p.parseFields()
+		if err != nil {
+			return nil, err
+		}
+		s.fields = fields
+	}
+	if err := p.expectKw("FROM"); err != nil {
+		return nil, err
+	}
+	targets, err := p.parseTargets()
+	if err != nil {
+		return nil, err
+	}
+	s.table = targets[0]
+	if p.acceptKw("WHERE") {
+		if s.where, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKw("FETCH") {
+		if _, err := p.parseIdiomList(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// parseTargets parses the comma-separated what of SELECT, CREATE, UPDATE
+// and DELETE, where a bare identifier names a table.
+func (p *parser) parseTargets() ([]expr, error) {
+	var out []expr
+	for {
+		x, err := p.parseTarget()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, x)
+		if !p.acceptOp(",") {
+			return out, nil
+		}
+	}
+}
+
+func (p *parser) parseTarget() (expr, error) {
+	t := p.peek()
+	if t.kind == tIdent || t.kind == tQuoted {
+		n := p.peekN(1)
+		recordID := n.isOpTok(":") && !n.space
+		if !recordID && !n.isOpTok("::") && !(n.isOpTok("(") && !n.space) {
+			p.next()
+			return tableExpr{t.text}, nil
+		}
+	}
+	return p.parseExpr()
+}
+
+func (p *parser) parseIdentList() ([]string, error) {
+	var out []string
+	for {
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, name)
+		if !p.acceptOp(",") {
+			return out, nil
+		}
+	}
+}
+
+func (p *parser) parseIdiomList() ([]expr, error) {
+	var out []expr
+	for {
+		x, err := p.parseIdiom()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, x)
+		if !p.acceptOp(",") {
+			return out, nil
+		}
+	}
+}
+
+// parseIdiom parses a field path such as name, a.b, tags[0] or ->edge->table.
+func (p *parser) parseIdiom() (*idiomExpr, error) {
+	start := p.i
+	t := p.peek()
+	var id *idiomExpr
+	switch {
+	case t.kind == tIdent || t.kind == tQuoted:
+		p.next()
+		id = &idiomExpr{parts: []part{fieldPart{t.text}}}
+	case t.isOpTok("->") || t.isOpTok("<-") || t.isOpTok("<->"):
+		id = &idiomExpr{}
+	default:
+		return nil, p.errorf("expected a field")
+	}
+	x, err := p.parsePostfix(id)
+	if err != nil {
+		return nil, err
+	}
+	id = x.(*idiomExpr)
+	id.text = p.text(start)
+	return id, nil
+}
+
+// ============================================================================
+// Data-changing statements
+// ============================================================================
+
+func (p *parser) parseData() (*dataClause, error) {
+	var d *dataClause
+	for {
+		var err error
+		switch {
+		case p.acceptKw("SET"):
+			if d == nil {
+				d = &dataClause{}
+			}
+			if d.set, err = p.parseAssignments(); err != nil {
+				return nil, err
+			}
+		case p.acceptKw("UNSET"):
+			if d == nil {
+				d = &dataClause{}
+			}
+			for {
+				id, err := p.parseIdiom()
+				if err != nil {
+					return nil, err
+				}
+				d.unset = append(d.unset, id)
+				if !p.acceptOp(",") {
+					break
+				}
+			}
+		case p.isKw("CONTENT", "MERGE", "REPLACE", "PATCH"):
+			kw := strings.ToUpper(p.next().text)
+			if d == nil {
+				d = &dataClause{}
+			}
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			switch kw {
+			case "CONTENT":
+				d.content = x
+			case "MERGE":
+				d.merge = x
+			case "REPLACE":
+				d.replace = x
+			case "PATCH":
+				d.patch = x
+			}
+		default:
+			return d, nil
+		}
+	}
+}
+
+func (p *parser) parseAssignments() ([]assignment, error) {
+	var out []assignment
+	for {
+		target, err := p.parseIdiom()
+		if err != nil {
+			return nil, err
+		}
+		op := p.peek()
+		if op.kind != tOp || (op.text != "=" && op.text != "+=" && op.text != "-=" && op.text != "+?=") {
+			return nil, p.errorf("expected an assignment operator")
+		}
+		p.next()
+		x, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, assignment{target: target, op: op.text, x: x})
+		if !p.acceptOp(",") {
+			return out, nil
+		}
+	}
+}
+
+func (p *parser) parseReturn() (returnClause, error) {
+	if !p.acceptKw("RETURN") {
+		return returnClause{}, nil
+	}
+	for _, kind := range []string{"NONE", "NULL", "BEFORE", "AFTER", "DIFF"} {
+		if p.isKw(kind) && (p.peekN(1).kind == tEOF || p.peekN(1).isOpTok(";") || p.peekN(1).isOpTok(")") || isKwTok(p.peekN(1), "TIMEOUT", "PARALLEL")) {
+			p.next()
+			return returnClause{kind: strings.ToLower(kind)}, nil
+		}
+	}
+	fields, err := p.parseFields()
+	return returnClause{kind: "fields", fields: fields}, err
+}
+
+// parseTail parses the trailing clauses shared by the write statements.
+func (p *parser) parseTail(ret *returnClause, where *expr) error {
+	for {
+		var err error
+		switch {
+		case where != nil && p.acceptKw("WHERE"):
+			if *where, err = p.parseExpr(); err != nil {
+				return err
+			}
+		case p.isKw("RETURN"):
+			if *ret, err = p.parseReturn(); err != nil {
+				return err
+			}
+		case p.acceptKw("TIMEOUT"):
+			if _, err := p.parseExpr(); err != nil {
+				return err
+			}
+		case p.acceptKw("PARALLEL"):
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *parser) parseCreate() (stmt, error) {
+	s := &createStmt{only: p.acceptKw("ONLY")}
+	var err error
+	if s.what, err = p.parseTargets(); err != nil {
+		return nil, err
+	}
+	if s.data, err = p.parseData(); err != nil {
+		return nil, err
+	}
+	return s, p.parseTail(&s.ret, nil)
+}
+
+func (p *parser) parseUpdate(upsert bool) (stmt, error) {
+	s := &updateStmt{upsert: upsert, only: p.acceptKw("ONLY")}
+	var err error
+	if s.what, err = p.parseTargets(); err != nil {
+		return nil, err
+	}
+	if s.data, err = p.parseData(); err != nil {
+		return nil, err
+	}
+	return s, p.parseTail(&s.ret, &s.where)
+}
+
+func (p *parser) parseDelete() (stmt, error) {
+	p.acceptKw("FROM")
+	s := &deleteStmt{only: p.acceptKw("ONLY")}
+	var err error
+	if s.what, err = p.parseTargets(); err != nil {
+		return nil, err
+	}
+	return s, p.parseTail(&s.ret, &s.where)
+}
+
+func (p *parser) parseInsert() (stmt, error) {
+	s := &insertStmt{}
+	s.relation = p.acceptKw("RELATION")
+	s.ignore = p.acceptKw("IGNORE")
+	if p.acceptKw("INTO") {
+		var err error
+		if s.into, err = p.parseTarget(); err != nil {
+			return nil, err
+		}
+	}
+	if p.isOp("(") {
+		if data, ok, err := p.tryValues(); err != nil {
+			return nil, err
+		} else if ok {
+			s.data = data
+		}
+	}
+	if s.data == nil {
+		x, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		s.data = x
+	}
+	if p.acceptKw("ON", "DUPLICATE", "KEY", "UPDATE") {
+		var err error
+		if s.update, err = p.parseAssignments(); err != nil {
+			return nil, err
+		}
+	}
+	return s, p.parseTail(&s.ret, nil)
+}
+
+// tryValues parses the (fields) VALUES (...), (...) form of INSERT.
+func (p *parser) tryValues() (expr, bool, error) {
+	save := p.i
+	p.next()
+	var cols []string
+	for {
+		id, err := p.parseIdiom()
+		if err != nil {
+			p.i = save
+			return nil, false, nil
+		}
+		cols = append(cols, id.text)
+		if !p.acceptOp(",") {
+			break
+		}
+	}
+	if !p.acceptOp(")") || !p.acceptKw("VALUES") {
+		p.i = save
+		return nil, false, nil
+	}
+	var rows []expr
+	for {
+		if err := p.expectOp("("); err != nil {
+			return nil, false, err
+		}
+		obj := &objectExpr{}
+		for i := 0; ; i++ {
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, false, err
+			}
+			if i < len(cols) {
+				obj.keys = append(obj.keys, strings.Trim(cols[i], "`"))
+				obj.vals = append(obj.vals, x)
+			}
+			if !p.acceptOp(",") {
+				break
+			}
+		}
+		if err := p.expectOp(")"); err != nil {
+			return nil, false, err
+		}
+		rows = append(rows, obj)
+		if !p.acceptOp(",") {
+			return &arrayExpr{elems: rows}, true, nil
+		}
+	}
+}
+
+func (p *parser) parseRelate() (stmt, error) {
+	s := &relateStmt{only: p.acceptKw("ONLY")}
+	p.noGraph++
+	defer func() { p.noGraph-- }()
+	from, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	dir := p.peek()
+	if !dir.isOpTok("->") && !dir.isOpTok("<-") {
+		return nil, p.errorf("expected -> or <-")
+	}
+	p.next()
+	if s.edge, err = p.parseTarget(); err != nil {
+		return nil, err
+	}
+	if err := p.expectOp(dir.text); err != nil {
+		return nil, err
+	}
+	to, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if dir.text == "<-" {
+		from, to = to, from
+	}
+	s.from, s.to = from, to
+	p.noGraph--
+	defer func() { p.noGraph++ }()
+	if s.data, err = p.parseData(); err != nil {
+		return nil, err
+	}
+	return s, p.parseTail(&s.ret, nil)
+}
+
+// ============================================================================
+// INFO, DEFINE, REMOVE, ALTER
+// ============================================================================
+
+func (p *parser) parseInfo() (stmt, error) {
+	if err := p.expectKw("FOR"); err != nil {
+		return nil, err
+	}
+	s := infoStmt{}
+	switch {
+	case p.acceptKw("ROOT"), p.acceptKw("KV"):
+		s.level = "root"
+	case p.acceptKw("NS"), p.acceptKw("NAMESPACE"):
+		s.level = "ns"
+	case p.acceptKw("DB"), p.acceptKw("DATABASE"):
+		s.level = "db"
+	case p.acceptKw("TABLE"), p.acceptKw("TB"):
+		s.level = "table"
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		s.name = name
+	case p.acceptKw("USER"):
+		s.level = "user"
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		s.name = name
+		p.skipClause()
+	default:
+		return nil, p.errorf("expected ROOT, NS, DB, TABLE or USER")
+	}
+	p.acceptKw("STRUCTURE")
+	return s, nil
+}
+
+func (p *parser) parseDefine() (stmt, error) {
+	start := p.i - 1
+	kindTok := p.next()
+	kind := strings.ToUpper(kindTok.text)
+	s := &defineStmt{}
+	if p.acceptKw("IF", "NOT", "EXISTS") {
+		s.ifNotExists = true
+	} else if p.acceptKw("OVERWRITE") {
+		s.overwrite = true
+	}
+	var err error
+	switch kind {
+	case "NAMESPACE", "NS":
+		if s.ns, err = p.ident(); err != nil {
+			return nil, err
+		}
+		p.skipClause()
+	case "DATABASE", "DB":
+		if s.db, err = p.ident(); err != nil {
+			return nil, err
+		}
+		p.skipClause()
+	case "TABLE":
+		s.table, err = p.parseTableDef()
+	case "FIELD":
+		s.field, err = p.parseFieldDef()
+	case "INDEX":
+		s.index, err = p.parseIndexDef()
+	case "EVENT":
+		s.event, err = p.parseEventDef()
+		if err == nil {
+			s.event.text = stripDefineFlags(p.text(start))
+		}
+	case "USER":
+		s.user, err = p.parseUserDef()
+	case "ACCESS":
+		s.access, err = p.parseAccessDef()
+		if err == nil {
+			s.access.text = stripDefineFlags(p.text(start))
+		}
+	case "PARAM":
+		t := p.peek()
+		if t.kind != tParam {
+			return nil, p.errorf("expected a parameter")
+		}
+		p.next()
+		if err := p.expectKw("VALUE"); err != nil {
+			return nil, err
+		}
+		x, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		p.skipClause()
+		s.param = &paramDef{name: t.text, value: x, text: stripDefineFlags(p.text(start))}
+	case "FUNCTION":
+		s.function, err = p.parseFuncDef()
+		if err == nil {
+			s.function.text = stripDefineFlags(p.text(start))
+		}
+	default:
+		name := ""
+		if t := p.peek(); t.kind == tIdent || t.kind == tQuoted || t.kind == tParam {
+			name = t.text
+			p.next()
+		}
+		p.skipClause()
+		s.other = &otherDef{kind: strings.ToLower(kind), name: name, text: stripDefineFlags(p.text(start))}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+var defineFlags = regexp.MustCompile(`(?i)^(DEFINE\s+\w+)\s+(IF\s+NOT\s+EXISTS|OVERWRITE)\b`)
+
+func stripDefineFlags(s string) string {
+	return defineFlags.ReplaceAllString(s, "$1")
+}
+
+func (p *parser) parseTableDef() (*tableDef, error) {
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	d := &tableDef{name: name, kind: "ANY"}
+	return d, p.parseTableClauses(d)
+}
+
+func (p *parser) parseTableClauses(d *tableDef) error {
+	for {
+		switch {
+		case p.acceptKw("DROP", "COMMENT"):
+			d.comment = ""
+		case p.acceptKw("DROP", "CHANGEFEED"):
+			d.changefeed = ""
+		case p.acceptKw("COMPACT"):
+		case p.acceptKw("DROP"):
+			d.drop = true
+		case p.acceptKw("SCHEMAFULL"), p.acceptKw("SCHEMAFUL"):
+			d.schemafull = true
+		case p.acceptKw("SCHEMALESS"):
+			d.schemafull = false
+		case p.acceptKw("TYPE"):
+			switch {
+			case p.acceptKw("ANY"):
+				d.kind = "ANY"
+			case p.acceptKw("NORMAL"):
+				d.kind = "NORMAL"
+			case p.acceptKw("RELATION"):
+				d.kind = "RELATION"
+				for {
+					switch {
+					case p.acceptKw("FROM"), p.acceptKw("IN"):
+						d.in = p.parseTableUnion()
+						continue
+					case p.acceptKw("TO"), p.acceptKw("OUT"):
+						d.out = p.parseTableUnion()
+						continue
+					case p.acceptKw("ENFORCED"):
+						continue
+					}
+					break
+				}
+			default:
+				return p.errorf("expected ANY, NORMAL or RELATION")
+			}
+		case p.acceptKw("CHANGEFEED"):
+			t := p.next()
+			d.changefeed = t.text
+			if p.acceptKw("INCLUDE", "ORIGINAL") {
+				d.changefeed += " INCLUDE ORIGINAL"
+			}
+		case p.acceptKw("COMMENT"):
+			t := p.next()
+			d.comment = t.text
+		case p.acceptKw("PERMISSIONS"):
+			perms, err := p.parsePermissions()
+			if err != nil {
+				return err
+			}
+			d.perms = perms
+		case p.acceptKw("AS"):
+			p.skipClause()
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *parser) parseTableUnion() []string {
+	var out []string
+	for {
+		t := p.peek()
+		if t.kind != tIdent && t.kind != tQuoted {
+			return out
+		}
+		p.next()
+		out = append(out, t.text)
+		if !p.acceptOp("|") && !p.acceptKw("OR") {
+			return out
+		}
+	}
+}
+
+func (p *parser) parsePermissions() (permissions, error) {
+	switch {
+	case p.acceptKw("FULL"):
+		return fullPermissions(), nil
+	case p.acceptKw("NONE"):
+		return permissions{}, nil
+	}
+	perms := permissions{}
+	for p.acceptKw("FOR") {
+		var ops []string
+		for {
+			t := p.peek()
+			if !isKwTok(t, permOps...) {
+				break
+			}
+			p.next()
+			ops = append(ops, strings.ToLower(t.text))
+			if !p.acceptOp(",") {
+				break
+			}
+		}
+		var e perm
+		switch {
+		case p.acceptKw("FULL"):
+			e.full = true
+		case p.acceptKw("NONE"):
+		case p.acceptKw("WHERE"):
+			start := p.i
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			e.cond, e.text = x, p.text(start)
+		default:
+			return nil, p.errorf("expected FULL, NONE or WHERE")
+		}
+		for _, op := range ops {
+			perms[op] = e
+		}
+		p.acceptOp(",")
+	}
+	return perms, nil
+}
+
+func (p *parser) parseFieldDef() (*fieldDef, error) {
+	name, err := p.parseFieldName()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKw("ON"); err != nil {
+		return nil, err
+	}
+	p.acceptKw("TABLE")
+	table, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	d := &fieldDef{name: name, table: table, path: fieldPath(name)}
+	return d, p.parseFieldClauses(d)
+}
+
+// parseFieldName reads a field name such as `name`, address.city or
+// tags[*], returning it without escaping.
+func (p *parser) parseFieldName() (string, error) {
+	var b strings.Builder
+	for !p.isKw("ON") {
+		t := p.next()
+		switch {
+		case t.kind == tIdent || t.kind == tQuoted:
+			b.WriteString(t.text)
+		case t.kind == tOp && (t.text == "." || t.text == "[" || t.text == "]" || t.text == "*"):
+			b.WriteString(t.text)
+		default:
+			return "", p.errorf("expected a field name")
+		}
+	}
+	if b.Len() == 0 {
+		return "", p.errorf("expected a field name")
+	}
+	return b.String(), nil
+}
+
+// fieldPath splits a field name such as address.city or tags[*] into its
+// components.
+func fieldPath(name string) []string {
+	return strings.Split(strings.ReplaceAll(name, "[*]", ".*"), ".")
+}
+
+var fieldClauseKeywords = []string{"DEFAULT", "VALUE", "ASSERT", "READONLY", "PERMISSIONS", "COMMENT", "REFERENCE", "FLEXIBLE", "TYPE"}
+
+func (p *parser) parseFieldClauses(d *fieldDef) error {
+	for {
+		switch {
+		case p.isKw("DROP"):
+			p.next()
+			switch {
+			case p.acceptKw("TYPE"):
+				d.typ = ""
+			case p.acceptKw("FLEXIBLE"):
+				d.flexible = false
+			case p.acceptKw("READONLY"):
+				d.readonly = false
+			case p.acceptKw("VALUE"):
+				d.value, d.valueText = nil, ""
+			case p.acceptKw("ASSERT"):
+				d.assert, d.assertText = nil, ""
+			case p.acceptKw("DEFAULT"):
+				d.def, d.defText, d.defAlways = nil, "", false
+			case p.acceptKw("COMMENT"):
+				d.comment = ""
+			case p.acceptKw("REFERENCE"):
+				d.reference = ""
+			default:
+				return p.errorf("expected a clause to drop")
+			}
+		case p.acceptKw("FLEXIBLE"):
+			d.flexible = true
+		case p.acceptKw("TYPE"):
+			d.typ = p.parseTypeSpec()
+		case p.acceptKw("DEFAULT"):
+			d.defAlways = p.acceptKw("ALWAYS")
+			start := p.i
+			x, err := p.parseExpr()
+			if err != nil {
+				return err
+			}
+			d.def, d.defText = x, p.text(start)
+		case p.acceptKw("VALUE"):
+			start := p.i
+			x, err := p.parseValue()
+			if err != nil {
+				return err
+			}
+			d.value, d.valueText = x, p.text(start)
+		case p.acceptKw("ASSERT"):
+			start := p.i
+			x, err := p.parseExpr()
+			if err != nil {
+				return err
+			}
+			d.assert, d.assertText = x, p.text(start)
+		case p.acceptKw("READONLY"):
+			d.readonly = true
+		case p.isKw("REFERENCE"):
+			start := p.i
+			p.next()
+			if p.acceptKw("ON", "DELETE") {
+				switch {
+				case p.acceptKw("THEN"):
+					if _, err := p.parseExpr(); err != nil {
+						return err
+					}
+				default:
+					p.next()
+				}
+			}
+			d.reference = p.text(start)
+		case p.acceptKw("PERMISSIONS"):
+			start := p.i
+			if _, err := p.parsePermissions(); err != nil {
+				return err
+			}
+			d.permsText = p.text(start)
+		case p.acceptKw("COMMENT"):
+			d.comment = p.next().text
+		default:
+			return nil
+		}
+	}
+}
+
+// parseTypeSpec reads a type such as option<array<record<users>>> or
+// "a" | "b" as raw text.
+func (p *parser) parseTypeSpec() string {
+	start := p.i
+	depth := 0
+	for {
+		t := p.peek()
+		if t.kind == tEOF || depth == 0 && (t.isOpTok(";") || t.isOpTok(")") || t.isOpTok(",") || isKwTok(t, fieldClauseKeywords...)) {
+			break
+		}
+		if t.kind == tOp {
+			switch t.text {
+			case "<", "(", "[", "{":
+				depth++
+			case ">", ")", "]", "}":
+				depth--
+			}
+		}
+		p.next()
+		if depth == 0 && !p.peek().isOpTok("|") && !t.isOpTok("|") && !p.peek().isOpTok("<") {
+			break
+		}
+	}
+	return p.text(start)
+}
+
+func (p *parser) parseIndexDef() (*indexDef, error) {
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKw("ON"); err != nil {
+		return nil, err
+	}
+	p.acceptKw("TABLE")
+	table, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	d := &indexDef{name: name, table: table}
+	if p.acceptKw("FIELDS") || p.acceptKw("COLUMNS") {
+		start := p.i
+		if d.fields, err = p.parseIdiomList(); err != nil {
+			return nil, err
+		}
+		d.fieldsText = p.text(start)
+	}
+	d.unique = p.acceptKw("UNIQUE")
+	d.rest = p.skipClause()
+	return d, nil
+}
+
+func (p *parser) parseEventDef() (*eventDef, error) {
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKw("ON"); err != nil {
+		return nil, err
+	}
+	p.acceptKw("TABLE")
+	table, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	d := &eventDef{name: name, table: table}
+	for {
+		switch {
+		case p.acceptKw("ASYNC"):
+		case p.acceptKw("RETRY"), p.acceptKw("MAXDEPTH"):
+			p.next()
+		case p.acceptKw("WHEN"):
+			start := p.i
+			if d.when, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+			d.whenText = p.text(start)
+		case p.acceptKw("THEN"):
+			start := p.i
+			for {
+				switch {
+				case p.isOp("("):
+					p.next()
+					stmts, err := p.parseStatements(func() bool { return p.isOp(")") })
+					if err != nil {
+						return nil, err
+					}
+					p.next()
+					d.then = append(d.then, stmts...)
+				case p.isOp("{"):
+					x, err := p.parsePrimary()
+					if err != nil {
+						return nil, err
+					}
+					d.then = append(d.then, exprStmt{x})
+				default:
+					s, err := p.parseStmt()
+					if err != nil {
+						return nil, err
+					}
+					d.then = append(d.then, s)
+				}
+				if !p.acceptOp(",") {
+					break
+				}
+			}
+			d.thenText = p.text(start)
+		case p.acceptKw("COMMENT"):
+			p.next()
+		default:
+			return d, nil
+		}
+		if p.peek().isOpTok(",") {
+			p.next()
+		}
+	}
+}
+
+func (p *parser) parseUserDef() (*userDef, error) {
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKw("ON"); err != nil {
+		return nil, err
+	}
+	d := &userDef{name: name, level: p.parseLevel(), roles: []string{"viewer"}, tokenDur: defaultTokenDuration}
+	for {
+		switch {
+		case p.acceptKw("PASSWORD"):
+			d.hash = hashPassword(p.next().text)
+		case p.acceptKw("PASSHASH"):
+			d.hash = p.next().text
+		case p.acceptKw("ROLES"):
+			roles, err := p.parseIdentList()
+			if err != nil {
+				return nil, err
+			}
+			d.roles = roles
+		case p.acceptKw("DURATION"):
+			if err := p.parseDurations(&d.tokenDur, &d.sessionDur); err != nil {
+				return nil, err
+			}
+		case p.acceptKw("COMMENT"):
+			d.comment = p.next().text
+		default:
+			return d, nil
+		}
+	}
+}
+
+func (p *parser) parseLevel() string {
+	switch {
+	case p.acceptKw("ROOT"):
+		return "ROOT"
+	case p.acceptKw("NAMESPACE"), p.acceptKw("NS"):
+		return "NAMESPACE"
+	}
+	if !p.acceptKw("DATABASE") {
+		p.acceptKw("DB")
+	}
+	return "DATABASE"
+}
+
+// parseDurations parses FOR TOKEN d, FOR SESSION d, FOR GRANT d.
+func (p *parser) parseDurations(token, session *time.Duration) error {
+	for p.acceptKw("FOR") {
+		target := strings.ToUpper(p.next().text)
+		var d time.Duration
+		if !p.acceptKw("NONE") {
+			t := p.next()
+			if t.kind != tDuration {
+				return p.errorf("expected a duration")
+			}
+			var err error
+			if d, err = parseDuration(t.text); err != nil {
+				return err
+			}
+		}
+		switch target {
+		case "TOKEN":
+			*token = d
+		case "SESSION":
+			*session = d
+		}
+		if !p.acceptOp(",") {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (p *parser) parseAccessDef() (*accessDef, error) {
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKw("ON"); err != nil {
+		return nil, err
+	}
+	d := &accessDef{name: name, level: p.parseLevel(), tokenDur: defaultTokenDuration}
+	if err := p.expectKw("TYPE"); err != nil {
+		return nil, err
+	}
+	d.kind = strings.ToUpper(p.next().text)
+	for {
+		switch {
+		case p.acceptKw("SIGNUP"):
+			if d.signup, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+		case p.acceptKw("SIGNIN"):
+			if d.signin, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+		case p.acceptKw("AUTHENTICATE"):
+			if _, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+		case p.acceptKw("DURATION"):
+			if err := p.parseDurations(&d.tokenDur, &d.sessionDur); err != nil {
+				return nil, err
+			}
+		case p.acceptKw("WITH"), p.acceptKw("ALGORITHM"), p.acceptKw("KEY"), p.acceptKw("URL"),
+			p.acceptKw("ISSUER"), p.acceptKw("JWT"), p.acceptKw("REFRESH"), p.acceptKw("COMMENT"):
+			if p.peek().kind == tString || isKwTok(p.peek(), "EDDSA", "ES256", "ES384", "ES512", "HS256", "HS384", "HS512", "PS256", "PS384", "PS512", "RS256", "RS384", "RS512") {
+				p.next()
+			}
+		default:
+			return d, nil
+		}
+	}
+}
+
+func (p *parser) parseFuncDef() (*funcDef, error) {
+	start := p.i
+	for !p.isOp("(") && p.peek().kind != tEOF {
+		p.next()
+	}
+	d := &funcDef{name: strings.ReplaceAll(p.text(start), " ", "")}
+	if err := p.expectOp("("); err != nil {
+		return nil, err
+	}
+	for !p.isOp(")") {
+		t := p.next()
+		if t.kind != tParam {
+			return nil, p.errorf("expected a parameter")
+		}
+		d.args = append(d.args, t.text)
+		if p.acceptOp(":") {
+			p.parseTypeSpec()
+		}
+		if !p.acceptOp(",") {
+			break
+		}
+	}
+	if err := p.expectOp(")"); err != nil {
+		return nil, err
+	}
+	if p.acceptOp("->") {
+		p.parseTypeSpec()
+	}
+	if !p.isOp("{") {
+		return nil, p.errorf("expected a function body")
+	}
+	body, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+	d.body = body
+	p.skipClause()
+	return d, nil
+}
+
+func (p *parser) parseRemove() (stmt, error) {
+	kind := strings.ToUpper(p.next().text)
+	s := removeStmt{kind: kind}
+	s.ifExists = p.acceptKw("IF", "EXISTS")
+	t := p.next()
+	if t.kind != tIdent && t.kind != tQuoted && t.kind != tParam {
+		return nil, p.errorf("expected a name")
+	}
+	s.name = t.text
+	if kind == "FUNCTION" {
+		for p.acceptOp("::") {
+			s.name += "::" + p.next().text
+		}
+	}
+	if p.acceptKw("ON") {
+		switch kind {
+		case "USER", "ACCESS":
+			s.level = p.parseLevel()
+		default:
+			p.acceptKw("TABLE")
+			name, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			s.table = name
+		}
+	}
+	return s, nil
+}
+
+func (p *parser) parseAlter() (stmt, error) {
+	switch {
+	case p.acceptKw("TABLE"):
+		s := &alterTableStmt{ifExists: p.acceptKw("IF", "EXISTS")}
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		s.name = name
+		if p.acceptKw("RENAME", "TO") {
+			if s.rename, err = p.ident(); err != nil {
+				return nil, err
+			}
+			return s, nil
+		}
+		start := p.i
+		if err := p.parseTableClauses(&tableDef{}); err != nil {
+			return nil, err
+		}
+		s.clauses = p.text(start)
+		return s, nil
+	case p.acceptKw("FIELD"):
+		s := &alterFieldStmt{ifExists: p.acceptKw("IF", "EXISTS")}
+		name, err := p.parseFieldName()
+		if err != nil {
+			return nil, err
+		}
+		s.name = name
+		if err := p.expectKw("ON"); err != nil {
+			return nil, err
+		}
+		p.acceptKw("TABLE")
+		if s.table, err = p.ident(); err != nil {
+			return nil, err
+		}
+		start := p.i
+		if err := p.parseFieldClauses(&fieldDef{}); err != nil {
+			return nil, err
+		}
+		s.clauses = p.text(start)
+		return s, nil
+	}
+	p.skipClause()
+	return noopStmt{}, nil
+}
+
+// applyClauses parses the clauses of an ALTER statement onto a definition.
+func applyClauses(src string, apply func(p *parser) error) error {
+	toks, err := lex(src)
+	if err != nil {
+		return err
+	}
+	p := &parser{src: src, toks: toks}
+	if err := apply(p); err != nil {
+		return err
+	}
+	if p.peek().kind != tEOF {
+		return p.errorf("unexpected clause")
+	}
+	return nil
+}
+
+// ============================================================================
+// Expressions
+// ============================================================================
+
+func (p *parser) parseExpr() (expr, error) { return p.parseBinary(1) }
+
+// binaryOp returns the operator at the current position, its precedence and
+// how many tokens it spans, or a zero precedence when there is none.
+func (p *parser) binaryOp() (string, int, int) {
+	t := p.peek()
+	if t.kind == tOp {
+		switch t.text {
+		case "||", "??", "?:":
+			return t.text, 1, 1
+		case "&&":
+			return "&&", 2, 1
+		case "=", "==", "!=", "!==", "?=", "*=", "~", "!~", "<", "<=", ">", ">=", "@@":
+			return t.text, 4, 1
+		case "+", "-":
+			return t.text, 5, 1
+		case "*", "/", "%", "×", "÷":
+			return t.text, 6, 1
+		case "**":
+			return "**", 7, 1
+		case "<|":
+			return "<|", 4, 1
+		}
+		return "", 0, 0
+	}
+	if t.kind != tIdent {
+		return "", 0, 0
+	}
+	switch strings.ToUpper(t.text) {
+	case "OR":
+		return "||", 1, 1
+	case "AND":
+		return "&&", 2, 1
+	case "IS":
+		if isKwTok(p.peekN(1), "NOT") {
+			return "!=", 4, 2
+		}
+		return "=", 4, 1
+	case "NOT":
+		if isKwTok(p.peekN(1), "IN", "INSIDE") {
+			return "NOTINSIDE", 4, 2
+		}
+	case "IN", "INSIDE":
+		return "INSIDE", 4, 1
+	case "CONTAINS", "CONTAINSNOT", "CONTAINSALL", "CONTAINSANY", "CONTAINSNONE",
+		"NOTINSIDE", "ALLINSIDE", "ANYINSIDE", "NONEINSIDE", "OUTSIDE", "INTERSECTS":
+		return strings.ToUpper(t.text), 4, 1
+	case "MATCHES":
+		return "@@", 4, 1
+	}
+	return "", 0, 0
+}
+
+func (p *parser) parseBinary(minPrec int) (expr, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, prec, n := p.binaryOp()
+		if prec == 0 || prec < minPrec {
+			return lhs, nil
+		}
+		p.i += n
+		if op == "<|" {
+			if lhs, err = p.parseKNN(lhs); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		next := prec + 1
+		if op == "**" {
+			next = prec
+		}
+		rhs, err := p.parseBinary(next)
+		if err != nil {
+			return nil, err
+		}
+		lhs = &binaryExpr{op: op, l: lhs, r: rhs}
+	}
+}
+
+// parseKNN parses the rest of x <|k[,ef|,DISTANCE]|> vec. The search is
+// exact, so the ef and distance arguments are only skipped.
+func (p *parser) parseKNN(x expr) (expr, error) {
+	k, err := strconv.Atoi(p.peek().text)
+	if p.peek().kind != tNumber || err != nil {
+		return nil, p.errorf("expected a neighbour count")
+	}
+	p.next()
+	for p.acceptOp(",") {
+		p.next()
+	}
+	if err := p.expectOp("|>"); err != nil {
+		return nil, err
+	}
+	vec, err := p.parseBinary(5)
+	if err != nil {
+		return nil, err
+	}
+	return &knnExpr{x: x, vec: vec, k: k}, nil
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	t := p.peek()
+	switch {
+	case t.isOpTok("!"):
+		p.next()
+		x, err := p.parseUnary()
+		return &unaryExpr{op: "!", x: x}, err
+	case t.isOpTok("-"):
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if lit, ok := x.(litExpr); ok {
+			switch v := lit.v.(type) {
+			case int64:
+				return litExpr{-v}, nil
+			case float64:
+				return litExpr{-v}, nil
+			}
+		}
+		return &unaryExpr{op: "-", x: x}, nil
+	case t.isOpTok("+"):
+		p.next()
+		return p.parseUnary()
+	case isKwTok(t, "NOT") && !isKwTok(p.peekN(1), "IN", "INSIDE"):
+		p.next()
+		x, err := p.parseBinary(3)
+		return &unaryExpr{op: "!", x: x}, err
+	}
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	return p.parsePostfix(x)
+}
+
+func (p *parser) parsePrimary() (expr, error) {
+	t := p.peek()
+	switch t.kind {
+	case tNumber:
+		p.next()
+		return numberLit(t)
+	case tDuration:
+		p.next()
+		d, err := parseDuration(t.text)
+		return litExpr{d}, err
+	case tString:
+		p.next()
+		return stringLit(t)
+	case tParam:
+		p.next()
+		return paramExpr{t.text}, nil
+	case tOp:
+		switch t.text {
+		case "(":
+			p.next()
+			var x expr
+			var err error
+			if p.isKw(statementKeywords...) && !p.peekN(1).isOpTok("(") {
+				var s stmt
+				s, err = p.parseStmt()
+				x = subqueryExpr{s}
+			} else {
+				x, err = p.parseExpr()
+			}
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expectOp(")")
+		case "[":
+			p.next()
+			arr := &arrayExpr{}
+			for !p.isOp("]") {
+				x, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				arr.elems = append(arr.elems, x)
+				if !p.acceptOp(",") {
+					break
+				}
+			}
+			return arr, p.expectOp("]")
+		case "{":
+			if p.isObject() {
+				return p.parseObject()
+			}
+			return p.parseBlock()
+		case "<":
+			p.next()
+			start := p.i
+			depth := 1
+			for depth > 0 && p.peek().kind != tEOF {
+				switch {
+				case p.isOp("<"):
+					depth++
+				case p.isOp(">"):
+					depth--
+				}
+				if depth > 0 {
+					p.next()
+				}
+			}
+			typ := p.text(start)
+			if err := p.expectOp(">"); err != nil {
+				return nil, err
+			}
+			x, err := p.parseUnary()
+			return &castExpr{typ: strings.ReplaceAll(typ, " ", ""), x: x}, err
+		case "->", "<-", "<->":
+			return &idiomExpr{}, nil
+		}
+	case tIdent, tQuoted:
+		n := p.peekN(1)
+		if t.kind == tIdent {
+			switch strings.ToUpper(t.text) {
+			case "TRUE":
+				p.next()
+				return litExpr{true}, nil
+			case "FALSE":
+				p.next()
+				return litExpr{false}, nil
+			case "NULL", "NONE":
+				p.next()
+				return litExpr{nil}, nil
+			case "IF":
+				if !n.isOpTok(":") {
+					p.next()
+					return p.parseIf()
+				}
+			}
+			if n.isOpTok("::") {
+				return p.parseCall()
+			}
+			if n.isOpTok("(") && !n.space {
+				return p.parseCall()
+			}
+		}
+		if n.isOpTok(":") && !n.space {
+			return p.parseRecordID()
+		}
+		p.next()
+		return &idiomExpr{parts: []part{fieldPart{t.text}}, text: t.text}, nil
+	}
+	return nil, p.errorf("unexpected token")
+}
+
+func numberLit(t token) (expr, error) {
+	switch {
+	case t.decimal:
+		return litExpr{models.DecimalString(t.text)}, nil
+	case t.float:
+		f, err := strconv.ParseFloat(t.text, 64)
+		return litExpr{f}, err
+	}
+	n, err := strconv.ParseInt(t.text, 10, 64)
+	if err != nil {
+		f, ferr := strconv.ParseFloat(t.text, 64)
+		return litExpr{f}, ferr
+	}
+	return litExpr{n}, nil
+}
+
+func stringLit(t token) (expr, error) {
+	switch t.prefix {
+	case 'r':
+		toks, err := lex(t.text)
+		if err != nil {
+			return nil, err
+		}
+		sub := &parser{src: t.text, toks: toks}
+		return sub.parseRecordID()
+	case 'd':
+		tm, err := time.Parse(time.RFC3339Nano, t.text)
+		if err != nil {
+			return nil, fmt.Errorf("Parse error: invalid datetime %q", t.text)
+		}
+		return litExpr{tm.UTC()}, nil
+	case 'u':
+		u, err := uuid.FromString(t.text)
+		if err != nil {
+			return nil, fmt.Errorf("Parse error: invalid uuid %q", t.text)
+		}
+		return litExpr{models.UUID{UUID: u}}, nil
+	}
+	return litExpr{t.text}, nil
+}
+
+// isObject decides whether the { at the current position opens an object
+// literal rather than a block.
+func (p *parser) isObject() bool {
+	k, c := p.peekN(1), p.peekN(2)
+	if k.isOpTok("}") {
+		return true
+	}
+	switch k.kind {
+	case tIdent, tQuoted, tString, tNumber:
+		return c.isOpTok(":")
+	}
+	return false
+}
+
+func (p *parser) parseObject() (expr, error) {
+	p.next()
+	obj := &objectExpr{}
+	for !p.isOp("}") {
+		k := p.next()
+		switch k.kind {
+		case tIdent, tQuoted, tString, tNumber:
+		default:
+			return nil, p.errorf("expected an object key")
+		}
+		if err := p.expectOp(":"); err != nil {
+			return nil, err
+		}
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		obj.keys = append(obj.keys, k.text)
+		obj.vals = append(obj.vals, v)
+		if !p.acceptOp(",") {
+			break
+		}
+	}
+	return obj, p.expectOp("}")
+}
+
+func (p *parser) parseBlock() (*blockExpr, error) {
+	if err := p.expectOp("{"); err != nil {
+		return nil, err
+	}
+	stmts, err := p.parseStatements(func() bool { return p.isOp("}") || p.peek().kind == tEOF })
+	if err != nil {
+		return nil, err
+	}
+	return &blockExpr{stmts: stmts}, p.expectOp("}")
+}
+
+func (p *parser) parseIf() (expr, error) {
+	x := &ifExpr{}
+	for {
+		cond, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		var then expr
+		if p.acceptKw("THEN") {
+			if then, err = p.parseValue(); err != nil {
+				return nil, err
+			}
+		} else if then, err = p.parseBlock(); err != nil {
+			return nil, err
+		}
+		x.conds = append(x.conds, cond)
+		x.thens = append(x.thens, then)
+		if p.acceptKw("ELSE", "IF") {
+			continue
+		}
+		if p.acceptKw("ELSE") {
+			if p.isOp("{") {
+				if x.els, err = p.parseBlock(); err != nil {
+					return nil, err
+				}
+			} else if x.els, err = p.parseValue(); err != nil {
+				return nil, err
+			}
+		}
+		p.acceptKw("END")
+		return x, nil
+	}
+}
+
+func (p *parser) parseCall() (expr, error) {
+	name := p.next().text
+	for p.acceptOp("::") {
+		t := p.next()
+		if t.kind != tIdent && t.kind != tQuoted {
+			return nil, p.errorf("expected a function name")
+		}
+		name += "::" + t.text
+	}
+	if err := p.expectOp("("); err != nil {
+		return nil, err
+	}
+	c := &callExpr{name: strings.ToLower(name)}
+	if strings.HasPrefix(c.name, "fn::") {
+		c.name = name
+	}
+	for !p.isOp(")") {
+		x, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		c.args = append(c.args, x)
+		if !p.acceptOp(",") {
+			break
+		}
+	}
+	return c, p.expectOp(")")
+}
+
+// parseRecordID parses table:id, where id is an identifier, integer, array,
+// object, escaped identifier or a generator such as rand().
+func (p *parser) parseRecordID() (expr, error) {
+	table, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectOp(":"); err != nil {
+		return nil, err
+	}
+	if p.isOp("..") && !p.peek().space {
+		return p.parseRange(table, nil, false)
+	}
+	r := &recordExpr{table: table}
+	if t := p.peek(); t.kind == tIdent && p.peekN(1).isOpTok("(") && isKwTok(t, "rand", "ulid", "uuid") {
+		p.i += 2
+		if err := p.expectOp(")"); err != nil {
+			return nil, err
+		}
+		r.gen = strings.ToLower(t.text)
+		return r, nil
+	}
+	if r.id, err = p.recordKey(); err != nil {
+		return nil, err
+	}
+	switch n := p.peekN(1); {
+	case p.isOp(">") && !p.peek().space && n.isOpTok("..") && !n.space:
+		p.next()
+		return p.parseRange(table, r.id, true)
+	case p.isOp("..") && !p.peek().space:
+		return p.parseRange(table, r.id, false)
+	}
+	return r, nil
+}
+
+// recordKey parses the key of a record id.
+func (p *parser) recordKey() (expr, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tIdent, t.kind == tQuoted, t.kind == tDuration:
+		p.next()
+		return litExpr{t.text}, nil
+	case t.kind == tNumber && !t.float && !t.decimal:
+		p.next()
+		n, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return litExpr{n}, nil
+	case t.isOpTok("-"):
+		p.next()
+		n, err := strconv.ParseInt(p.next().text, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return litExpr{-n}, nil
+	case t.isOpTok("["), t.isOpTok("{"):
+		return p.parsePrimary()
+	}
+	return nil, p.errorf("expected a record id")
+}
+
+// parseRange parses the rest of a record range, from the `..` on:
+// table:from..to, where from is exclusive after `>` and to inclusive after
+// `=`; either may be left out.
+func (p *parser) parseRange(table string, from expr, fromExcl bool) (expr, error) {
+	p.next()
+	r := &rangeExpr{table: table, from: from, fromExcl: fromExcl}
+	if p.isOp("=") && !p.peek().space {
+		p.next()
+		r.toIncl = true
+	}
+	t := p.peek()
+	if t.space || !(t.kind == tIdent || t.kind == tQuoted || t.kind == tDuration || t.kind == tNumber ||
+		t.isOpTok("-") || t.isOpTok("[") || t.isOpTok("{")) {
+		return r, nil
+	}
+	var err error
+	r.to, err = p.recordKey()
+	return r, err
+}
+
+// parsePostfix parses the path parts following x: .field, [index],
+// [WHERE cond], .method() and graph traversals.
+func (p *parser) parsePostfix(x expr) (expr, error) {
+	var id *idiomExpr
+	wrap := func() {
+		if id != nil {
+			return
+		}
+		if ie, ok := x.(*idiomExpr); ok {
+			id = ie
+			return
+		}
+		id = &idiomExpr{base: x}
+	}
+	for {
+		t := p.peek()
+		switch {
+		case t.isOpTok("."):
+			n := p.peekN(1)
+			switch {
+			case n.isOpTok("{") && (p.peekN(2).kind == tNumber || p.peekN(2).isOpTok("..")):
+				p.i += 2
+				wrap()
+				r, err := p.parseRecurse()
+				if err != nil {
+					return nil, err
+				}
+				id.parts = append(id.parts, r)
+				continue
+			case n.isOpTok("*"):
+				p.i += 2
+				wrap()
+				id.parts = append(id.parts, allPart{})
+				continue
+			case n.kind == tIdent || n.kind == tQuoted:
+				p.i += 2
+				wrap()
+				if n.kind == tIdent && p.isOp("(") && !p.peek().space {
+					p.next()
+					m := methodPart{name: strings.ToLower(n.text)}
+					for !p.isOp(")") {
+						a, err := p.parseExpr()
+						if err != nil {
+							return nil, err
+						}
+						m.args = append(m.args, a)
+						if !p.acceptOp(",") {
+							break
+						}
+					}
+					if err := p.expectOp(")"); err != nil {
+						return nil, err
+					}
+					id.parts = append(id.parts, m)
+					continue
+				}
+				id.parts = append(id.parts, fieldPart{n.text})
+				continue
+			}
+		case t.isOpTok("[") && !t.space:
+			p.next()
+			wrap()
+			switch {
+			case p.isOp("*") && p.peekN(1).isOpTok("]"):
+				p.i += 2
+				id.parts = append(id.parts, allPart{})
+			case p.isOp("$") && p.peekN(1).isOpTok("]"):
+				p.i += 2
+				id.parts = append(id.parts, lastPart{})
+			case p.isKw("WHERE") || p.isOp("?"):
+				p.next()
+				cond, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				id.parts = append(id.parts, wherePart{cond})
+				if err := p.expectOp("]"); err != nil {
+					return nil, err
+				}
+			default:
+				ix, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				id.parts = append(id.parts, indexPart{ix})
+				if err := p.expectOp("]"); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		case p.noGraph == 0 && (t.isOpTok("->") || t.isOpTok("<-") || t.isOpTok("<->")):
+			p.next()
+			wrap()
+			g, err := p.parseGraph(t.text)
+			if err != nil {
+				return nil, err
+			}
+			id.parts = append(id.parts, g)
+			continue
+		}
+		if id == nil {
+			return x, nil
+		}
+		return id, nil
+	}
+}
+
+// parseRecurse parses a recursive path after its ".{": the depth range,
+// the +collect, +path or +shortest=record instruction and +inclusive, then
+// the repeated path, in parentheses or up to the end of the idiom.
+func (p *parser) parseRecurse() (recursePart, error) {
+	r := recursePart{min: 1, max: maxRecursion}
+	depth := func() (int, error) {
+		n, err := strconv.Atoi(p.next().text)
+		if err != nil || n < 1 || n > maxRecursion {
+			return 0, fmt.Errorf("invalid recursion depth")
+		}
+		return n, nil
+	}
+	var err error
+	if p.peek().kind == tNumber {
+		if r.min, err = depth(); err != nil {
+			return r, err
+		}
+		r.max = r.min
+	}
+	if p.acceptOp("..") {
+		r.max = maxRecursion
+		if p.peek().kind == tNumber {
+			if r.max, err = depth(); err != nil {
+				return r, err
+			}
+		}
+	}
+	for p.acceptOp("+") {
+		name, err := p.ident()
+		if err != nil {
+			return r, err
+		}
+		switch name = strings.ToLower(name); name {
+		case "collect", "path":
+			r.instr = name
+		case "shortest":
+			r.instr = name
+			if err := p.expectOp("="); err != nil {
+				return r, err
+			}
+			if r.target, err = p.parsePrimary(); err != nil {
+				return r, err
+			}
+		case "inclusive":
+			r.inclusive = true
+		default:
+			return r, fmt.Errorf("unknown recursion instruction +%s", name)
+		}
+	}
+	if err := p.expectOp("}"); err != nil {
+		return r, err
+	}
+	paren := p.isOp("(") && !p.peek().space
+	if paren {
+		p.next()
+	}
+	path, err := p.parsePostfix(nil)
+	if err != nil {
+		return r, err
+	}
+	id, ok := path.(*idiomExpr)
+	if !ok {
+		return r, fmt.Errorf("expected a path to recurse")
+	}
+	r.path = id.parts
+	if paren {
+		err = p.expectOp(")")
+	}
+	return r, err
+}
+
+func (p *parser) parseGraph(dir string) (expr, error) {
+	g := graphPart{dir: dir}
+	switch {
+	case p.acceptOp("?"):
+	case p.isOp("("):
+		p.next()
+		for {
+			switch {
+			case p.acceptOp("?"):
+			default:
+				name, err := p.ident()
+				if err != nil {
+					return nil, err
+				}
+				g.tables = append(g.tables, name)
+			}
+			if !p.acceptOp(",") {
+				break
+			}
+		}
+		if p.acceptKw("WHERE") {
+			cond, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			g.cond = cond
+		}
+		if err := p.expectOp(")"); err != nil {
+			return nil, err
+		}
+	default:
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		g.tables = []string{name}
+	}
+	return g, nil
+}
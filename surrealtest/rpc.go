@@ -0,0 +1,523 @@
+package surrealtest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	websocket "github.com/lxzan/gws"
+	"github.com/surrealdb/surrealdb.go/pkg/models"
+	"github.com/surrealdb/surrealdb.go/surrealcbor"
+)
+
+// codec decodes requests; it maps SurrealDB's CBOR tags onto the models
+// types the rest of the server works with.
+var codec = surrealcbor.New()
+
+type request struct {
+	ID      any    `json:"id"`
+	Method  string `json:"method"`
+	Params  []any  `json:"params"`
+	Session any    `json:"session"`
+	Txn     any    `json:"txn"`
+}
+
+// session is one RPC session: the default session of a connection or one
+// created with attach.
+type session struct {
+	conn   *conn
+	ns, db string
+	vars   map[string]any
+	auth   *authState
+}
+
+// conn is one WebSocket connection and its sessions, keyed by session id
+// ("" for the default session).
+type conn struct {
+	socket   *websocket.Conn
+	sessions map[string]*session
+}
+
+func newConn(socket *websocket.Conn) *conn {
+	c := &conn{socket: socket, sessions: map[string]*session{}}
+	c.sessions[""] = c.newSession()
+	return c
+}
+
+func (c *conn) newSession() *session {
+	return &session{conn: c, vars: map[string]any{}}
+}
+
+func (c *conn) send(msg map[string]any) {
+	data, err := cbor.Marshal(wire(msg))
+	if err != nil {
+		return
+	}
+	c.socket.WriteAsync(websocket.OpcodeBinary, data, nil)
+}
+
+// notify sends a live query notification if the change passes the query's
+// WHERE clause, shaping the result by its fields or as a diff.
+func (c *conn) notify(s *Server, n notification) {
+	ex := &executor{srv: s, sess: n.lq.sess, tx: newTxn()}
+	e := &env{ex: ex}
+	if n.lq.where != nil {
+		v, err := ex.eval(e.withDoc(n.result), n.lq.where)
+		if err != nil || !truthy(v) {
+			return
+		}
+	}
+	result := n.result
+	switch doc, _ := n.result.(map[string]any); {
+	case n.lq.diff && n.action == "CREATE":
+		result = diff(nil, doc)
+	case n.lq.diff:
+		result = []any{}
+	case len(n.lq.fields) > 0 && !n.lq.fields[0].all:
+		v, err := ex.projectFields(e.withDoc(doc), n.lq.fields, nil, doc)
+		if err != nil {
+			return
+		}
+		result = v
+	}
+	c.send(map[string]any{"result": map[string]any{
+		"id":     n.lq.id,
+		"action": n.action,
+		"record": n.record,
+		"result": result,
+	}})
+}
+
+type handler struct {
+	websocket.BuiltinEventHandler
+	srv *Server
+}
+
+func (h *handler) OnMessage(socket *websocket.Conn, message *websocket.Message) {
+	// The decoder may alias byte strings into its input, and gws recycles
+	// message buffers, so stored bytes values need their own copy.
+	data := append([]byte(nil), message.Bytes()...)
+	message.Close()
+	h.srv.handle(socket, data)
+}
+
+func (h *handler) OnClose(socket *websocket.Conn, _ error) {
+	s := h.srv
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.conns[socket]
+	delete(s.conns, socket)
+	for id, lq := range s.lives {
+		if lq.conn == c {
+			delete(s.lives, id)
+		}
+	}
+}
+
+func (s *Server) handle(socket *websocket.Conn, data []byte) {
+	var req request
+	if err := codec.Unmarshal(data, &req); err != nil {
+		return
+	}
+	s.mu.Lock()
+	c := s.conns[socket]
+	var result any
+	var err error
+	if c != nil {
+		result, err = s.dispatch(c, &req)
+	}
+	s.mu.Unlock()
+	if c == nil {
+		return
+	}
+	if err != nil {
+		c.send(map[string]any{"id": req.ID, "error": map[string]any{"code": int64(-32000), "message": err.Error()}})
+		return
+	}
+	c.send(map[string]any{"id": req.ID, "result": result})
+}
+
+func key(v any) string {
+	switch x := normalize(v).(type) {
+	case nil:
+		return ""
+	case models.UUID:
+		return x.String()
+	case string:
+		return x
+	default:
+		return render(x)
+	}
+}
+
+func (s *Server) dispatch(c *conn, req *request) (any, error) {
+	params := normalize(req.Params)
+	args, _ := params.([]any)
+	sid := key(req.Session)
+
+	switch req.Method {
+	case "attach":
+		c.sessions[sid] = c.newSession()
+		return nil, nil
+	case "detach":
+		if sid == "" {
+			return nil, fmt.Errorf("The default session can not be detached")
+		}
+		delete(c.sessions, sid)
+		return nil, nil
+	}
+	sess := c.sessions[sid]
+	if sess == nil {
+		return nil, fmt.Errorf("The session '%s' does not exist", sid)
+	}
+
+	switch req.Method {
+	case "ping":
+		return nil, nil
+	case "version":
+		return version, nil
+	case "use":
+		if ns, ok := arg(args, 0).(string); ok {
+			sess.ns = ns
+		}
+		if db, ok := arg(args, 1).(string); ok {
+			sess.db = db
+		}
+		return nil, nil
+	case "let":
+		sess.vars[str(arg(args, 0))] = arg(args, 1)
+		return nil, nil
+	case "unset":
+		delete(sess.vars, str(arg(args, 0)))
+		return nil, nil
+	case "reset":
+		*sess = *c.newSession()
+		return nil, nil
+	case "signin", "signup":
+		return s.signin(sess, req.Method == "signup", arg(args, 0))
+	case "authenticate":
+		return nil, s.authenticate(sess, str(arg(args, 0)))
+	case "invalidate":
+		sess.auth = nil
+		return nil, nil
+	case "begin":
+		id := newUUID()
+		s.txns[id.String()] = newTxn()
+		return id, nil
+	case "commit", "cancel":
+		id := key(arg(args, 0))
+		tx := s.txns[id]
+		if tx == nil {
+			return nil, fmt.Errorf("The transaction '%s' does not exist", id)
+		}
+		delete(s.txns, id)
+		if req.Method == "commit" {
+			s.commit(tx)
+		}
+		return nil, nil
+	}
+
+	if a := sess.auth; a != nil && !a.sessionExp.IsZero() && time.Now().After(a.sessionExp) {
+		return nil, fmt.Errorf("The session has expired")
+	}
+	ex := &executor{srv: s, sess: sess}
+	if id := key(req.Txn); id != "" {
+		if ex.tx = s.txns[id]; ex.tx == nil {
+			return nil, fmt.Errorf("The transaction '%s' does not exist", id)
+		}
+	}
+
+	if req.Method == "query" {
+		stmts, err := parse(str(arg(args, 0)))
+		if err != nil {
+			return nil, err
+		}
+		vars, _ := arg(args, 1).(map[string]any)
+		out := []any{}
+		for _, r := range ex.run(stmts, vars) {
+			res := map[string]any{"status": "OK", "time": r.dur.String(), "result": r.result}
+			if r.err != nil {
+				res["status"], res["result"] = "ERR", r.err.Error()
+			}
+			out = append(out, res)
+		}
+		return out, nil
+	}
+
+	st, err := rpcStatement(req.Method, args)
+	if err != nil {
+		return nil, err
+	}
+	if st == nil {
+		return s.rpcOther(ex, req.Method, args)
+	}
+	if ex.tx != nil {
+		return ex.execTop(&env{ex: ex}, st)
+	}
+	ex.tx = newTxn()
+	v, err := ex.execTop(&env{ex: ex}, st)
+	if err == nil {
+		s.commit(ex.tx)
+	}
+	return v, err
+}
+
+// rpcStatement builds the statement a data RPC method runs, or nil for the
+// methods that are not statements.
+func rpcStatement(method string, args []any) (stmt, error) {
+	what := arg(args, 0)
+	_, record := what.(models.RecordID)
+	if s, ok := what.(string); ok {
+		what = models.Table(s)
+	}
+	target := []expr{litExpr{what}}
+	data := litExpr{arg(args, 1)}
+	switch method {
+	case "select":
+		return &selectStmt{fields: []field{{all: true}}, from: target, only: record}, nil
+	case "create":
+		return &createStmt{only: true, what: target, data: &dataClause{content: data}}, nil
+	case "update", "upsert":
+		// The driver updates a record it is creating with a preset id, so
+		// update on a record creates it when it is missing, as upsert does.
+		d := &dataClause{content: data}
+		if arg(args, 1) == nil {
+			d = nil
+		}
+		return &updateStmt{upsert: record || method == "upsert", only: record, what: target, data: d}, nil
+	case "merge":
+		return &updateStmt{upsert: record, only: record, what: target, data: &dataClause{merge: data}}, nil
+	case "patch":
+		return &updateStmt{only: record, what: target, data: &dataClause{patch: data}}, nil
+	case "delete":
+		return &deleteStmt{only: record, what: target, ret: returnClause{kind: "before"}}, nil
+	case "insert":
+		return &insertStmt{into: litExpr{what}, data: data}, nil
+	case "insert_relation":
+		var into expr
+		if what != nil {
+			into = litExpr{what}
+		}
+		return &insertStmt{relation: true, into: into, data: data}, nil
+	case "relate":
+		_, in := arg(args, 0).(models.RecordID)
+		_, out := arg(args, 2).(models.RecordID)
+		var d *dataClause
+		if arg(args, 3) != nil {
+			d = &dataClause{content: litExpr{arg(args, 3)}}
+		}
+		return &relateStmt{
+			only: in && out,
+			from: litExpr{arg(args, 0)},
+			edge: litExpr{arg(args, 1)},
+			to:   litExpr{arg(args, 2)},
+			data: d,
+		}, nil
+	}
+	return nil, nil
+}
+
+func (s *Server) rpcOther(ex *executor, method string, args []any) (any, error) {
+	switch method {
+	case "info":
+		if r, ok := ex.sess.auth.authValue().(models.RecordID); ok {
+			ex.tx = newTxn()
+			return asValue(ex.fetch(r)), nil
+		}
+		return nil, nil
+	case "live":
+		if ex.tx == nil {
+			ex.tx = newTxn()
+		}
+		diff, _ := arg(args, 1).(bool)
+		what := arg(args, 0)
+		if s, ok := what.(string); ok {
+			what = models.Table(s)
+		}
+		return ex.execLive(&env{ex: ex}, &liveStmt{diff: diff, table: litExpr{what}})
+	case "kill":
+		return nil, s.kill(arg(args, 0))
+	case "run":
+		if ex.tx == nil {
+			ex.tx = newTxn()
+			defer s.commit(ex.tx)
+		}
+		fnArgs, _ := arg(args, 2).([]any)
+		return ex.callFunc(&env{ex: ex}, str(arg(args, 0)), fnArgs)
+	}
+	return nil, fmt.Errorf("Method not found")
+}
+
+// ============================================================================
+// Authentication
+// ============================================================================
+
+var errAuth = fmt.Errorf("There was a problem with authentication")
+
+// credential reads a sign-in field, accepting SurrealDB's short and long
+// spellings in any case.
+func credential(m map[string]any, names ...string) string {
+	for k, v := range m {
+		for _, n := range names {
+			if strings.EqualFold(k, n) {
+				if s, ok := v.(string); ok {
+					return s
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func (s *Server) signin(sess *session, signup bool, data any) (any, error) {
+	m, ok := data.(map[string]any)
+	if !ok {
+		return nil, errAuth
+	}
+	ns := credential(m, "NS", "namespace")
+	db := credential(m, "DB", "database")
+	ac := credential(m, "AC", "access")
+	if ac != "" {
+		return s.recordSignin(sess, signup, ns, db, ac, m)
+	}
+	if signup {
+		return nil, errAuth
+	}
+	user := credential(m, "user", "username")
+	pass := credential(m, "pass", "password")
+
+	var users map[string]*userDef
+	level := "ROOT"
+	switch {
+	case ns != "" && db != "":
+		d, err := s.database(ns, db)
+		if err != nil {
+			return nil, errAuth
+		}
+		users, level = d.users, "DATABASE"
+	case ns != "":
+		n := s.namespace(ns)
+		if n == nil {
+			return nil, errAuth
+		}
+		users, level = n.users, "NAMESPACE"
+	default:
+		users = s.rootUsers
+	}
+	u := users[user]
+	if u == nil || u.hash != hashPassword(pass) {
+		return nil, errAuth
+	}
+	a := &authState{level: level, ns: ns, db: db, user: user, roles: u.roles}
+	return s.issue(sess, a, u.tokenDur, u.sessionDur), nil
+}
+
+// recordSignin runs the SIGNIN or SIGNUP clause of a record access method
+// with the credentials as parameters.
+func (s *Server) recordSignin(sess *session, signup bool, ns, db, ac string, vars map[string]any) (any, error) {
+	d, err := s.database(ns, db)
+	if err != nil {
+		return nil, errAuth
+	}
+	access := d.access[ac]
+	if access == nil {
+		if n := s.namespace(ns); n != nil {
+			access = n.access[ac]
+		}
+	}
+	if access == nil || access.kind != "RECORD" {
+		return nil, errAuth
+	}
+	clause := access.signin
+	if signup {
+		clause = access.signup
+	}
+	if clause == nil {
+		return nil, errAuth
+	}
+	system := &session{ns: ns, db: db, vars: map[string]any{}, auth: &authState{level: "DATABASE", ns: ns, db: db}}
+	ex := &executor{srv: s, sess: system, tx: newTxn()}
+	v, err := ex.eval((&env{ex: ex}).with(vars), clause)
+	if err != nil {
+		return nil, err
+	}
+	s.commit(ex.tx)
+	if arr, ok := v.([]any); ok {
+		v = arg(arr, 0)
+	}
+	if doc, ok := v.(map[string]any); ok {
+		v = doc["id"]
+	}
+	r, ok := v.(models.RecordID)
+	if !ok {
+		return nil, errAuth
+	}
+	a := &authState{level: "RECORD", ns: ns, db: db, access: ac, record: r}
+	return s.issue(sess, a, access.tokenDur, access.sessionDur), nil
+}
+
+// issue signs sess in as a and returns a token that authenticate accepts.
+func (s *Server) issue(sess *session, a *authState, tokenDur, sessionDur time.Duration) string {
+	now := time.Now()
+	if tokenDur > 0 {
+		a.tokenExp = now.Add(tokenDur)
+	}
+	if sessionDur > 0 {
+		a.sessionExp = now.Add(sessionDur)
+	}
+	claims := map[string]any{"iat": now.Unix(), "iss": "SurrealDB", "jti": newUUID().String()}
+	if !a.tokenExp.IsZero() {
+		claims["exp"] = a.tokenExp.Unix()
+	}
+	for k, v := range map[string]string{"NS": a.ns, "DB": a.db, "AC": a.access} {
+		if v != "" {
+			claims[k] = v
+		}
+	}
+	if a.level == "RECORD" {
+		claims["ID"] = renderRecordID(a.record)
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "HS512", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	enc := base64.RawURLEncoding
+	token := enc.EncodeToString(header) + "." + enc.EncodeToString(payload) + "." + enc.EncodeToString([]byte(randomString(32)))
+	s.tokens[token] = a
+	c := *a
+	sess.auth = &c
+	return token
+}
+
+func (s *Server) authenticate(sess *session, token string) error {
+	a := s.tokens[token]
+	if a == nil {
+		return errAuth
+	}
+	if !a.tokenExp.IsZero() && time.Now().After(a.tokenExp) {
+		return fmt.Errorf("The token has expired")
+	}
+	c := *a
+	sess.auth = &c
+	return nil
+}
+
+// ExpireSessions expires every signed-in session and token, as if their
+// durations had run out. Each session's next request fails with "The session
+// has expired" until it signs in again.
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	past := time.Now().Add(-time.Second)
+	for _, a := range s.tokens {
+		a.tokenExp = past
+	}
+	for _, c := range s.conns {
+		for _, sess := range c.sessions {
+			if sess.auth != nil {
+				sess.auth.sessionExp = past
+			}
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package surrealtest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type tableDef struct {
+	name       string
+	kind       string // ANY, NORMAL or RELATION
+	in, out    []string
+	schemafull bool
+	drop       bool
+	changefeed string
+	comment    string
+	perms      permissions
+}
+
+func (d *tableDef) text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "DEFINE TABLE %s TYPE %s", escapeIdent(d.name), d.kind)
+	if d.kind == "RELATION" {
+		if len(d.in) > 0 {
+			b.WriteString(" IN " + strings.Join(d.in, " | "))
+		}
+		if len(d.out) > 0 {
+			b.WriteString(" OUT " + strings.Join(d.out, " | "))
+		}
+	}
+	if d.drop {
+		b.WriteString(" DROP")
+	}
+	if d.schemafull {
+		b.WriteString(" SCHEMAFULL")
+	} else {
+		b.WriteString(" SCHEMALESS")
+	}
+	if d.changefeed != "" {
+		b.WriteString(" CHANGEFEED " + d.changefeed)
+	}
+	if d.comment != "" {
+		b.WriteString(" COMMENT " + render(d.comment))
+	}
+	b.WriteString(" " + d.perms.text())
+	return b.String()
+}
+
+// perm is one FOR clause of a PERMISSIONS definition.
+type perm struct {
+	full bool
+	cond expr
+	text string
+}
+
+// permissions maps select, create, update and delete to their clause. A
+// missing entry means NONE.
+type permissions map[string]perm
+
+var permOps = []string{"select", "create", "update", "delete"}
+
+func fullPermissions() permissions {
+	p := permissions{}
+	for _, op := range permOps {
+		p[op] = perm{full: true}
+	}
+	return p
+}
+
+func (p permissions) text() string {
+	full, none := true, true
+	for _, op := range permOps {
+		e := p[op]
+		full = full && e.full
+		none = none && !e.full && e.cond == nil
+	}
+	switch {
+	case full:
+		return "PERMISSIONS FULL"
+	case none:
+		return "PERMISSIONS NONE"
+	}
+	parts := make([]string, 0, len(permOps))
+	for _, op := range permOps {
+		e := p[op]
+		switch {
+		case e.full:
+			parts = append(parts, "FOR "+op+" FULL")
+		case e.cond != nil:
+			parts = append(parts, "FOR "+op+" WHERE "+e.text)
+		default:
+			parts = append(parts, "FOR "+op+" NONE")
+		}
+	}
+	return "PERMISSIONS " + strings.Join(parts, ", ")
+}
+
+type fieldDef struct {
+	name  string
+	table string
+	// path is the field path split on dots; only top-level fields carry
+	// behaviour.
+	path       []string
+	flexible   bool
+	typ        string
+	def        expr
+	defText    string
+	defAlways  bool
+	value      expr
+	valueText  string
+	assert     expr
+	assertText string
+	readonly   bool
+	reference  string
+	permsText  string
+	comment    string
+}
+
+func (d *fieldDef) text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "DEFINE FIELD %s ON %s", escapeFieldName(d.name), escapeIdent(d.table))
+	if d.flexible {
+		b.WriteString(" FLEXIBLE")
+	}
+	if d.typ != "" {
+		b.WriteString(" TYPE " + d.typ)
+	}
+	if d.reference != "" {
+		b.WriteString(" " + d.reference)
+	}
+	if d.def != nil {
+		b.WriteString(" DEFAULT ")
+		if d.defAlways {
+			b.WriteString("ALWAYS ")
+		}
+		b.WriteString(d.defText)
+	}
+	if d.readonly {
+		b.WriteString(" READONLY")
+	}
+	if d.value != nil {
+		b.WriteString(" VALUE " + d.valueText)
+	}
+	if d.assert != nil {
+		b.WriteString(" ASSERT " + d.assertText)
+	}
+	if d.comment != "" {
+		b.WriteString(" COMMENT " + render(d.comment))
+	}
+	if d.permsText != "" {
+		b.WriteString(" PERMISSIONS " + d.permsText)
+	} else {
+		b.WriteString(" PERMISSIONS FULL")
+	}
+	return b.String()
+}
+
+// escapeFieldName escapes each part of a dotted field name.
+func escapeFieldName(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		base, all := strings.CutSuffix(part, "[*]")
+		if base != "*" {
+			base = escapeIdent(base)
+		}
+		if all {
+			base += "[*]"
+		}
+		parts[i] = base
+	}
+	return strings.Join(parts, ".")
+}
+
+type indexDef struct {
+	name, table string
+	fields      []expr
+	fieldsText  string
+	unique      bool
+	// rest is the raw index kind and options, e.g. SEARCH ANALYZER ... BM25.
+	rest string
+}
+
+func (d *indexDef) text() string {
+	s := fmt.Sprintf("DEFINE INDEX %s ON %s FIELDS %s", escapeIdent(d.name), escapeIdent(d.table), d.fieldsText)
+	if d.unique {
+		s += " UNIQUE"
+	}
+	if d.rest != "" {
+		s += " " + d.rest
+	}
+	return s
+}
+
+type eventDef struct {
+	name, table string
+	when        expr
+	whenText    string
+	then        []stmt
+	thenText    string
+	text        string
+}
+
+type userDef struct {
+	name       string
+	level      string // ROOT, NAMESPACE or DATABASE
+	hash       string
+	roles      []string
+	tokenDur   time.Duration
+	sessionDur time.Duration
+	comment    string
+}
+
+func (d *userDef) text() string {
+	s := fmt.Sprintf("DEFINE USER %s ON %s PASSHASH %s ROLES %s", escapeIdent(d.name), d.level,
+		render(d.hash), strings.ToUpper(strings.Join(d.roles, ", ")))
+	s += fmt.Sprintf(" DURATION FOR TOKEN %s, FOR SESSION ", formatDuration(d.tokenDur))
+	if d.sessionDur > 0 {
+		s += formatDuration(d.sessionDur)
+	} else {
+		s += "NONE"
+	}
+	if d.comment != "" {
+		s += " COMMENT " + render(d.comment)
+	}
+	return s
+}
+
+type accessDef struct {
+	name, level string
+	kind        string // RECORD, JWT or BEARER
+	signup      expr
+	signin      expr
+	tokenDur    time.Duration
+	sessionDur  time.Duration
+	text        string
+}
+
+type paramDef struct {
+	name  string
+	value expr
+	text  string
+}
+
+type funcDef struct {
+	name string
+	args []string
+	body *blockExpr
+	text string
+}
+
+type otherDef struct {
+	kind, name, text string
+}
+
+// Default token and session durations, as in SurrealDB.
+const (
+	defaultTokenDuration = time.Hour
+)
@@ -0,0 +1,127 @@
+// Package surrealtest provides an in-memory SurrealDB for tests.
+//
+// Server speaks the CBOR WebSocket RPC protocol on a loopback port and
+// understands enough SurrealQL to back the surrealdb-gorm driver: record
+// CRUD, graph edges, schema definitions, INFO, transactions, live queries
+// and authentication. Nothing is persisted; every Server starts empty.
+//
+//	srv := surrealtest.New(t)
+//	db, err := gorm.Open(surrealdb.Open(srv.DSN()), &gorm.Config{})
+//
+// It is a test double, not a database: statements outside the supported
+// subset return an error rather than silently misbehaving.
+package surrealtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	websocket "github.com/lxzan/gws"
+)
+
+// Default root credentials and the namespace and database of DSN.
+const (
+	RootUser     = "root"
+	RootPassword = "root"
+	Namespace    = "test"
+	Database     = "test"
+)
+
+// version is what the version RPC and /version report.
+const version = "surrealdb-3.0.0"
+
+// Server is an in-memory SurrealDB reachable over WebSocket RPC.
+type Server struct {
+	// mu serializes every request; the data below is only touched with it
+	// held.
+	mu         sync.Mutex
+	namespaces map[string]*namespace
+	rootUsers  map[string]*userDef
+	tokens     map[string]*authState
+	txns       map[string]*txn
+	lives      map[string]*liveQuery
+	conns      map[*websocket.Conn]*conn
+
+	http     *httptest.Server
+	upgrader *websocket.Upgrader
+}
+
+// NewServer starts a Server on a loopback port. Call Close when done.
+func NewServer() *Server {
+	s := &Server{
+		namespaces: map[string]*namespace{},
+		rootUsers: map[string]*userDef{
+			RootUser: {name: RootUser, level: "ROOT", hash: hashPassword(RootPassword), roles: []string{"owner"}, tokenDur: defaultTokenDuration},
+		},
+		tokens: map[string]*authState{},
+		txns:   map[string]*txn{},
+		lives:  map[string]*liveQuery{},
+		conns:  map[*websocket.Conn]*conn{},
+	}
+	s.upgrader = websocket.NewUpgrader(&handler{srv: s}, &websocket.ServerOption{SubProtocols: []string{"cbor"}})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(http.ResponseWriter, *http.Request) {})
+	mux.HandleFunc("/version", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(version))
+	})
+	mux.HandleFunc("/rpc", func(w http.ResponseWriter, r *http.Request) {
+		socket, err := s.upgrader.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[socket] = newConn(socket)
+		s.mu.Unlock()
+		go socket.ReadLoop()
+	})
+	s.http = httptest.NewServer(mux)
+	return s
+}
+
+// New starts a Server that is closed when the test ends.
+func New(tb testing.TB) *Server {
+	tb.Helper()
+	s := NewServer()
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// URL is the server's WebSocket RPC endpoint, ws://127.0.0.1:port/rpc.
+func (s *Server) URL() string {
+	return strings.Replace(s.http.URL, "http://", "ws://", 1) + "/rpc"
+}
+
+// DSN is a driver DSN for the test namespace and database, signed in as root.
+func (s *Server) DSN() string {
+	q := url.Values{}
+	q.Set("namespace", Namespace)
+	q.Set("database", Database)
+	q.Set("username", RootUser)
+	q.Set("password", RootPassword)
+	return s.URL() + "?" + q.Encode()
+}
+
+// DropConnections closes every open WebSocket, as a server restart would,
+// while keeping the data. Clients that reconnect find it intact.
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	sockets := make([]*websocket.Conn, 0, len(s.conns))
+	for socket := range s.conns {
+		sockets = append(sockets, socket)
+	}
+	s.mu.Unlock()
+	for _, socket := range sockets {
+		socket.NetConn().Close()
+	}
+}
+
+// Close drops all connections and stops the server.
+func (s *Server) Close() {
+	s.DropConnections()
+	s.http.Close()
+}
@@ -0,0 +1,227 @@
+package surrealtest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	surrealdb "github.com/dailaim/surrealdb-gorm"
+	"github.com/dailaim/surrealdb-gorm/models"
+	"github.com/dailaim/surrealdb-gorm/surrealtest"
+)
+
+type Author struct {
+	models.BaseModel
+	Name  string `json:"name" gorm:"not null"`
+	Email string `json:"email" gorm:"uniqueIndex"`
+	Age   int    `json:"age"`
+}
+
+func open(t *testing.T) (*surrealtest.Server, *gorm.DB) {
+	t.Helper()
+	srv := surrealtest.New(t)
+	db, err := gorm.Open(surrealdb.Open(srv.DSN()), &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	require.NoError(t, db.AutoMigrate(&Author{}))
+	return srv, db
+}
+
+func TestCRUD(t *testing.T) {
+	_, db := open(t)
+
+	ada := Author{Name: "Ada", Email: "ada@example.com", Age: 36}
+	require.NoError(t, db.Create(&ada).Error)
+	require.NotNil(t, ada.ID)
+	require.NoError(t, db.Create(&Author{Name: "Alan", Email: "alan@example.com", Age: 41}).Error)
+
+	var found Author
+	require.NoError(t, db.First(&found, "id = ?", ada.ID).Error)
+	require.Equal(t, "Ada", found.Name)
+
+	var older []Author
+	require.NoError(t, db.Where("age > ?", 40).Order("name").Find(&older).Error)
+	require.Len(t, older, 1)
+	require.Equal(t, "Alan", older[0].Name)
+
+	require.NoError(t, db.Model(&found).Update("age", 37).Error)
+	require.NoError(t, db.First(&found, "id = ?", ada.ID).Error)
+	require.Equal(t, 37, found.Age)
+
+	var count int64
+	require.NoError(t, db.Model(&Author{}).Count(&count).Error)
+	require.EqualValues(t, 2, count)
+
+	require.NoError(t, db.Delete(&found).Error)
+	require.NoError(t, db.Model(&Author{}).Count(&count).Error)
+	require.EqualValues(t, 1, count)
+}
+
+func TestSchemaEnforced(t *testing.T) {
+	_, db := open(t)
+
+	require.NoError(t, db.Create(&Author{Name: "Ada", Email: "ada@example.com"}).Error)
+	err := db.Create(&Author{Name: "Imposter", Email: "ada@example.com"}).Error
+	require.ErrorContains(t, err, "already contains")
+
+	require.True(t, db.Migrator().HasTable(&Author{}))
+	require.True(t, db.Migrator().HasColumn(&Author{}, "email"))
+}
+
+func TestTransactionRollback(t *testing.T) {
+	_, db := open(t)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		require.NoError(t, tx.Create(&Author{Name: "Ada", Email: "ada@example.com"}).Error)
+		return gorm.ErrInvalidData
+	})
+	require.ErrorIs(t, err, gorm.ErrInvalidData)
+
+	var count int64
+	require.NoError(t, db.Model(&Author{}).Count(&count).Error)
+	require.Zero(t, count)
+}
+
+func TestQueryStatements(t *testing.T) {
+	_, db := open(t)
+
+	require.NoError(t, db.Exec(`
+		CREATE person:one SET name = 'One', tags = ['a', 'b'];
+		CREATE person:two SET name = 'Two', tags = ['b'];
+		RELATE person:one->knows->person:two SET since = 2020;
+	`).Error)
+
+	var people []struct {
+		Name   string `json:"name"`
+		Friend string `json:"friend"`
+	}
+	require.NoError(t, db.Raw("SELECT name, array::first(->knows->person.name) AS friend FROM person ORDER BY name").Scan(&people).Error)
+	require.Len(t, people, 2)
+	require.Equal(t, "One", people[0].Name)
+	require.Equal(t, "Two", people[0].Friend)
+	require.Empty(t, people[1].Friend)
+
+	var linked []map[string]interface{}
+	require.NoError(t, db.Raw("SELECT name, ->knows->person.*.name AS friends, (SELECT VALUE name FROM $parent.id WHERE name = 'One') AS filtered FROM person ORDER BY name").Scan(&linked).Error)
+	require.Len(t, linked, 2)
+	require.JSONEq(t, `["Two"]`, string(linked[0]["friends"].([]byte)))
+	require.JSONEq(t, `["One"]`, string(linked[0]["filtered"].([]byte)))
+	require.JSONEq(t, `[]`, string(linked[1]["filtered"].([]byte)))
+
+	var groups []struct {
+		Tags string `json:"tags"`
+		N    int    `json:"n"`
+	}
+	require.NoError(t, db.Raw("SELECT tags, count() AS n FROM person SPLIT tags GROUP BY tags ORDER BY tags").Scan(&groups).Error)
+	require.Len(t, groups, 2)
+	require.Equal(t, "b", groups[1].Tags)
+	require.Equal(t, 2, groups[1].N)
+
+	// record ranges, and keys lexed like numbers with an exponent
+	require.NoError(t, db.Exec("CREATE person:6e26zg SET name = 'Exp'; CREATE person:1 SET name = 'N1'; CREATE person:2 SET name = 'N2'").Error)
+	for sql, want := range map[string][]string{
+		"SELECT VALUE name FROM person:..":               {"N1", "N2", "Exp", "One", "Two"},
+		"SELECT VALUE name FROM person:1>..":             {"N2", "Exp", "One", "Two"},
+		"SELECT VALUE name FROM person:1..=2":            {"N1", "N2"},
+		"SELECT VALUE name FROM person:⟨one⟩>.. LIMIT 1": {"Two"},
+		"SELECT VALUE name FROM person:6e26zg":           {"Exp"},
+	} {
+		var names []string
+		require.NoError(t, db.Raw(sql).Scan(&names).Error, sql)
+		require.Equal(t, want, names, sql)
+	}
+}
+
+func TestRecursivePaths(t *testing.T) {
+	_, db := open(t)
+	require.NoError(t, db.Exec(`
+		CREATE person:you, person:friend1, person:friend2, person:acq1, person:acq2, person:star;
+		RELATE person:you->knows->person:friend1;
+		RELATE person:you->knows->person:friend2;
+		RELATE person:friend1->knows->person:friend2;
+		RELATE person:friend2->knows->person:acq1;
+		RELATE person:friend2->knows->person:acq2;
+		RELATE person:acq2->knows->person:star;
+		RELATE person:star->knows->person:you;
+	`).Error)
+
+	ids := func(from string) []string {
+		var keys []string
+		require.NoError(t, db.Raw("SELECT VALUE record::id(id) FROM "+from).Scan(&keys).Error, from)
+		return keys
+	}
+	require.ElementsMatch(t, []string{"friend1", "friend2"},
+		ids("person:you.{1+collect}->knows->person"))
+	require.ElementsMatch(t, []string{"acq1", "acq2", "star"},
+		ids("person:you.{2..3+collect}(->knows->person)"))
+	require.Len(t, ids("person:you.{..+collect+inclusive}(->knows->person)"), 6)
+	require.Equal(t, []string{"friend2", "acq2", "star"},
+		ids("person:you.{..+shortest=person:star}(->knows->person)"))
+	require.Equal(t, []string{"you", "friend2", "acq2", "star"},
+		ids("person:you.{..+shortest=person:star+inclusive}->knows->person"))
+	require.Empty(t, ids("person:you.{..2+shortest=person:star}->knows->person"))
+	require.ElementsMatch(t, []string{"friend2", "acq1", "acq2"},
+		ids("person:you.{2}(->knows->person)"))
+}
+
+func TestLiveQuery(t *testing.T) {
+	_, db := open(t)
+
+	live, err := surrealdb.NewLiveQuery(db, "authors", false)
+	require.NoError(t, err)
+	notifications, err := live.Notifications()
+	require.NoError(t, err)
+
+	require.NoError(t, db.Create(&Author{Name: "Ada", Email: "ada@example.com"}).Error)
+	select {
+	case n := <-notifications:
+		require.EqualValues(t, "CREATE", n.Action)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no live notification")
+	}
+	require.NoError(t, live.Kill())
+}
+
+func TestDropConnectionsKeepsData(t *testing.T) {
+	srv := surrealtest.New(t)
+	reconnected := make(chan struct{}, 1)
+	db, err := gorm.Open(&surrealdb.Dialector{
+		DSN:               srv.DSN(),
+		ReconnectInterval: 50 * time.Millisecond,
+		OnReconnect: func(string) {
+			select {
+			case reconnected <- struct{}{}:
+			default:
+			}
+		},
+	}, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	require.NoError(t, db.AutoMigrate(&Author{}))
+	require.NoError(t, db.Create(&Author{Name: "Ada", Email: "ada@example.com"}).Error)
+
+	srv.DropConnections()
+	select {
+	case <-reconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("driver did not reconnect")
+	}
+
+	var count int64
+	require.NoError(t, db.Model(&Author{}).Count(&count).Error)
+	require.EqualValues(t, 1, count)
+}
@@ -0,0 +1,374 @@
+package surrealtest
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/surrealdb/surrealdb.go/pkg/models"
+)
+
+type namespace struct {
+	name   string
+	dbs    map[string]*database
+	users  map[string]*userDef
+	access map[string]*accessDef
+}
+
+func newNamespace(name string) *namespace {
+	return &namespace{
+		name:   name,
+		dbs:    map[string]*database{},
+		users:  map[string]*userDef{},
+		access: map[string]*accessDef{},
+	}
+}
+
+type database struct {
+	name      string
+	tables    map[string]*table
+	users     map[string]*userDef
+	access    map[string]*accessDef
+	params    map[string]*paramDef
+	paramVals map[string]any
+	functions map[string]*funcDef
+	// others holds the recorded-only definitions keyed by kind and name.
+	others map[string]*otherDef
+}
+
+func newDatabase(name string) *database {
+	return &database{
+		name:      name,
+		tables:    map[string]*table{},
+		users:     map[string]*userDef{},
+		access:    map[string]*accessDef{},
+		params:    map[string]*paramDef{},
+		paramVals: map[string]any{},
+		functions: map[string]*funcDef{},
+		others:    map[string]*otherDef{},
+	}
+}
+
+// table holds one table's definitions and records. Records are keyed by
+// valueKey of their id and are never mutated once stored.
+type table struct {
+	name    string
+	def     *tableDef // nil for a table created implicitly by a write
+	fields  []*fieldDef
+	indexes []*indexDef
+	events  []*eventDef
+	records map[string]map[string]any
+
+	// Transaction bookkeeping for a copy-on-write clone: base is the table
+	// it was cloned from (nil when created inside the transaction), dirty
+	// the record keys written and defsDirty whether definitions changed.
+	base      *table
+	dirty     map[string]bool
+	defsDirty bool
+}
+
+func newTable(name string) *table {
+	return &table{name: name, records: map[string]map[string]any{}}
+}
+
+func (t *table) clone() *table {
+	c := &table{
+		name:    t.name,
+		def:     t.def,
+		fields:  append([]*fieldDef(nil), t.fields...),
+		indexes: append([]*indexDef(nil), t.indexes...),
+		events:  append([]*eventDef(nil), t.events...),
+		records: make(map[string]map[string]any, len(t.records)),
+		base:    t,
+		dirty:   map[string]bool{},
+	}
+	for k, v := range t.records {
+		c.records[k] = v
+	}
+	return c
+}
+
+func (t *table) isRelation() bool { return t.def != nil && t.def.kind == "RELATION" }
+
+func (t *table) schemafull() bool { return t.def != nil && t.def.schemafull }
+
+func (t *table) get(id any) map[string]any { return t.records[valueKey(id)] }
+
+func (t *table) put(doc map[string]any) {
+	k := valueKey(doc["id"].(models.RecordID).ID)
+	t.records[k] = doc
+	if t.dirty != nil {
+		t.dirty[k] = true
+	}
+}
+
+func (t *table) remove(id any) {
+	k := valueKey(id)
+	delete(t.records, k)
+	if t.dirty != nil {
+		t.dirty[k] = true
+	}
+}
+
+// scan returns the table's records ordered by id.
+func (t *table) scan() []map[string]any {
+	out := make([]map[string]any, 0, len(t.records))
+	for _, doc := range t.records {
+		out = append(out, doc)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return compareValues(out[i]["id"], out[j]["id"]) < 0
+	})
+	return out
+}
+
+func (t *table) field(name string) *fieldDef {
+	for _, f := range t.fields {
+		if f.name == name || len(f.path) == 1 && f.path[0] == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func (t *table) setField(d *fieldDef) {
+	for i, f := range t.fields {
+		if f.name == d.name {
+			t.fields[i] = d
+			return
+		}
+	}
+	t.fields = append(t.fields, d)
+}
+
+func (t *table) index(name string) int {
+	for i, x := range t.indexes {
+		if x.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (t *table) event(name string) int {
+	for i, x := range t.events {
+		if x.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// txn is a transaction over one server. Tables are cloned on first write and
+// merged back on commit, so an open transaction reads its own writes while
+// other sessions keep seeing the committed state. Definitions outside tables
+// (namespaces, users, functions, ...) apply immediately.
+type txn struct {
+	tables map[*database]map[string]*table
+	// removed marks tables dropped inside the transaction.
+	removed map[*database]map[string]bool
+	// notes are live query notifications held back until commit.
+	notes []notification
+	done  bool
+}
+
+func newTxn() *txn {
+	return &txn{tables: map[*database]map[string]*table{}, removed: map[*database]map[string]bool{}}
+}
+
+// table returns the table as this transaction sees it, or nil.
+func (tx *txn) table(db *database, name string) *table {
+	if t, ok := tx.tables[db][name]; ok {
+		return t
+	}
+	if tx.removed[db][name] {
+		return nil
+	}
+	return db.tables[name]
+}
+
+// writable returns a clone of the table that this transaction may write,
+// creating the table when create is set.
+func (tx *txn) writable(db *database, name string, create bool) *table {
+	if t, ok := tx.tables[db][name]; ok {
+		return t
+	}
+	if tx.tables[db] == nil {
+		tx.tables[db] = map[string]*table{}
+	}
+	var t *table
+	if base := tx.table(db, name); base != nil {
+		t = base.clone()
+	} else if create {
+		t = newTable(name)
+		t.dirty = map[string]bool{}
+		t.defsDirty = true
+	} else {
+		return nil
+	}
+	tx.tables[db][name] = t
+	delete(tx.removed[db], name)
+	return t
+}
+
+// drop removes a table inside the transaction.
+func (tx *txn) drop(db *database, name string) {
+	delete(tx.tables[db], name)
+	if tx.removed[db] == nil {
+		tx.removed[db] = map[string]bool{}
+	}
+	tx.removed[db][name] = true
+}
+
+// tableNames lists the tables of db visible to the transaction, sorted.
+func (tx *txn) tableNames(db *database) []string {
+	seen := map[string]bool{}
+	for name := range db.tables {
+		if !tx.removed[db][name] {
+			seen[name] = true
+		}
+	}
+	for name := range tx.tables[db] {
+		seen[name] = true
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// commit merges the transaction into the committed state. The caller holds
+// the server lock.
+func (tx *txn) commit() {
+	for db, names := range tx.removed {
+		for name := range names {
+			delete(db.tables, name)
+		}
+	}
+	for db, tables := range tx.tables {
+		for name, t := range tables {
+			base := db.tables[name]
+			if t.base == nil || base == nil || base != t.base && t.defsDirty {
+				t.base, t.dirty, t.defsDirty = nil, nil, false
+				db.tables[name] = t
+				continue
+			}
+			for k := range t.dirty {
+				if doc, ok := t.records[k]; ok {
+					base.records[k] = doc
+				} else {
+					delete(base.records, k)
+				}
+			}
+			if t.defsDirty {
+				base.def, base.fields, base.indexes, base.events = t.def, t.fields, t.indexes, t.events
+			}
+		}
+	}
+	tx.done = true
+}
+
+// ============================================================================
+// Authentication state
+// ============================================================================
+
+// authState is who a session is signed in as.
+type authState struct {
+	level  string // "", ROOT, NAMESPACE, DATABASE or RECORD
+	ns, db string
+	user   string
+	access string
+	record models.RecordID
+	roles  []string
+	// tokenExp is when the token expires; sessionExp when the session does.
+	tokenExp   time.Time
+	sessionExp time.Time
+}
+
+func (a *authState) isSystem() bool {
+	return a != nil && a.level != "" && a.level != "RECORD"
+}
+
+// authValue is $auth: the signed-in record, or NONE.
+func (a *authState) authValue() any {
+	if a != nil && a.level == "RECORD" {
+		return a.record
+	}
+	return nil
+}
+
+// ============================================================================
+// Live queries
+// ============================================================================
+
+type liveQuery struct {
+	id     models.UUID
+	conn   *conn
+	ns, db string
+	table  string
+	diff   bool
+	fields []field
+	where  expr
+	sess   *session
+}
+
+type notification struct {
+	lq     *liveQuery
+	action string
+	result any
+	record models.RecordID
+}
+
+// notify queues notifications for the live queries watching a change to
+// table in ns/db. before and after are nil for a create or delete.
+func (s *Server) liveChanges(tx *txn, ns, db, tbl string, id models.RecordID, before, after map[string]any) {
+	for _, lq := range s.lives {
+		if lq.ns != ns || lq.db != db || lq.table != tbl {
+			continue
+		}
+		action := "UPDATE"
+		doc := after
+		switch {
+		case before == nil:
+			action = "CREATE"
+		case after == nil:
+			action = "DELETE"
+			doc = before
+		}
+		tx.notes = append(tx.notes, notification{lq: lq, action: action, result: doc, record: id})
+	}
+}
+
+func (s *Server) deliver(tx *txn) {
+	for _, n := range tx.notes {
+		n.lq.conn.notify(s, n)
+	}
+	tx.notes = nil
+}
+
+func (s *Server) namespace(name string) *namespace {
+	if ns, ok := s.namespaces[name]; ok {
+		return ns
+	}
+	return nil
+}
+
+func (s *Server) database(ns, db string) (*database, error) {
+	if ns == "" {
+		return nil, fmt.Errorf("Specify a namespace to use")
+	}
+	if db == "" {
+		return nil, fmt.Errorf("Specify a database to use")
+	}
+	n := s.namespace(ns)
+	if n == nil {
+		return nil, fmt.Errorf("The namespace '%s' does not exist", ns)
+	}
+	d, ok := n.dbs[db]
+	if !ok {
+		return nil, fmt.Errorf("The database '%s' does not exist", db)
+	}
+	return d, nil
+}
@@ -0,0 +1,594 @@
+package surrealtest
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/gofrs/uuid"
+	"github.com/surrealdb/surrealdb.go/pkg/models"
+)
+
+// Values inside the server are plain Go values: nil (NONE and NULL alike),
+// bool, int64, float64, models.DecimalString, string, time.Time,
+// time.Duration, models.UUID, models.RecordID, models.Table, []byte, []any
+// and map[string]any. normalize converts whatever the CBOR decoder produced
+// into that set.
+func normalize(v any) any {
+	switch x := v.(type) {
+	case nil, bool, string, int64, float64, time.Duration, []byte, models.Table, models.DecimalString:
+		return x
+	case models.CustomNil, *models.CustomNil:
+		return nil
+	case int:
+		return int64(x)
+	case int8:
+		return int64(x)
+	case int16:
+		return int64(x)
+	case int32:
+		return int64(x)
+	case uint:
+		return normalize(uint64(x))
+	case uint8:
+		return int64(x)
+	case uint16:
+		return int64(x)
+	case uint32:
+		return int64(x)
+	case uint64:
+		if x > math.MaxInt64 {
+			return float64(x)
+		}
+		return int64(x)
+	case float32:
+		return float64(x)
+	case time.Time:
+		return x.UTC()
+	case models.CustomDateTime:
+		return x.Time.UTC()
+	case *models.CustomDateTime:
+		if x == nil {
+			return nil
+		}
+		return x.Time.UTC()
+	case models.CustomDuration:
+		return x.Duration
+	case *models.CustomDuration:
+		if x == nil {
+			return nil
+		}
+		return x.Duration
+	case models.CustomDurationString:
+		d, err := parseDuration(string(x))
+		if err != nil {
+			return string(x)
+		}
+		return d
+	case models.UUID:
+		return x
+	case *models.UUID:
+		if x == nil {
+			return nil
+		}
+		return *x
+	case models.UUIDString:
+		u, err := uuid.FromString(string(x))
+		if err != nil {
+			return string(x)
+		}
+		return models.UUID{UUID: u}
+	case models.RecordID:
+		return models.RecordID{Table: x.Table, ID: normalize(x.ID)}
+	case *models.RecordID:
+		if x == nil {
+			return nil
+		}
+		return models.RecordID{Table: x.Table, ID: normalize(x.ID)}
+	case []any:
+		out := make([]any, len(x))
+		for i, e := range x {
+			out[i] = normalize(e)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(x))
+		for k, e := range x {
+			if e = normalize(e); e != nil {
+				out[k] = e
+			}
+		}
+		return out
+	case map[any]any:
+		out := make(map[string]any, len(x))
+		for k, e := range x {
+			if e = normalize(e); e != nil {
+				out[fmt.Sprint(k)] = e
+			}
+		}
+		return out
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return nil
+		}
+		return normalize(rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		out := make([]any, rv.Len())
+		for i := range out {
+			out[i] = normalize(rv.Index(i).Interface())
+		}
+		return out
+	case reflect.Map:
+		out := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			if e := normalize(iter.Value().Interface()); e != nil {
+				out[fmt.Sprint(iter.Key().Interface())] = e
+			}
+		}
+		return out
+	case reflect.String:
+		return rv.String()
+	}
+	return v
+}
+
+// wire converts a server value into what is sent back over CBOR, using the
+// tags documented for the SurrealDB CBOR protocol.
+func wire(v any) any {
+	switch x := v.(type) {
+	case time.Time:
+		return cbor.Tag{Number: models.TagCustomDatetime, Content: []int64{x.Unix(), int64(x.Nanosecond())}}
+	case time.Duration:
+		return cbor.Tag{Number: models.TagCustomDuration, Content: []int64{int64(x / time.Second), int64(x % time.Second)}}
+	case models.UUID:
+		return cbor.Tag{Number: models.TagSpecBinaryUUID, Content: x.Bytes()}
+	case models.RecordID:
+		return cbor.Tag{Number: models.TagRecordID, Content: []any{x.Table, wire(x.ID)}}
+	case models.Table:
+		return cbor.Tag{Number: models.TagTable, Content: string(x)}
+	case models.DecimalString:
+		return cbor.Tag{Number: models.TagStringDecimal, Content: string(x)}
+	case []any:
+		out := make([]any, len(x))
+		for i, e := range x {
+			out[i] = wire(e)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(x))
+		for k, e := range x {
+			out[k] = wire(e)
+		}
+		return out
+	}
+	return v
+}
+
+// deepCopy copies the arrays and objects of v so that stored documents are
+// never mutated in place.
+func deepCopy(v any) any {
+	switch x := v.(type) {
+	case []any:
+		out := make([]any, len(x))
+		for i, e := range x {
+			out[i] = deepCopy(e)
+		}
+		return out
+	case map[string]any:
+		return copyDoc(x)
+	}
+	return v
+}
+
+func copyDoc(doc map[string]any) map[string]any {
+	out := make(map[string]any, len(doc))
+	for k, e := range doc {
+		out[k] = deepCopy(e)
+	}
+	return out
+}
+
+func isNumber(v any) bool {
+	switch v.(type) {
+	case int64, float64, models.DecimalString:
+		return true
+	}
+	return false
+}
+
+func toFloat(v any) (float64, bool) {
+	switch x := v.(type) {
+	case int64:
+		return float64(x), true
+	case float64:
+		return x, true
+	case models.DecimalString:
+		f, err := strconv.ParseFloat(string(x), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toInt(v any) (int64, bool) {
+	switch x := v.(type) {
+	case int64:
+		return x, true
+	case float64:
+		return int64(x), true
+	case models.DecimalString:
+		f, err := strconv.ParseFloat(string(x), 64)
+		return int64(f), err == nil
+	}
+	return 0, false
+}
+
+func truthy(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case int64:
+		return x != 0
+	case float64:
+		return x != 0
+	case models.DecimalString:
+		f, _ := toFloat(x)
+		return f != 0
+	case string:
+		return x != ""
+	case []any:
+		return len(x) > 0
+	case map[string]any:
+		return len(x) > 0
+	case time.Duration:
+		return x != 0
+	}
+	return true
+}
+
+// typeRank orders values of different types the way SurrealDB sorts them.
+func typeRank(v any) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case int64, float64, models.DecimalString:
+		return 2
+	case string:
+		return 3
+	case time.Duration:
+		return 4
+	case time.Time:
+		return 5
+	case models.UUID:
+		return 6
+	case []any:
+		return 7
+	case map[string]any:
+		return 8
+	case []byte:
+		return 9
+	case models.RecordID:
+		return 10
+	case models.Table:
+		return 11
+	}
+	return 12
+}
+
+func compareValues(a, b any) int {
+	ra, rb := typeRank(a), typeRank(b)
+	if ra != rb {
+		return cmpInt(ra, rb)
+	}
+	switch x := a.(type) {
+	case nil:
+		return 0
+	case bool:
+		y := b.(bool)
+		if x == y {
+			return 0
+		}
+		if !x {
+			return -1
+		}
+		return 1
+	case int64:
+		if y, ok := b.(int64); ok {
+			return cmpInt64(x, y)
+		}
+	case string:
+		return strings.Compare(x, b.(string))
+	case time.Duration:
+		return cmpInt64(int64(x), int64(b.(time.Duration)))
+	case time.Time:
+		return x.Compare(b.(time.Time))
+	case models.UUID:
+		return bytes.Compare(x.Bytes(), b.(models.UUID).Bytes())
+	case []any:
+		y := b.([]any)
+		for i := 0; i < len(x) && i < len(y); i++ {
+			if c := compareValues(x[i], y[i]); c != 0 {
+				return c
+			}
+		}
+		return cmpInt(len(x), len(y))
+	case map[string]any:
+		y := b.(map[string]any)
+		kx, ky := sortedKeys(x), sortedKeys(y)
+		for i := 0; i < len(kx) && i < len(ky); i++ {
+			if c := strings.Compare(kx[i], ky[i]); c != 0 {
+				return c
+			}
+			if c := compareValues(x[kx[i]], y[ky[i]]); c != 0 {
+				return c
+			}
+		}
+		return cmpInt(len(kx), len(ky))
+	case []byte:
+		return bytes.Compare(x, b.([]byte))
+	case models.RecordID:
+		y := b.(models.RecordID)
+		if c := strings.Compare(x.Table, y.Table); c != 0 {
+			return c
+		}
+		return compareValues(x.ID, y.ID)
+	case models.Table:
+		return strings.Compare(string(x), string(b.(models.Table)))
+	}
+	if isNumber(a) {
+		fa, _ := toFloat(a)
+		fb, _ := toFloat(b)
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func cmpInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func equalValues(a, b any) bool {
+	if typeRank(a) != typeRank(b) {
+		return false
+	}
+	return compareValues(a, b) == 0
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// kindOf names the type of v as SurrealDB does in error messages.
+func kindOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "none"
+	case bool:
+		return "bool"
+	case int64:
+		return "int"
+	case float64:
+		return "float"
+	case models.DecimalString:
+		return "decimal"
+	case string:
+		return "string"
+	case time.Time:
+		return "datetime"
+	case time.Duration:
+		return "duration"
+	case models.UUID:
+		return "uuid"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	case []byte:
+		return "bytes"
+	case models.RecordID:
+		return "record"
+	case models.Table:
+		return "table"
+	}
+	return "any"
+}
+
+// render formats v as SurrealQL, for error messages and index keys.
+func render(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "NONE"
+	case string:
+		return "'" + strings.ReplaceAll(x, "'", "\\'") + "'"
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64) + "f"
+	case models.DecimalString:
+		return string(x) + "dec"
+	case time.Time:
+		return "d'" + x.Format(time.RFC3339Nano) + "'"
+	case time.Duration:
+		return formatDuration(x)
+	case models.UUID:
+		return "u'" + x.String() + "'"
+	case models.RecordID:
+		return renderRecordID(x)
+	case models.Table:
+		return string(x)
+	case []any:
+		parts := make([]string, len(x))
+		for i, e := range x {
+			parts[i] = render(e)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case map[string]any:
+		keys := sortedKeys(x)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = escapeIdent(k) + ": " + render(x[k])
+		}
+		return "{ " + strings.Join(parts, ", ") + " }"
+	}
+	return fmt.Sprint(v)
+}
+
+func renderRecordID(r models.RecordID) string {
+	switch id := r.ID.(type) {
+	case string:
+		if needsEscape(id) || isDigits(id) {
+			return escapeIdent(r.Table) + ":⟨" + strings.ReplaceAll(id, "⟩", "\\⟩") + "⟩"
+		}
+		return escapeIdent(r.Table) + ":" + id
+	}
+	return escapeIdent(r.Table) + ":" + render(r.ID)
+}
+
+func escapeIdent(s string) string {
+	if needsEscape(s) || isDigits(s) {
+		return "`" + strings.ReplaceAll(s, "`", "\\`") + "`"
+	}
+	return s
+}
+
+func needsEscape(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+var durationUnits = []struct {
+	name string
+	d    time.Duration
+}{
+	{"y", 365 * 24 * time.Hour},
+	{"w", 7 * 24 * time.Hour},
+	{"d", 24 * time.Hour},
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+	{"ms", time.Millisecond},
+	{"µs", time.Microsecond},
+	{"ns", time.Nanosecond},
+}
+
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return "0ns"
+	}
+	var b strings.Builder
+	for _, u := range durationUnits {
+		if n := d / u.d; n > 0 {
+			b.WriteString(strconv.FormatInt(int64(n), 10))
+			b.WriteString(u.name)
+			d -= n * u.d
+		}
+	}
+	return b.String()
+}
+
+// parseDuration parses SurrealQL durations such as 1h30m, 2d or 500ms.
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	var total time.Duration
+	rest := s
+	for rest != "" {
+		i := 0
+		for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+			i++
+		}
+		if i == 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		n, err := strconv.ParseInt(rest[:i], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		rest = rest[i:]
+		unit := ""
+		for _, u := range []string{"ns", "us", "µs", "ms", "s", "m", "h", "d", "w", "y"} {
+			if strings.HasPrefix(rest, u) && len(u) > len(unit) {
+				unit = u
+			}
+		}
+		if unit == "" {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		rest = rest[len(unit):]
+		if unit == "us" {
+			unit = "µs"
+		}
+		for _, u := range durationUnits {
+			if u.name == unit {
+				total += time.Duration(n) * u.d
+			}
+		}
+	}
+	return total, nil
+}
+
+// valueKey is a stable map key for v, used for record keys, GROUP BY and
+// unique indexes.
+func valueKey(v any) string {
+	return kindOf(v) + ":" + render(v)
+}
@@ -0,0 +1,78 @@
+package surrealdb_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dailaim/surrealdb-gorm/models"
+)
+
+type AggOrder struct {
+	models.BaseModel
+	Customer string
+	Total    float64
+}
+
+type aggRow struct {
+	Customer string
+	Total    float64
+}
+
+func TestGroupByHaving(t *testing.T) {
+	db := setupDB(t)
+	require.NoError(t, db.AutoMigrate(&AggOrder{}))
+	db.Exec("DELETE FROM agg_orders")
+	t.Cleanup(func() { db.Exec("DELETE FROM agg_orders") })
+
+	for _, o := range []AggOrder{
+		{Customer: "ada", Total: 80},
+		{Customer: "ada", Total: 40},
+		{Customer: "alan", Total: 30},
+		{Customer: "alan", Total: 20},
+		{Customer: "grace", Total: 150},
+	} {
+		require.NoError(t, db.Create(&o).Error)
+	}
+
+	// HAVING on an aliased aggregate, through Scan and Find
+	var rows []aggRow
+	require.NoError(t, db.Model(&AggOrder{}).Select("customer, sum(total) as total").
+		Group("customer").Having("total > ?", 100).Order("customer").Scan(&rows).Error)
+	require.Equal(t, []aggRow{{"ada", 120}, {"grace", 150}}, rows)
+
+	rows = nil
+	require.NoError(t, db.Model(&AggOrder{}).Select("customer, sum(total) as total").
+		Group("customer").Having("total > ?", 100).Order("customer").Find(&rows).Error)
+	require.Equal(t, []aggRow{{"ada", 120}, {"grace", 150}}, rows)
+
+	// HAVING on an aggregate that is not selected
+	rows = nil
+	require.NoError(t, db.Model(&AggOrder{}).Select("customer, max(total) as total").
+		Group("customer").Having("count(*) > ?", 1).Order("customer").Scan(&rows).Error)
+	require.Equal(t, []aggRow{{"ada", 80}, {"alan", 30}}, rows)
+
+	// AVG and MIN per group
+	var stats []struct {
+		Customer string
+		Avg      float64
+		Low      float64
+	}
+	require.NoError(t, db.Model(&AggOrder{}).Select("customer, avg(total) AS avg, min(total) AS low").
+		Group("customer").Order("customer").Scan(&stats).Error)
+	require.Len(t, stats, 3)
+	require.Equal(t, 60.0, stats[0].Avg)
+	require.Equal(t, 20.0, stats[1].Low)
+
+	// whole-table aggregates
+	var sum struct{ Total float64 }
+	require.NoError(t, db.Model(&AggOrder{}).Select("sum(total) AS total").Where("customer <> ?", "grace").Scan(&sum).Error)
+	require.Equal(t, 170.0, sum.Total)
+
+	var n int64
+	require.NoError(t, db.Model(&AggOrder{}).Distinct("customer").Count(&n).Error)
+	require.EqualValues(t, 3, n)
+
+	require.NoError(t, db.Model(&AggOrder{}).Group("customer").Count(&n).Error)
+	require.EqualValues(t, 3, n)
+}
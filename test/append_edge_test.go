@@ -0,0 +1,84 @@
+package surrealdb_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	surrealdb "github.com/dailaim/surrealdb-gorm"
+	"github.com/dailaim/surrealdb-gorm/models"
+)
+
+type Shop struct {
+	models.BaseModel
+	Name  string
+	Items []Item `gorm:"many2many:stocks;joinForeignKey:in;joinReferences:out"`
+}
+
+type Item struct {
+	models.BaseModel
+	Name  string
+	Shops []Shop `gorm:"many2many:stocks;joinForeignKey:out;joinReferences:in"`
+}
+
+type Stock struct {
+	models.EdgeBaseModel[Shop, Item]
+	Price float64
+	Qty   int
+}
+
+func TestAppendEdge(t *testing.T) {
+	db := setupDB(t)
+	require.NoError(t, db.AutoMigrate(&Shop{}, &Item{}, &Stock{}))
+	cleanup := func() {
+		db.Exec("DELETE FROM stocks")
+		db.Exec("DELETE FROM shops")
+		db.Exec("DELETE FROM items")
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	shop := Shop{Name: "corner"}
+	pen, ink, pad := Item{Name: "pen"}, Item{Name: "ink"}, Item{Name: "pad"}
+	for _, v := range []interface{}{&shop, &pen, &ink, &pad} {
+		require.NoError(t, db.Create(v).Error)
+	}
+	stockOf := func(item Item) Stock {
+		var s []Stock
+		require.NoError(t, db.Where("out = ?", item.ID).Find(&s).Error)
+		require.Len(t, s, 1)
+		return s[0]
+	}
+
+	// a map payload, with the edge model's timestamps
+	require.NoError(t, surrealdb.AppendEdge(db.Model(&shop).Association("Items"), &pen, map[string]any{"price": 9.99}))
+	require.Len(t, shop.Items, 1)
+	s := stockOf(pen)
+	require.Equal(t, 9.99, s.Price)
+	require.Equal(t, shop.ID.String(), s.EdgeIn().String())
+	require.False(t, s.CreatedAt.IsZero())
+
+	// the edge model's fields, from the relation's out side
+	require.NoError(t, surrealdb.AppendEdgeModel(db.Model(&ink).Association("Shops"), &shop, &Stock{Price: 2.5, Qty: 3}))
+	s = stockOf(ink)
+	require.Equal(t, 3, s.Qty)
+	require.Equal(t, shop.ID.String(), s.EdgeIn().String())
+	require.EqualValues(t, 2, db.Model(&shop).Association("Items").Count())
+
+	// inside a transaction
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := surrealdb.AppendEdge(tx.Model(&shop).Association("Items"), []*Item{&pad}, map[string]any{"qty": 1}); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	require.EqualError(t, err, "rollback")
+	require.EqualValues(t, 2, db.Model(&shop).Association("Items").Count())
+
+	require.Error(t, surrealdb.AppendEdge(db.Model(&shop).Association("Items"), &pad, map[string]any{"qty = 1, price": 0}))
+	require.Error(t, surrealdb.AppendEdge(db.Model(&shop).Association("Items"), &Item{Name: "unsaved"}, nil))
+	require.Error(t, surrealdb.AppendEdgeModel(db.Model(&shop).Association("Items"), &pad, &Wishlist{}))
+	require.Error(t, surrealdb.AppendEdge(db.Model(&shop).Association("Missing"), &pad, nil))
+}
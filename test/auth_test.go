@@ -0,0 +1,82 @@
+package surrealdb_test
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/dailaim/surrealdb-gorm"
+	"github.com/dailaim/surrealdb-gorm/models"
+)
+
+type AuthNote struct {
+	models.BaseModel
+	Body string `json:"body"`
+}
+
+// testConfig splits testDSN into a Config so tests can swap the Auth mode.
+func testConfig(t *testing.T) surrealdb.Config {
+	u, err := url.Parse(testDSN())
+	require.NoError(t, err)
+	q := u.Query()
+	cfg := surrealdb.Config{
+		Namespace: q.Get("namespace"),
+		Database:  q.Get("database"),
+		Username:  q.Get("username"),
+		Password:  q.Get("password"),
+	}
+	u.RawQuery = ""
+	cfg.Endpoint = u.String()
+	return cfg
+}
+
+func openWithAuth(t *testing.T, auth surrealdb.Auth) (*gorm.DB, error) {
+	cfg := testConfig(t)
+	cfg.Auth = auth
+	return gorm.Open(surrealdb.New(cfg), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+}
+
+func TestDatabaseUserAuth(t *testing.T) {
+	root := setupDB(t)
+	require.NoError(t, root.AutoMigrate(&AuthNote{}))
+	m := root.Migrator().(surrealdb.Migrator)
+	require.NoError(t, m.DefineUser(surrealdb.UserOptions{Name: "svc_editor", Level: "DATABASE", Password: "s3cret", Roles: "EDITOR"}))
+	defer m.RemoveUser("svc_editor", "DATABASE")
+
+	db, err := openWithAuth(t, surrealdb.DatabaseAuth{Username: "svc_editor", Password: "s3cret"})
+	require.NoError(t, err)
+	note := AuthNote{Body: "from a database user"}
+	require.NoError(t, db.Create(&note).Error)
+	require.NotNil(t, note.ID)
+
+	_, err = openWithAuth(t, surrealdb.DatabaseAuth{Username: "svc_editor", Password: "wrong"})
+	require.Error(t, err)
+}
+
+func TestRecordAccessAuth(t *testing.T) {
+	root := setupDB(t)
+	m := root.Migrator().(surrealdb.Migrator)
+	require.NoError(t, m.Define(`TABLE IF NOT EXISTS account SCHEMALESS PERMISSIONS FOR select, update, delete WHERE id = $auth.id`))
+	require.NoError(t, m.Define(`ACCESS OVERWRITE account_access ON DATABASE TYPE RECORD
+		SIGNUP (CREATE account SET email = $email, pass = crypto::argon2::generate($pass))
+		SIGNIN (SELECT * FROM account WHERE email = $email AND crypto::argon2::compare(pass, $pass))
+		DURATION FOR SESSION 1h`))
+	defer m.Remove("ACCESS account_access ON DATABASE")
+
+	email := fmt.Sprintf("u%d@example.com", time.Now().UnixNano())
+	params := map[string]interface{}{"email": email, "pass": "pw"}
+
+	db, err := openWithAuth(t, surrealdb.RecordAuth{Access: "account_access", Params: params, SignUp: true})
+	require.NoError(t, err)
+	var rows []map[string]interface{}
+	require.NoError(t, db.Raw("SELECT * FROM account").Scan(&rows).Error)
+	require.Len(t, rows, 1, "record user must only see its own account")
+
+	_, err = openWithAuth(t, surrealdb.RecordAuth{Access: "account_access", Params: params})
+	require.NoError(t, err)
+}
@@ -0,0 +1,132 @@
+package surrealdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/dailaim/surrealdb-gorm/clauses"
+	"github.com/dailaim/surrealdb-gorm/models"
+)
+
+type ClauseAccount struct {
+	models.BaseModel
+	Email    string `gorm:"index:idx_clause_email"`
+	Password string
+	Tags     []string `gorm:"type:array<string>"`
+}
+
+func TestSelectClauses(t *testing.T) {
+	db := setupDB(t)
+	require.NoError(t, db.AutoMigrate(&ClauseAccount{}))
+	db.Exec("DELETE FROM clause_accounts")
+	t.Cleanup(func() { db.Exec("DELETE FROM clause_accounts") })
+
+	require.NoError(t, db.Create(&ClauseAccount{Email: "ada@example.com", Password: "hash1", Tags: []string{"admin", "dev"}}).Error)
+	require.NoError(t, db.Create(&ClauseAccount{Email: "alan@example.com", Password: "hash2", Tags: []string{"dev"}}).Error)
+
+	// clauses are placed in SurrealQL's SELECT order
+	stmt := db.Session(&gorm.Session{DryRun: true}).Clauses(
+		clauses.TempFiles{},
+		clauses.Timeout{Duration: 1500 * time.Millisecond},
+		clauses.Parallel{},
+		clauses.Split{Fields: []string{"tags"}},
+		clauses.WithIndex{Names: []string{"idx_clause_email"}},
+		clauses.Omit{Fields: []string{"password"}},
+	).Where("email = ?", "ada@example.com").Find(&[]ClauseAccount{}).Statement
+	require.Equal(t,
+		"SELECT * OMIT password FROM `clause_accounts` WITH INDEX idx_clause_email WHERE email = $p1 AND (`deleted_at` IS NULL OR `deleted_at` IS NONE) SPLIT tags TIMEOUT 1s500ms PARALLEL TEMPFILES",
+		stmt.SQL.String())
+
+	// OMIT hides the field
+	var accounts []ClauseAccount
+	require.NoError(t, db.Clauses(clauses.Omit{Fields: []string{"password"}}).Order("email").Find(&accounts).Error)
+	require.Len(t, accounts, 2)
+	require.Equal(t, "ada@example.com", accounts[0].Email)
+	require.Empty(t, accounts[0].Password)
+
+	// SPLIT yields a row per tag
+	var rows []map[string]interface{}
+	require.NoError(t, db.Model(&ClauseAccount{}).Clauses(clauses.Split{Fields: []string{"tags"}}).
+		Select("email", "tags").Order("tags").Find(&rows).Error)
+	require.Len(t, rows, 3)
+	require.Equal(t, "admin", rows[0]["tags"])
+
+	// WITH, TIMEOUT, PARALLEL and TEMPFILES run
+	accounts = nil
+	require.NoError(t, db.Clauses(
+		clauses.WithNoIndex{},
+		clauses.Timeout{Duration: 5 * time.Second},
+		clauses.Parallel{},
+		clauses.TempFiles{},
+	).Where("email = ?", "alan@example.com").Find(&accounts).Error)
+	require.Len(t, accounts, 1)
+	require.Equal(t, []string{"dev"}, accounts[0].Tags)
+
+	accounts = nil
+	require.NoError(t, db.Clauses(clauses.WithIndex{Names: []string{"idx_clause_email"}}).
+		Where("email = ?", "ada@example.com").Find(&accounts).Error)
+	require.Len(t, accounts, 1)
+}
+
+type ClauseItem struct {
+	models.BaseModel
+	Name string
+	Code string
+}
+
+func TestOrderByModifiers(t *testing.T) {
+	db := setupDB(t)
+	require.NoError(t, db.AutoMigrate(&ClauseItem{}))
+	db.Exec("DELETE FROM clause_items")
+	t.Cleanup(func() { db.Exec("DELETE FROM clause_items") })
+
+	for _, it := range []ClauseItem{
+		{Name: "alice", Code: "item10"},
+		{Name: "Bob", Code: "item2"},
+		{Name: "carol", Code: "item1"},
+	} {
+		require.NoError(t, db.Create(&it).Error)
+	}
+	names := func(items []ClauseItem) []string {
+		var out []string
+		for _, it := range items {
+			out = append(out, it.Name)
+		}
+		return out
+	}
+
+	var items []ClauseItem
+	require.NoError(t, db.Order("name").Find(&items).Error)
+	require.Equal(t, []string{"Bob", "alice", "carol"}, names(items))
+
+	items = nil
+	require.NoError(t, db.Clauses(clauses.OrderBy{Columns: []clauses.OrderByColumn{
+		{Column: clause.Column{Name: "name"}, Collate: true},
+	}}).Find(&items).Error)
+	require.Equal(t, []string{"alice", "Bob", "carol"}, names(items))
+
+	items = nil
+	require.NoError(t, db.Clauses(clauses.OrderBy{Columns: []clauses.OrderByColumn{
+		{Column: clause.Column{Name: "code"}, Numeric: true, Desc: true},
+	}}).Find(&items).Error)
+	require.Equal(t, []string{"alice", "Bob", "carol"}, names(items))
+
+	items = nil
+	require.NoError(t, db.Clauses(clauses.OrderBy{Rand: true}).Limit(2).Find(&items).Error)
+	require.Len(t, items, 2)
+
+	// merges with db.Order either way round
+	dry := db.Session(&gorm.Session{DryRun: true})
+	stmt := dry.Order("code").Clauses(clauses.OrderBy{Columns: []clauses.OrderByColumn{
+		{Column: clause.Column{Name: "name"}, Collate: true, Desc: true},
+	}}).Order("id").Find(&items).Statement
+	require.Contains(t, stmt.SQL.String(), "ORDER BY code,`name` COLLATE DESC,id")
+
+	stmt = dry.Order("code").Clauses(clauses.OrderBy{Rand: true}).Find(&items).Statement
+	require.Contains(t, stmt.SQL.String(), "ORDER BY RAND()")
+	require.NotContains(t, stmt.SQL.String(), "code")
+}
@@ -0,0 +1,53 @@
+package surrealdb_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dailaim/surrealdb-gorm"
+)
+
+func TestDialectorClose(t *testing.T) {
+	db := setupPooledDB(t, 2, 4)
+	d := getDialector(db)
+
+	live, err := surrealdb.NewLiveQuery(db, "users", false)
+	require.NoError(t, err)
+	ch, err := live.Notifications()
+	require.NoError(t, err)
+
+	tx := db.Begin()
+	require.NoError(t, tx.Error)
+	require.NoError(t, tx.Create(&User{Name: "uncommitted"}).Error)
+
+	require.NoError(t, d.Close(context.Background()))
+
+	select {
+	case _, open := <-ch:
+		require.False(t, open, "live notifications must stop")
+	case <-time.After(5 * time.Second):
+		t.Fatal("live notification channel still open after Close")
+	}
+	require.Zero(t, d.Stats().OpenConns)
+	require.Zero(t, d.Stats().LiveQueries)
+	require.Error(t, db.Exec("RETURN 1").Error)
+	require.NoError(t, d.Close(context.Background()), "Close is idempotent")
+
+	// The uncommitted write was cancelled.
+	other := setupDB(t)
+	var n int64
+	other.Model(&User{}).Where("name = ?", "uncommitted").Count(&n)
+	require.Zero(t, n)
+}
+
+func TestSQLDBCloseShutsDown(t *testing.T) {
+	db := setupPooledDB(t, 1, 2)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+	require.Zero(t, getDialector(db).Stats().OpenConns)
+	require.Error(t, db.Exec("RETURN 1").Error)
+}
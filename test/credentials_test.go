@@ -0,0 +1,43 @@
+package surrealdb_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/dailaim/surrealdb-gorm"
+)
+
+// TestCredentialsRenewExpiredSession signs in as a database user whose
+// sessions last two seconds and checks that statements keep working past
+// expiry: the driver asks the provider again and retries.
+func TestCredentialsRenewExpiredSession(t *testing.T) {
+	root := setupDB(t)
+	require.NoError(t, root.AutoMigrate(&AuthNote{}))
+	m := root.Migrator().(surrealdb.Migrator)
+	require.NoError(t, m.DefineUser(surrealdb.UserOptions{
+		Name: "svc_rotating", Level: "DATABASE", Password: "s3cret", Roles: "EDITOR",
+		Duration: "FOR SESSION 2s",
+	}))
+	defer m.RemoveUser("svc_rotating", "DATABASE")
+
+	var calls atomic.Int32
+	cfg := testConfig(t)
+	cfg.Credentials = func(context.Context) (surrealdb.Auth, error) {
+		calls.Add(1)
+		return surrealdb.DatabaseAuth{Username: "svc_rotating", Password: "s3cret"}, nil
+	}
+	db, err := gorm.Open(surrealdb.New(cfg), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, calls.Load())
+
+	require.NoError(t, db.Create(&AuthNote{Body: "before expiry"}).Error)
+	time.Sleep(3 * time.Second)
+	require.NoError(t, db.Create(&AuthNote{Body: "after expiry"}).Error)
+	require.GreaterOrEqual(t, calls.Load(), int32(2), "the provider is asked again once the session expired")
+}
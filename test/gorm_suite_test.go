@@ -3,7 +3,7 @@
 // Coverage areas (same categories as the official GORM test suite):
 //
 //   1. CRUD basics            — Create / Find / Update / Delete
-//   2. Where conditions       — string, struct, map, IN, BETWEEN, AND, OR, Not
+//   2. Where conditions       — string, struct, map, IN / NOT IN, LIKE, BETWEEN, AND, OR, Not
 //   3. Query helpers          — First / Last / Take / Find / Pluck / Count
 //   4. Projection / Order     — Select specific fields, ORDER BY, LIMIT
 //   5. Update variants        — Model.Update / Updates(struct) / Updates(map) / Save / UpdateColumns
@@ -206,15 +206,97 @@ func TestWhereIN(t *testing.T) {
 		{Name: "Julia", Age: 45},
 	})
 
-	// SurrealDB does not support SQL `IN (a,b)` syntax — use OR conditions
+	// IN (a, b) is translated to SurrealQL's INSIDE [a, b]
 	var result []SuitePerson
-	err := db.Where("name = ? OR name = ?", "Hannah", "Julia").Find(&result).Error
+	err := db.Where("name IN ?", []string{"Hannah", "Julia"}).Find(&result).Error
 	if err != nil {
 		t.Fatalf("Where IN: %v", err)
 	}
 	if len(result) != 2 {
 		t.Errorf("want 2, got %d: %+v", len(result), result)
 	}
+
+	result = nil
+	if err := db.Where(map[string]interface{}{"age": []int{21, 33}}).Find(&result).Error; err != nil {
+		t.Fatalf("Where map IN: %v", err)
+	}
+	if len(result) != 2 {
+		t.Errorf("map IN: want 2, got %d: %+v", len(result), result)
+	}
+
+	result = nil
+	if err := db.Where("name IN ?", []string{}).Find(&result).Error; err != nil {
+		t.Fatalf("Where IN empty: %v", err)
+	}
+	if len(result) != 0 {
+		t.Errorf("empty IN: want 0, got %d: %+v", len(result), result)
+	}
+
+	result = nil
+	sub := db.Model(&SuitePerson{}).Select("age").Where("age > ?", 30)
+	if err := db.Where("age IN (?)", sub).Order("age").Find(&result).Error; err != nil {
+		t.Fatalf("Where IN subquery: %v", err)
+	}
+	if len(result) != 2 || result[0].Name != "Ivan" {
+		t.Errorf("subquery IN: want [Ivan Julia], got %+v", result)
+	}
+}
+
+func TestWhereNotIN(t *testing.T) {
+	db := setupPersonDB(t)
+	seedPersons(t, db, []SuitePerson{
+		{Name: "Kate", Age: 21},
+		{Name: "Liam", Age: 33},
+		{Name: "Mona", Age: 45},
+	})
+
+	var result []SuitePerson
+	if err := db.Where("name NOT IN ?", []string{"Kate", "Mona"}).Find(&result).Error; err != nil {
+		t.Fatalf("Where NOT IN: %v", err)
+	}
+	if len(result) != 1 || result[0].Name != "Liam" {
+		t.Errorf("want [Liam], got %+v", result)
+	}
+
+	result = nil
+	if err := db.Not(map[string]interface{}{"age": []int{21, 33}}).Find(&result).Error; err != nil {
+		t.Fatalf("Not map IN: %v", err)
+	}
+	if len(result) != 1 || result[0].Name != "Mona" {
+		t.Errorf("Not map IN: want [Mona], got %+v", result)
+	}
+}
+
+func TestWhereLike(t *testing.T) {
+	db := setupPersonDB(t)
+	seedPersons(t, db, []SuitePerson{
+		{Name: "Alice", Email: "alice@example.com"},
+		{Name: "Malik", Email: "malik@test.org"},
+		{Name: "Bob", Email: "bob@example.com"},
+	})
+
+	cases := []struct {
+		query string
+		arg   string
+		want  int
+	}{
+		{"name LIKE ?", "Ali%", 1},
+		{"email LIKE ?", "%@example.com", 2},
+		{"name LIKE ?", "%li%", 2},
+		{"name NOT LIKE ?", "%li%", 1},
+		{"name ILIKE ?", "%ALI%", 2},
+		{"name LIKE ?", "B_b", 1},
+		{"name LIKE ?", "Bob", 1},
+	}
+	for _, c := range cases {
+		var result []SuitePerson
+		if err := db.Where(c.query, c.arg).Find(&result).Error; err != nil {
+			t.Fatalf("%s %q: %v", c.query, c.arg, err)
+		}
+		if len(result) != c.want {
+			t.Errorf("%s %q: want %d, got %d: %+v", c.query, c.arg, c.want, len(result), result)
+		}
+	}
 }
 
 func TestWhereBetween(t *testing.T) {
@@ -0,0 +1,177 @@
+package surrealdb_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	surrealdb "github.com/dailaim/surrealdb-gorm"
+	"github.com/dailaim/surrealdb-gorm/models"
+)
+
+type Member struct {
+	models.BaseModel
+	Name string
+	Age  int
+}
+
+type Follow struct {
+	models.Edge[Member, Member]
+	Close bool
+}
+
+func TestGraphQuery(t *testing.T) {
+	db := setupDB(t)
+	require.NoError(t, db.AutoMigrate(&Member{}, &Follow{}))
+	cleanup := func() {
+		db.Exec("DELETE FROM follows")
+		db.Exec("DELETE FROM members")
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	people := map[string]*Member{}
+	for _, p := range []Member{{Name: "alice", Age: 30}, {Name: "bob", Age: 35}, {Name: "carol", Age: 25}, {Name: "dave", Age: 40}} {
+		p := p
+		require.NoError(t, db.Create(&p).Error)
+		people[p.Name] = &p
+	}
+	follow := func(from, to string, close bool) {
+		require.NoError(t, db.Create(&Follow{Edge: models.NewEdge[Member, Member](people[from].ID, people[to].ID), Close: close}).Error)
+	}
+	follow("alice", "bob", true)
+	follow("alice", "carol", false)
+	follow("alice", "dave", false)
+	follow("bob", "alice", false)
+
+	names := func(ps []Member) []string {
+		var out []string
+		for _, p := range ps {
+			out = append(out, p.Name)
+		}
+		sort.Strings(out)
+		return out
+	}
+	alice := people["alice"].ID
+
+	// ->follows->members[WHERE age > $p2], the edge name resolved from "follow"
+	stmt := surrealdb.From[Member](db.Session(&gorm.Session{DryRun: true}), alice).Out("follow").Out("members").
+		Where("age > ?", 30).Find(&[]Member{}).Statement
+	require.Contains(t, stmt.SQL.String(), "FROM $p1->follows->members[WHERE age > $p2]")
+
+	var found []Member
+	require.NoError(t, surrealdb.From[Member](db, alice).Out("follow").Out("members").Where("age > ?", 30).Find(&found).Error)
+	require.Equal(t, []string{"bob", "dave"}, names(found))
+
+	// a condition on the edge
+	found = nil
+	require.NoError(t, surrealdb.From[Member](db, alice).Out("follows").Where("close = ?", true).Out("members").Find(&found).Error)
+	require.Equal(t, []string{"bob"}, names(found))
+
+	// incoming edges, starting from a record key
+	found = nil
+	require.NoError(t, surrealdb.From[Member](db, alice.ID).In("follows").In("members").Find(&found).Error)
+	require.Equal(t, []string{"bob"}, names(found))
+
+	// edges in either direction
+	var edges []Follow
+	require.NoError(t, surrealdb.From[Member](db, people["bob"].ID).Both("follows").Find(&edges).Error)
+	require.Len(t, edges, 2)
+
+	// a partial path is reused
+	follows := surrealdb.From[Member](db, alice).Out("follows")
+	var n int64
+	require.NoError(t, follows.Out("members").DB().Model(&Member{}).Count(&n).Error)
+	require.EqualValues(t, 3, n)
+	found = nil
+	require.NoError(t, follows.Out("members").Where("name = ?", "carol").Find(&found).Error)
+	require.Equal(t, []string{"carol"}, names(found))
+
+	require.Error(t, surrealdb.From[Member](db, alice).Where("age > ?", 1).Find(&found).Error)
+	require.Error(t, surrealdb.From[Member](db, alice).Out("follows; DELETE members").Find(&found).Error)
+	require.Error(t, surrealdb.From[Follow](db, alice).Out("members").Find(&found).Error)
+}
+
+type Manage struct {
+	models.Edge[Member, Member]
+}
+
+func TestTraverseAndShortestPath(t *testing.T) {
+	db := setupDB(t)
+	require.NoError(t, db.AutoMigrate(&Member{}, &Manage{}))
+	cleanup := func() {
+		db.Exec("DELETE FROM manages")
+		db.Exec("DELETE FROM members")
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	// ceo -> cto -> lead -> dev, ceo -> cfo, and a shortcut cto -> dev2 -> dev
+	members := map[string]*Member{}
+	for _, name := range []string{"ceo", "cto", "cfo", "lead", "dev", "dev2"} {
+		m := &Member{Name: name}
+		require.NoError(t, db.Create(m).Error)
+		members[name] = m
+	}
+	manage := func(from, to string) *Manage {
+		e := &Manage{Edge: models.NewEdge[Member, Member](members[from].ID, members[to].ID)}
+		require.NoError(t, db.Create(e).Error)
+		return e
+	}
+	manage("ceo", "cto")
+	manage("ceo", "cfo")
+	manage("cto", "lead")
+	manage("lead", "dev")
+	manage("cto", "dev2")
+	manage("dev2", "dev")
+	names := func(ms []Member) []string {
+		var out []string
+		for _, m := range ms {
+			out = append(out, m.Name)
+		}
+		sort.Strings(out)
+		return out
+	}
+
+	var found []Member
+	require.NoError(t, surrealdb.Traverse(db, members["ceo"].ID, "manages", 1, 1).Find(&found).Error)
+	require.Equal(t, []string{"cfo", "cto"}, names(found))
+
+	found = nil
+	require.NoError(t, surrealdb.Traverse(db, members["ceo"].ID, "manage", 2, 3).Find(&found).Error)
+	require.Equal(t, []string{"dev", "dev2", "lead"}, names(found))
+
+	// up the tree, with a condition
+	found = nil
+	require.NoError(t, surrealdb.Traverse(db, members["dev"].ID, "<-manages", 1, 0).Where("name <> ?", "ceo").Find(&found).Error)
+	require.Equal(t, []string{"cto", "dev2", "lead"}, names(found))
+
+	stmt := surrealdb.Traverse(db.Session(&gorm.Session{DryRun: true}), members["ceo"].ID, "manages", 1, 3).Find(&found).Statement
+	require.Contains(t, stmt.SQL.String(), "FROM $p1.{1..3+collect}(->manages->?)")
+
+	require.Error(t, surrealdb.Traverse(db, members["ceo"].ID, "members", 1, 2).Find(&found).Error)
+	require.Error(t, surrealdb.Traverse(db, members["ceo"].ID, "manages", 3, 2).Find(&found).Error)
+
+	// nodes and the edges between them, in order
+	path, err := surrealdb.ShortestPath[Member, Manage](db, members["ceo"].ID, members["dev"].ID, "manages")
+	require.NoError(t, err)
+	require.Len(t, path.Nodes, 4)
+	require.Equal(t, "ceo", path.Nodes[0].Name)
+	require.Equal(t, "cto", path.Nodes[1].Name)
+	require.Equal(t, "dev", path.Nodes[3].Name)
+	require.Len(t, path.Edges, 3)
+	for i, e := range path.Edges {
+		require.Equal(t, path.Nodes[i].ID.String(), e.EdgeIn().String())
+		require.Equal(t, path.Nodes[i+1].ID.String(), e.EdgeOut().String())
+	}
+
+	back, err := surrealdb.ShortestPath[Member, Manage](db, members["dev"].ID, members["ceo"].ID, "<-manages")
+	require.NoError(t, err)
+	require.Len(t, back.Nodes, 4)
+	require.Equal(t, "ceo", back.Nodes[3].Name)
+
+	_, err = surrealdb.ShortestPath[Member, Manage](db, members["cfo"].ID, members["dev"].ID, "manages")
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
@@ -353,6 +353,86 @@ func TestPreloadFromOut(t *testing.T) {
 	t.Logf("[preload from out] product loaded %d buyer(s)", len(loadedProduct.Buyers))
 }
 
+// TestAssociationFromOut verifies the association mode of Product.Buyers, whose
+// owner is the edge's out: edges still run buyer->wishlists->product.
+func TestAssociationFromOut(t *testing.T) {
+	db := setupDB(t)
+	db.AutoMigrate(&Buyer{}, &Product{}, &Wishlist{})
+	cleanupGraph(t, db)
+	t.Cleanup(func() { cleanupGraph(t, db) })
+
+	b1, b2, b3 := Buyer{Name: "OutAssoc1"}, Buyer{Name: "OutAssoc2"}, Buyer{Name: "OutAssoc3"}
+	product := Product{Name: "OutAssocProduct"}
+	for _, v := range []interface{}{&b1, &b2, &b3, &product} {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	assoc := func() *gorm.Association { return db.Model(&product).Association("Buyers") }
+
+	if err := assoc().Append(&b1, &b2); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	var edges []Wishlist
+	if err := db.Find(&edges).Error; err != nil {
+		t.Fatalf("find edges: %v", err)
+	}
+	if len(edges) != 2 {
+		t.Fatalf("expected 2 edges, got %d", len(edges))
+	}
+	for _, e := range edges {
+		if e.EdgeOut().String() != product.ID.String() {
+			t.Errorf("edge %s -> %s should end at the product %s", e.EdgeIn(), e.EdgeOut(), product.ID)
+		}
+	}
+
+	if n := assoc().Count(); n != 2 {
+		t.Errorf("expected count=2, got %d", n)
+	}
+	if n := db.Model(&b1).Association("Products").Count(); n != 1 {
+		t.Errorf("expected buyer count=1, got %d", n)
+	}
+	var buyers []Buyer
+	if err := assoc().Find(&buyers, "name = ?", "OutAssoc2"); err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if len(buyers) != 1 || buyers[0].Name != "OutAssoc2" {
+		t.Fatalf("expected [OutAssoc2], got %+v", buyers)
+	}
+
+	if err := assoc().Delete(&b1); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if n := assoc().Count(); n != 1 {
+		t.Errorf("expected count=1 after delete, got %d", n)
+	}
+
+	if err := assoc().Replace(&b3); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	buyers = nil
+	if err := assoc().Find(&buyers); err != nil {
+		t.Fatalf("find after replace: %v", err)
+	}
+	if len(buyers) != 1 || buyers[0].Name != "OutAssoc3" {
+		t.Fatalf("expected [OutAssoc3] after replace, got %+v", buyers)
+	}
+	if n := db.Model(&b3).Association("Products").Count(); n != 1 {
+		t.Errorf("expected buyer count=1 after replace, got %d", n)
+	}
+
+	if err := assoc().Clear(); err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	var n int64
+	if err := db.Model(&Wishlist{}).Count(&n).Error; err != nil {
+		t.Fatalf("count edges: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("expected no edges after clear, got %d", n)
+	}
+}
+
 // TestDeleteEdgeDirect verifies db.Delete(&wishlist) removes the edge record by ID.
 func TestDeleteEdgeDirect(t *testing.T) {
 	db := setupDB(t)
@@ -469,3 +549,77 @@ func TestSoftDeleteEdge(t *testing.T) {
 
 	t.Log("[soft-delete-edge] passed")
 }
+
+type Fan struct {
+	models.BaseModel
+	Name      string
+	Idols     []Fan `gorm:"many2many:fandoms;joinForeignKey:in;joinReferences:out"`
+	Followers []Fan `gorm:"many2many:fandoms;joinForeignKey:out;joinReferences:in"`
+}
+
+type Fandom struct {
+	models.Edge[Fan, Fan]
+}
+
+// TestSelfAssociation checks that Delete, Replace and Count act on the named
+// association when both ends of a self-referential edge table are mapped.
+func TestSelfAssociation(t *testing.T) {
+	db := setupDB(t)
+	if err := db.AutoMigrate(&Fan{}, &Fandom{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	cleanup := func() {
+		db.Exec("DELETE FROM fandoms")
+		db.Exec("DELETE FROM fans")
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	a, b, c, d := Fan{Name: "a"}, Fan{Name: "b"}, Fan{Name: "c"}, Fan{Name: "d"}
+	for _, v := range []*Fan{&a, &b, &c, &d} {
+		if err := db.Create(v).Error; err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	idols := func(f *Fan) *gorm.Association { return db.Model(f).Association("Idols") }
+	followers := func(f *Fan) *gorm.Association { return db.Model(f).Association("Followers") }
+
+	if err := idols(&a).Append(&b, &c); err != nil {
+		t.Fatalf("append idols: %v", err)
+	}
+	if err := followers(&a).Append(&d); err != nil {
+		t.Fatalf("append followers: %v", err)
+	}
+	if n, m := idols(&a).Count(), followers(&a).Count(); n != 2 || m != 1 {
+		t.Fatalf("expected 2 idols and 1 follower, got %d and %d", n, m)
+	}
+
+	// d follows a; deleting it from a's followers leaves a's idols alone.
+	if err := followers(&a).Delete(&d); err != nil {
+		t.Fatalf("delete follower: %v", err)
+	}
+	if n, m := idols(&a).Count(), followers(&a).Count(); n != 2 || m != 0 {
+		t.Fatalf("expected 2 idols and no follower after delete, got %d and %d", n, m)
+	}
+
+	// b's followers are replaced without touching what b follows.
+	if err := idols(&b).Append(&c); err != nil {
+		t.Fatalf("append idol: %v", err)
+	}
+	if err := followers(&b).Replace(&d); err != nil {
+		t.Fatalf("replace followers: %v", err)
+	}
+	var fans []Fan
+	if err := followers(&b).Find(&fans); err != nil {
+		t.Fatalf("find followers: %v", err)
+	}
+	if len(fans) != 1 || fans[0].Name != "d" {
+		t.Fatalf("expected [d] as b's followers, got %+v", fans)
+	}
+	if n := idols(&b).Count(); n != 1 {
+		t.Errorf("expected b to still follow c, got %d idols", n)
+	}
+	if n := idols(&a).Count(); n != 1 {
+		t.Errorf("expected a to follow only c after b's followers were replaced, got %d", n)
+	}
+}
@@ -0,0 +1,41 @@
+package surrealdb_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/dailaim/surrealdb-gorm"
+)
+
+func TestPingAndStats(t *testing.T) {
+	db := setupPooledDB(t, 2, 4)
+	d := getDialector(db)
+	require.NoError(t, d.Ping(context.Background()))
+
+	live, err := surrealdb.NewLiveQuery(db, "users", false)
+	require.NoError(t, err)
+	defer live.Kill()
+
+	st := d.Stats()
+	require.GreaterOrEqual(t, st.OpenConns, 2)
+	require.Equal(t, 1, st.LiveQueries)
+	require.Zero(t, st.InFlight)
+	require.NoError(t, st.LastError)
+}
+
+func TestOnAuthFailure(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Password = "definitely-wrong"
+	var endpoint string
+	var authErr error
+	cfg.OnAuthFailure = func(ep string, err error) { endpoint, authErr = ep, err }
+
+	_, err := gorm.Open(surrealdb.New(cfg), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.Error(t, err)
+	require.Error(t, authErr)
+	require.NotContains(t, endpoint, "definitely-wrong")
+}
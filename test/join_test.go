@@ -0,0 +1,132 @@
+package surrealdb_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dailaim/surrealdb-gorm/models"
+	"github.com/dailaim/surrealdb-gorm/types"
+)
+
+type JoinAuthor struct {
+	models.BaseModel
+	Name    string
+	Profile *JoinProfile `gorm:"foreignKey:AuthorID"`
+}
+
+type JoinProfile struct {
+	models.BaseModel
+	Bio      string
+	AuthorID *types.RecordID
+}
+
+type JoinPost struct {
+	models.BaseModel
+	Title    string
+	AuthorID *types.RecordID
+	Author   *JoinAuthor
+}
+
+func TestJoins(t *testing.T) {
+	db := setupDB(t)
+	require.NoError(t, db.AutoMigrate(&JoinAuthor{}, &JoinProfile{}, &JoinPost{}))
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM join_authors; DELETE FROM join_profiles; DELETE FROM join_posts")
+	})
+
+	ada := JoinAuthor{Name: "Ada"}
+	alan := JoinAuthor{Name: "Alan"}
+	require.NoError(t, db.Create(&ada).Error)
+	require.NoError(t, db.Create(&alan).Error)
+	require.NoError(t, db.Create(&JoinProfile{Bio: "Analyst", AuthorID: ada.ID}).Error)
+	require.NoError(t, db.Create(&JoinPost{Title: "Notes", AuthorID: ada.ID}).Error)
+	require.NoError(t, db.Create(&JoinPost{Title: "Machines", AuthorID: alan.ID}).Error)
+	require.NoError(t, db.Create(&JoinPost{Title: "Draft"}).Error)
+
+	// belongs-to: the record link is dereferenced
+	var posts []JoinPost
+	require.NoError(t, db.Joins("Author").Order("title").Find(&posts).Error)
+	require.Len(t, posts, 3)
+	require.Nil(t, posts[0].Author, "Draft has no author")
+	require.NotNil(t, posts[1].Author)
+	require.Equal(t, "Alan", posts[1].Author.Name)
+	require.Equal(t, "Ada", posts[2].Author.Name)
+
+	// conditions filter the joined record, InnerJoins the rows
+	posts = nil
+	require.NoError(t, db.Joins("Author", db.Where(&JoinAuthor{Name: "Ada"})).Order("title").Find(&posts).Error)
+	require.Len(t, posts, 3)
+	require.Nil(t, posts[1].Author)
+	require.Equal(t, "Ada", posts[2].Author.Name)
+
+	posts = nil
+	require.NoError(t, db.InnerJoins("Author", db.Where("name = ?", "Alan")).Find(&posts).Error)
+	require.Len(t, posts, 1)
+	require.Equal(t, "Machines", posts[0].Title)
+	require.Equal(t, "Alan", posts[0].Author.Name)
+
+	var count int64
+	require.NoError(t, db.Model(&JoinPost{}).InnerJoins("Author").Count(&count).Error)
+	require.EqualValues(t, 2, count)
+
+	// has-one: the record pointing back is selected
+	var authors []JoinAuthor
+	require.NoError(t, db.Joins("Profile").Order("name").Find(&authors).Error)
+	require.Len(t, authors, 2)
+	require.NotNil(t, authors[0].Profile)
+	require.Equal(t, "Analyst", authors[0].Profile.Bio)
+	require.Nil(t, authors[1].Profile)
+
+	// unsupported joins are reported, not sent
+	err := db.Joins("JOIN join_authors ON join_authors.id = join_posts.author_id").Find(&posts).Error
+	require.ErrorContains(t, err, "raw JOINs")
+}
+
+func TestJoinsLinkAndEdge(t *testing.T) {
+	db := setupDB(t)
+	require.NoError(t, db.AutoMigrate(&Book{}, &Person{}, &Buyer{}, &Product{}, &Wishlist{}))
+	cleanupGraph(t, db)
+	t.Cleanup(func() { cleanupGraph(t, db) })
+
+	// types.Link field
+	book := Book{Title: "Joins in SurrealQL"}
+	require.NoError(t, db.Create(&book).Error)
+	reader := Person{Name: "JoinReader", Book: types.Link[Book]{ID: book.ID}}
+	require.NoError(t, db.Create(&reader).Error)
+
+	var p Person
+	require.NoError(t, db.Joins("Book").First(&p, "id = ?", reader.ID).Error)
+	require.NotNil(t, p.Book.Data)
+	require.Equal(t, "Joins in SurrealQL", p.Book.Data.Title)
+
+	// many2many through an edge table
+	alice := Buyer{Name: "JoinAlice"}
+	bob := Buyer{Name: "JoinBob"}
+	p1 := Product{Name: "JoinP1"}
+	p2 := Product{Name: "JoinP2"}
+	for _, v := range []interface{}{&alice, &bob, &p1, &p2} {
+		require.NoError(t, db.Create(v).Error)
+	}
+	require.NoError(t, db.Model(&alice).Association("Products").Append(&p1, &p2))
+
+	var buyer Buyer
+	require.NoError(t, db.Joins("Products").First(&buyer, "id = ?", alice.ID).Error)
+	require.Len(t, buyer.Products, 2)
+
+	buyer = Buyer{}
+	require.NoError(t, db.Joins("Products", db.Where(&Product{Name: "JoinP2"})).First(&buyer, "id = ?", alice.ID).Error)
+	require.Len(t, buyer.Products, 1)
+	require.Equal(t, "JoinP2", buyer.Products[0].Name)
+
+	var buyers []Buyer
+	require.NoError(t, db.InnerJoins("Products").Where("name LIKE ?", "Join%").Find(&buyers).Error)
+	require.Len(t, buyers, 1)
+	require.Equal(t, "JoinAlice", buyers[0].Name)
+
+	// Reverse direction
+	var product Product
+	require.NoError(t, db.Joins("Buyers").First(&product, "id = ?", p1.ID).Error)
+	require.Len(t, product.Buyers, 1)
+	require.Equal(t, "JoinAlice", product.Buyers[0].Name)
+}
@@ -0,0 +1,88 @@
+package surrealdb_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	surrealdb "github.com/dailaim/surrealdb-gorm"
+	"github.com/dailaim/surrealdb-gorm/models"
+)
+
+type PageItem struct {
+	models.BaseModel
+	Name string
+	Rank int
+}
+
+func TestPaginate(t *testing.T) {
+	db := setupDB(t)
+	require.NoError(t, db.AutoMigrate(&PageItem{}))
+	db.Exec("DELETE FROM page_items")
+	t.Cleanup(func() { db.Exec("DELETE FROM page_items") })
+
+	for i := 0; i < 7; i++ {
+		require.NoError(t, db.Create(&PageItem{Name: "item", Rank: (i * 3) % 7}).Error)
+	}
+
+	// record id order, read as record ranges
+	seen := map[string]bool{}
+	cursor, pages := "", 0
+	for {
+		var items []PageItem
+		tx := surrealdb.Paginate(db, cursor, 3).Find(&items)
+		require.NoError(t, tx.Error)
+		for _, it := range items {
+			require.False(t, seen[it.ID.String()], "record %s on two pages", it.ID)
+			seen[it.ID.String()] = true
+		}
+		pages++
+		if cursor = surrealdb.NextCursor(tx); cursor == "" {
+			require.Len(t, items, 1)
+			break
+		}
+		require.Len(t, items, 3)
+	}
+	require.Equal(t, 3, pages)
+	require.Len(t, seen, 7)
+
+	dry := surrealdb.Paginate(db.Session(&gorm.Session{DryRun: true}), "", 3).Find(&[]PageItem{}).Statement
+	require.Contains(t, dry.SQL.String(), "FROM `page_items`:..")
+
+	// sort key order, ties broken by record id
+	var ranks []int
+	cursor = ""
+	for {
+		var items []PageItem
+		tx := surrealdb.Paginate(db.Order("rank DESC"), cursor, 2).Find(&items)
+		require.NoError(t, tx.Error)
+		for _, it := range items {
+			ranks = append(ranks, it.Rank)
+		}
+		if cursor = surrealdb.NextCursor(tx); cursor == "" {
+			break
+		}
+	}
+	require.Equal(t, []int{6, 5, 4, 3, 2, 1, 0}, ranks)
+
+	// conditions apply to every page
+	var items []PageItem
+	tx := surrealdb.Paginate(db.Where("rank >= ?", 4), "", 5).Find(&items)
+	require.NoError(t, tx.Error)
+	require.Len(t, items, 3)
+	require.Empty(t, surrealdb.NextCursor(tx))
+
+	require.Error(t, surrealdb.Paginate(db, "not a cursor", 2).Find(&items).Error)
+	require.Error(t, surrealdb.Paginate(db, "", 0).Find(&items).Error)
+
+	// FindInBatches reads record ranges too
+	var batches, total int
+	require.NoError(t, db.Model(&PageItem{}).FindInBatches(&items, 3, func(tx *gorm.DB, batch int) error {
+		batches++
+		total += len(items)
+		return nil
+	}).Error)
+	require.Equal(t, 3, batches)
+	require.Equal(t, 7, total)
+}
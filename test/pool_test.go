@@ -0,0 +1,82 @@
+package surrealdb_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/dailaim/surrealdb-gorm"
+	"github.com/dailaim/surrealdb-gorm/models"
+)
+
+type PoolItem struct {
+	models.BaseModel
+	N int `json:"n"`
+}
+
+func setupPooledDB(t *testing.T, minConns, maxConns int) *gorm.DB {
+	d := surrealdb.Open(testDSN()).(*surrealdb.Dialector)
+	d.MinConns = minConns
+	d.MaxConns = maxConns
+	db, err := gorm.Open(d, &gorm.Config{Logger: logger.Default.LogMode(logger.Warn)})
+	require.NoError(t, err)
+	return db
+}
+
+// TestPoolConcurrentWrites spreads concurrent creates across a pool of
+// connections and checks every write landed.
+func TestPoolConcurrentWrites(t *testing.T) {
+	db := setupPooledDB(t, 2, 4)
+	require.NoError(t, db.AutoMigrate(&PoolItem{}))
+	db.Exec("DELETE pool_items")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			errs <- db.Create(&PoolItem{N: n}).Error
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	var count int64
+	require.NoError(t, db.Model(&PoolItem{}).Count(&count).Error)
+	require.EqualValues(t, 32, count)
+}
+
+// TestPoolTransactionPinned runs a transaction while other statements hit the
+// pool; the transaction must keep read-your-own-writes on its own connection.
+func TestPoolTransactionPinned(t *testing.T) {
+	db := setupPooledDB(t, 2, 4)
+	require.NoError(t, db.AutoMigrate(&PoolItem{}))
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		item := PoolItem{N: 1000}
+		if err := tx.Create(&item).Error; err != nil {
+			return err
+		}
+		// Unrelated traffic on the pool while the transaction is open.
+		for i := 0; i < 8; i++ {
+			var n int64
+			if err := db.Model(&PoolItem{}).Count(&n).Error; err != nil {
+				return err
+			}
+		}
+		var got PoolItem
+		if err := tx.First(&got, "n = ?", 1000).Error; err != nil {
+			return fmt.Errorf("read-your-own-writes inside pooled tx: %w", err)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/dailaim/surrealdb-gorm"
 	"github.com/dailaim/surrealdb-gorm/models"
 	"github.com/stretchr/testify/require"
 )
@@ -51,3 +52,37 @@ func TestReconnectAfterDrop(t *testing.T) {
 	require.True(t, sawFailure, "expected a failure while the DB was restarting")
 	require.True(t, recovered, "expected the connection to recover after reconnect")
 }
+
+// TestLiveQuerySurvivesReconnect restarts the database out-of-band like
+// TestReconnectAfterDrop and checks the live query resumes on the same channel.
+func TestLiveQuerySurvivesReconnect(t *testing.T) {
+	if os.Getenv("SURREALDB_RECONNECT_TEST") == "" {
+		t.Skip("set SURREALDB_RECONNECT_TEST=1 and restart the DB during the run")
+	}
+	db := setupDB(t)
+	require.NoError(t, db.AutoMigrate(&RecModel{}))
+	live, err := surrealdb.NewLiveQuery(db, "rec_models", false)
+	require.NoError(t, err)
+	defer live.Kill()
+	ch, err := live.Notifications()
+	require.NoError(t, err)
+
+	var resubscribed bool
+	deadline := time.After(60 * time.Second)
+	for !resubscribed {
+		select {
+		case n := <-ch:
+			resubscribed = n.Action == surrealdb.ActionResubscribed
+		case <-deadline:
+			t.Fatal("expected a resubscribed notification after the restart")
+		}
+	}
+
+	require.NoError(t, db.Create(&RecModel{N: 2}).Error)
+	select {
+	case n := <-ch:
+		require.Equal(t, live.ID, n.ID.String())
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected notifications to resume on the same channel")
+	}
+}
@@ -0,0 +1,53 @@
+package surrealdb_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/dailaim/surrealdb-gorm"
+	"github.com/dailaim/surrealdb-gorm/models"
+)
+
+type ResolverItem struct {
+	models.BaseModel
+	Name string `json:"name"`
+}
+
+// TestReadReplicas points the replica list at the test server itself, so it
+// checks routing end to end rather than replication.
+func TestReadReplicas(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Replicas = []string{cfg.Endpoint, cfg.Endpoint}
+	db, err := gorm.Open(surrealdb.New(cfg), &gorm.Config{Logger: logger.Default.LogMode(logger.Warn)})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&ResolverItem{}))
+	db.Exec("DELETE resolver_items")
+
+	item := ResolverItem{Name: "primary write"}
+	require.NoError(t, db.Create(&item).Error)
+
+	var got ResolverItem
+	require.NoError(t, db.WithContext(surrealdb.UsePrimary(context.Background())).First(&got, item.ID).Error)
+	require.Equal(t, "primary write", got.Name)
+
+	var all []ResolverItem
+	require.NoError(t, db.Find(&all).Error)
+	require.Len(t, all, 1)
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&ResolverItem{Name: "in tx"}).Error; err != nil {
+			return err
+		}
+		var n int64
+		if err := tx.Model(&ResolverItem{}).Count(&n).Error; err != nil {
+			return err
+		}
+		require.EqualValues(t, 2, n, "reads inside a transaction stay on the primary")
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,76 @@
+package surrealdb_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	sdk "github.com/surrealdb/surrealdb.go"
+
+	"github.com/dailaim/surrealdb-gorm"
+	"github.com/dailaim/surrealdb-gorm/models"
+)
+
+type SessionPost struct {
+	models.BaseModel
+	Title string `json:"title"`
+}
+
+// signUpToken issues an end-user JWT through the record access method, the way
+// an application's login endpoint would.
+func signUpToken(t *testing.T, email string) string {
+	cfg := testConfig(t)
+	ctx := context.Background()
+	conn, err := sdk.FromEndpointURLString(ctx, cfg.Endpoint)
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+	token, err := conn.SignUp(ctx, map[string]interface{}{
+		"NS": cfg.Namespace, "DB": cfg.Database, "AC": "post_access",
+		"email": email, "pass": "pw",
+	})
+	require.NoError(t, err)
+	return token
+}
+
+func TestAsTokenRowLevelPermissions(t *testing.T) {
+	root := setupDB(t)
+	m := root.Migrator().(surrealdb.Migrator)
+	require.NoError(t, root.AutoMigrate(&SessionPost{}))
+	require.NoError(t, m.Define(`TABLE OVERWRITE session_posts SCHEMALESS PERMISSIONS
+		FOR select, update, delete WHERE owner = $auth.id
+		FOR create FULL`))
+	require.NoError(t, m.Define(`FIELD OVERWRITE owner ON session_posts VALUE $auth.id`))
+	require.NoError(t, m.Define(`TABLE IF NOT EXISTS post_author SCHEMALESS PERMISSIONS FOR select WHERE id = $auth.id`))
+	require.NoError(t, m.Define(`ACCESS OVERWRITE post_access ON DATABASE TYPE RECORD
+		SIGNUP (CREATE post_author SET email = $email, pass = crypto::argon2::generate($pass))
+		SIGNIN (SELECT * FROM post_author WHERE email = $email AND crypto::argon2::compare(pass, $pass))
+		DURATION FOR SESSION 1h`))
+	defer m.Remove("ACCESS post_access ON DATABASE")
+	root.Exec("DELETE FROM session_posts")
+
+	stamp := time.Now().UnixNano()
+	alice := surrealdb.AsToken(context.Background(), signUpToken(t, fmt.Sprintf("alice%d@example.com", stamp)))
+	bob := surrealdb.AsToken(context.Background(), signUpToken(t, fmt.Sprintf("bob%d@example.com", stamp)))
+
+	require.NoError(t, root.WithContext(alice).Create(&SessionPost{Title: "alice's"}).Error)
+	require.NoError(t, root.WithContext(bob).Create(&SessionPost{Title: "bob's"}).Error)
+
+	var seen []SessionPost
+	require.NoError(t, root.WithContext(alice).Find(&seen).Error)
+	require.Len(t, seen, 1)
+	require.Equal(t, "alice's", seen[0].Title)
+
+	// Bob cannot delete Alice's post: the WHERE filter hides it from his session.
+	require.NoError(t, root.WithContext(bob).Where("title = ?", "alice's").Delete(&SessionPost{}).Error)
+
+	// The root session is untouched by the end-user sessions and sees both rows.
+	var all []SessionPost
+	require.NoError(t, root.Find(&all).Error)
+	require.Len(t, all, 2)
+
+	// A bad token fails instead of silently running as root.
+	err := root.WithContext(surrealdb.AsToken(context.Background(), "not-a-jwt")).Find(&seen).Error
+	require.Error(t, err)
+}
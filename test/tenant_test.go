@@ -0,0 +1,90 @@
+package surrealdb_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/dailaim/surrealdb-gorm"
+	"github.com/dailaim/surrealdb-gorm/models"
+)
+
+type TenantOrder struct {
+	models.BaseModel
+	Tenant string `json:"tenant"`
+}
+
+func TestWithDatabaseIsolatesTenants(t *testing.T) {
+	db := setupPooledDB(t, 1, 4)
+	cfg := testConfig(t)
+	tenants := []string{"tenant_a", "tenant_b", "tenant_c"}
+	for _, name := range tenants {
+		tdb := db.WithContext(surrealdb.WithDatabase(context.Background(), cfg.Namespace, name))
+		require.NoError(t, tdb.AutoMigrate(&TenantOrder{}))
+		require.NoError(t, tdb.Exec("DELETE FROM tenant_orders").Error)
+	}
+
+	// Concurrent writes for different tenants must not leak into each other.
+	var wg sync.WaitGroup
+	errs := make(chan error, len(tenants)*5)
+	for _, name := range tenants {
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				ctx := surrealdb.WithDatabase(context.Background(), cfg.Namespace, name)
+				errs <- db.WithContext(ctx).Create(&TenantOrder{Tenant: name}).Error
+			}(name)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	for _, name := range tenants {
+		var orders []TenantOrder
+		ctx := surrealdb.WithDatabase(context.Background(), cfg.Namespace, name)
+		require.NoError(t, db.WithContext(ctx).Find(&orders).Error)
+		require.Len(t, orders, 5, "tenant %s", name)
+		for _, o := range orders {
+			require.Equal(t, name, o.Tenant)
+		}
+	}
+}
+
+func TestWithDatabaseTransaction(t *testing.T) {
+	db := setupPooledDB(t, 1, 2)
+	cfg := testConfig(t)
+	ctx := surrealdb.WithDatabase(context.Background(), cfg.Namespace, "tenant_tx")
+	tdb := db.WithContext(ctx)
+	require.NoError(t, tdb.AutoMigrate(&TenantOrder{}))
+	require.NoError(t, tdb.Exec("DELETE FROM tenant_orders").Error)
+
+	err := tdb.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&TenantOrder{Tenant: "tenant_tx"}).Error; err != nil {
+			return err
+		}
+		var n int64
+		if err := tx.Model(&TenantOrder{}).Count(&n).Error; err != nil {
+			return err
+		}
+		if n != 1 {
+			return fmt.Errorf("read-your-own-writes inside tenant tx: got %d rows", n)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	var n int64
+	require.NoError(t, tdb.Model(&TenantOrder{}).Count(&n).Error)
+	require.EqualValues(t, 1, n)
+	var inDefault int64
+	db.Model(&TenantOrder{}).Count(&inDefault)
+	require.Zero(t, inDefault, "tenant writes must not reach the configured database")
+}
@@ -0,0 +1,240 @@
+package surrealdb_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	surrealdb "github.com/dailaim/surrealdb-gorm"
+	"github.com/dailaim/surrealdb-gorm/models"
+)
+
+type Reader struct {
+	models.BaseModel
+	Name   string
+	Novels []Novel `gorm:"many2many:likes;joinForeignKey:in;joinReferences:out"`
+}
+
+type Novel struct {
+	models.BaseModel
+	Title string
+}
+
+type Like struct {
+	models.EdgeBaseModel[Reader, Novel]
+	Stars int
+}
+
+func (Like) UniqueEdges() bool { return true }
+
+func TestUniqueEdges(t *testing.T) {
+	db := setupDB(t)
+	require.NoError(t, db.AutoMigrate(&Reader{}, &Novel{}, &Like{}))
+	cleanup := func() {
+		db.Exec("DELETE FROM likes")
+		db.Exec("DELETE FROM readers")
+		db.Exec("DELETE FROM novels")
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	reader := Reader{Name: "ada"}
+	dune, emma := Novel{Title: "dune"}, Novel{Title: "emma"}
+	for _, v := range []interface{}{&reader, &dune, &emma} {
+		require.NoError(t, db.Create(v).Error)
+	}
+	likes := func() []Like {
+		var ls []Like
+		require.NoError(t, db.Unscoped().Find(&ls).Error)
+		return ls
+	}
+
+	first := Like{EdgeBaseModel: models.NewEdgeBaseModel[Reader, Novel](reader.ID, dune.ID), Stars: 3}
+	require.NoError(t, db.Create(&first).Error)
+	require.NotNil(t, first.ID)
+	again := Like{EdgeBaseModel: models.NewEdgeBaseModel[Reader, Novel](reader.ID, dune.ID), Stars: 5}
+	require.NoError(t, db.Create(&again).Error)
+	require.Equal(t, first.ID.String(), again.ID.String())
+	ls := likes()
+	require.Len(t, ls, 1)
+	require.Equal(t, 5, ls[0].Stars)
+
+	d := db.Dialector.(*surrealdb.Dialector)
+	_, err := surrealdb.Relate(context.Background(), d, reader.ID.String(), "likes", dune.ID.String(), map[string]interface{}{"stars": 4})
+	require.NoError(t, err)
+	ls = likes()
+	require.Len(t, ls, 1)
+	require.Equal(t, 4, ls[0].Stars)
+
+	require.NoError(t, db.Model(&reader).Association("Novels").Append(&dune, &emma))
+	require.Len(t, likes(), 2)
+	require.NoError(t, surrealdb.AppendEdge(db.Model(&reader).Association("Novels"), &dune, map[string]any{"stars": 1}))
+	require.Len(t, likes(), 2)
+	require.EqualValues(t, 2, db.Model(&reader).Association("Novels").Count())
+
+	// a soft-deleted edge is restored rather than duplicated
+	require.NoError(t, db.Delete(&first).Error)
+	var live []Like
+	require.NoError(t, db.Find(&live).Error)
+	require.Len(t, live, 1)
+	require.NoError(t, db.Create(&Like{EdgeBaseModel: models.NewEdgeBaseModel[Reader, Novel](reader.ID, dune.ID), Stars: 2}).Error)
+	require.Len(t, likes(), 2)
+	live = nil
+	require.NoError(t, db.Find(&live).Error)
+	require.Len(t, live, 2)
+
+	// the same book for another reader is another edge
+	bob := Reader{Name: "bob"}
+	require.NoError(t, db.Create(&bob).Error)
+	require.NoError(t, db.Model(&bob).Association("Novels").Append(&emma))
+	require.Len(t, likes(), 3)
+
+	// the index refuses duplicates written around the dialect
+	require.Error(t, db.Exec("RELATE "+reader.ID.String()+"->likes->"+emma.ID.String()).Error)
+}
+
+// TestUniqueEdgesConcurrent creates the same edge from several goroutines;
+// those that lose the race to the [in, out] index update the edge instead.
+func TestUniqueEdgesConcurrent(t *testing.T) {
+	db := setupPooledDB(t, 2, 4)
+	require.NoError(t, db.AutoMigrate(&Reader{}, &Novel{}, &Like{}))
+	cleanup := func() {
+		db.Exec("DELETE FROM likes")
+		db.Exec("DELETE FROM readers")
+		db.Exec("DELETE FROM novels")
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	reader, dune := Reader{Name: "ada"}, Novel{Title: "dune"}
+	require.NoError(t, db.Create(&reader).Error)
+	require.NoError(t, db.Create(&dune).Error)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			errs <- db.Create(&Like{EdgeBaseModel: models.NewEdgeBaseModel[Reader, Novel](reader.ID, dune.ID), Stars: n}).Error
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	var ls []Like
+	require.NoError(t, db.Find(&ls).Error)
+	require.Len(t, ls, 1)
+}
+
+// TestUniqueEdgesWithoutMigrate works on a schema another process migrated:
+// this connection never runs AutoMigrate, so the edge model is taken from
+// the statement or the join table, or the [in, out] index is relied on.
+func TestUniqueEdgesWithoutMigrate(t *testing.T) {
+	require.NoError(t, setupDB(t).AutoMigrate(&Reader{}, &Novel{}, &Like{}))
+	db := setupDB(t)
+	cleanup := func() {
+		db.Exec("DELETE FROM likes")
+		db.Exec("DELETE FROM readers")
+		db.Exec("DELETE FROM novels")
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	reader := Reader{Name: "ada"}
+	dune, emma := Novel{Title: "dune"}, Novel{Title: "emma"}
+	for _, v := range []interface{}{&reader, &dune, &emma} {
+		require.NoError(t, db.Create(v).Error)
+	}
+	likes := func() []Like {
+		var ls []Like
+		require.NoError(t, db.Find(&ls).Error)
+		return ls
+	}
+
+	for stars := 1; stars <= 2; stars++ {
+		require.NoError(t, db.Create(&Like{EdgeBaseModel: models.NewEdgeBaseModel[Reader, Novel](reader.ID, dune.ID), Stars: stars}).Error)
+	}
+	ls := likes()
+	require.Len(t, ls, 1)
+	require.Equal(t, 2, ls[0].Stars)
+
+	d := db.Dialector.(*surrealdb.Dialector)
+	_, err := surrealdb.Relate(context.Background(), d, reader.ID.String(), "likes", dune.ID.String(), map[string]interface{}{"stars": 4})
+	require.NoError(t, err)
+	// associations need the edge table, which AutoMigrate would register
+	d.RegisterEdgeTable("likes")
+	require.NoError(t, db.Model(&reader).Association("Novels").Append(&dune, &emma))
+	require.NoError(t, surrealdb.AppendEdge(db.Model(&reader).Association("Novels"), &emma, map[string]any{"stars": 5}))
+	ls = likes()
+	require.Len(t, ls, 2)
+	var duneLike Like
+	for _, l := range ls {
+		if l.EdgeOut().String() == dune.ID.String() {
+			require.Equal(t, 4, l.Stars)
+			duneLike = l
+		} else {
+			require.Equal(t, 5, l.Stars)
+		}
+	}
+
+	// with the edge model as the join table, a soft delete is undone too
+	require.NoError(t, db.SetupJoinTable(&Reader{}, "Novels", &Like{}))
+	require.NoError(t, db.Delete(&duneLike).Error)
+	require.Len(t, likes(), 1)
+	require.NoError(t, surrealdb.AppendEdge(db.Model(&reader).Association("Novels"), &dune, nil))
+	require.Len(t, likes(), 2)
+}
+
+type Fave struct {
+	models.EdgeBaseModel[Reader, Novel]
+	Stars int
+}
+
+func (Fave) TableName() string { return "reader-faves" }
+func (Fave) UniqueEdges() bool { return true }
+
+// TestUniqueEdgesQuotedTable upserts edges in a table whose name is not a
+// bare identifier, so it has to be quoted or bound in SurrealQL.
+func TestUniqueEdgesQuotedTable(t *testing.T) {
+	db := setupDB(t)
+	require.NoError(t, db.AutoMigrate(&Reader{}, &Novel{}))
+	require.NoError(t, db.Exec("DEFINE TABLE IF NOT EXISTS `reader-faves` TYPE RELATION SCHEMALESS").Error)
+	require.NoError(t, db.Exec("DEFINE INDEX IF NOT EXISTS `idx_reader-faves_in_out` ON `reader-faves` FIELDS `in`, `out` UNIQUE").Error)
+	d := db.Dialector.(*surrealdb.Dialector)
+	d.RegisterEdgeTable("reader-faves")
+	cleanup := func() {
+		db.Exec("DELETE FROM `reader-faves`")
+		db.Exec("DELETE FROM readers")
+		db.Exec("DELETE FROM novels")
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	reader, dune := Reader{Name: "ada"}, Novel{Title: "dune"}
+	require.NoError(t, db.Create(&reader).Error)
+	require.NoError(t, db.Create(&dune).Error)
+	faves := func() []Fave {
+		var fs []Fave
+		require.NoError(t, db.Find(&fs).Error)
+		return fs
+	}
+
+	for stars := 1; stars <= 2; stars++ {
+		require.NoError(t, db.Create(&Fave{EdgeBaseModel: models.NewEdgeBaseModel[Reader, Novel](reader.ID, dune.ID), Stars: stars}).Error)
+	}
+	fs := faves()
+	require.Len(t, fs, 1)
+	require.Equal(t, 2, fs[0].Stars)
+
+	_, err := surrealdb.Relate(context.Background(), d, reader.ID.String(), "reader-faves", dune.ID.String(), map[string]interface{}{"stars": 4})
+	require.NoError(t, err)
+	fs = faves()
+	require.Len(t, fs, 1)
+	require.Equal(t, 4, fs[0].Stars)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/surrealdb/surrealdb.go"
 	sdkModels "github.com/surrealdb/surrealdb.go/pkg/models"
@@ -31,6 +32,9 @@ type SurrealTx struct {
 	dialector *Dialector
 	ctx       context.Context
 	sdkTx     *surrealdb.Transaction
+	conn      *poolConn // pooled connection pinned for the transaction's lifetime
+	unlease   func()    // releases the session the transaction runs in, if any
+	done      sync.Once
 }
 
 // SDKTx returns the underlying *surrealdb.Transaction so that callbacks
@@ -114,6 +118,7 @@ func (t *SurrealTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt
 
 // Commit commits the transaction, making all changes visible to other sessions.
 func (t *SurrealTx) Commit() error {
+	defer t.releaseConn()
 	if t.sdkTx.IsClosed() {
 		return nil // already committed or rolled back — treat as no-op
 	}
@@ -122,10 +127,30 @@ func (t *SurrealTx) Commit() error {
 
 // Rollback cancels the transaction, discarding all changes.
 func (t *SurrealTx) Rollback() error {
+	return t.cancel(t.ctx)
+}
+
+func (t *SurrealTx) cancel(ctx context.Context) error {
+	defer t.releaseConn()
 	if t.sdkTx.IsClosed() {
 		return nil
 	}
-	return t.sdkTx.Cancel(t.ctx)
+	return t.sdkTx.Cancel(ctx)
+}
+
+// releaseConn hands the pinned connection back to the pool once the
+// transaction is finished, however it finished. Only the first call does.
+func (t *SurrealTx) releaseConn() {
+	t.done.Do(func() {
+		t.dialector.txs.Delete(t)
+		if t.unlease != nil {
+			t.unlease()
+		}
+		if t.conn != nil {
+			t.conn.unpin()
+			t.conn.release()
+		}
+	})
 }
 
 // ============================================================================
@@ -175,7 +200,7 @@ func (t *Tx) Err() error { return t.err }
 //	})
 func Transaction(db *gorm.DB, fn func(tx *Tx) error) error {
 	d, ok := db.Dialector.(*Dialector)
-	if !ok || d.Conn == nil {
+	if !ok {
 		return fmt.Errorf("surrealdb: connection not initialized")
 	}
 
@@ -208,7 +233,12 @@ func Transaction(db *gorm.DB, fn func(tx *Tx) error) error {
 	all = append(all, "COMMIT TRANSACTION;")
 	fullQuery := strings.Join(all, "\n")
 
-	results, err := surrealdb.Query[interface{}](ctx, d.Conn, fullQuery, tx.params)
+	target, release, err := d.acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("surrealdb transaction: %w", err)
+	}
+	defer release()
+	results, err := queryOn[interface{}](ctx, target, fullQuery, tx.params)
 	if err != nil {
 		return fmt.Errorf("surrealdb transaction: %w", err)
 	}
@@ -0,0 +1,1175 @@
+package surrealdb
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ============================================================================
+// GORM SQL → SurrealQL translation
+// ============================================================================
+
+// sqlTokenKind classifies a token of GORM's SQL output.
+type sqlTokenKind int
+
+const (
+	tokWord   sqlTokenKind = iota // keyword or bare identifier
+	tokIdent                      // `quoted` or ⟨quoted⟩ identifier
+	tokString                     // '...' or "..." literal
+	tokNumber                     // numeric literal, including durations like 1d
+	tokParam                      // $name
+	tokPunct                      // operator or punctuation
+)
+
+// sqlToken is one token and the whitespace (or comment) written before it,
+// so untouched parts of a statement render exactly as GORM built them.
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+	pre  string
+}
+
+// name is the identifier a word or quoted identifier refers to.
+func (t sqlToken) name() string {
+	if t.kind == tokIdent {
+		if strings.HasPrefix(t.text, "⟨") {
+			return strings.TrimSuffix(strings.TrimPrefix(t.text, "⟨"), "⟩")
+		}
+		return strings.ReplaceAll(strings.TrimSuffix(t.text[1:], "`"), "\\`", "`")
+	}
+	if t.kind == tokWord {
+		return t.text
+	}
+	return ""
+}
+
+func (t sqlToken) isName() bool { return t.kind == tokWord || t.kind == tokIdent }
+
+// is reports whether t is one of the keywords, case-insensitively. Quoted
+// identifiers never are.
+func (t sqlToken) is(keywords ...string) bool {
+	if t.kind != tokWord {
+		return false
+	}
+	for _, kw := range keywords {
+		if strings.EqualFold(t.text, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t sqlToken) isPunct(text string) bool { return t.kind == tokPunct && t.text == text }
+
+// sqlOperators lists the multi-character operators, longest first.
+var sqlOperators = []string{
+	"<->", "<>", "!=", "<=", ">=", "==", "->", "<-", "::", "||", "&&",
+	"?:", "??", "..", "+=", "-=", "*=", "**", "?=", "!~", "@@",
+}
+
+// tokenizeSQL splits sql into tokens. It never fails: anything it does not
+// recognise becomes a one-character punctuation token, and an unterminated
+// literal runs to the end of the input.
+func tokenizeSQL(sql string) []sqlToken {
+	var toks []sqlToken
+	i, start := 0, 0
+	for i < len(sql) {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case strings.HasPrefix(sql[i:], "/*"):
+			if end := strings.Index(sql[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(sql)
+			}
+			continue
+		case strings.HasPrefix(sql[i:], "--"):
+			if end := strings.IndexByte(sql[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(sql)
+			}
+			continue
+		}
+
+		pre := sql[start:i]
+		kind, end := tokPunct, i+1
+		switch {
+		case c == '`' || c == '\'' || c == '"':
+			kind = tokString
+			if c == '`' {
+				kind = tokIdent
+			}
+			end = quoteEnd(sql, i, c)
+		case strings.HasPrefix(sql[i:], "⟨"):
+			kind = tokIdent
+			if j := strings.Index(sql[i:], "⟩"); j >= 0 {
+				end = i + j + len("⟩")
+			} else {
+				end = len(sql)
+			}
+		case c == '$':
+			kind = tokParam
+			end = wordEnd(sql, i+1)
+		case c >= '0' && c <= '9':
+			kind = tokNumber
+			end = wordEnd(sql, i)
+			// A decimal point followed by digits belongs to the number; a
+			// range (1..5) does not.
+			for end+1 < len(sql) && sql[end] == '.' && sql[end+1] >= '0' && sql[end+1] <= '9' {
+				end = wordEnd(sql, end+1)
+			}
+		case isWordByte(c) || c >= utf8.RuneSelf && isWordRune(sql[i:]):
+			kind = tokWord
+			end = wordEnd(sql, i)
+		default:
+			for _, op := range sqlOperators {
+				if strings.HasPrefix(sql[i:], op) {
+					end = i + len(op)
+					break
+				}
+			}
+			if end == i+1 && c >= utf8.RuneSelf {
+				_, size := utf8.DecodeRuneInString(sql[i:])
+				end = i + size
+			}
+		}
+		toks = append(toks, sqlToken{kind: kind, text: sql[i:end], pre: pre})
+		i, start = end, end
+	}
+	return toks
+}
+
+// quoteEnd returns the index just past the literal opened by quote at i.
+// Backslash escapes and doubled quotes stay inside it.
+func quoteEnd(sql string, i int, quote byte) int {
+	for j := i + 1; j < len(sql); j++ {
+		switch sql[j] {
+		case '\\':
+			j++
+		case quote:
+			if j+1 < len(sql) && sql[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(sql)
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func isWordRune(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsLetter(r)
+}
+
+func wordEnd(sql string, i int) int {
+	for i < len(sql) {
+		if isWordByte(sql[i]) {
+			i++
+			continue
+		}
+		if sql[i] >= utf8.RuneSelf && isWordRune(sql[i:]) {
+			_, size := utf8.DecodeRuneInString(sql[i:])
+			i += size
+			continue
+		}
+		break
+	}
+	return i
+}
+
+// renderSQL joins tokens back into a statement. Tokens inserted without
+// whitespace are separated by a single space where they would otherwise run
+// together.
+func renderSQL(toks []sqlToken) string {
+	var b strings.Builder
+	for i, t := range toks {
+		if i > 0 && t.pre == "" && needsSpace(toks[i-1], t) {
+			b.WriteByte(' ')
+		} else {
+			b.WriteString(t.pre)
+		}
+		b.WriteString(t.text)
+	}
+	return b.String()
+}
+
+// needsSpace reports whether a and b would lex differently if adjacent.
+func needsSpace(a, b sqlToken) bool {
+	return a.kind != tokPunct && b.kind != tokPunct
+}
+
+// words builds tokens from SurrealQL text spliced into a statement.
+func words(sql string) []sqlToken {
+	toks := tokenizeSQL(sql)
+	if len(toks) > 0 {
+		toks[0].pre = " "
+	}
+	return toks
+}
+
+// closing returns the index of the bracket closing the one opened at i, or
+// len(toks) when it is unbalanced.
+func closing(toks []sqlToken, i int) int {
+	depth := 0
+	for j := i; j < len(toks); j++ {
+		if toks[j].kind != tokPunct {
+			continue
+		}
+		switch toks[j].text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return len(toks)
+}
+
+// scopeEnd returns the end of the clause list starting at i: the index of
+// the first closing bracket or `;` at the same nesting level, or len(toks).
+func scopeEnd(toks []sqlToken, i int) int {
+	for j := i; j < len(toks); j++ {
+		t := toks[j]
+		if t.kind != tokPunct {
+			continue
+		}
+		switch t.text {
+		case "(", "[", "{":
+			j = closing(toks, j)
+		case ")", "]", "}", ";":
+			return j
+		}
+	}
+	return len(toks)
+}
+
+// findTop returns the index of the first of keywords in toks[from:to] at
+// the same nesting level as from, or -1.
+func findTop(toks []sqlToken, from, to int, keywords ...string) int {
+	for j := from; j < to; j++ {
+		switch {
+		case toks[j].isPunct("(") || toks[j].isPunct("[") || toks[j].isPunct("{"):
+			j = closing(toks, j)
+		case toks[j].is(keywords...):
+			return j
+		}
+	}
+	return -1
+}
+
+// statements splits toks at top-level semicolons into [start, end) ranges.
+func statements(toks []sqlToken) [][2]int {
+	var out [][2]int
+	start := 0
+	for start <= len(toks) {
+		end := scopeEnd(toks, start)
+		for end < len(toks) && !toks[end].isPunct(";") {
+			// An unbalanced closing bracket: keep scanning past it.
+			end = scopeEnd(toks, end+1)
+		}
+		out = append(out, [2]int{start, end})
+		start = end + 1
+	}
+	return out
+}
+
+// translator rewrites the SQL GORM builds into SurrealQL. It works on
+// tokens, so string literals, quoted identifiers, parameters and nested
+// subqueries are never mistaken for the constructs being rewritten.
+type translator struct {
+	// table is the statement's table. alias is the name GORM used for it
+	// when that differs (an edge table registered under another name).
+	table, alias string
+	// edgeTable resolves registered edge tables, see Dialector.FindEdgeTable.
+	edgeTable func(string) (string, bool)
+	// params are the statement's bound values; LIMIT and START params are
+	// inlined and removed from it.
+	params map[string]interface{}
+}
+
+// newTranslator returns the translator for a statement on table.
+func (d *Dialector) newTranslator(table string, params map[string]interface{}) *translator {
+	tr := &translator{table: table, edgeTable: d.FindEdgeTable, params: params}
+	if table != "" {
+		if canonical, ok := d.FindEdgeTable(table); ok && canonical != table {
+			tr.table, tr.alias = canonical, table
+		}
+	}
+	return tr
+}
+
+// translate returns sql as SurrealQL.
+func (tr *translator) translate(sql string) string {
+	return eachStatement(sql, tr.statement)
+}
+
+// eachStatement applies rewrite to every statement of sql.
+func eachStatement(sql string, rewrite func([]sqlToken) []sqlToken) string {
+	toks := tokenizeSQL(sql)
+	var out []sqlToken
+	for n, r := range statements(toks) {
+		if n > 0 {
+			out = append(out, toks[r[0]-1]) // the `;`
+		}
+		stmt := append([]sqlToken(nil), toks[r[0]:r[1]]...)
+		out = append(out, rewrite(stmt)...)
+	}
+	return renderSQL(out)
+}
+
+func (tr *translator) statement(toks []sqlToken) []sqlToken {
+	if len(toks) == 0 {
+		return toks
+	}
+	toks = tr.canonicalTable(toks)
+	toks = tr.operators(toks)
+	toks = tr.membership(toks)
+	toks = tr.like(toks)
+	toks = tr.edgeJoins(toks)
+	toks = tr.countAll(toks)
+	toks = aggregates(toks)
+	toks = tr.unqualify(toks)
+	toks = deleteFrom(toks)
+	toks = softDelete(toks)
+	toks = tr.inlinePaging(toks)
+	return toks
+}
+
+// tableKeywords precede a table name.
+var tableKeywords = []string{"FROM", "UPDATE", "DELETE", "INTO", "JOIN", "ONLY", "TABLE", "UPSERT", "CREATE"}
+
+// canonicalTable renames the edge table alias to its canonical name where
+// it names a table: after FROM, JOIN, ... or as a column qualifier. A column
+// that happens to share the alias keeps its name.
+func (tr *translator) canonicalTable(toks []sqlToken) []sqlToken {
+	if tr.alias == "" {
+		return toks
+	}
+	for i, t := range toks {
+		if !t.isName() || t.name() != tr.alias {
+			continue
+		}
+		qualifier := i+1 < len(toks) && toks[i+1].isPunct(".") && (i == 0 || !toks[i-1].isPunct("."))
+		if qualifier || i > 0 && toks[i-1].is(tableKeywords...) {
+			toks[i] = sqlToken{kind: tokIdent, text: "`" + tr.table + "`", pre: t.pre}
+		}
+	}
+	return toks
+}
+
+// operators maps SQL operator and keyword spellings to SurrealQL's.
+func (tr *translator) operators(toks []sqlToken) []sqlToken {
+	for i, t := range toks {
+		switch {
+		case t.isPunct("<>"):
+			toks[i].text = "!="
+		case t.is("OFFSET"):
+			toks[i].text = "START"
+		}
+	}
+	return toks
+}
+
+// operandKeywords are words that cannot end the left operand of IN.
+var operandKeywords = []string{"WHERE", "AND", "OR", "NOT", "ON", "HAVING", "SELECT", "WHEN", "THEN", "ELSE", "RETURN", "IF", "BY", "SET", "FROM"}
+
+// membership rewrites SQL's `x IN (a, b)` and `x NOT IN (a, b)` to
+// SurrealQL's array membership `x INSIDE [a, b]` and `x NOTINSIDE [a, b]`:
+// `(a, b)` is not an array in SurrealQL, so the SQL form silently matches
+// nothing. A single parameter bound to a slice is used as the array itself,
+// GORM's `IN (NULL)` for an empty slice becomes `[]`, and a subquery selects
+// VALUE so its rows are the values compared. Row-value lists, `(a, b) IN
+// ((1, 2))`, have no SurrealQL equivalent and are left as they are.
+func (tr *translator) membership(toks []sqlToken) []sqlToken {
+	for i := 1; i+1 < len(toks); i++ {
+		if !toks[i].is("IN") {
+			continue
+		}
+		op := i
+		if toks[i-1].is("NOT") {
+			op = i - 1
+		}
+		if op == 0 || !isOperandEnd(toks[op-1]) || op >= 2 && toks[op-2].is("FOR") {
+			continue
+		}
+
+		operator := sqlToken{kind: tokWord, text: "INSIDE", pre: toks[op].pre}
+		if op < i {
+			operator.text = "NOTINSIDE"
+		}
+		var rhs []sqlToken
+		end := i + 1
+		switch {
+		case toks[i+1].kind == tokParam:
+			rhs = toks[i+1 : i+2]
+			end = i + 2
+		case toks[i+1].isPunct("("):
+			end = closing(toks, i+1) + 1
+			if end > len(toks) {
+				continue
+			}
+			inner := toks[i+2 : end-1]
+			if len(inner) > 0 && inner[0].isPunct("(") {
+				continue
+			}
+			rhs = tr.membershipList(toks[i+1], inner)
+		default:
+			rhs = toks[i+1 : i+2]
+			end = i + 2
+		}
+
+		rewritten := append([]sqlToken{operator}, rhs...)
+		// Continue inside the rewritten operand: a subquery has its own.
+		toks = append(toks[:op], append(rewritten, toks[end:]...)...)
+		i = op
+	}
+	return toks
+}
+
+// membershipList returns the right-hand side of INSIDE for the
+// parenthesised list inner.
+func (tr *translator) membershipList(open sqlToken, inner []sqlToken) []sqlToken {
+	switch {
+	case len(inner) > 0 && inner[0].is("SELECT"):
+		sub := append([]sqlToken{open}, selectValue(inner)...)
+		return append(sub, sqlToken{kind: tokPunct, text: ")"})
+	case len(inner) == 1 && inner[0].kind == tokParam && isListValue(tr.params[strings.TrimPrefix(inner[0].text, "$")]):
+		return []sqlToken{{kind: tokParam, text: inner[0].text, pre: open.pre}}
+	case len(inner) == 1 && inner[0].is("NULL"), len(inner) == 0:
+		inner = nil
+	}
+	list := append([]sqlToken{{kind: tokPunct, text: "[", pre: open.pre}}, inner...)
+	return append(list, sqlToken{kind: tokPunct, text: "]"})
+}
+
+// isOperandEnd reports whether t can end the expression left of IN.
+func isOperandEnd(t sqlToken) bool {
+	switch t.kind {
+	case tokIdent, tokString, tokNumber, tokParam:
+		return true
+	case tokWord:
+		return !t.is(operandKeywords...)
+	}
+	return t.isPunct(")") || t.isPunct("]") || t.isPunct("}")
+}
+
+// selectValue makes a single-field subquery SELECT VALUE, so it returns
+// the field's values rather than one object per row.
+func selectValue(sub []sqlToken) []sqlToken {
+	from := findTop(sub, 1, len(sub), "FROM")
+	if from < 2 || sub[1].is("VALUE") || sub[1].isPunct("*") && from == 2 ||
+		findTop(sub, 1, from, "AS") >= 0 || hasTopComma(sub[1:from]) {
+		return sub
+	}
+	return append(sub[:1], append(words("VALUE"), sub[1:]...)...)
+}
+
+// hasTopComma reports whether toks contains a comma outside brackets.
+func hasTopComma(toks []sqlToken) bool {
+	for j := 0; j < len(toks); j++ {
+		switch {
+		case toks[j].isPunct("(") || toks[j].isPunct("[") || toks[j].isPunct("{"):
+			j = closing(toks, j)
+		case toks[j].isPunct(","):
+			return true
+		}
+	}
+	return false
+}
+
+// isListValue reports whether a bound value is sent as an array. Bytes and
+// the SDK's geometry types are slices in Go but not arrays in SurrealQL.
+func isListValue(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	rt := reflect.TypeOf(v)
+	if rt.Kind() != reflect.Slice && rt.Kind() != reflect.Array || rt.Elem().Kind() == reflect.Uint8 {
+		return false
+	}
+	return !strings.HasSuffix(rt.PkgPath(), "surrealdb.go/pkg/models")
+}
+
+// like rewrites SQL's LIKE, NOT LIKE and ILIKE, which SurrealQL lacks, to
+// string functions: 'abc%' is string::starts_with, '%abc' string::ends_with,
+// '%abc%' string::contains and a pattern without wildcards an equality. Any
+// other pattern is matched with string::matches and an anchored regex. ILIKE
+// compares lowercased. The pattern must be a literal or a bound string; it is
+// rewritten in place.
+func (tr *translator) like(toks []sqlToken) []sqlToken {
+	for i := 1; i+1 < len(toks); i++ {
+		if !toks[i].is("LIKE", "ILIKE") {
+			continue
+		}
+		op, negate := i, toks[i-1].is("NOT")
+		if negate {
+			op = i - 1
+		}
+		if op == 0 || !isOperandEnd(toks[op-1]) {
+			continue
+		}
+		pattern, ok := tr.stringValue(toks[i+1])
+		if !ok {
+			continue
+		}
+		// An ESCAPE clause names the escape character, one or none.
+		end, escape := i+2, byte('\\')
+		if end+1 < len(toks) && toks[end].is("ESCAPE") {
+			e, ok := tr.stringValue(toks[end+1])
+			if !ok || len(e) > 1 {
+				continue
+			}
+			escape = 0
+			if e != "" {
+				escape = e[0]
+			}
+			end += 2
+		}
+		start := operandStart(toks, op-1)
+		lhs := strings.TrimSpace(renderSQL(toks[start:op]))
+		fold := toks[i].is("ILIKE")
+		if fold {
+			lhs = "string::lowercase(" + lhs + ")"
+		}
+		fn, arg := likeFunc(pattern, escape, fold)
+		rhs := tr.withString(toks[i+1], arg)
+
+		var expr string
+		switch {
+		case fn == "" && negate:
+			expr = fmt.Sprintf("%s != %s", lhs, rhs)
+		case fn == "":
+			expr = fmt.Sprintf("%s = %s", lhs, rhs)
+		case negate:
+			expr = fmt.Sprintf("!%s(%s, %s)", fn, lhs, rhs)
+		default:
+			expr = fmt.Sprintf("%s(%s, %s)", fn, lhs, rhs)
+		}
+		rewritten := tokenizeSQL(expr)
+		rewritten[0].pre = toks[start].pre
+		toks = append(toks[:start], append(rewritten, toks[end:]...)...)
+		i = start + len(rewritten) - 1
+	}
+	return toks
+}
+
+// likeFunc returns the string function and argument that match pattern, a
+// LIKE pattern with escape as its escape character, or none when it is 0. An
+// empty function means the pattern has no wildcards and arg is compared for
+// equality.
+func likeFunc(pattern string, escape byte, fold bool) (fn, arg string) {
+	// Split the pattern into literal runs and wildcards.
+	var parts []string
+	var lit strings.Builder
+	wild := func(w string) {
+		parts = append(parts, lit.String(), w)
+		lit.Reset()
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case escape != 0 && c == escape && i+1 < len(pattern):
+			i++
+			lit.WriteByte(pattern[i])
+		case c == '%' || c == '_':
+			wild(string(c))
+		default:
+			lit.WriteByte(c)
+		}
+	}
+	parts = append(parts, lit.String())
+	if fold {
+		for n := 0; n < len(parts); n += 2 {
+			parts[n] = strings.ToLower(parts[n])
+		}
+	}
+
+	// parts alternates literal, wildcard, literal, ...
+	switch {
+	case len(parts) == 1:
+		return "", parts[0]
+	case len(parts) == 3 && parts[1] == "%" && parts[2] == "":
+		return "string::starts_with", parts[0]
+	case len(parts) == 3 && parts[1] == "%" && parts[0] == "":
+		return "string::ends_with", parts[2]
+	case len(parts) == 5 && parts[1] == "%" && parts[3] == "%" && parts[0] == "" && parts[4] == "":
+		return "string::contains", parts[2]
+	}
+	var re strings.Builder
+	re.WriteString("(?s)^")
+	for n, part := range parts {
+		switch {
+		case n%2 == 0:
+			re.WriteString(regexp.QuoteMeta(part))
+		case part == "%":
+			re.WriteString(".*")
+		default:
+			re.WriteString(".")
+		}
+	}
+	re.WriteString("$")
+	return "string::matches", re.String()
+}
+
+// stringValue returns the string a literal or bound parameter holds.
+func (tr *translator) stringValue(t sqlToken) (string, bool) {
+	switch t.kind {
+	case tokParam:
+		s, ok := tr.params[strings.TrimPrefix(t.text, "$")].(string)
+		return s, ok
+	case tokString:
+		if len(t.text) < 2 || t.text[0] != t.text[len(t.text)-1] {
+			return "", false
+		}
+		q := t.text[:1]
+		s := t.text[1 : len(t.text)-1]
+		s = strings.ReplaceAll(s, q+q, q)
+		s = strings.ReplaceAll(s, "\\"+q, q)
+		return s, true
+	}
+	return "", false
+}
+
+// withString binds s where t was: the parameter's value is replaced, a
+// literal is written anew.
+func (tr *translator) withString(t sqlToken, s string) string {
+	if t.kind == tokParam {
+		tr.params[strings.TrimPrefix(t.text, "$")] = s
+		return t.text
+	}
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	return "'" + strings.ReplaceAll(s, "'", "\\'") + "'"
+}
+
+// operandStart returns the index of the first token of the operand ending
+// at end: a column, a qualified or nested path, or a function call.
+func operandStart(toks []sqlToken, end int) int {
+	j := end
+	for {
+		if toks[j].isPunct(")") || toks[j].isPunct("]") {
+			j = opening(toks, j)
+			// A function call: name, or a name::path.
+			for j > 0 && toks[j-1].isName() {
+				j--
+				if j < 2 || !toks[j-1].isPunct("::") {
+					break
+				}
+				j--
+			}
+		}
+		if j >= 2 && toks[j-1].isPunct(".") && (toks[j-2].isName() || toks[j-2].isPunct(")") || toks[j-2].isPunct("]")) {
+			j -= 2
+			continue
+		}
+		return j
+	}
+}
+
+// opening returns the index of the bracket opening the one closed at i, or
+// 0 when it is unbalanced.
+func opening(toks []sqlToken, i int) int {
+	depth := 0
+	for j := i; j >= 0; j-- {
+		if toks[j].kind != tokPunct {
+			continue
+		}
+		switch toks[j].text {
+		case ")", "]", "}":
+			depth++
+		case "(", "[", "{":
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return 0
+}
+
+// countAll rewrites count(*) and count(1) to count(). A SELECT that starts
+// with such a count aggregates the whole table, which SurrealQL spells
+// GROUP ALL; GORM's association counts over an edge table are turned into
+// a count of the owner's edges.
+func (tr *translator) countAll(toks []sqlToken) []sqlToken {
+	for i := 0; i+3 < len(toks); i++ {
+		if toks[i].is("count") && toks[i+1].isPunct("(") && toks[i+3].isPunct(")") &&
+			(toks[i+2].isPunct("*") || toks[i+2].kind == tokNumber && toks[i+2].text == "1") {
+			toks = append(toks[:i+2], toks[i+3:]...)
+		}
+	}
+	for i := 0; i+3 < len(toks); i++ {
+		if !toks[i].is("SELECT") || !toks[i+1].is("count") || !toks[i+2].isPunct("(") || !toks[i+3].isPunct(")") {
+			continue
+		}
+		end := scopeEnd(toks, i+1)
+		if findTop(toks, i+1, end, "GROUP") >= 0 {
+			continue
+		}
+		if i == 0 && end == len(toks) && findTop(toks, 1, end, "JOIN") >= 0 {
+			if rewritten := tr.countEdges(toks); rewritten != nil {
+				return rewritten
+			}
+		}
+		at := findTop(toks, i+1, end, tailKeywords...)
+		if at < 0 {
+			at = end
+		}
+		group := words("GROUP ALL")
+		toks = append(toks[:at], append(group, toks[at:]...)...)
+	}
+	return toks
+}
+
+// countEdges rewrites an association count over an edge table itself to
+// a count of the owner's edge rows, or returns nil.
+func (tr *translator) countEdges(toks []sqlToken) []sqlToken {
+	from := findTop(toks, 1, len(toks), "FROM")
+	if from < 0 || from+1 >= len(toks) || toks[from+1].kind != tokIdent {
+		return nil
+	}
+	canonical, ok := tr.edgeTable(toks[from+1].name())
+	if !ok {
+		return nil
+	}
+	field, param := edgeOwner(toks, 1, len(toks), "")
+	if field == "" {
+		return nil
+	}
+	return tokenizeSQL(fmt.Sprintf("SELECT count() FROM `%s` WHERE `%s` = %s GROUP ALL", canonical, field, param))
+}
+
+// edgeJoins rewrites GORM's many2many association reads, which join the
+// target table through an edge table, to a graph traversal from the owner:
+//
+//	SELECT * FROM products JOIN wishlists ON wishlists.out = products.id AND wishlists.in = $p1
+//	SELECT * FROM $p1->wishlists->products
+//
+// The side of the edge the owner is on sets the direction, so a relation
+// whose owner is the edge's out reads $p1<-wishlists<-buyers.
+func (tr *translator) edgeJoins(toks []sqlToken) []sqlToken {
+	if !toks[0].is("SELECT") {
+		return toks
+	}
+	end := scopeEnd(toks, 1)
+	from := findTop(toks, 1, end, "FROM")
+	if from < 0 || from+4 >= end || toks[from+1].kind != tokIdent || !toks[from+2].is("JOIN") ||
+		toks[from+3].kind != tokIdent || !toks[from+4].is("ON") {
+		return toks
+	}
+	target := toks[from+1].name()
+	if _, ok := tr.edgeTable(target); ok {
+		return toks
+	}
+	canonical, ok := tr.edgeTable(toks[from+3].name())
+	if !ok {
+		return toks
+	}
+	on := findTop(toks, from+5, end, append([]string{"WHERE", "GROUP"}, tailKeywords...)...)
+	if on < 0 {
+		on = end
+	}
+	field, param := edgeOwner(toks, from+5, on, canonical)
+	if field == "" {
+		return toks
+	}
+	traversal := fmt.Sprintf("%s->%s->%s", param, canonical, target)
+	if field == "out" {
+		traversal = fmt.Sprintf("%s<-%s<-%s", param, canonical, target)
+	}
+	rest := append(words(traversal), toks[on:]...)
+	return append(toks[:from+1], rest...)
+}
+
+// edgeOwner finds `<qualifier>`.`in|out` = $param in toks[from:to], the
+// owner side of an association's edge; an empty qualifier matches any.
+func edgeOwner(toks []sqlToken, from, to int, qualifier string) (field, param string) {
+	for _, f := range []string{"in", "out"} {
+		for i := max(from, 1); i+3 < to; i++ {
+			if toks[i].isPunct(".") && toks[i+1].isName() && toks[i+1].name() == f &&
+				toks[i+2].isPunct("=") && toks[i+3].kind == tokParam &&
+				(qualifier == "" || toks[i-1].isName() && toks[i-1].name() == qualifier) {
+				return f, toks[i+3].text
+			}
+		}
+	}
+	return "", ""
+}
+
+// tailKeywords start the clauses a SELECT ends with, after GROUP BY.
+var tailKeywords = []string{"ORDER", "LIMIT", "START", "FETCH", "TIMEOUT", "PARALLEL", "TEMPFILES", "EXPLAIN"}
+
+// sqlAggregates maps SQL aggregate functions to their SurrealQL names.
+var sqlAggregates = map[string]string{
+	"count": "count",
+	"sum":   "math::sum",
+	"avg":   "math::mean",
+	"min":   "math::min",
+	"max":   "math::max",
+}
+
+// aggregates maps the SQL aggregate functions of select lists and HAVING
+// clauses to SurrealQL: SUM, AVG, MIN and MAX become math::sum, math::mean,
+// math::min and math::max, and a DISTINCT argument is deduplicated with
+// array::distinct, COUNT(DISTINCT x) counting the distinct values. A SELECT
+// of SQL aggregates without GROUP BY aggregates the whole table, which
+// SurrealQL spells GROUP ALL, and HAVING, which SurrealQL lacks, becomes
+// the WHERE of an outer SELECT over the grouped rows.
+func aggregates(toks []sqlToken) []sqlToken {
+	// Right to left, so subqueries are rewritten before the statements
+	// containing them.
+	for i := len(toks) - 1; i >= 0; i-- {
+		if !toks[i].is("SELECT") {
+			continue
+		}
+		end := scopeEnd(toks, i+1)
+		sel := groupSelect(append([]sqlToken(nil), toks[i:end]...))
+		toks = append(toks[:i], append(sel, toks[end:]...)...)
+	}
+	return toks
+}
+
+// groupSelect rewrites the aggregates of one SELECT, s[0] being SELECT.
+func groupSelect(s []sqlToken) []sqlToken {
+	from := findTop(s, 1, len(s), "FROM")
+	if from < 0 {
+		return s
+	}
+	var list []sqlToken
+	aggregated := false
+	for n, r := range splitTop(s, 1, from) {
+		if n > 0 {
+			list = append(list, s[r[0]-1]) // the `,`
+		}
+		item, mapped := mapAggregates(s[r[0]:r[1]])
+		if mapped && isCountDistinct(s[r[0]:r[1]]) && findTop(item, 0, len(item), "AS") < 0 {
+			// Named like the count() it stands for.
+			item = append(item, words("AS count")...)
+		}
+		list = append(list, item...)
+		aggregated = aggregated || mapped
+	}
+	s = append(append([]sqlToken{s[0]}, list...), s[from:]...)
+	from = 1 + len(list)
+
+	having := findTop(s, from, len(s), "HAVING")
+	if (aggregated || having >= 0) && findTop(s, from, len(s), "GROUP") < 0 {
+		at := findTop(s, from, len(s), append([]string{"HAVING"}, tailKeywords...)...)
+		if at < 0 {
+			at = len(s)
+		}
+		s = append(s[:at], append(words("GROUP ALL"), s[at:]...)...)
+	}
+	if having = findTop(s, from, len(s), "HAVING"); having >= 0 {
+		s = havingSelect(s, from, having)
+	}
+	return s
+}
+
+// havingSelect rewrites SELECT ... GROUP BY ... HAVING cond [ORDER ...] to
+// SELECT * FROM (SELECT ... GROUP BY ...) WHERE cond [ORDER ...]. The
+// aggregates in cond are replaced by the alias of the select item computing
+// them, or computed under a hidden alias the outer SELECT omits.
+func havingSelect(s []sqlToken, from, having int) []sqlToken {
+	end := findTop(s, having+1, len(s), tailKeywords...)
+	if end < 0 {
+		end = len(s)
+	}
+	// SELECT VALUE x: the inner SELECT keeps the field, the outer one
+	// takes its value.
+	first, value := 1, ""
+	if s[1].is("VALUE") {
+		if first, value = 2, valueField(s[2:from]); value == "" {
+			return s
+		}
+	}
+	aliases := map[string]sqlToken{}
+	for _, r := range splitTop(s, first, from) {
+		item := s[r[0]:r[1]]
+		if as := findTop(item, 0, len(item), "AS"); as > 0 && as+1 < len(item) {
+			aliases[tokenKey(item[:as])] = item[as+1]
+		}
+	}
+
+	var cond, extra []sqlToken
+	var hidden []string
+	for j := having + 1; j < end; j++ {
+		close := aggregateCall(s, j)
+		if close < 0 {
+			cond = append(cond, s[j])
+			continue
+		}
+		call, _ := mapAggregates(s[j : close+1])
+		key := tokenKey(call)
+		alias, ok := aliases[key]
+		if !ok {
+			name := fmt.Sprintf("__having%d", len(hidden)+1)
+			hidden = append(hidden, name)
+			call[0].pre = " "
+			extra = append(append(append(extra, sqlToken{kind: tokPunct, text: ","}), call...), words("AS "+name)...)
+			alias = sqlToken{kind: tokWord, text: name}
+			aliases[key] = alias
+		}
+		alias.pre = s[j].pre
+		cond = append(cond, alias)
+		j = close
+	}
+
+	inner := append(append(append([]sqlToken{s[0]}, s[first:from]...), extra...), s[from:having]...)
+	inner[0].pre = ""
+	outer := words("SELECT *")
+	if value != "" {
+		outer = words("SELECT VALUE " + value)
+	}
+	outer[0].pre = s[0].pre
+	if len(hidden) > 0 && value == "" {
+		outer = append(outer, words("OMIT "+strings.Join(hidden, ", "))...)
+	}
+	outer = append(append(outer, words("FROM (")...), inner...)
+	outer = append(append(outer, sqlToken{kind: tokPunct, text: ")"}), words("WHERE")...)
+	return append(append(outer, cond...), s[end:]...)
+}
+
+// valueField is the field a SELECT VALUE item is returned under: its alias
+// or column name, or "" for another expression.
+func valueField(item []sqlToken) string {
+	if as := findTop(item, 0, len(item), "AS"); as >= 0 && as+2 == len(item) {
+		item = item[as+1:]
+	}
+	if len(item) == 1 && item[0].isName() {
+		return item[0].text
+	}
+	return ""
+}
+
+// mapAggregates maps the SQL aggregate calls in toks, outside subqueries,
+// and reports whether any was rewritten.
+func mapAggregates(toks []sqlToken) ([]sqlToken, bool) {
+	var out []sqlToken
+	mapped := false
+	for j := 0; j < len(toks); j++ {
+		t := toks[j]
+		if t.isPunct("(") && j+1 < len(toks) && toks[j+1].is("SELECT") {
+			end := min(closing(toks, j), len(toks)-1)
+			out = append(out, toks[j:end+1]...)
+			j = end
+			continue
+		}
+		end := aggregateCall(toks, j)
+		if end < 0 {
+			out = append(out, t)
+			continue
+		}
+		fn, args := sqlAggregates[strings.ToLower(t.text)], toks[j+2:end]
+		distinct := len(args) > 1 && args[0].is("DISTINCT")
+		if fn == "count" && !distinct {
+			out = append(out, t)
+			continue
+		}
+		if distinct {
+			wrapped := append(tokenizeSQL("array::distinct("), args[1:]...)
+			wrapped[len(wrapped)-len(args)+1].pre = ""
+			args = append(wrapped, sqlToken{kind: tokPunct, text: ")"})
+			if fn == "count" {
+				fn = "array::len"
+			}
+		}
+		call := tokenizeSQL(fn + "(")
+		call[0].pre = t.pre
+		out = append(append(append(out, call...), args...), sqlToken{kind: tokPunct, text: ")"})
+		mapped = true
+		j = end
+	}
+	return out, mapped
+}
+
+// aggregateCall returns the index of the `)` closing the SQL aggregate call
+// starting at toks[j], or -1.
+func aggregateCall(toks []sqlToken, j int) int {
+	t := toks[j]
+	if t.kind != tokWord || j+1 >= len(toks) || !toks[j+1].isPunct("(") {
+		return -1
+	}
+	if _, ok := sqlAggregates[strings.ToLower(t.text)]; !ok {
+		return -1
+	}
+	if j > 0 && (toks[j-1].isPunct("::") || toks[j-1].isPunct(".")) {
+		return -1 // math::max, a field named count
+	}
+	if end := closing(toks, j+1); end < len(toks) {
+		return end
+	}
+	return -1
+}
+
+// isCountDistinct reports whether item is exactly COUNT(DISTINCT ...).
+func isCountDistinct(item []sqlToken) bool {
+	return len(item) > 3 && item[0].is("count") && item[2].is("DISTINCT") && aggregateCall(item, 0) == len(item)-1
+}
+
+// splitTop splits toks[from:to] at top-level commas into [start, end)
+// ranges.
+func splitTop(toks []sqlToken, from, to int) [][2]int {
+	var out [][2]int
+	start := from
+	for j := from; j < to; j++ {
+		switch {
+		case toks[j].isPunct("(") || toks[j].isPunct("[") || toks[j].isPunct("{"):
+			j = closing(toks, j)
+		case toks[j].isPunct(","):
+			out = append(out, [2]int{start, j})
+			start = j + 1
+		}
+	}
+	return append(out, [2]int{start, to})
+}
+
+// tokenKey identifies an expression regardless of spacing, case and
+// identifier quoting.
+func tokenKey(toks []sqlToken) string {
+	var b strings.Builder
+	for _, t := range toks {
+		if t.isName() {
+			b.WriteString(strings.ToLower(t.name()))
+		} else {
+			b.WriteString(t.text)
+		}
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// unqualify drops the statement table's qualifier from column references:
+// `users`.`name` becomes `name`. SurrealQL statements address one table.
+func (tr *translator) unqualify(toks []sqlToken) []sqlToken {
+	if tr.table == "" {
+		return toks
+	}
+	out := toks[:0]
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		if t.isName() && t.name() == tr.table && i+2 < len(toks) && toks[i+1].isPunct(".") &&
+			(toks[i+2].isName() || toks[i+2].isPunct("*")) && (i == 0 || !toks[i-1].isPunct(".")) {
+			toks[i+2].pre = t.pre
+			i++
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// deleteFrom rewrites DELETE FROM x to SurrealQL's DELETE x.
+func deleteFrom(toks []sqlToken) []sqlToken {
+	if len(toks) > 1 && toks[0].is("DELETE") && toks[1].is("FROM") {
+		return append(toks[:1], toks[2:]...)
+	}
+	return toks
+}
+
+// softDelete adapts GORM's soft-delete condition. A missing deleted_at is
+// NONE, not NULL, in SurrealDB: the parenthesised form GORM appends to reads
+// is dropped (QueryCallback adds its own), and any other check matches both.
+func softDelete(toks []sqlToken) []sqlToken {
+	isDeletedAt := func(t sqlToken) bool { return t.isName() && t.name() == "deleted_at" }
+	for i := 0; i+5 < len(toks); i++ {
+		if toks[i].is("AND") && toks[i+1].isPunct("(") && isDeletedAt(toks[i+2]) &&
+			toks[i+3].is("IS") && toks[i+4].is("NULL") && toks[i+5].isPunct(")") {
+			toks = append(toks[:i], toks[i+6:]...)
+			i--
+		}
+	}
+	for i := 0; i+2 < len(toks); i++ {
+		if !isDeletedAt(toks[i]) || !toks[i+1].is("IS") || !toks[i+2].is("NULL") {
+			continue
+		}
+		start := i
+		if i >= 2 && toks[i-1].isPunct(".") && toks[i-2].isName() {
+			start = i - 2
+		}
+		if i+6 < len(toks) && toks[i+3].is("OR") && isDeletedAt(toks[i+4]) && toks[i+6].is("NONE") {
+			i += 6
+			continue
+		}
+		column := strings.TrimSpace(renderSQL(toks[start : i+1]))
+		expanded := tokenizeSQL(fmt.Sprintf("(%s IS NULL OR %s IS NONE)", column, column))
+		expanded[0].pre = toks[start].pre
+		toks = append(toks[:start], append(expanded, toks[i+3:]...)...)
+		i = start + len(expanded) - 1
+	}
+	return toks
+}
+
+// inlinePaging writes LIMIT and START values into the statement:
+// SurrealDB does not accept parameters there on every version.
+func (tr *translator) inlinePaging(toks []sqlToken) []sqlToken {
+	for i := 0; i+1 < len(toks); i++ {
+		if !toks[i].is("LIMIT", "START") || toks[i+1].kind != tokParam {
+			continue
+		}
+		key := strings.TrimPrefix(toks[i+1].text, "$")
+		if val, ok := tr.params[key]; ok {
+			delete(tr.params, key)
+			toks[i+1].kind = tokNumber
+			toks[i+1].text = fmt.Sprintf("%v", val)
+		}
+	}
+	return toks
+}
+
+// updateSetID drops `id = ...` assignments from an UPDATE's SET clause: the
+// record id is immutable and lives in the UPDATE target.
+func updateSetID(toks []sqlToken) []sqlToken {
+	if len(toks) == 0 || !toks[0].is("UPDATE") {
+		return toks
+	}
+	set := findTop(toks, 1, len(toks), "SET")
+	if set < 0 {
+		return toks
+	}
+	end := findTop(toks, set+1, len(toks), "WHERE", "RETURN", "TIMEOUT", "PARALLEL")
+	if end < 0 {
+		end = len(toks)
+	}
+	// Split the assignments at top-level commas.
+	var kept [][]sqlToken
+	start := set + 1
+	for j := start; j <= end; j++ {
+		if j < end && (toks[j].isPunct("(") || toks[j].isPunct("[") || toks[j].isPunct("{")) {
+			j = closing(toks, j)
+			continue
+		}
+		if j < end && !toks[j].isPunct(",") {
+			continue
+		}
+		part := toks[start:j]
+		if !(len(part) > 1 && part[0].isName() && part[0].name() == "id" && part[1].isPunct("=")) {
+			kept = append(kept, part)
+		}
+		start = j + 1
+	}
+	out := append([]sqlToken{}, toks[:set+1]...)
+	for n, part := range kept {
+		if n > 0 {
+			out = append(out, sqlToken{kind: tokPunct, text: ","})
+		}
+		part = append([]sqlToken{}, part...)
+		part[0].pre = " "
+		out = append(out, part...)
+	}
+	return append(out, toks[end:]...)
+}
@@ -0,0 +1,285 @@
+package surrealdb
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fxamacker/cbor/v2"
+	websocket "github.com/lxzan/gws"
+	"github.com/surrealdb/surrealdb.go"
+	"github.com/surrealdb/surrealdb.go/pkg/connection"
+	"github.com/surrealdb/surrealdb.go/pkg/connection/gorillaws"
+	"github.com/surrealdb/surrealdb.go/pkg/connection/gws"
+	httpconn "github.com/surrealdb/surrealdb.go/pkg/connection/http"
+	"github.com/surrealdb/surrealdb.go/pkg/connection/rpc"
+	"github.com/surrealdb/surrealdb.go/pkg/constants"
+)
+
+// newSocket returns the raw WebSocket for conf. The SDK's WebSocket
+// transports always dial with Go's default TLS settings, so a dialector with
+// a TLSConfig uses tlsSocket instead.
+func (dialector *Dialector) newSocket(conf *connection.Config) connection.WebSocketConnection {
+	if dialector.TLSConfig != nil && conf.URL.Scheme == "wss" {
+		return newTLSSocket(conf, dialector.TLSConfig)
+	}
+	return gws.New(conf)
+}
+
+// dialPlain is the non-reconnecting connection to an endpoint when a
+// TLSConfig is set or the dialector records: wss uses tlsSocket and https an
+// HTTP client carrying the config, otherwise the SDK's transports are used.
+// Other schemes are left to the SDK.
+func (dialector *Dialector) dialPlain(ctx context.Context, u *url.URL) (*surrealdb.DB, error) {
+	conf := connection.NewConfig(u)
+	var conn connection.Connection
+	switch u.Scheme {
+	case "ws", "wss":
+		if dialector.TLSConfig != nil && u.Scheme == "wss" {
+			conn = newTLSSocket(conf, dialector.TLSConfig)
+		} else {
+			conn = gorillaws.New(conf)
+		}
+	case "http", "https":
+		if dialector.TLSConfig != nil && u.Scheme == "https" {
+			conn = httpconn.New(conf).SetHTTPClient(&http.Client{
+				Timeout:   constants.DefaultHTTPTimeout,
+				Transport: &http.Transport{TLSClientConfig: dialector.TLSConfig.Clone()},
+			})
+		} else {
+			conn = httpconn.New(conf)
+		}
+	default:
+		return surrealdb.FromEndpointURLString(ctx, u.String())
+	}
+	return surrealdb.FromConnection(ctx, dialector.recorded(conn, conf))
+}
+
+// tlsSocket is the SDK's gws transport dialing with a caller-supplied
+// *tls.Config (private CAs, client certificates for mTLS, pinned server
+// names). The embedded connection is never connected; it only contributes
+// its codec and response/notification channel bookkeeping.
+type tlsSocket struct {
+	*gws.Connection
+	tls *tls.Config
+
+	mu       sync.Mutex
+	conn     *websocket.Conn
+	closeCh  chan struct{}
+	closeErr error
+	closed   atomic.Bool
+	nextID   atomic.Uint64
+}
+
+var _ connection.WebSocketConnection = (*tlsSocket)(nil)
+
+func newTLSSocket(conf *connection.Config, cfg *tls.Config) *tlsSocket {
+	return &tlsSocket{Connection: gws.New(conf), tls: cfg, closeCh: make(chan struct{})}
+}
+
+// Connect dials the server and completes the TLS and WebSocket handshakes.
+// Like the SDK transports, a tlsSocket connects once; rews dials a fresh one
+// to reconnect.
+func (s *tlsSocket) Connect(ctx context.Context) error {
+	addr := s.BaseURL + "/rpc"
+	u, err := url.Parse(addr)
+	if err != nil {
+		return err
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "443")
+	}
+	cfg := s.tls.Clone()
+	if cfg.ServerName == "" {
+		cfg.ServerName = u.Hostname()
+	}
+	netConn, err := (&tls.Dialer{Config: cfg}).DialContext(ctx, "tcp", host)
+	if err != nil {
+		return err
+	}
+	conn, resp, err := websocket.NewClientFromConn(&tlsSocketHandler{s: s}, &websocket.ClientOption{
+		Addr:              addr,
+		RequestHeader:     http.Header{"Sec-WebSocket-Protocol": []string{"cbor"}},
+		PermessageDeflate: websocket.PermessageDeflate{Enabled: true},
+	}, netConn)
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.conn = conn
+	s.mu.Unlock()
+	go conn.ReadLoop()
+	return nil
+}
+
+func (s *tlsSocket) Close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	s.markClosedLocked(nil)
+	_ = s.conn.WriteClose(constants.CloseMessageCode, nil)
+	_ = s.conn.NetConn().Close()
+	s.conn = nil
+	return nil
+}
+
+func (s *tlsSocket) markClosedLocked(err error) {
+	select {
+	case <-s.closeCh:
+	default:
+		s.closeErr = err
+		close(s.closeCh)
+	}
+	s.closed.Store(true)
+}
+
+func (s *tlsSocket) IsClosed() bool {
+	return s.closed.Load()
+}
+
+func (s *tlsSocket) Send(ctx context.Context, method string, params ...any) (*connection.RPCResponse[cbor.RawMessage], error) {
+	return s.Call(ctx, &connection.RPCRequest{Method: method, Params: params})
+}
+
+func (s *tlsSocket) Call(ctx context.Context, req *connection.RPCRequest) (*connection.RPCResponse[cbor.RawMessage], error) {
+	ctx, cancel := context.WithTimeout(ctx, constants.DefaultWSTimeout)
+	defer cancel()
+	select {
+	case <-s.closeCh:
+		if s.closeErr != nil {
+			return nil, s.closeErr
+		}
+		return nil, errors.New("surrealdb: connection is closed")
+	default:
+	}
+
+	if req.ID == nil || req.ID == "" {
+		req.ID = strconv.FormatUint(s.nextID.Add(1), 10)
+	}
+	id := fmt.Sprint(req.ID)
+	ch, err := s.CreateResponseChannel(id)
+	if err != nil {
+		return nil, err
+	}
+	defer s.RemoveResponseChannel(id)
+
+	if err := s.write(req); err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res, open := <-ch:
+		if !open {
+			return nil, errors.New("surrealdb: response channel closed")
+		}
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		return &res, nil
+	}
+}
+
+func (s *tlsSocket) write(v any) error {
+	data, err := s.Marshaler.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return errors.New("surrealdb: connection is closed")
+	}
+	return s.conn.WriteMessage(websocket.OpcodeBinary, data)
+}
+
+// dispatch routes one frame: responses to the waiting Call, notifications to
+// their live query channel.
+func (s *tlsSocket) dispatch(data []byte) {
+	var res connection.RPCResponse[cbor.RawMessage]
+	if err := s.Unmarshaler.Unmarshal(data, &res); err != nil {
+		return
+	}
+	if res.ID != nil && res.ID != "" {
+		if ch, ok := s.GetResponseChannel(fmt.Sprint(res.ID)); ok {
+			ch <- res
+			close(ch)
+		}
+		return
+	}
+	if res.Result == nil {
+		return
+	}
+	var n connection.Notification
+	if err := s.Unmarshaler.Unmarshal(*res.Result, &n); err != nil || n.ID == nil {
+		return
+	}
+	s.SendNotification(n.ID.String(), n)
+}
+
+func (s *tlsSocket) Use(ctx context.Context, namespace, database string) error {
+	return connection.Send[any](s, ctx, nil, "use", namespace, database)
+}
+
+func (s *tlsSocket) Let(ctx context.Context, key string, value any) error {
+	return connection.Send[any](s, ctx, nil, "let", key, value)
+}
+
+func (s *tlsSocket) Unset(ctx context.Context, key string) error {
+	return connection.Send[any](s, ctx, nil, "unset", key)
+}
+
+func (s *tlsSocket) Authenticate(ctx context.Context, token string) error {
+	return rpc.Authenticate(s, ctx, token)
+}
+
+func (s *tlsSocket) SignUp(ctx context.Context, authData any) (string, error) {
+	return rpc.SignUp(s, ctx, authData)
+}
+
+func (s *tlsSocket) SignIn(ctx context.Context, authData any) (string, error) {
+	return rpc.SignIn(s, ctx, authData)
+}
+
+func (s *tlsSocket) SignUpWithRefresh(ctx context.Context, authData any) (*connection.Tokens, error) {
+	return rpc.SignUpWithRefresh(s, ctx, authData)
+}
+
+func (s *tlsSocket) SignInWithRefresh(ctx context.Context, authData any) (*connection.Tokens, error) {
+	return rpc.SignInWithRefresh(s, ctx, authData)
+}
+
+func (s *tlsSocket) Invalidate(ctx context.Context) error {
+	return rpc.Invalidate(s, ctx)
+}
+
+// tlsSocketHandler receives the gws events of one tlsSocket.
+type tlsSocketHandler struct {
+	websocket.BuiltinEventHandler
+	s *tlsSocket
+}
+
+func (h *tlsSocketHandler) OnClose(_ *websocket.Conn, err error) {
+	h.s.mu.Lock()
+	h.s.markClosedLocked(err)
+	h.s.mu.Unlock()
+}
+
+func (h *tlsSocketHandler) OnMessage(_ *websocket.Conn, message *websocket.Message) {
+	defer message.Close()
+	h.s.dispatch(message.Bytes())
+}
@@ -24,6 +24,10 @@ type Link[T any] struct {
 func (l *Link[T]) UnmarshalJSON(data []byte) error {
 	// 1. Caso FETCH: Intentamos desserializar el objeto completo (T)
 	// Si el primer caracter es '{', es un objeto
+	if id := recordIDObject(data); id != nil {
+		l.ID = id
+		return nil
+	}
 	if len(data) > 0 && data[0] == '{' {
 		var obj T
 		// Use SurrealMapToStruct to respect GORM tags
@@ -73,7 +77,12 @@ func (l *Link[T]) Scan(value interface{}) error {
 		return nil
 	}
 
-	// 2. Si empieza con '{', es un objeto (FETCH realizado)
+	// 2. Si empieza con '{', es un objeto (FETCH realizado), salvo que sea
+	// el RecordID en la forma de objeto del SDK
+	if id := recordIDObject(data); id != nil {
+		l.ID = id
+		return nil
+	}
 	if len(data) > 0 && data[0] == '{' {
 		var obj T
 		// Usamos SurrealMapToStruct para respetar tags de GORM (column:...)
@@ -104,6 +113,20 @@ func (l *Link[T]) Scan(value interface{}) error {
 	return l.ID.StringToRecordID(string(data))
 }
 
+// recordIDObject decodifica data cuando es un record id en la forma de objeto
+// del SDK, {"Table":"x","ID":"y"}, y no un registro traído con FETCH.
+func recordIDObject(data []byte) *RecordID {
+	var raw map[string]json.RawMessage
+	if json.Unmarshal(data, &raw) != nil || len(raw) != 2 || raw["Table"] == nil || raw["ID"] == nil {
+		return nil
+	}
+	var id RecordID
+	if err := id.UnmarshalJSON(data); err != nil || id.Table == "" {
+		return nil
+	}
+	return &id
+}
+
 // Value implementa driver.Valuer (Escritura hacia la BD)
 // Cuando guardas la Persona, solo quieres guardar el ID del libro, no todo el objeto anidado
 func (l Link[T]) Value() (driver.Value, error) {
@@ -0,0 +1,47 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type linkedRecord struct {
+	ID   *RecordID `json:"id"`
+	Name string    `json:"name"`
+}
+
+func (r *linkedRecord) GetID() *RecordID {
+	return r.ID
+}
+
+func TestLinkScan(t *testing.T) {
+	cases := []interface{}{
+		"people:ada",
+		[]byte(`"people:ada"`),
+		map[string]interface{}{"Table": "people", "ID": "ada"},
+		[]byte(`{"Table":"people","ID":"ada"}`),
+	}
+	for _, in := range cases {
+		var l Link[linkedRecord]
+		require.NoError(t, l.Scan(in), "scan %T", in)
+		require.NotNil(t, l.ID, "scan %T", in)
+		require.Equal(t, "people:ada", l.ID.String(), "scan %#v", in)
+		require.Nil(t, l.Data, "scan %#v", in)
+	}
+
+	// a fetched record fills Data and takes its id
+	var l Link[linkedRecord]
+	require.NoError(t, l.Scan(map[string]interface{}{"id": "people:ada", "name": "Ada"}))
+	require.NotNil(t, l.Data)
+	require.Equal(t, "Ada", l.Data.Name)
+	require.Equal(t, "people:ada", l.ID.String())
+}
+
+func TestLinkUnmarshalJSON(t *testing.T) {
+	var l Link[linkedRecord]
+	require.NoError(t, json.Unmarshal([]byte(`{"Table":"people","ID":"ada"}`), &l))
+	require.Equal(t, "people:ada", l.ID.String())
+	require.Nil(t, l.Data)
+}
@@ -1,11 +1,32 @@
 package surrealdb
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/fxamacker/cbor/v2"
+	websocket "github.com/lxzan/gws"
+	"github.com/surrealdb/surrealdb.go"
+	"github.com/surrealdb/surrealdb.go/pkg/connection"
+	sdkModels "github.com/surrealdb/surrealdb.go/pkg/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 
+	localModels "github.com/dailaim/surrealdb-gorm/models"
+	"github.com/dailaim/surrealdb-gorm/surrealtest"
 	TypesM "github.com/dailaim/surrealdb-gorm/types"
 )
 
@@ -43,6 +64,8 @@ func TestRewriteExecSQL(t *testing.T) {
 		{"UPDATE users SET name = $p1, id = $p2 WHERE x", "UPDATE users SET name = $p1 WHERE x"},
 		// Unaffected statements pass through
 		{"SELECT * FROM users", "SELECT * FROM users"},
+		// Literals and nested values are not assignments
+		{"UPDATE users SET `name` = 'a, id = 1', `tags` = [1, 2], `id` = $p2 WHERE x", "UPDATE users SET `name` = 'a, id = 1', `tags` = [1, 2] WHERE x"},
 	}
 	for _, c := range cases {
 		if got := normalizeWS(rewriteExecSQL(c.in)); got != c.want {
@@ -51,6 +74,257 @@ func TestRewriteExecSQL(t *testing.T) {
 	}
 }
 
+func TestInsertEnds(t *testing.T) {
+	cases := []struct {
+		query   string
+		in, out int
+	}{
+		{"INSERT INTO `wishlists` (`in`,`out`) VALUES ($p1,$p2)", 0, 1},
+		{"INSERT INTO `wishlists` (`out`,`in`) VALUES ($p1,$p2)", 1, 0},
+		{"INSERT INTO wishlists (buyer_id, product_id) VALUES ($p1,$p2)", 0, 1},
+		{"INSERT INTO wishlists VALUES ($p1,$p2)", 0, 1},
+	}
+	for _, c := range cases {
+		if in, out := insertEnds(c.query); in != c.in || out != c.out {
+			t.Errorf("insertEnds(%q) = %d, %d, want %d, %d", c.query, in, out, c.in, c.out)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	d := &Dialector{}
+	d.RegisterEdgeTable("wishlists")
+	cases := []struct {
+		name, table, in, want string
+		params, wantParams    map[string]interface{}
+	}{
+		{name: "not equal", in: "SELECT * FROM `users` WHERE `age` <> $p1", want: "SELECT * FROM `users` WHERE `age` != $p1"},
+		{name: "not equal in literal", in: "SELECT * FROM `users` WHERE `note` <> 'a<>b'", want: "SELECT * FROM `users` WHERE `note` != 'a<>b'"},
+		{name: "count", in: "SELECT count(*) FROM `users` WHERE `age` > $p1", want: "SELECT count() FROM `users` WHERE `age` > $p1 GROUP ALL"},
+		{name: "count(1) before limit", in: "SELECT count(1) FROM `users` LIMIT 1", want: "SELECT count() FROM `users` GROUP ALL LIMIT 1"},
+		{name: "count in literal", in: "SELECT * FROM `users` WHERE `note` = 'SELECT count(*)'", want: "SELECT * FROM `users` WHERE `note` = 'SELECT count(*)'"},
+		{name: "nested count", in: "SELECT * FROM `users` WHERE `n` = (SELECT count(*) FROM `posts`)", want: "SELECT * FROM `users` WHERE `n` = (SELECT count() FROM `posts` GROUP ALL)"},
+		{name: "already grouped", in: "SELECT count(*) FROM `users` GROUP ALL", want: "SELECT count() FROM `users` GROUP ALL"},
+		{name: "offset", in: "SELECT * FROM `users` LIMIT 10 OFFSET 20", want: "SELECT * FROM `users` LIMIT 10 START 20"},
+		{name: "offset column", in: "SELECT `offset` FROM `users` WHERE `note` = ' OFFSET '", want: "SELECT `offset` FROM `users` WHERE `note` = ' OFFSET '"},
+		{
+			name: "paging params", in: "SELECT * FROM `users` WHERE `a` = $p1 LIMIT $p2 OFFSET $p3",
+			params: map[string]interface{}{"p1": "x", "p2": 10, "p3": 20},
+			want:   "SELECT * FROM `users` WHERE `a` = $p1 LIMIT 10 START 20",
+		},
+		{name: "qualifiers", table: "users", in: "SELECT `users`.`name`, users.age FROM `users` WHERE `users`.`id` = $p1", want: "SELECT `name`, age FROM `users` WHERE `id` = $p1"},
+		{name: "column named like the table", table: "users", in: "SELECT * FROM `users` WHERE `users`.`users` = $p1 AND `users` = 'users.name'", want: "SELECT * FROM `users` WHERE `users` = $p1 AND `users` = 'users.name'"},
+		{name: "other qualifiers kept", table: "users", in: "SELECT * FROM `users` WHERE `posts`.`users` = $p1", want: "SELECT * FROM `users` WHERE `posts`.`users` = $p1"},
+		{name: "delete from", table: "users", in: "DELETE FROM `users` WHERE `users`.`id` = $p1", want: "DELETE `users` WHERE `id` = $p1"},
+		{name: "statements", in: "DELETE FROM a; SELECT count(*) FROM b;", want: "DELETE a; SELECT count() FROM b GROUP ALL;"},
+		{name: "soft delete read", in: "SELECT * FROM `users` WHERE `age` > 1 AND (`deleted_at` IS NULL)", want: "SELECT * FROM `users` WHERE `age` > 1"},
+		{
+			name: "soft delete update", table: "users", in: "UPDATE `users` SET `deleted_at` = $p1 WHERE `users`.`id` = $p2 AND `users`.`deleted_at` IS NULL",
+			want: "UPDATE `users` SET `deleted_at` = $p1 WHERE `id` = $p2 AND (`deleted_at` IS NULL OR `deleted_at` IS NONE)",
+		},
+		{
+			name: "soft delete rows", table: "users", in: "SELECT * FROM `users` WHERE `a` = $p1 AND `users`.`deleted_at` IS NULL",
+			want: "SELECT * FROM `users` WHERE `a` = $p1 AND (`deleted_at` IS NULL OR `deleted_at` IS NONE)",
+		},
+		{
+			name: "soft delete update idempotent", in: "UPDATE `users` SET `a` = 1 WHERE (`deleted_at` IS NULL OR `deleted_at` IS NONE)",
+			want: "UPDATE `users` SET `a` = 1 WHERE (`deleted_at` IS NULL OR `deleted_at` IS NONE)",
+		},
+		{name: "edge alias", table: "wishlist", in: "SELECT * FROM wishlist WHERE `wishlist`.`in` = $p1 AND `wishlist` = 1", want: "SELECT * FROM `wishlists` WHERE `in` = $p1 AND `wishlist` = 1"},
+		{
+			name: "in list", in: "SELECT * FROM `users` WHERE `status` IN ($p1,$p2) AND `age` NOT IN ($p3,$p4)",
+			params: map[string]interface{}{"p1": "a", "p2": "b", "p3": 1, "p4": 2},
+			want:   "SELECT * FROM `users` WHERE `status` INSIDE [$p1,$p2] AND `age` NOTINSIDE [$p3,$p4]",
+		},
+		{name: "in empty", in: "SELECT * FROM `users` WHERE `status` IN (NULL)", want: "SELECT * FROM `users` WHERE `status` INSIDE []"},
+		{
+			name: "in slice param", in: "SELECT * FROM `users` WHERE `status` IN ($p1) OR `name` IN ($p2)",
+			params: map[string]interface{}{"p1": []string{"a", "b"}, "p2": "x"},
+			want:   "SELECT * FROM `users` WHERE `status` INSIDE $p1 OR `name` INSIDE [$p2]",
+		},
+		{
+			name: "in subquery", table: "posts", in: "SELECT * FROM `posts` WHERE `author` IN (SELECT `id` FROM `users` WHERE `age` IN ($p1))",
+			want: "SELECT * FROM `posts` WHERE `author` INSIDE (SELECT VALUE `id` FROM `users` WHERE `age` INSIDE [$p1])",
+		},
+		{name: "in subquery value", in: "SELECT * FROM a WHERE b IN (SELECT VALUE c FROM d)", want: "SELECT * FROM a WHERE b INSIDE (SELECT VALUE c FROM d)"},
+		{name: "in row values", in: "SELECT * FROM a WHERE (`x`,`y`) IN ((1,2),(3,4))", want: "SELECT * FROM a WHERE (`x`,`y`) IN ((1,2),(3,4))"},
+		{name: "in as field", in: "SELECT in, out FROM likes WHERE in = $p1 AND out IN ($p2)", want: "SELECT in, out FROM likes WHERE in = $p1 AND out INSIDE [$p2]"},
+		{name: "in literal", in: "SELECT * FROM a WHERE b = ' IN (1)'", want: "SELECT * FROM a WHERE b = ' IN (1)'"},
+		{name: "for in", in: "FOR $x IN (SELECT * FROM a) { DELETE $x; }", want: "FOR $x IN (SELECT * FROM a) { DELETE $x; }"},
+		{
+			name: "like", table: "users", in: "SELECT * FROM `users` WHERE `name` LIKE $p1 AND `email` LIKE $p2 AND `users`.`city` LIKE $p3",
+			params:     map[string]interface{}{"p1": "ali%", "p2": "%@example.com", "p3": "%york%"},
+			want:       "SELECT * FROM `users` WHERE string::starts_with(`name`, $p1) AND string::ends_with(`email`, $p2) AND string::contains(`city`, $p3)",
+			wantParams: map[string]interface{}{"p1": "ali", "p2": "@example.com", "p3": "york"},
+		},
+		{
+			name: "not like", in: "SELECT * FROM `users` WHERE `name` NOT LIKE $p1 OR `code` NOT LIKE $p2",
+			params: map[string]interface{}{"p1": "%x%", "p2": "abc"},
+			want:   "SELECT * FROM `users` WHERE !string::contains(`name`, $p1) OR `code` != $p2",
+		},
+		{
+			name: "ilike", in: "SELECT * FROM `users` WHERE lower(`name`) ILIKE '%Ali%'",
+			want: "SELECT * FROM `users` WHERE string::contains(string::lowercase(lower(`name`)), 'ali')",
+		},
+		{
+			name: "like regex", in: "SELECT * FROM `users` WHERE `a`.`b` LIKE $p1",
+			params:     map[string]interface{}{"p1": "a_c%d.e"},
+			want:       "SELECT * FROM `users` WHERE string::matches(`a`.`b`, $p1)",
+			wantParams: map[string]interface{}{"p1": `(?s)^a.c.*d\.e$`},
+		},
+		{name: "like escaped wildcard", in: "SELECT * FROM t WHERE a LIKE '50\\%%'", want: "SELECT * FROM t WHERE string::starts_with(a, '50%')"},
+		{
+			name: "like escape", in: "SELECT * FROM `users` WHERE `name` LIKE $p1 ESCAPE '!' AND `code` NOT LIKE 'a!_%' ESCAPE '!' ORDER BY `name`",
+			params:     map[string]interface{}{"p1": "%10!%%"},
+			want:       "SELECT * FROM `users` WHERE string::contains(`name`, $p1) AND !string::starts_with(`code`, 'a_') ORDER BY `name`",
+			wantParams: map[string]interface{}{"p1": "10%"},
+		},
+		{name: "like unknown pattern", in: "SELECT * FROM t WHERE a LIKE b", want: "SELECT * FROM t WHERE a LIKE b"},
+		{
+			name: "count edge rows", table: "wishlists",
+			in:   "SELECT count(*) FROM `wishlists` JOIN `products` ON `products`.`id` = `wishlists`.`out` AND `wishlists`.`in` = $p1",
+			want: "SELECT count() FROM `wishlists` WHERE `in` = $p1 GROUP ALL",
+		},
+		{
+			name: "count through edge", table: "products",
+			in:   "SELECT count(*) FROM `products` JOIN `wishlists` ON `wishlists`.`out` = `products`.`id` AND `wishlists`.`in` = $p1 WHERE `products`.`price` > $p2",
+			want: "SELECT count() FROM $p1->wishlists->products WHERE `price` > $p2 GROUP ALL",
+		},
+		{
+			name: "count through reverse edge", table: "buyers",
+			in:   "SELECT count(*) FROM `buyers` JOIN `wishlists` ON `wishlists`.`in` = `buyers`.`id` AND `wishlists`.`out` = $p1",
+			want: "SELECT count() FROM $p1<-wishlists<-buyers GROUP ALL",
+		},
+		{
+			name: "find through edge", table: "products",
+			in:   "SELECT `products`.`name` FROM `products` JOIN `wishlists` ON `wishlists`.`out` = `products`.`id` AND `wishlists`.`in` = $p1 ORDER BY `products`.`name`",
+			want: "SELECT `name` FROM $p1->wishlists->products ORDER BY `name`",
+		},
+		{
+			name: "find through reverse edge", table: "buyers",
+			in:   "SELECT * FROM `buyers` JOIN `wishlists` ON `wishlists`.`in` = `buyers`.`id` AND `wishlists`.`out` = $p1 WHERE `buyers`.`name` = $p2",
+			want: "SELECT * FROM $p1<-wishlists<-buyers WHERE `name` = $p2",
+		},
+		{
+			name: "join through table", table: "products",
+			in:   "SELECT * FROM `products` JOIN `tags` ON `tags`.`out` = `products`.`id` AND `tags`.`in` = $p1",
+			want: "SELECT * FROM `products` JOIN `tags` ON `tags`.`out` = `id` AND `tags`.`in` = $p1",
+		},
+		{
+			name: "aggregates", in: "SELECT `customer`, sum(`total`) as total, AVG(total), min(total), max(total) FROM `orders` GROUP BY `customer`",
+			want: "SELECT `customer`, math::sum(`total`) as total, math::mean(total), math::min(total), math::max(total) FROM `orders` GROUP BY `customer`",
+		},
+		{name: "aggregates whole table", in: "SELECT sum(total), count(*) FROM `orders` LIMIT 1", want: "SELECT math::sum(total), count() FROM `orders` GROUP ALL LIMIT 1"},
+		{name: "count distinct", in: "SELECT COUNT(DISTINCT(`customer`)) FROM `orders`", want: "SELECT array::len(array::distinct((`customer`))) AS count FROM `orders` GROUP ALL"},
+		{name: "sum distinct", in: "SELECT sum(DISTINCT total) AS s FROM t GROUP BY a", want: "SELECT math::sum(array::distinct(total)) AS s FROM t GROUP BY a"},
+		{name: "surrealql functions kept", in: "SELECT math::max(a), count() FROM t", want: "SELECT math::max(a), count() FROM t"},
+		{name: "aggregates outside select list kept", in: "SELECT * FROM t WHERE max(a, b) > 1", want: "SELECT * FROM t WHERE max(a, b) > 1"},
+		{
+			name: "having alias", in: "SELECT customer, sum(total) as total FROM `orders` GROUP BY `customer` HAVING total > $p1 ORDER BY total DESC LIMIT 5",
+			want: "SELECT * FROM (SELECT customer, math::sum(total) as total FROM `orders` GROUP BY `customer`) WHERE total > $p1 ORDER BY total DESC LIMIT 5",
+		},
+		{
+			name: "having aggregate", in: "SELECT customer, sum(total) AS total FROM orders GROUP BY customer HAVING SUM(`total`) > 1 AND count(*) > 2",
+			want: "SELECT * OMIT __having1 FROM (SELECT customer, math::sum(total) AS total, count() AS __having1 FROM orders GROUP BY customer) WHERE total > 1 AND __having1 > 2",
+		},
+		{
+			name: "having in subquery", in: "SELECT * FROM users WHERE id INSIDE (SELECT VALUE user FROM orders GROUP BY user HAVING count() > 1)",
+			want: "SELECT * FROM users WHERE id INSIDE (SELECT VALUE user FROM (SELECT user, count() AS __having1 FROM orders GROUP BY user) WHERE __having1 > 1)",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			params := c.params
+			if params == nil {
+				params = map[string]interface{}{}
+			}
+			if got := d.newTranslator(c.table, params).translate(c.in); got != c.want {
+				t.Errorf("translate(%q) =\n  %q\nwant\n  %q", c.in, got, c.want)
+			}
+			if c.wantParams != nil && !reflect.DeepEqual(params, c.wantParams) {
+				t.Errorf("params = %v, want %v", params, c.wantParams)
+			}
+		})
+	}
+}
+
+// rowPool records the statement RowCallback sends.
+type rowPool struct {
+	gorm.ConnPool
+	query string
+	args  []interface{}
+}
+
+func (p *rowPool) QueryRowContext(_ context.Context, query string, args ...interface{}) *sql.Row {
+	p.query, p.args = query, args
+	return nil
+}
+
+func TestRowCallbackRawSQL(t *testing.T) {
+	pool := &rowPool{}
+	db := &gorm.DB{Config: &gorm.Config{Dialector: &Dialector{}}}
+	db.Statement = &gorm.Statement{DB: db, ConnPool: pool, Context: context.Background(), Table: "users"}
+	raw := "SELECT users.name, sum(age) AS total FROM users WHERE name = ? GROUP BY name"
+	db.Statement.SQL.WriteString(raw)
+	db.Statement.Vars = []interface{}{"ann"}
+
+	RowCallback(db)
+	if pool.query != raw || !reflect.DeepEqual(pool.args, []interface{}{"ann"}) {
+		t.Fatalf("db.Raw SQL must run as written, got %q %v", pool.query, pool.args)
+	}
+}
+
+func TestLikeFunc(t *testing.T) {
+	cases := []struct {
+		pattern string
+		fold    bool
+		fn, arg string
+	}{
+		{"abc", false, "", "abc"},
+		{"abc%", false, "string::starts_with", "abc"},
+		{"%abc", false, "string::ends_with", "abc"},
+		{"%abc%", false, "string::contains", "abc"},
+		{"%ABC%", true, "string::contains", "abc"},
+		{"%", false, "string::starts_with", ""},
+		{"a_c%d.e", false, "string::matches", `(?s)^a.c.*d\.e$`},
+		{"%a%b%", false, "string::matches", `(?s)^.*a.*b.*$`},
+		{`100\%`, false, "", "100%"},
+		{`a\_b%`, false, "string::starts_with", "a_b"},
+	}
+	for _, c := range cases {
+		fn, arg := likeFunc(c.pattern, '\\', c.fold)
+		if fn != c.fn || arg != c.arg {
+			t.Errorf("likeFunc(%q) = %q, %q; want %q, %q", c.pattern, fn, arg, c.fn, c.arg)
+		}
+	}
+	if fn, arg := likeFunc("50!%%", '!', false); fn != "string::starts_with" || arg != "50%" {
+		t.Errorf("likeFunc with escape ! = %q, %q", fn, arg)
+	}
+	if fn, arg := likeFunc(`a\%`, 0, false); fn != "string::starts_with" || arg != `a\` {
+		t.Errorf("likeFunc without escape = %q, %q", fn, arg)
+	}
+}
+
+func TestTokenizeSQL(t *testing.T) {
+	toks := tokenizeSQL("SELECT `a``b`, 'it''s', \"q\\\"\", ⟨x y⟩, $p1, 1.5, 1..5 FROM t /* c */ WHERE a <-> b")
+	var got []string
+	for _, tok := range toks {
+		got = append(got, tok.text)
+	}
+	want := []string{"SELECT", "`a``b`", ",", "'it''s'", ",", `"q\""`, ",", "⟨x y⟩", ",", "$p1", ",", "1.5", ",", "1", "..", "5",
+		"FROM", "t", "WHERE", "a", "<->", "b"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("tokens:\n  %q\nwant\n  %q", got, want)
+	}
+	if toks[7].name() != "x y" || toks[1].kind != tokIdent || toks[3].kind != tokString {
+		t.Errorf("unexpected token kinds or names: %+v", toks[:8])
+	}
+	const sql = "SELECT  *\n FROM t /* keep */ WHERE a = 'x'"
+	if got := renderSQL(tokenizeSQL(sql)); got != sql {
+		t.Errorf("render must round-trip: %q", got)
+	}
+}
+
 func TestOptimizeFindByIDList(t *testing.T) {
 	rid := func(s string) *TypesM.RecordID { r, _ := TypesM.ParseRecordID(s); return r }
 
@@ -84,3 +358,767 @@ func TestOptimizeFindByIDList(t *testing.T) {
 		t.Errorf("no id-in should be untouched, got %q", got)
 	}
 }
+
+func TestConnPoolSpreadsAndReaps(t *testing.T) {
+	dials := 0
+	open := func(context.Context) (*surrealdb.DB, error) { dials++; return nil, nil }
+	p := newConnPool(nil, open, 1, 3, time.Minute)
+	defer close(p.done)
+	ctx := context.Background()
+
+	// Busy connections make the pool dial until it reaches MaxConns.
+	a, _ := p.acquire(ctx)
+	b, _ := p.acquire(ctx)
+	c, _ := p.acquire(ctx)
+	if a == b || b == c || a == c || dials != 2 {
+		t.Fatalf("expected 3 distinct connections after 2 dials, got dials=%d", dials)
+	}
+	// At MaxConns the least-loaded connection is shared instead.
+	d, _ := p.acquire(ctx)
+	if dials != 2 || p.size() != 3 || d != a {
+		t.Fatalf("expected reuse of the first connection at max, dials=%d size=%d", dials, p.size())
+	}
+	for _, pc := range []*poolConn{a, b, c, d} {
+		pc.release()
+	}
+
+	// Idle surplus connections are reaped; pinned ones (open tx / live query) stay.
+	b.pin()
+	p.reapIdle(time.Now().Add(2 * time.Minute))
+	if p.size() != 2 {
+		t.Fatalf("expected first + pinned connection to survive reaping, size=%d", p.size())
+	}
+	b.unpin()
+	p.reapIdle(time.Now().Add(4 * time.Minute))
+	if p.size() != 1 {
+		t.Fatalf("expected the pool to shrink to MinConns, size=%d", p.size())
+	}
+}
+
+func TestConfigDSNWithAuth(t *testing.T) {
+	root := Config{Endpoint: "ws://h:8000/rpc", Namespace: "n", Database: "d", Username: "u", Password: "p"}
+	if got := root.dsn(); !strings.Contains(got, "username=u") || !strings.Contains(got, "password=p") {
+		t.Errorf("root config should carry credentials in the DSN, got %q", got)
+	}
+	scoped := Config{Endpoint: "ws://h:8000/rpc", Namespace: "n", Database: "d",
+		Auth: DatabaseAuth{Username: "svc", Password: "secret"}}
+	if got := scoped.dsn(); strings.Contains(got, "username") || strings.Contains(got, "secret") {
+		t.Errorf("explicit Auth must not leak into the DSN, got %q", got)
+	}
+
+	// A record SIGNUP runs once; pooled connections reuse the issued token.
+	d := &Dialector{Auth: RecordAuth{Access: "user", SignUp: true}, token: "jwt"}
+	if got, ok := d.pooledAuth().(TokenAuth); !ok || got.Token != "jwt" {
+		t.Errorf("pooledAuth after signup = %#v, want TokenAuth", d.pooledAuth())
+	}
+	d.Auth = RecordAuth{Access: "user"}
+	if _, ok := d.pooledAuth().(RecordAuth); !ok {
+		t.Errorf("pooledAuth for signin = %#v, want RecordAuth", d.pooledAuth())
+	}
+}
+
+func TestAsTokenContext(t *testing.T) {
+	if _, ok := tokenFromContext(context.Background()); ok {
+		t.Fatal("plain context must not carry a token")
+	}
+	ctx := AsToken(context.Background(), "jwt")
+	if tok, ok := tokenFromContext(ctx); !ok || tok != "jwt" {
+		t.Fatalf("tokenFromContext = %q, %v", tok, ok)
+	}
+	if _, ok := tokenFromContext(AsToken(context.Background(), "")); ok {
+		t.Fatal("an empty token must fall back to the root session")
+	}
+
+	// Without a pool there is nowhere to attach a session; the statement must
+	// fail rather than run with the driver's privileges.
+	d := &Dialector{Conn: &surrealdb.DB{}}
+	if _, _, err := d.acquire(ctx); err == nil {
+		t.Fatal("acquire under AsToken without a pool should fail")
+	}
+	if target, _, err := d.acquire(context.Background()); err != nil || target.db == nil {
+		t.Fatalf("acquire without a token = %+v, %v", target, err)
+	}
+}
+
+func TestWithDatabaseSessionKey(t *testing.T) {
+	d := &Dialector{namespace: "app", database: "main"}
+	if _, ok := d.sessionKeyFor(context.Background()); ok {
+		t.Fatal("plain context should use the connection's own session")
+	}
+	if _, ok := d.sessionKeyFor(WithDatabase(context.Background(), "app", "main")); ok {
+		t.Fatal("the configured database needs no extra session")
+	}
+
+	key, ok := d.sessionKeyFor(WithDatabase(context.Background(), "app", "tenant_1"))
+	if !ok || key != (sessionKey{ns: "app", db: "tenant_1"}) {
+		t.Fatalf("tenant key = %+v, %v", key, ok)
+	}
+	// AsToken and WithDatabase compose into one session.
+	ctx := AsToken(WithDatabase(context.Background(), "app", "tenant_1"), "jwt")
+	if key, _ := d.sessionKeyFor(ctx); key != (sessionKey{token: "jwt", ns: "app", db: "tenant_1"}) {
+		t.Fatalf("composed key = %+v", key)
+	}
+	if key, _ := d.sessionKeyFor(AsToken(context.Background(), "jwt")); key != (sessionKey{token: "jwt", ns: "app", db: "main"}) {
+		t.Fatalf("token key = %+v", key)
+	}
+}
+
+func TestSessionCacheHandshakeUnlocked(t *testing.T) {
+	// A server that never answers stands in for a hung handshake.
+	upgrader := websocket.NewUpgrader(websocket.BuiltinEventHandler{}, &websocket.ServerOption{SubProtocols: []string{"cbor"}})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if socket, err := upgrader.Upgrade(w, r); err == nil {
+			go socket.ReadLoop()
+		}
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	u, _ := url.Parse(strings.Replace(srv.URL, "http", "ws", 1) + "/rpc")
+	d := &Dialector{}
+	conn, err := d.dialPlain(ctx, u)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	cachedKey, slowKey := sessionKey{ns: "app", db: "cached"}, sessionKey{ns: "app", db: "slow"}
+	cached := &connSession{session: &surrealdb.Session{}, ready: make(chan struct{})}
+	close(cached.ready)
+	c := &sessionCache{sessions: map[sessionKey]*connSession{cachedKey: cached}}
+
+	hungCtx, unhang := context.WithCancel(ctx)
+	hung := make(chan error, 1)
+	go func() {
+		_, _, err := c.session(hungCtx, d, conn, slowKey)
+		hung <- err
+	}()
+	for {
+		c.mu.Lock()
+		_, started := c.sessions[slowKey]
+		c.mu.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Cached sessions and releases do not wait for the handshake.
+	quick, stop := context.WithTimeout(ctx, time.Second)
+	defer stop()
+	s, release, err := c.session(quick, d, conn, cachedKey)
+	if err != nil || s != cached.session {
+		t.Fatalf("cached session behind a hung handshake: %v", err)
+	}
+	release()
+
+	// Another caller for the same key waits for that handshake instead of
+	// starting its own, for as long as its context allows.
+	short, stopShort := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer stopShort()
+	if _, _, err := c.session(short, d, conn, slowKey); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected to wait out the handshake, got %v", err)
+	}
+
+	unhang()
+	if err := <-hung; err == nil {
+		t.Fatal("the hung handshake should fail once cancelled")
+	}
+	if _, ok := c.sessions[slowKey]; ok {
+		t.Fatal("a failed handshake must not stay cached")
+	}
+}
+
+func TestUseDatabaseNames(t *testing.T) {
+	// Names are rejected before anything is sent on the connection.
+	for _, name := range []string{"tenant`; REMOVE NAMESPACE app; --", "a b", ""} {
+		if err := useDatabase(context.Background(), (*surrealdb.DB)(nil), "app", name, true); err == nil {
+			t.Errorf("database name %q should be rejected", name)
+		}
+		if err := useDatabase(context.Background(), (*surrealdb.DB)(nil), name, "main", true); err == nil {
+			t.Errorf("namespace name %q should be rejected", name)
+		}
+	}
+	if !databaseNameRe.MatchString("tenant_42") || !databaseNameRe.MatchString("acme-corp") {
+		t.Error("plain tenant names must be accepted")
+	}
+}
+
+func TestSessionCacheKeepsLeased(t *testing.T) {
+	srv := surrealtest.NewServer()
+	defer srv.Close()
+	d := &Dialector{DSN: srv.DSN()}
+	if _, err := gorm.Open(d, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer d.Close(context.Background())
+	ctx := context.Background()
+	pc, err := d.pool.acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.release()
+	key := func(i int) sessionKey { return sessionKey{ns: "app", db: fmt.Sprintf("tenant_%d", i)} }
+
+	// A session in use, as by a scoped live query, outlives the LRU bound.
+	held, release, err := pc.sessions.session(ctx, d, pc.db, key(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= maxSessionsPerConn; i++ {
+		_, done, err := pc.sessions.session(ctx, d, pc.db, key(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		done()
+	}
+	if cs, ok := pc.sessions.sessions[key(0)]; !ok || cs.session != held {
+		t.Fatal("a leased session must not be evicted")
+	}
+	if _, ok := pc.sessions.sessions[key(1)]; ok {
+		t.Fatal("the least recently used idle session should be evicted")
+	}
+	if n := len(pc.sessions.sessions); n != maxSessionsPerConn {
+		t.Fatalf("expected %d cached sessions, got %d", maxSessionsPerConn, n)
+	}
+
+	// Once released it ages out like any other.
+	release()
+	release()
+	for i := maxSessionsPerConn + 1; i <= 2*maxSessionsPerConn; i++ {
+		_, done, err := pc.sessions.session(ctx, d, pc.db, key(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		done()
+	}
+	if _, ok := pc.sessions.sessions[key(0)]; ok {
+		t.Fatal("a released session should be evictable")
+	}
+}
+
+func TestTxReleasesClosedConn(t *testing.T) {
+	srv := surrealtest.NewServer()
+	defer srv.Close()
+	d := &Dialector{DSN: srv.DSN()}
+	if _, err := gorm.Open(d, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer d.Close(context.Background())
+	ctx := context.Background()
+
+	for _, finish := range []func(*SurrealTx) error{(*SurrealTx).Commit, (*SurrealTx).Rollback} {
+		pool, err := d.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx := pool.(*SurrealTx)
+		// The SDK transaction ends behind the driver's back, e.g. on a
+		// server-side abort.
+		if err := tx.sdkTx.Cancel(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := finish(tx); err != nil {
+			t.Fatal(err)
+		}
+		if err := finish(tx); err != nil {
+			t.Fatal(err)
+		}
+		if pinned, busy := tx.conn.pinned.Load(), tx.conn.inFlight.Load(); pinned != 0 || busy != 0 {
+			t.Fatalf("connection not released: pinned=%d inFlight=%d", pinned, busy)
+		}
+		if _, ok := d.txs.Load(tx); ok {
+			t.Fatal("finished transaction still tracked")
+		}
+	}
+}
+
+// TestEdgeIndexError pins the text of the database refusing a second edge
+// on a unique [in, out] index: relateEdge tells that refusal from other
+// errors by its wording, so a change in it must fail here rather than turn
+// a lost race into an error.
+func TestEdgeIndexError(t *testing.T) {
+	srv := surrealtest.NewServer()
+	defer srv.Close()
+	d := &Dialector{DSN: srv.DSN()}
+	db, err := gorm.Open(d, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer d.Close(context.Background())
+	ctx := context.Background()
+
+	for _, sql := range []string{
+		"DEFINE TABLE likes TYPE RELATION SCHEMALESS",
+		fmt.Sprintf("DEFINE INDEX IF NOT EXISTS `%s` ON `likes` FIELDS `in`, `out` UNIQUE", edgeIndexName("likes")),
+	} {
+		if err := db.Exec(sql).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	target, release, err := d.acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	in, out := sdkModels.NewRecordID("readers", "ada"), sdkModels.NewRecordID("novels", "dune")
+	if _, err := relateSet(ctx, target, &in, "likes", &out, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	_, err = relateSet(ctx, target, &in, "likes", &out, nil, false)
+	if err == nil {
+		t.Fatal("second edge between the same records accepted")
+	}
+	want := "Database index `idx_likes_in_out` already contains [readers:ada, novels:dune], with record `likes:"
+	if !strings.Contains(err.Error(), want) {
+		t.Fatalf("error = %q, want it to contain %q", err, want)
+	}
+	if !isEdgeIndexError(err, "likes") {
+		t.Fatalf("isEdgeIndexError(%q, likes) = false", err)
+	}
+	if isEdgeIndexError(err, "follows") {
+		t.Fatalf("isEdgeIndexError(%q, follows) = true", err)
+	}
+}
+
+func TestIsReadOnlySQL(t *testing.T) {
+	cases := []struct {
+		sql  string
+		want bool
+	}{
+		{"SELECT * FROM `users` WHERE `age` > $p1", true},
+		{"  select count() FROM `users` GROUP ALL", true},
+		{"SELECT\n*\nFROM users", true},
+		{"SELECT * FROM users WHERE updated_at > $p1", true},
+		{"SELECT * FROM users;", true},
+		{"UPDATE users MERGE $p1", false},
+		{"DELETE FROM users", false},
+		{"SELECT * FROM (CREATE users SET name = 'x')", false},
+		{"SELECT * FROM users; DELETE users", false},
+		{"LET $x = 1; SELECT * FROM users", false},
+		{"INFO FOR DB", false},
+	}
+	for _, c := range cases {
+		if got := isReadOnlySQL(c.sql); got != c.want {
+			t.Errorf("isReadOnlySQL(%q) = %v, want %v", c.sql, got, c.want)
+		}
+	}
+}
+
+func TestReadTargetRoutesToReplicas(t *testing.T) {
+	primary, r1, r2 := &surrealdb.DB{}, &surrealdb.DB{}, &surrealdb.DB{}
+	d := &Dialector{Conn: primary}
+	d.pool = newConnPool(primary, nil, 1, 1, 0)
+	d.replicas = []*connPool{newConnPool(r1, nil, 1, 1, 0), newConnPool(r2, nil, 1, 1, 0)}
+
+	stmt := func(ctx context.Context) *gorm.DB {
+		return &gorm.DB{Statement: &gorm.Statement{Context: ctx}}
+	}
+	seen := map[*surrealdb.DB]int{}
+	for i := 0; i < 4; i++ {
+		target, release, err := d.readTarget(stmt(context.Background()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[target.db]++
+		release()
+	}
+	if seen[r1] != 2 || seen[r2] != 2 || seen[primary] != 0 {
+		t.Fatalf("reads should round-robin over replicas, got primary=%d r1=%d r2=%d", seen[primary], seen[r1], seen[r2])
+	}
+
+	target, release, _ := d.readTarget(stmt(UsePrimary(context.Background())))
+	release()
+	if target.db != primary {
+		t.Fatal("UsePrimary must pin reads to the primary")
+	}
+
+	// A replica that can't serve the read falls through to the next one.
+	d.replicas[0].closed = true
+	for i := 0; i < 2; i++ {
+		target, release, _ := d.readTarget(stmt(context.Background()))
+		release()
+		if target.db != r2 {
+			t.Fatal("a closed replica should be skipped")
+		}
+	}
+	d.replicas[1].closed = true
+	if target, release, _ := d.readTarget(stmt(context.Background())); target.db != primary {
+		t.Fatal("with no replica available reads fall back to the primary")
+	} else {
+		release()
+	}
+}
+
+func TestLiveForwarderResubscribe(t *testing.T) {
+	id := &sdkModels.UUID{}
+	first := make(chan connection.Notification, 1)
+	f := newLiveForwarder()
+	go f.run(first, id)
+
+	first <- connection.Notification{Action: connection.CreateAction}
+	if n := <-f.out; n.Action != connection.CreateAction || n.ID != id {
+		t.Fatalf("forwarded %+v, want CREATE with the stable ID", n)
+	}
+
+	// After a reconnect the forwarder moves to the new channel, announces the
+	// gap, and keeps delivering on the same out channel.
+	close(first)
+	second := make(chan connection.Notification, 1)
+	go f.resume(second)
+	if n := <-f.out; n.Action != ActionResubscribed || n.ID != id {
+		t.Fatalf("expected a resubscribed notification, got %+v", n)
+	}
+	second <- connection.Notification{Action: connection.UpdateAction}
+	if n := <-f.out; n.Action != connection.UpdateAction {
+		t.Fatalf("forwarded %+v after resubscribe", n)
+	}
+
+	f.halt()
+	f.halt()
+	if _, ok := <-f.out; ok {
+		t.Fatal("halt should close the out channel")
+	}
+}
+
+func TestLiveResubscribeFailed(t *testing.T) {
+	d := &Dialector{}
+	id := &sdkModels.UUID{}
+	f := newLiveForwarder()
+	go f.run(make(chan connection.Notification), id)
+	h := &liveHandle{d: d, id: id, fwd: f}
+	d.liveConns.Store(id.String(), h)
+
+	// Giving up on a query reaches its consumer, then closes the channel.
+	lost := errors.New("socket gone")
+	go h.fail(lost)
+	if n := <-f.out; n.Action != ActionResubscribeFailed || n.ID != id || n.Result != lost {
+		t.Fatalf("expected a resubscribe-failed notification, got %+v", n)
+	}
+	if _, ok := <-f.out; ok {
+		t.Fatal("the channel should close after a failed resubscribe")
+	}
+	st := d.Stats()
+	if st.LiveQueries != 0 || st.LiveQueriesLost != 1 || st.LastError != lost {
+		t.Fatalf("Stats = %+v", st)
+	}
+	if err := h.resubscribe(context.Background()); err != nil {
+		t.Fatalf("a dropped query is not re-issued, got %v", err)
+	}
+
+	// A query whose session no longer authenticates is given up on after
+	// maxResubscribeAttempts.
+	srv := surrealtest.NewServer()
+	defer srv.Close()
+	d = &Dialector{DSN: srv.DSN()}
+	if _, err := gorm.Open(d, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer d.Close(context.Background())
+	d.ReconnectInterval = time.Millisecond
+	pc, err := d.pool.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc.release()
+	pc.pin()
+	h = &liveHandle{d: d, id: &sdkModels.UUID{}, table: "notes", scoped: true, key: sessionKey{token: "expired"}, conn: pc, sdb: pc.db}
+	d.liveConns.Store(h.id.String(), h)
+	if err := h.resubscribe(context.Background()); err == nil || !strings.Contains(err.Error(), "attempts") {
+		t.Fatalf("expected resubscribe to give up, got %v", err)
+	}
+	if st := d.Stats(); st.LiveQueries != 0 || st.LiveQueriesLost != 1 || pc.pinned.Load() != 0 {
+		t.Fatalf("a lost query must be dropped and unpinned: %+v pinned=%d", st, pc.pinned.Load())
+	}
+}
+
+func TestConnWatchEvents(t *testing.T) {
+	w := &connWatch{}
+	events := make(chan connEvent, 4)
+	w.setHandler(func(ev connEvent) { events <- ev })
+
+	a, b := &watchedConn{watch: w}, &watchedConn{watch: w}
+	w.connected(a)
+	w.dropped(a)
+	w.dropped(a) // polled again while still down
+	w.connected(b)
+	w.dropped(a) // a stale socket no longer counts
+
+	got := []connEvent{<-events, <-events}
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected extra event %v", ev)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if !(got[0] == connDropped && got[1] == connReconnected) && !(got[0] == connReconnected && got[1] == connDropped) {
+		t.Fatalf("events = %v, want one drop and one reconnect", got)
+	}
+	if w.socket() != b {
+		t.Fatal("socket should track the latest connection")
+	}
+}
+
+func TestStatsAndHooks(t *testing.T) {
+	var dropped, failed string
+	d := &Dialector{
+		OnDisconnect:  func(endpoint string) { dropped = endpoint },
+		OnAuthFailure: func(endpoint string, err error) { failed = endpoint },
+	}
+	d.pool = newConnPool(&surrealdb.DB{}, nil, 1, 1, 0)
+	d.replicas = []*connPool{newConnPool(&surrealdb.DB{}, nil, 1, 1, 0)}
+	pc, _ := d.pool.acquire(context.Background())
+	d.liveConns.Store("lq", &liveHandle{})
+
+	d.handleConnEvent(nil, "ws://root:secret@db:8000/rpc?username=root&password=secret", connDropped)
+	if dropped != "ws://db:8000/rpc" {
+		t.Fatalf("OnDisconnect endpoint = %q, credentials must be stripped", dropped)
+	}
+	d.authFailed("wss://db/rpc", errPoolClosed)
+	if failed != "wss://db/rpc" {
+		t.Fatalf("OnAuthFailure endpoint = %q", failed)
+	}
+
+	st := d.Stats()
+	if st.OpenConns != 2 || st.InFlight != 1 || st.LiveQueries != 1 {
+		t.Fatalf("Stats = %+v", st)
+	}
+	if st.LastError != errPoolClosed || st.LastErrorAt.IsZero() {
+		t.Fatalf("Stats.LastError = %v at %v", st.LastError, st.LastErrorAt)
+	}
+	pc.release()
+	if d.Stats().InFlight != 0 {
+		t.Fatal("released statements should leave InFlight")
+	}
+}
+
+// rpcStandIn answers every CBOR RPC request on /rpc with `true`, standing in
+// for a TLS-terminated SurrealDB.
+type rpcStandIn struct {
+	websocket.BuiltinEventHandler
+}
+
+func (rpcStandIn) OnMessage(socket *websocket.Conn, message *websocket.Message) {
+	defer message.Close()
+	var req map[string]any
+	if err := cbor.Unmarshal(message.Bytes(), &req); err != nil {
+		return
+	}
+	res, _ := cbor.Marshal(map[string]any{"id": req["id"], "result": true})
+	_ = socket.WriteMessage(websocket.OpcodeBinary, res)
+}
+
+func TestTLSConfig(t *testing.T) {
+	upgrader := websocket.NewUpgrader(rpcStandIn{}, &websocket.ServerOption{SubProtocols: []string{"cbor"}})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(http.ResponseWriter, *http.Request) {})
+	mux.HandleFunc("/rpc", func(w http.ResponseWriter, r *http.Request) {
+		if socket, err := upgrader.Upgrade(w, r); err == nil {
+			go socket.ReadLoop()
+		}
+	})
+	srv := httptest.NewTLSServer(mux)
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	trusted := &Dialector{TLSConfig: &tls.Config{RootCAs: roots}}
+	untrusted := &Dialector{TLSConfig: &tls.Config{}}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, scheme := range []string{"wss", "https"} {
+		u, _ := url.Parse(strings.Replace(srv.URL, "https", scheme, 1))
+		if _, err := untrusted.dialPlain(ctx, u); err == nil {
+			t.Errorf("%s: expected the self-signed certificate to be rejected", scheme)
+		}
+		if _, err := trusted.dialPlain(ctx, u); err != nil {
+			t.Fatalf("%s: dial with RootCAs: %v", scheme, err)
+		}
+	}
+
+	// The reconnecting path dials the same transport.
+	u, _ := url.Parse(strings.Replace(srv.URL, "https", "wss", 1))
+	sock := trusted.newSocket(connection.NewConfig(u))
+	if err := sock.Connect(ctx); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer sock.Close(ctx)
+	if _, err := sock.Send(ctx, "ping"); err != nil {
+		t.Fatalf("rpc over TLS: %v", err)
+	}
+	if err := sock.Close(ctx); err != nil || !sock.IsClosed() {
+		t.Fatalf("close: %v, closed=%v", err, sock.IsClosed())
+	}
+}
+
+// fakeSigner records the credentials a connection was signed in with.
+type fakeSigner struct{ signIns []surrealdb.Auth }
+
+func (f *fakeSigner) SignIn(_ context.Context, authData any) (string, error) {
+	f.signIns = append(f.signIns, authData.(surrealdb.Auth))
+	return "token", nil
+}
+func (f *fakeSigner) SignUp(context.Context, any) (string, error) { return "", nil }
+func (f *fakeSigner) Authenticate(context.Context, string) error  { return nil }
+
+func TestCredentialsProvider(t *testing.T) {
+	calls := 0
+	d := &Dialector{Credentials: func(context.Context) (Auth, error) {
+		calls++
+		return RootAuth{Username: "root", Password: fmt.Sprintf("rotated-%d", calls)}, nil
+	}}
+	conn := &fakeSigner{}
+	for i := 0; i < 2; i++ {
+		if _, root, err := d.signIn(context.Background(), conn, true); err != nil || !root {
+			t.Fatalf("signIn: root=%v err=%v", root, err)
+		}
+	}
+	if conn.signIns[0].Password != "rotated-1" || conn.signIns[1].Password != "rotated-2" {
+		t.Errorf("each sign-in must ask the provider again, got %+v", conn.signIns)
+	}
+
+	failing := &Dialector{Credentials: func(context.Context) (Auth, error) { return nil, errors.New("vault sealed") }}
+	if _, _, err := failing.signIn(context.Background(), conn, false); err == nil || !strings.Contains(err.Error(), "vault sealed") {
+		t.Errorf("provider error must surface, got %v", err)
+	}
+}
+
+func TestRetryExpired(t *testing.T) {
+	expired := errors.New("There was a problem with authentication: The token has expired")
+	reauths := 0
+	target := rpcTarget{reauth: func(context.Context) error { reauths++; return nil }}
+
+	attempts := 0
+	res, err := retryExpired(context.Background(), target, func() (int, error) {
+		attempts++
+		if attempts == 1 {
+			return 0, expired
+		}
+		return 42, nil
+	})
+	if err != nil || res != 42 || attempts != 2 || reauths != 1 {
+		t.Fatalf("res=%d err=%v attempts=%d reauths=%d", res, err, attempts, reauths)
+	}
+
+	// Retried once only, and never for other errors or non-renewable targets.
+	attempts = 0
+	_, err = retryExpired(context.Background(), target, func() (int, error) { attempts++; return 0, expired })
+	if err == nil || attempts != 2 {
+		t.Errorf("expected one retry, got attempts=%d err=%v", attempts, err)
+	}
+	attempts = 0
+	_, _ = retryExpired(context.Background(), target, func() (int, error) { attempts++; return 0, errors.New("parse error") })
+	_, _ = retryExpired(context.Background(), rpcTarget{}, func() (int, error) { attempts++; return 0, expired })
+	if attempts != 2 {
+		t.Errorf("unexpected retries: %d attempts", attempts)
+	}
+}
+
+func TestCloseViaSQLDB(t *testing.T) {
+	primary, replica := &surrealdb.DB{}, &surrealdb.DB{}
+	d := &Dialector{Conn: primary, ownsConn: true}
+	var closed []*surrealdb.DB
+	record := func(_ context.Context, db *surrealdb.DB) { closed = append(closed, db) }
+	d.pool = newConnPool(primary, nil, 1, 1, 0)
+	d.pool.closeConn = record
+	rp := newConnPool(replica, nil, 1, 1, 0)
+	rp.closeConn = record
+	d.replicas = []*connPool{rp}
+	d.sqlDB = sql.OpenDB(&sdConnector{dialector: d})
+
+	sqlDB, err := d.GetDBConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sqlDB.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if len(closed) != 2 {
+		t.Fatalf("every pooled connection must be closed, got %d", len(closed))
+	}
+	if _, _, err := d.acquire(context.Background()); !errors.Is(err, errPoolClosed) {
+		t.Fatalf("statements after Close must fail, got %v", err)
+	}
+	if err := d.Close(context.Background()); err != nil || len(closed) != 2 {
+		t.Fatalf("Close must be idempotent: err=%v closed=%d", err, len(closed))
+	}
+}
+
+type tapedNote struct {
+	localModels.BaseModel
+	Title string `json:"title"`
+}
+
+func TestRecordReplay(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "notes.jsonl")
+	srv := surrealtest.NewServer()
+	session := func(d *Dialector) []tapedNote {
+		t.Helper()
+		db, err := gorm.Open(d, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		defer d.Close(context.Background())
+		if err := db.AutoMigrate(&tapedNote{}); err != nil {
+			t.Fatalf("migrate: %v", err)
+		}
+		if err := db.Create(&tapedNote{Title: "first"}).Error; err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		var notes []tapedNote
+		if err := db.Where("title = ?", "first").Find(&notes).Error; err != nil {
+			t.Fatalf("find: %v", err)
+		}
+		if err := db.Exec("THROW 'boom'").Error; err == nil || !strings.Contains(err.Error(), "boom") {
+			t.Fatalf("server errors must be kept, got %v", err)
+		}
+		return notes
+	}
+
+	recorded := session(&Dialector{DSN: srv.DSN(), Record: golden})
+	srv.Close()
+	data, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"query":"SELECT * FROM`) {
+		t.Errorf("golden file should carry the SurrealQL:\n%s", data)
+	}
+	if strings.Contains(string(data), surrealtest.RootPassword+`"`) {
+		t.Errorf("credentials must be redacted:\n%s", data)
+	}
+
+	replayed := session(&Dialector{DSN: srv.DSN(), Replay: golden})
+	if len(replayed) != 1 || len(recorded) != 1 || replayed[0].Title != "first" ||
+		replayed[0].ID.String() != recorded[0].ID.String() {
+		t.Fatalf("replay diverged: recorded %+v, replayed %+v", recorded, replayed)
+	}
+
+	strict := &Dialector{DSN: srv.DSN(), Replay: golden}
+	db, err := gorm.Open(strict, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer strict.Close(context.Background())
+	if err := db.Exec("DEFINE TABLE unrecorded").Error; err == nil || !strings.Contains(err.Error(), "no recorded") {
+		t.Errorf("unrecorded statements must fail, got %v", err)
+	}
+}
+
+func TestRecordRange(t *testing.T) {
+	tests := []struct {
+		after *sdkModels.RecordID
+		want  string
+	}{
+		{nil, "`users`:.."},
+		{&sdkModels.RecordID{Table: "users", ID: "ab1"}, "`users`:⟨ab1⟩>.."},
+		{&sdkModels.RecordID{Table: "users", ID: "a⟩b"}, "`users`:⟨a\\⟩b⟩>.."},
+		{&sdkModels.RecordID{Table: "users", ID: int64(42)}, "`users`:42>.."},
+		{&sdkModels.RecordID{Table: "users", ID: []interface{}{"a", 1}}, ""},
+	}
+	for _, tt := range tests {
+		if got := recordRange("users", tt.after); got != tt.want {
+			t.Errorf("recordRange(%v) = %q, want %q", tt.after, got, tt.want)
+		}
+	}
+}